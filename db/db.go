@@ -0,0 +1,14 @@
+// Package db はマイグレーションと初期データのSQLファイルをバイナリに埋め込む
+package db
+
+import "embed"
+
+// Migrations ... バージョン付きマイグレーションファイル（{version}_{name}.up.sql / .down.sql）
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+// Seeds ... 初期データ投入用のSQLファイル（ファイル名順に実行され、何度実行しても同じ結果になる）
+//
+//go:embed seeds/*.sql
+var Seeds embed.FS
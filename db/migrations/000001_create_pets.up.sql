@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS pets
+(
+    id               TEXT PRIMARY KEY,
+    name             TEXT    NOT NULL,
+    breed            TEXT    NOT NULL,
+    gender           TEXT    NOT NULL,
+    price            NUMERIC NOT NULL,
+    image_url        TEXT,
+    likes            INTEGER NOT NULL,
+    shop_name        TEXT    NOT NULL,
+    shop_location    TEXT   NOT NULL,
+    birth_date       DATE,
+    reference_number TEXT   NOT NULL,
+    tags             TEXT[]  NOT NULL,
+    created_at       TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at       TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
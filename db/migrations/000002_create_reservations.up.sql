@@ -0,0 +1,28 @@
+-- 新規に作成する reservations テーブル
+CREATE TABLE IF NOT EXISTS reservations
+(
+    -- 予約ごとに一意のIDを持たせる (UUID, SERIALなど)
+    id int GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    -- ユーザを識別するID（外部の認証IDや社内システムIDなど任意）
+    user_id        TEXT    NOT NULL,
+    -- ユーザの氏名
+    user_name      TEXT    NOT NULL,
+    -- ユーザのメールアドレス
+    email          TEXT    NOT NULL,
+    -- 見学予定日時
+    reservation_date_time TIMESTAMP NOT NULL,
+    -- 予約ステータス pending, confirmed, cancelled
+    status TEXT NOT NULL,
+    -- 予約対象のペットID
+    -- petsテーブルのidを参照 (FK)
+    pet_id         TEXT    NOT NULL REFERENCES pets (id) ON DELETE CASCADE,
+    -- 予約レコードが作られた日時
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    -- 予約レコードが更新された日時
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes for reservations table
+CREATE INDEX IF NOT EXISTS idx_reservations_pet_id ON reservations(pet_id);
+CREATE INDEX IF NOT EXISTS idx_reservations_user_id ON reservations(user_id);
+CREATE INDEX IF NOT EXISTS idx_reservations_status ON reservations(status);
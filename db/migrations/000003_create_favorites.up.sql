@@ -0,0 +1,16 @@
+-- お気に入りを管理するテーブル
+CREATE TABLE IF NOT EXISTS favorites
+(
+    -- 予約ごとに一意のIDを持たせる (UUID, SERIALなど)
+    id int GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    -- ユーザを識別するID（外部の認証IDや社内システムIDなど任意）
+    user_id        TEXT    NOT NULL,
+    -- 予約対象のペットID
+    -- petsテーブルのidを参照 (FK)
+    pet_id         TEXT    NOT NULL REFERENCES pets (id) ON DELETE CASCADE
+);
+
+-- Indexes for favorites table
+CREATE INDEX IF NOT EXISTS idx_favorites_user_id ON favorites(user_id);
+-- Composite unique index to ensure one favorite per user-pet combination
+CREATE UNIQUE INDEX IF NOT EXISTS idx_favorites_user_pet ON favorites(user_id, pet_id);
@@ -0,0 +1,23 @@
+-- 通知を管理するテーブル
+CREATE TABLE IF NOT EXISTS notifications
+(
+    -- 通知ごとに一意のIDを持たせる
+    id int GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    -- ユーザを識別するID
+    user_id        TEXT    NOT NULL,
+    -- 通知のタイトル
+    title          TEXT    NOT NULL,
+    -- 通知のメッセージ内容
+    message        TEXT    NOT NULL,
+    -- 既読状態
+    is_read        BOOLEAN NOT NULL DEFAULT FALSE,
+    -- 通知の種類
+    type           TEXT    NOT NULL,
+    -- 通知レコードが作られた日時
+    created_at     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    -- 通知レコードが更新された日時
+    updated_at     TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes for notifications table
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id);
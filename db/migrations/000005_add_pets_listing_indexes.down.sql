@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_pets_tags;
+DROP INDEX IF EXISTS idx_pets_created_at_id;
+DROP INDEX IF EXISTS idx_pets_birth_date_id;
+DROP INDEX IF EXISTS idx_pets_likes_id;
+DROP INDEX IF EXISTS idx_pets_price_id;
@@ -0,0 +1,8 @@
+-- GET /v1/petsの並び替え・キーセットページング用のインデックス
+CREATE INDEX IF NOT EXISTS idx_pets_price_id ON pets (price, id);
+CREATE INDEX IF NOT EXISTS idx_pets_likes_id ON pets (likes, id);
+CREATE INDEX IF NOT EXISTS idx_pets_birth_date_id ON pets (birth_date, id);
+CREATE INDEX IF NOT EXISTS idx_pets_created_at_id ON pets (created_at, id);
+
+-- タグの包含検索（tags @> ARRAY[...]）用のインデックス
+CREATE INDEX IF NOT EXISTS idx_pets_tags ON pets USING GIN (tags);
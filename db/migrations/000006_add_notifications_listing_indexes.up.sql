@@ -0,0 +1,5 @@
+-- GET /v1/notificationsの新しい順のキーセットページング用のインデックス
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id_id ON notifications (user_id, id DESC);
+
+-- GET /v1/notifications/unread_count用の未読通知の部分インデックス
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id_unread ON notifications (user_id, type) WHERE is_read = false;
@@ -0,0 +1,25 @@
+-- 業務データの変更と同じトランザクションで書き込み、リレーが外部に配信するイベントを管理するテーブル
+CREATE TABLE IF NOT EXISTS outbox
+(
+    -- イベントごとに一意のIDを持たせる（配信先での重複排除にも使う）
+    id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    -- イベントの名前（pet.liked, reservation.createdなど）
+    event_type      TEXT      NOT NULL,
+    -- イベントの内容
+    payload         JSONB     NOT NULL,
+    -- 配信状態 pending, published, dead
+    status          TEXT      NOT NULL DEFAULT 'pending',
+    -- 配信を試みた回数
+    attempts        INT       NOT NULL DEFAULT 0,
+    -- 次に配信を試みる日時（失敗時はバックオフして後ろにずらす）
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- 直近の配信失敗の内容
+    last_error      TEXT,
+    -- 配信に成功した日時
+    published_at    TIMESTAMP,
+    -- イベントが作られた日時
+    created_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- リレーが配信待ちのイベントを古い順に取得するための部分インデックス
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (next_attempt_at, id) WHERE status = 'pending';
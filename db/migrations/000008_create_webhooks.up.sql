@@ -0,0 +1,80 @@
+-- 提携店舗が登録したWebhookの送信先を管理するテーブル
+CREATE TABLE IF NOT EXISTS webhook_subscriptions
+(
+    -- 送信先ごとに一意のIDを持たせる
+    id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    -- 送信対象のペットを扱う店舗
+    shop_name  TEXT      NOT NULL,
+    -- 購読するイベントの名前（pet.liked, reservation.createdなど）
+    event_type TEXT      NOT NULL,
+    -- イベントをPOSTするURL
+    url        TEXT      NOT NULL,
+    -- 署名に使う共有鍵
+    secret     TEXT      NOT NULL,
+    -- falseの場合は送信しない
+    active     BOOLEAN   NOT NULL DEFAULT TRUE,
+    -- 送信先が登録された日時
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    -- 送信先が更新された日時
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- イベントの送信先を店舗とイベントの名前で検索するためのインデックス
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_shop_event ON webhook_subscriptions (shop_name, event_type);
+
+-- 送信先ごとのイベントの送信状態を管理するテーブル
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    -- 送信ごとに一意のIDを持たせる（送信先での重複排除にも使う）
+    id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    -- 送信先
+    subscription_id bigint    NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    -- 送信するイベント（outboxのID）
+    event_id        bigint    NOT NULL,
+    -- イベントの名前
+    event_type      TEXT      NOT NULL,
+    -- イベントの内容
+    payload         JSONB     NOT NULL,
+    -- 送信状態 pending, succeeded, dead
+    status          TEXT      NOT NULL DEFAULT 'pending',
+    -- 送信を試みた回数
+    attempts        INT       NOT NULL DEFAULT 0,
+    -- 次に送信を試みる日時（失敗時はバックオフして後ろにずらす）
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- 直近の送信の応答ステータスコード（応答がなかった場合はNULL）
+    response_code   INT,
+    -- 直近の送信失敗の内容
+    last_error      TEXT,
+    -- 送信に成功した日時
+    delivered_at    TIMESTAMP,
+    -- 送信が作られた日時
+    created_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 送信待ちを古い順に取得するための部分インデックス
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries (next_attempt_at, id) WHERE status = 'pending';
+-- 送信先ごとの送信履歴を取得するためのインデックス
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id, id);
+-- 同じイベントを二重に送信しないよう確認するためのインデックス
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries (event_id);
+
+-- 送信の試行ごとの結果を記録するテーブル
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts
+(
+    -- 試行ごとに一意のIDを持たせる
+    id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    -- 試行した送信
+    delivery_id   bigint    NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+    -- 何回目の試行か
+    attempt       INT       NOT NULL,
+    -- 応答ステータスコード（応答がなかった場合はNULL）
+    response_code INT,
+    -- 失敗した場合の内容
+    error         TEXT,
+    -- 応答までの時間（ミリ秒）
+    duration_ms   BIGINT    NOT NULL,
+    -- 試行した日時
+    attempted_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery ON webhook_delivery_attempts (delivery_id, attempt);
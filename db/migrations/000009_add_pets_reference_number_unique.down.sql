@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_pets_reference_number;
@@ -0,0 +1,2 @@
+-- 管理番号はペットごとに一意にする（管理APIで登録・変更する際の重複を防ぐ）
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pets_reference_number ON pets (reference_number);
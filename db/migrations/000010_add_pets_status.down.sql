@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_pets_status;
+ALTER TABLE pets DROP COLUMN IF EXISTS status;
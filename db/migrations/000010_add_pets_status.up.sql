@@ -0,0 +1,16 @@
+-- ペットの販売状況（available: 予約可能, reserved: 見学予約あり, adopted: 成約済み, withdrawn: 掲載終了）
+ALTER TABLE pets
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'available'
+        CHECK (status IN ('available', 'reserved', 'adopted', 'withdrawn'));
+
+-- 有効な見学予約（pending, confirmed）が既にあるペットは予約中とする
+UPDATE pets
+SET status = 'reserved'
+WHERE status = 'available'
+  AND EXISTS (SELECT 1
+              FROM reservations r
+              WHERE r.pet_id = pets.id
+                AND r.status IN ('pending', 'confirmed'));
+
+-- GET /v1/petsのstatusでの絞り込み用のインデックス
+CREATE INDEX IF NOT EXISTS idx_pets_status ON pets (status);
@@ -0,0 +1,19 @@
+DROP VIEW IF EXISTS pets_with_shop;
+DROP INDEX IF EXISTS idx_pets_shop_id;
+
+ALTER TABLE pets
+    ADD COLUMN IF NOT EXISTS shop_name TEXT,
+    ADD COLUMN IF NOT EXISTS shop_location TEXT;
+
+UPDATE pets
+SET shop_name     = s.name,
+    shop_location = s.location
+FROM shops s
+WHERE s.id = pets.shop_id;
+
+ALTER TABLE pets
+    ALTER COLUMN shop_name SET NOT NULL,
+    ALTER COLUMN shop_location SET NOT NULL,
+    DROP COLUMN shop_id;
+
+DROP TABLE IF EXISTS shops;
@@ -0,0 +1,49 @@
+-- ペットを扱う店舗を管理するテーブル
+CREATE TABLE IF NOT EXISTS shops
+(
+    -- 店舗ごとに一意のIDを持たせる
+    id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    -- 店舗名
+    name       TEXT      NOT NULL,
+    -- 所在地
+    location   TEXT      NOT NULL,
+    -- 店舗が登録された日時
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    -- 店舗が更新された日時
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (name, location)
+);
+
+-- 既存のペットが持つ店舗名と所在地の組から店舗を作成する
+INSERT INTO shops (name, location)
+SELECT DISTINCT shop_name, shop_location
+FROM pets
+ORDER BY shop_name, shop_location
+ON CONFLICT (name, location) DO NOTHING;
+
+-- ペットから店舗を参照する
+ALTER TABLE pets
+    ADD COLUMN IF NOT EXISTS shop_id bigint REFERENCES shops (id);
+
+UPDATE pets
+SET shop_id = s.id
+FROM shops s
+WHERE s.name = pets.shop_name
+  AND s.location = pets.shop_location;
+
+ALTER TABLE pets
+    ALTER COLUMN shop_id SET NOT NULL,
+    DROP COLUMN shop_name,
+    DROP COLUMN shop_location;
+
+-- GET /v1/shops/:id/petsでの絞り込み用のインデックス
+CREATE INDEX IF NOT EXISTS idx_pets_shop_id ON pets (shop_id);
+
+-- ペットの参照時に店舗名と所在地を結合するビュー
+-- p.*はビューの作成時に展開されるため、petsにカラムを追加した場合はビューを作り直す
+CREATE OR REPLACE VIEW pets_with_shop AS
+SELECT p.*,
+       s.name     AS shop_name,
+       s.location AS shop_location
+FROM pets p
+         JOIN shops s ON s.id = p.shop_id;
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS uq_reservations_slot_seat;
+
+ALTER TABLE reservations
+    DROP COLUMN IF EXISTS slot_seat,
+    DROP COLUMN IF EXISTS slot_timezone,
+    DROP COLUMN IF EXISTS slot_starts_at,
+    DROP COLUMN IF EXISTS shop_id;
+
+DROP TABLE IF EXISTS shop_opening_hours;
+
+ALTER TABLE shops
+    DROP COLUMN IF EXISTS slot_capacity,
+    DROP COLUMN IF EXISTS slot_minutes,
+    DROP COLUMN IF EXISTS timezone;
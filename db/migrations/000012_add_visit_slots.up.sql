@@ -0,0 +1,39 @@
+-- 店舗ごとの見学予約の受付設定（予約枠は営業時間をslot_minutesごとに区切り、1枠でslot_capacity件まで受け付ける）
+ALTER TABLE shops
+    ADD COLUMN IF NOT EXISTS timezone      TEXT NOT NULL DEFAULT 'Asia/Tokyo',
+    ADD COLUMN IF NOT EXISTS slot_minutes  INT  NOT NULL DEFAULT 60 CHECK (slot_minutes BETWEEN 5 AND 480),
+    ADD COLUMN IF NOT EXISTS slot_capacity INT  NOT NULL DEFAULT 2 CHECK (slot_capacity BETWEEN 1 AND 100);
+
+-- 曜日ごとの営業時間（登録のない曜日は定休日）
+CREATE TABLE IF NOT EXISTS shop_opening_hours
+(
+    shop_id   bigint   NOT NULL REFERENCES shops (id) ON DELETE CASCADE,
+    -- 0: 日曜日 〜 6: 土曜日
+    weekday   SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    -- 店舗のタイムゾーンでの開店・閉店時刻（hh:mm）
+    opens_at  TEXT     NOT NULL CHECK (opens_at ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'),
+    closes_at TEXT     NOT NULL CHECK (closes_at ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$'),
+    PRIMARY KEY (shop_id, weekday),
+    CHECK (opens_at < closes_at)
+);
+
+-- 営業時間が未登録の店舗は毎日10:00〜18:00とする
+INSERT INTO shop_opening_hours (shop_id, weekday, opens_at, closes_at)
+SELECT s.id, d.weekday, '10:00', '18:00'
+FROM shops s
+         CROSS JOIN generate_series(0, 6) AS d(weekday)
+WHERE NOT EXISTS (SELECT 1 FROM shop_opening_hours h WHERE h.shop_id = s.id);
+
+-- 見学予約が確保した予約枠（既存の予約は枠を持たない）
+ALTER TABLE reservations
+    ADD COLUMN IF NOT EXISTS shop_id        bigint REFERENCES shops (id),
+    -- 予約枠の開始日時
+    ADD COLUMN IF NOT EXISTS slot_starts_at TIMESTAMPTZ,
+    -- 予約時の店舗のタイムゾーン（slot_starts_atを現地時刻で返すために使う）
+    ADD COLUMN IF NOT EXISTS slot_timezone  TEXT,
+    -- 予約枠の席番号（1〜定員）。キャンセルした予約はNULLにして席を空ける
+    ADD COLUMN IF NOT EXISTS slot_seat      INT;
+
+-- 同じ枠の同じ席は1件の予約しか確保できないため、同時に予約されても定員を超えない
+-- （NULLは重複とみなされないため、枠を持たない予約・キャンセルした予約は対象外となる）
+CREATE UNIQUE INDEX IF NOT EXISTS uq_reservations_slot_seat ON reservations (shop_id, slot_starts_at, slot_seat);
@@ -0,0 +1,23 @@
+-- Idempotency-Keyヘッダーを指定したリクエストの処理結果を保持するテーブル
+-- 同じキーで再送されたリクエストには保存したレスポンスを返し、処理を重複させない
+CREATE TABLE IF NOT EXISTS idempotency_keys
+(
+    -- リクエスト元（ユーザーID・サービス名。認証が無効な場合は空文字）
+    principal       TEXT        NOT NULL,
+    -- クライアントが指定したIdempotency-Keyヘッダーの値
+    idempotency_key TEXT        NOT NULL,
+    -- メソッド・パス・リクエストボディのSHA-256（同じキーで異なるリクエストが送られたことを検出する）
+    fingerprint     TEXT        NOT NULL,
+    -- 保存したレスポンス（処理中の場合はNULL）
+    status_code     INT,
+    content_type    TEXT,
+    response_body   TEXT,
+    -- 処理を開始した日時
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- 処理結果を破棄する日時（経過後は同じキーを新しいリクエストとして扱う）
+    expires_at      TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (principal, idempotency_key)
+);
+
+-- 期限切れの処理結果を削除するためのインデックス
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
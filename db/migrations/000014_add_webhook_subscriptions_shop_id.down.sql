@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS idx_webhook_subscriptions_shop_event;
+
+ALTER TABLE webhook_subscriptions
+    ADD COLUMN IF NOT EXISTS shop_name TEXT;
+
+UPDATE webhook_subscriptions
+SET shop_name = s.name
+FROM shops s
+WHERE s.id = webhook_subscriptions.shop_id;
+
+ALTER TABLE webhook_subscriptions
+    ALTER COLUMN shop_name SET NOT NULL,
+    DROP COLUMN shop_id;
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_shop_event ON webhook_subscriptions (shop_name, event_type);
@@ -0,0 +1,21 @@
+-- Webhookの送信先から店舗を店舗名ではなくIDで参照する
+ALTER TABLE webhook_subscriptions
+    ADD COLUMN IF NOT EXISTS shop_id bigint REFERENCES shops (id);
+
+-- 同じ店舗名の店舗が複数ある場合は最初に登録された店舗を参照する
+UPDATE webhook_subscriptions
+SET shop_id = (SELECT MIN(s.id) FROM shops s WHERE s.name = webhook_subscriptions.shop_name);
+
+-- 店舗名に一致する店舗がない送信先は、いずれのペットのイベントも送信されないため削除する
+DELETE
+FROM webhook_subscriptions
+WHERE shop_id IS NULL;
+
+DROP INDEX IF EXISTS idx_webhook_subscriptions_shop_event;
+
+ALTER TABLE webhook_subscriptions
+    ALTER COLUMN shop_id SET NOT NULL,
+    DROP COLUMN shop_name;
+
+-- イベントの送信先を店舗とイベントの名前で検索するためのインデックス
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_shop_event ON webhook_subscriptions (shop_id, event_type);
@@ -0,0 +1,187 @@
+-- ペットと店舗の初期データ
+-- 既に存在する行はスキップするため、何度実行しても結果は変わらない
+INSERT INTO shops (name, location)
+VALUES ('uma-arai shop 2nd', 'Kanagawa')
+ON CONFLICT (name, location) DO NOTHING;
+
+INSERT INTO pets (
+    id,
+    name,
+    breed,
+    gender,
+    price,
+    image_url,
+    likes,
+    shop_id,
+    birth_date,
+    reference_number,
+    tags
+) VALUES
+-- 1
+('1',
+ 'cute cat',
+ 'brown cat',
+ 'Male',
+ 360000,
+ 'https://images.unsplash.com/photo-1583083527882-4bee9aba2eea?ixlib=rb-1.2.1&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=777&q=80',
+ 10,
+ (SELECT id FROM shops WHERE name = 'uma-arai shop 2nd' AND location = 'Kanagawa'),
+ '2023-10-14',
+ '0000001',
+ '{"cute","famous","cool","new"}'
+),
+
+-- 2
+('2',
+ 'サイベリアン',
+ 'Siberian cat',
+ 'Female',
+ 400000,
+ 'https://images.unsplash.com/photo-1586289883499-f11d28aaf52f?ixlib=rb-1.2.1&ixid=MnwxMjA3fDB8MHxleHBsb3JlLWZlZWR8OHx8fGVufDB8fHx8&auto=format&fit=crop&w=500&q=60',
+ 3,
+ (SELECT id FROM shops WHERE name = 'uma-arai shop 2nd' AND location = 'Kanagawa'),
+ '2023-10-14',
+ '0000002',
+ '{"cute","famous","cool","on-sale"}'
+),
+
+-- 3
+('3',
+ 'Red cat',
+ 'red cat',
+ 'Male',
+ 240000,
+ 'https://images.unsplash.com/photo-1606491048802-8342506d6471?ixlib=rb-1.2.1&ixid=MnwxMjA3fDB8MHxleHBsb3JlLWZlZWR8MTF8fHxlbnwwfHx8fA%3D%3D&auto=format&fit=crop&w=500&q=60',
+ 7,
+ (SELECT id FROM shops WHERE name = 'uma-arai shop 2nd' AND location = 'Kanagawa'),
+ '2023-10-14',
+ '0000003',
+ '{"cute","famous","cool"}'
+),
+
+-- 4
+('4',
+ 'cute kitten',
+ 'white cat',
+ 'Female',
+ 550000,
+ 'https://images.unsplash.com/photo-1605450648855-63f9161b7ef7?ixlib=rb-1.2.1&ixid=MnwxMjA3fDB8MHxzZWFyY2h8ODZ8fGtpdHRlbnxlbnwwfHwwfHw%3D&auto=format&fit=crop&w=500&q=60',
+ 12,
+ (SELECT id FROM shops WHERE name = 'uma-arai shop 2nd' AND location = 'Kanagawa'),
+ '2023-10-14',
+ '0000004',
+ '{"cute","famous","cool"}'
+),
+
+-- 5
+('5',
+ 'Matcha',
+ 'Minuet',
+ 'Male',
+ 400000,
+ 'https://pbs.twimg.com/media/FaxOK5HUIAANRYo?format=jpg&name=4096x4096',
+ 5,
+ (SELECT id FROM shops WHERE name = 'uma-arai shop 2nd' AND location = 'Kanagawa'),
+ '2023-10-14',
+ '0000005',
+ '{"くりくりの目","きれいな毛並み","おてんば"}'
+),
+
+-- 6 (id: uma-chan)
+('77',
+ 'uma-chan',
+ 'kage',
+ 'Female',
+ 49800000,
+ 'https://images.unsplash.com/photo-1557413606-2a63a06a1f1d?ixlib=rb-1.2.1&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=500&q=60',
+ 15,
+ (SELECT id FROM shops WHERE name = 'uma-arai shop 2nd' AND location = 'Kanagawa'),
+ '2023-10-14',
+ '0000006',
+ '{"cute","famous","cool"}'
+),
+
+-- 7 (id: arai-san)
+('100',
+ 'arai-san',
+ 'white cat',
+ 'Male',
+ 50000,
+ 'https://images.unsplash.com/photo-1601247387326-f8bcb5a234d4?ixlib=rb-1.2.1&ixid=MnwxMjA3fDB8MHxwaG90by1wYWdlfHx8fGVufDB8fHx8&auto=format&fit=crop&w=500&q=60',
+ 9,
+ (SELECT id FROM shops WHERE name = 'uma-arai shop 2nd' AND location = 'Kanagawa'),
+ '2023-10-14',
+ '0000007',
+ '{"cute","famous","cool"}'
+),
+
+-- 8 (id: 6)
+('6',
+ 'cute kitten',
+ 'white cat',
+ 'Female',
+ 550000,
+ 'https://images.unsplash.com/photo-1597626133663-53df9633b799?ixlib=rb-1.2.1&ixid=MnwxMjA3fDB8MHxleHBsb3JlLWZlZWR8MTV8fHxlbnwwfHx8fA%3D%3D&auto=format&fit=crop&w=500&q=60',
+ 2,
+ (SELECT id FROM shops WHERE name = 'uma-arai shop 2nd' AND location = 'Kanagawa'),
+ '2023-10-14',
+ '0000008',
+ '{"cute","famous","cool"}'
+),
+
+-- 9 (id: 7)
+('7',
+ 'cute kitten',
+ 'white cat',
+ 'Male',
+ 550000,
+ 'https://images.unsplash.com/photo-1621238281284-d186cb6813fb?ixlib=rb-1.2.1&ixid=MnwxMjA3fDB8MHxzZWFyY2h8MTh8fGtpdHRlbnxlbnwwfHwwfHw%3D&auto=format&fit=crop&w=500&q=60',
+ 11,
+ (SELECT id FROM shops WHERE name = 'uma-arai shop 2nd' AND location = 'Kanagawa'),
+ '2023-10-14',
+ '0000009',
+ '{"cute","famous","cool"}'
+),
+
+-- 10 (id: 8)
+('8',
+ 'cute kitten',
+ 'white cat',
+ 'Female',
+ 550000,
+ 'https://images.unsplash.com/photo-1557166984-b00337652c94?ixlib=rb-1.2.1&ixid=MnwxMjA3fDB8MHxzZWFyY2h8MTl8fGtpdHRlbnxlbnwwfHwwfHw%3D&auto=format&fit=crop&w=500&q=60',
+ 4,
+ (SELECT id FROM shops WHERE name = 'uma-arai shop 2nd' AND location = 'Kanagawa'),
+ '2023-10-14',
+ '0000010',
+ '{"cute","famous","cool"}'
+),
+
+-- 11 (id: 9)
+('9',
+ 'cute kitten',
+ 'white cat',
+ 'Male',
+ 550000,
+ 'https://images.unsplash.com/flagged/photo-1557427161-4701a0fa2f42?ixlib=rb-1.2.1&ixid=MnwxMjA3fDB8MHxzZWFyY2h8Mjh8fGtpdHRlbnxlbnwwfHwwfHw%3D&auto=format&fit=crop&w=500&q=60',
+ 8,
+ (SELECT id FROM shops WHERE name = 'uma-arai shop 2nd' AND location = 'Kanagawa'),
+ '2023-10-14',
+ '0000011',
+ '{"cute","famous","cool"}'
+),
+
+-- 12 (id: 10)
+('10',
+ 'cute kitten',
+ 'white cat',
+ 'Female',
+ 550000,
+ 'https://images.unsplash.com/photo-1582797493098-23d8d0cc6769?ixlib=rb-1.2.1&ixid=MnwxMjA3fDB8MHxzZWFyY2h8NjZ8fGtpdHRlbnxlbnwwfHwwfHw%3D&auto=format&fit=crop&w=500&q=60',
+ 6,
+ (SELECT id FROM shops WHERE name = 'uma-arai shop 2nd' AND location = 'Kanagawa'),
+ '2023-10-14',
+ '0000012',
+ '{"cute","famous","cool"}'
+)
+ON CONFLICT (id) DO NOTHING;
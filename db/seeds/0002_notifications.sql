@@ -0,0 +1,19 @@
+-- Notification test data
+-- 同一ユーザ・同一タイトルの通知が既に存在する場合はスキップする
+INSERT INTO notifications (
+    user_id,
+    title,
+    message,
+    is_read,
+    type
+)
+SELECT v.user_id, v.title, v.message, v.is_read, v.type
+FROM (VALUES
+('user1', '新しい子が増えました', '可愛い「あらいさん」が新しく増えました。ぜひチェックしてください！', false, 'new_pet'),
+('user1', 'お気に入りペットの価格変更', 'お気に入り登録しているペットの価格が変更されました。', false, 'price_change'),
+('user2', '予約確認', 'お目当ての子：「うまちゃん」。', true, 'reservation'),
+('user1', 'キャンペーン情報', '今週末限定のキャンペーンが開始されました！', false, 'campaign')
+) AS v(user_id, title, message, is_read, type)
+WHERE NOT EXISTS (
+    SELECT 1 FROM notifications n WHERE n.user_id = v.user_id AND n.title = v.title
+);
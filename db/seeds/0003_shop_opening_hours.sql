@@ -0,0 +1,7 @@
+-- 店舗の営業時間の初期データ（毎日10:00〜18:00）
+-- 営業時間が登録済みの店舗はスキップするため、何度実行しても結果は変わらない
+INSERT INTO shop_opening_hours (shop_id, weekday, opens_at, closes_at)
+SELECT s.id, d.weekday, '10:00', '18:00'
+FROM shops s
+         CROSS JOIN generate_series(0, 6) AS d(weekday)
+WHERE NOT EXISTS (SELECT 1 FROM shop_opening_hours h WHERE h.shop_id = s.id);
@@ -42,11 +42,21 @@ func (e *businessError) HTTPStatus() int {
 	return e.def.StatusCode
 }
 
+// Message localeのメッセージを返す
+// 該当するメッセージがない場合は地域を除いた言語（ja-JP -> ja）、DefaultLocaleの順に探す
 func (e *businessError) Message(locale string) string {
-	if msg, ok := e.def.Message[locale]; ok {
-		return msg
+	for _, l := range fallbackLocales(locale) {
+		if msg, ok := e.def.Message[l]; ok {
+			return msg
+		}
 	}
-	return e.def.Message["en"]
+	// DefaultLocaleのメッセージも定義されていない場合はいずれかのメッセージを返す
+	for _, l := range SupportedLocales {
+		if msg, ok := e.def.Message[l]; ok {
+			return msg
+		}
+	}
+	return e.code
 }
 
 // 5xxは非公開、4xxは公開
@@ -67,23 +77,36 @@ func (e *businessError) Unwrap() error {
 func NewBusinessError(code string, originalErr error) BusinessError {
 	def, ok := messages[code]
 	if !ok {
-		// 定義漏れ時のフォールバック
-		def = messageDef{
-			StatusCode:  500,
-			MessageCode: "internal_error",
-			Message:     map[string]string{"en": "internal error"},
-		}
+		// 定義漏れ時のフォールバック（定義漏れはmessages_test.goで検出する）
+		def = messages[internalErrorCode]
 	}
 	return &businessError{code: code, def: def, originalErr: originalErr}
 }
 
+// internalErrorCode 予期せぬエラーのエラーコード
+const internalErrorCode = "99999E"
+
+// ErrorResponse エラーレスポンス
+type ErrorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
 // NewEchoHTTPError errorを受け取り、エラーがBusinessErrorかどうかを判定
-// 最終的にはecho.NewHTTPErrorを返す
+// 最終的にはcontextのロケールに応じたErrorResponseを持つecho.NewHTTPErrorを返す
 func NewEchoHTTPError(ctx context.Context, err error) *echo.HTTPError {
+	locale := LocaleFromContext(ctx)
+	requestID := RequestIDFromContext(ctx)
+
 	var be BusinessError
 	if errors.As(err, &be) {
 		if be.IsPublic() {
-			return echo.NewHTTPError(be.HTTPStatus(), be.Error())
+			return echo.NewHTTPError(be.HTTPStatus(), ErrorResponse{
+				Code:      be.Code(),
+				Message:   be.Message(locale),
+				RequestID: requestID,
+			})
 		}
 		// 5xxエラーは詳細を隠しつつlogに記録
 		if be.OriginalError() != nil {
@@ -92,8 +115,18 @@ func NewEchoHTTPError(ctx context.Context, err error) *echo.HTTPError {
 				Str("origin_error", be.OriginalError().Error()).
 				Msg("business logic error")
 		}
+		// エラーコードは問い合わせに利用できるよう返却する
+		return echo.NewHTTPError(be.HTTPStatus(), ErrorResponse{
+			Code:      be.Code(),
+			Message:   NewBusinessError(internalErrorCode, nil).Message(locale),
+			RequestID: requestID,
+		})
 	}
 
 	// 予期せぬエラー
-	return echo.NewHTTPError(http.StatusInternalServerError, "internal server error")
+	return echo.NewHTTPError(http.StatusInternalServerError, ErrorResponse{
+		Code:      internalErrorCode,
+		Message:   NewBusinessError(internalErrorCode, nil).Message(locale),
+		RequestID: requestID,
+	})
 }
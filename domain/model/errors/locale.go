@@ -0,0 +1,118 @@
+package errors
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale ロケールを決定できない場合に使うロケール
+const DefaultLocale = "en"
+
+// SupportedLocales messages.jsonで提供しているロケール
+var SupportedLocales = []string{"ja", "en"}
+
+type (
+	localeKey    struct{}
+	requestIDKey struct{}
+)
+
+// WithLocale 応答メッセージのロケールを保持したcontextを返す
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFromContext contextに保持されたロケールを返す（未設定の場合はDefaultLocale）
+func LocaleFromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey{}).(string); ok && locale != "" {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// WithRequestID エラーレスポンスに含めるリクエストIDを保持したcontextを返す
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext contextに保持されたリクエストIDを返す
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// NegotiateLocale langクエリパラメータ、Accept-Languageヘッダーの順にサポートしているロケールを決定する
+func NegotiateLocale(acceptLanguage string, lang string) string {
+	if locale, ok := matchLocale(lang); ok {
+		return locale
+	}
+
+	type candidate struct {
+		tag     string
+		quality float64
+	}
+	candidates := make([]candidate, 0)
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if fields[0] == "" {
+			continue
+		}
+		c := candidate{tag: fields[0], quality: 1}
+		for _, param := range fields[1:] {
+			if q, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if v, err := strconv.ParseFloat(q, 64); err == nil {
+					c.quality = v
+				}
+			}
+		}
+		if c.quality > 0 {
+			candidates = append(candidates, c)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	for _, c := range candidates {
+		if locale, ok := matchLocale(c.tag); ok {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// matchLocale 言語タグ（ja-JPなど）に対応するサポート済みのロケールを返す
+func matchLocale(tag string) (string, bool) {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if tag == "" {
+		return "", false
+	}
+	// 既定のロケールへのフォールバックは呼び出し側で行うため、サブタグの除去のみ試す
+	for _, locale := range fallbackLocales(tag) {
+		if locale != tag && !strings.HasPrefix(tag, locale+"-") {
+			break
+		}
+		for _, supported := range SupportedLocales {
+			if locale == supported {
+				return supported, true
+			}
+		}
+	}
+	return "", false
+}
+
+// fallbackLocales メッセージを探すロケールの順序を返す
+// 例: "ja-JP" -> ["ja-jp", "ja", "en"]
+func fallbackLocales(locale string) []string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	chain := []string{locale}
+	for i := strings.LastIndex(locale, "-"); i > 0; i = strings.LastIndex(locale, "-") {
+		locale = locale[:i]
+		chain = append(chain, locale)
+	}
+	if chain[len(chain)-1] != DefaultLocale {
+		chain = append(chain, DefaultLocale)
+	}
+	return chain
+}
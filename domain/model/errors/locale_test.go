@@ -0,0 +1,76 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestNegotiateLocale(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		lang           string
+		expected       string
+	}{
+		{name: "未指定", expected: "en"},
+		{name: "地域付きの言語タグ", acceptLanguage: "ja-JP", expected: "ja"},
+		{name: "品質値の順", acceptLanguage: "fr;q=0.9, en;q=0.5, ja;q=0.8", expected: "ja"},
+		{name: "q=0は除外", acceptLanguage: "ja;q=0, en", expected: "en"},
+		{name: "未対応の言語のみ", acceptLanguage: "fr-FR, de", expected: "en"},
+		{name: "langパラメータを優先", acceptLanguage: "en", lang: "ja", expected: "ja"},
+		{name: "未対応のlangパラメータは無視", acceptLanguage: "ja", lang: "xx", expected: "ja"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := NegotiateLocale(tt.acceptLanguage, tt.lang); actual != tt.expected {
+				t.Errorf("expected %s but got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBusinessError_MessageFallback(t *testing.T) {
+	be := &businessError{code: "X", def: messageDef{Message: map[string]string{"ja": "日本語", "en": "English"}}}
+	if msg := be.Message("ja-JP"); msg != "日本語" {
+		t.Errorf("expected ja message for ja-JP but got %q", msg)
+	}
+	if msg := be.Message("fr"); msg != "English" {
+		t.Errorf("expected default locale message for fr but got %q", msg)
+	}
+
+	jaOnly := &businessError{code: "X", def: messageDef{Message: map[string]string{"ja": "日本語"}}}
+	if msg := jaOnly.Message("en"); msg != "日本語" {
+		t.Errorf("expected any available message but got %q", msg)
+	}
+}
+
+func TestNewEchoHTTPError(t *testing.T) {
+	ctx := WithRequestID(WithLocale(context.Background(), "ja"), "req-1")
+
+	// 4xxはコードとロケールに応じたメッセージを返す
+	he := NewEchoHTTPError(ctx, NewBusinessError("20001E", nil))
+	res, ok := he.Message.(ErrorResponse)
+	if !ok || he.Code != http.StatusNotFound {
+		t.Fatalf("unexpected error: %+v", he)
+	}
+	if res.Code != "20001E" || res.Message != "予約が見つかりません。" || res.RequestID != "req-1" {
+		t.Errorf("unexpected response: %+v", res)
+	}
+
+	// 5xxはコードのみ返し、詳細は隠す
+	he = NewEchoHTTPError(ctx, NewBusinessError("10001E", errors.New("connection refused")))
+	res = he.Message.(ErrorResponse)
+	if he.Code != http.StatusInternalServerError || res.Code != "10001E" || res.Message != "システムエラーが発生しました。" {
+		t.Errorf("unexpected response: %d %+v", he.Code, res)
+	}
+
+	// BusinessError以外は予期せぬエラーとして扱う
+	he = NewEchoHTTPError(context.Background(), errors.New("boom"))
+	res = he.Message.(ErrorResponse)
+	if he.Code != http.StatusInternalServerError || res.Code != "99999E" || res.Message != "internal server error." {
+		t.Errorf("unexpected response: %d %+v", he.Code, res)
+	}
+}
@@ -3,7 +3,10 @@ package errors
 import (
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"sort"
 )
 
 //go:embed messages.json
@@ -25,4 +28,68 @@ func init() {
 	if err := json.Unmarshal(data, &messages); err != nil {
 		panic(fmt.Sprintf("invalid messages.json: %v", err))
 	}
+	if err := validateMessages(messages); err != nil {
+		panic(fmt.Sprintf("invalid messages.json: %v", err))
+	}
+}
+
+// validateMessages エラーカタログの定義漏れ・誤りをすべて列挙して返す
+//
+// messageCodeがキーと一致すること、statusCodeがHTTPステータスとして有効であること、
+// SupportedLocalesのすべてのメッセージが定義されていることを確認する
+func validateMessages(defs map[string]messageDef) error {
+	codes := make([]string, 0, len(defs))
+	for code := range defs {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	var errs []error
+	for _, code := range codes {
+		def := defs[code]
+		if def.MessageCode != code {
+			errs = append(errs, fmt.Errorf("%s: messageCode %q does not match its key", code, def.MessageCode))
+		}
+		if http.StatusText(def.StatusCode) == "" {
+			errs = append(errs, fmt.Errorf("%s: invalid statusCode %d", code, def.StatusCode))
+		}
+		for _, locale := range SupportedLocales {
+			if def.Message[locale] == "" {
+				errs = append(errs, fmt.Errorf("%s: missing %s message", code, locale))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// IsDefined エラーコードがカタログに定義されているかを判定する
+func IsDefined(code string) bool {
+	_, ok := messages[code]
+	return ok
+}
+
+// CatalogEntry 公開用のエラーカタログの1件
+type CatalogEntry struct {
+	Code     string            `json:"code"`
+	Status   int               `json:"status"`
+	Messages map[string]string `json:"messages"`
+}
+
+// PublicCatalog クライアントに公開するエラー（4xx）の一覧をエラーコード順に返す
+func PublicCatalog() []CatalogEntry {
+	entries := make([]CatalogEntry, 0, len(messages))
+	for code, def := range messages {
+		if def.StatusCode < http.StatusBadRequest || def.StatusCode >= http.StatusInternalServerError {
+			continue
+		}
+		msgs := make(map[string]string, len(def.Message))
+		for locale, msg := range def.Message {
+			msgs[locale] = msg
+		}
+		entries = append(entries, CatalogEntry{Code: code, Status: def.StatusCode, Messages: msgs})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Code < entries[j].Code
+	})
+	return entries
 }
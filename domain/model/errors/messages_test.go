@@ -0,0 +1,88 @@
+package errors
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestValidateMessages(t *testing.T) {
+	if err := validateMessages(messages); err != nil {
+		t.Fatalf("messages.json is invalid: %v", err)
+	}
+
+	invalid := map[string]messageDef{
+		"00001E": {StatusCode: 400, MessageCode: "00002E", Message: map[string]string{"ja": "a", "en": "a"}},
+		"00002E": {StatusCode: 999, MessageCode: "00002E", Message: map[string]string{"ja": "a", "en": "a"}},
+		"00003E": {StatusCode: 400, MessageCode: "00003E", Message: map[string]string{"ja": "a"}},
+	}
+	err := validateMessages(invalid)
+	if err == nil {
+		t.Fatalf("expected error but got nil")
+	}
+	for _, expected := range []string{"00001E: messageCode", "00002E: invalid statusCode", "00003E: missing en message"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in %v", expected, err)
+		}
+	}
+}
+
+// errorCodePattern ソースコード中のエラーコードのリテラル（"10001E"など）
+var errorCodePattern = regexp.MustCompile(`"(\d{5}[EIW])"`)
+
+// TestErrorCodesAreDefined ソースコードで参照しているエラーコードがすべてmessages.jsonに定義されていることを確認する
+func TestErrorCodesAreDefined(t *testing.T) {
+	root := filepath.Join("..", "..", "..")
+	if _, err := os.Stat(filepath.Join(root, "go.mod")); err != nil {
+		t.Fatalf("module root not found: %v", err)
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); name == "vendor" || (strings.HasPrefix(name, ".") && path != root) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for i, line := range strings.Split(string(src), "\n") {
+			for _, m := range errorCodePattern.FindAllStringSubmatch(line, -1) {
+				if !IsDefined(m[1]) {
+					t.Errorf("%s:%d: error code %s is not defined in messages.json", path, i+1, m[1])
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to scan source: %v", err)
+	}
+}
+
+func TestPublicCatalog(t *testing.T) {
+	catalog := PublicCatalog()
+	if len(catalog) == 0 {
+		t.Fatalf("expected public entries")
+	}
+	for i, entry := range catalog {
+		if entry.Status < 400 || entry.Status >= 500 {
+			t.Errorf("expected only 4xx entries but got %+v", entry)
+		}
+		if i > 0 && catalog[i-1].Code >= entry.Code {
+			t.Errorf("expected entries to be sorted by code")
+		}
+	}
+}
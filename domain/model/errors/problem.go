@@ -0,0 +1,98 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MIMEApplicationProblemJSON RFC 7807のエラーレスポンスのContent-Type
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// problemTypeBlank 問題の種類を個別に定義しない場合のtype（RFC 7807 4.2）
+const problemTypeBlank = "about:blank"
+
+// ProblemDetails RFC 7807形式のエラーレスポンス
+//
+// 拡張メンバーとして業務エラーコード（code）、リクエストID、項目ごとの入力エラーを持つ
+type ProblemDetails struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// StatusCode errorに対応するHTTPステータスを返す
+func StatusCode(err error) int {
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return he.Code
+	}
+	var be BusinessError
+	if errors.As(err, &be) {
+		return be.HTTPStatus()
+	}
+	return http.StatusInternalServerError
+}
+
+// NewProblemDetails ハンドラーが返したerrorをRFC 7807形式のエラーレスポンスに変換する
+//
+//   - BusinessError: NewEchoHTTPErrorと同様にロケールに応じたメッセージとエラーコードを返す
+//   - echo.HTTPError: ErrorResponse・ValidationErrorResponseはその内容を、それ以外はメッセージをdetailとして返す
+//   - それ以外のerror（panicを含む）: 予期せぬエラーとして詳細を隠して返す
+func NewProblemDetails(ctx context.Context, err error, instance string) ProblemDetails {
+	var he *echo.HTTPError
+	if !errors.As(err, &he) {
+		he = NewEchoHTTPError(ctx, err)
+	}
+
+	problem := ProblemDetails{
+		Type:      problemTypeBlank,
+		Title:     http.StatusText(he.Code),
+		Status:    he.Code,
+		Instance:  instance,
+		RequestID: RequestIDFromContext(ctx),
+	}
+
+	switch m := he.Message.(type) {
+	case ErrorResponse:
+		problem.Code = m.Code
+		problem.Detail = m.Message
+	case ValidationErrorResponse:
+		problem.Code = m.Code
+		problem.Detail = m.Message
+		problem.Errors = m.Errors
+	case string:
+		problem.Detail = m
+	case nil:
+	default:
+		if e, ok := m.(error); ok {
+			problem.Detail = e.Error()
+		}
+	}
+
+	// Bindのエラーなどエラーコードを持たないものはステータスに応じたコードを補う
+	if problem.Code == "" {
+		switch {
+		case he.Code == http.StatusBadRequest:
+			problem.Code = validationErrorCode
+		case he.Code >= http.StatusInternalServerError:
+			problem.Code = internalErrorCode
+		}
+	}
+	if problem.Detail == "" {
+		if he.Code >= http.StatusInternalServerError {
+			problem.Detail = NewBusinessError(internalErrorCode, nil).Message(LocaleFromContext(ctx))
+		} else {
+			problem.Detail = problem.Title
+		}
+	}
+
+	return problem
+}
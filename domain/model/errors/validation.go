@@ -0,0 +1,83 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+// validationErrorCode 入力値が不正な場合のエラーコード
+const validationErrorCode = "00004E"
+
+// validationRuleCodes 入力チェックのルールと項目ごとのエラーコードの対応
+var validationRuleCodes = map[string]string{
+	"required": "00101E",
+	"email":    "00102E",
+	"yyyymmdd": "00103E",
+	"oneof":    "00104E",
+	"min":      "00105E",
+	"max":      "00105E",
+	"gte":      "00105E",
+	"lte":      "00105E",
+	"gtfield":  "00105E",
+	"datetime": "00106E",
+	"numeric":  "00107E",
+	"http_url": "00108E",
+	"rfc3339":  "00109E",
+	"hhmm":     "00110E",
+	"timezone": "00111E",
+	// Webhookで購読できるイベントの名前はoneofと同じエラーコードとする
+	"webhook_event": "00104E",
+}
+
+// defaultFieldErrorCode 対応するエラーコードがないルールのエラーコード
+const defaultFieldErrorCode = "00199E"
+
+// FieldError 項目ごとの入力エラー
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationErrorResponse 入力値が不正な場合のレスポンス
+type ValidationErrorResponse struct {
+	ErrorResponse
+	Errors []FieldError `json:"errors"`
+}
+
+// NewValidationHTTPError 入力チェックのエラーを、不正な項目をすべて列挙した400のHTTPErrorに変換する
+func NewValidationHTTPError(ctx context.Context, err error) *echo.HTTPError {
+	var ves validator.ValidationErrors
+	if !errors.As(err, &ves) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	locale := LocaleFromContext(ctx)
+	res := ValidationErrorResponse{
+		ErrorResponse: ErrorResponse{
+			Code:      validationErrorCode,
+			Message:   NewBusinessError(validationErrorCode, nil).Message(locale),
+			RequestID: RequestIDFromContext(ctx),
+		},
+		Errors: make([]FieldError, 0, len(ves)),
+	}
+	for _, fe := range ves {
+		code, ok := validationRuleCodes[fe.Tag()]
+		if !ok {
+			code = defaultFieldErrorCode
+		}
+		message := NewBusinessError(code, nil).Message(locale)
+		res.Errors = append(res.Errors, FieldError{
+			Field:   fe.Field(),
+			Code:    code,
+			Message: strings.ReplaceAll(message, "{field}", fe.Field()),
+		})
+	}
+
+	return echo.NewHTTPError(http.StatusBadRequest, res)
+}
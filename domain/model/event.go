@@ -0,0 +1,93 @@
+package model
+
+// ドメインイベントの名前
+const (
+	EventPetAdded                 = "pet.added"
+	EventPetPriceChanged          = "pet.price_changed"
+	EventPetStatusChanged         = "pet.status_changed"
+	EventPetDeleted               = "pet.deleted"
+	EventPetLiked                 = "pet.liked"
+	EventReservationCreated       = "reservation.created"
+	EventReservationStatusChanged = "reservation.status_changed"
+)
+
+// DomainEvent ... 業務上の変更を購読者に伝えるイベント
+type DomainEvent interface {
+	// EventName イベントの名前（購読のキー）
+	EventName() string
+}
+
+type (
+	// PetAdded ... ペットが追加された
+	PetAdded struct {
+		Pet Pet `json:"pet"`
+	}
+
+	// PetPriceChanged ... ペットの価格が変更された
+	PetPriceChanged struct {
+		Pet      Pet     `json:"pet"`
+		OldPrice float64 `json:"old_price"`
+		NewPrice float64 `json:"new_price"`
+	}
+
+	// PetStatusChanged ... ペットのステータスが見学予約・管理者の操作で変更された
+	PetStatusChanged struct {
+		Pet Pet `json:"pet"`
+		// Action ペットに対する操作（reserve, release, adopt, withdraw, relist）
+		Action string `json:"action"`
+		From   string `json:"from"`
+		To     string `json:"to"`
+	}
+
+	// PetDeleted ... ペットが削除された
+	PetDeleted struct {
+		Pet Pet `json:"pet"`
+		// FavoriteUserIDs お気に入り登録していたユーザ（削除とともにお気に入りも削除されるため、通知用に保持する。外部には配信しない）
+		FavoriteUserIDs []string `json:"-"`
+	}
+
+	// PetLiked ... ペットのいいねが登録・解除された
+	PetLiked struct {
+		PetId  string `json:"pet_id"`
+		UserId string `json:"user_id"`
+		// Value trueの場合は登録、falseの場合は解除
+		Value bool `json:"value"`
+		// Likes 更新後のいいね数
+		Likes int `json:"likes"`
+	}
+
+	// ReservationCreated ... 見学予約が作成された
+	ReservationCreated struct {
+		Reservation Reservation `json:"reservation"`
+	}
+
+	// ReservationStatusChanged ... 見学予約が確定・キャンセル・日程変更された
+	ReservationStatusChanged struct {
+		Reservation ReservationDetail `json:"reservation"`
+		// Action 予約に対する操作（confirm, cancel, reschedule）
+		Action string `json:"action"`
+		From   string `json:"from"`
+		To     string `json:"to"`
+	}
+)
+
+// EventName ...
+func (PetAdded) EventName() string { return EventPetAdded }
+
+// EventName ...
+func (PetPriceChanged) EventName() string { return EventPetPriceChanged }
+
+// EventName ...
+func (PetStatusChanged) EventName() string { return EventPetStatusChanged }
+
+// EventName ...
+func (PetDeleted) EventName() string { return EventPetDeleted }
+
+// EventName ...
+func (PetLiked) EventName() string { return EventPetLiked }
+
+// EventName ...
+func (ReservationCreated) EventName() string { return EventReservationCreated }
+
+// EventName ...
+func (ReservationStatusChanged) EventName() string { return EventReservationStatusChanged }
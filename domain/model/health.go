@@ -0,0 +1,24 @@
+package model
+
+const (
+	// HealthStatusOK 正常
+	HealthStatusOK = "ok"
+	// HealthStatusDegraded 非必須コンポーネントが異常（readinessは成功とする）
+	HealthStatusDegraded = "degraded"
+	// HealthStatusFail 異常
+	HealthStatusFail = "fail"
+)
+
+// HealthReport ... readinessの結果
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+}
+
+// ComponentHealth ... コンポーネントごとの疎通確認結果
+type ComponentHealth struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// IdempotencyRecord ... Idempotency-Keyヘッダーを指定したリクエストの処理結果
+type IdempotencyRecord struct {
+	// Principal リクエスト元（"<Kind>:<ID>"。認証が無効な場合は空文字）
+	Principal string `db:"principal"`
+	Key       string `db:"idempotency_key"`
+	// Fingerprint メソッド・パス・リクエストボディのSHA-256
+	Fingerprint string `db:"fingerprint"`
+	// StatusCode, ContentType, ResponseBody 保存したレスポンス（処理中の場合はnil）
+	StatusCode   *int      `db:"status_code"`
+	ContentType  *string   `db:"content_type"`
+	ResponseBody *string   `db:"response_body"`
+	CreatedAt    time.Time `db:"created_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+// Completed レスポンスを保存済み（処理が完了している）かを判定する
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != nil
+}
+
+// IdempotencyPrincipal 処理結果を区別するリクエスト元の識別子を返す（認証が無効な場合は空文字）
+func IdempotencyPrincipal(principal *Principal) string {
+	if principal == nil {
+		return ""
+	}
+	return principal.Kind + ":" + principal.ID
+}
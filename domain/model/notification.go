@@ -1,5 +1,21 @@
 package model
 
+const (
+	// NotificationEventCreated 通知が作成された
+	NotificationEventCreated = "notification.created"
+	// NotificationEventRead 通知が既読になった
+	NotificationEventRead = "notification.read"
+)
+
+// 通知の種類
+const (
+	NotificationTypeNewPet      = "new_pet"
+	NotificationTypePriceChange = "price_change"
+	NotificationTypePetStatus   = "pet_status"
+	NotificationTypeReservation = "reservation"
+	NotificationTypeCampaign    = "campaign"
+)
+
 type (
 	// Notification ... entity for notification db result
 	Notification struct {
@@ -21,6 +37,34 @@ type (
 	NotificationCount struct {
 		Data int `json:"data"`
 	}
+
+	// NotificationFilter ... 通知一覧の検索条件（新しい順に取得する）
+	NotificationFilter struct {
+		// IsRead 既読・未読で絞り込む
+		IsRead *bool `query:"is_read"`
+		// Type 通知の種類で絞り込む
+		Type string `query:"type" validate:"omitempty,max=32"`
+		// Since 指定した日時（RFC3339）より後に作成された通知に絞り込む
+		Since string `query:"since" validate:"omitempty,rfc3339"`
+		// Limit 1ページの件数（0の場合は20件）
+		Limit int `query:"limit" validate:"gte=0,lte=100"`
+		// Cursor 前のページのレスポンスで返却されたnext_cursor
+		Cursor string `query:"cursor"`
+	}
+
+	// NotificationEvent ... 通知の作成・既読化をストリームの購読者に伝えるイベント
+	NotificationEvent struct {
+		// ID ブローカーが採番するイベントID（SSEのidとしてLast-Event-IDでの再開に使う）
+		ID string `json:"-"`
+		// Type NotificationEventCreatedまたはNotificationEventRead
+		Type string `json:"type"`
+		// UserId イベントを受け取るユーザ
+		UserId string `json:"user_id"`
+		// Notification 作成された通知（NotificationEventCreatedの場合）
+		Notification *Notification `json:"notification,omitempty"`
+		// NotificationID 既読にした通知のID（NotificationEventReadの場合。0はユーザの未読通知すべて）
+		NotificationID int `json:"notification_id,omitempty"`
+	}
 )
 
 // TableName ... override table name accessor
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// アウトボックスの配信状態
+const (
+	OutboxStatusPending   = "pending"
+	OutboxStatusPublished = "published"
+	// OutboxStatusDead 再試行の上限に達し、配信を諦めた（デッドレター）
+	OutboxStatusDead = "dead"
+)
+
+// OutboxMessage ... entity for outbox db result
+type OutboxMessage struct {
+	ID            int64      `json:"id" db:"id"`
+	EventType     string     `json:"event_type" db:"event_type"`
+	Payload       string     `json:"payload" db:"payload"`
+	Status        string     `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     *string    `json:"last_error" db:"last_error"`
+	PublishedAt   *time.Time `json:"published_at" db:"published_at"`
+	CreatedAt     *time.Time `json:"created_at" db:"created_at"`
+}
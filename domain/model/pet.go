@@ -4,6 +4,27 @@ import (
 	"time"
 )
 
+// ペットのステータス
+const (
+	// PetStatusAvailable 見学予約を受け付けている
+	PetStatusAvailable = "available"
+	// PetStatusReserved 有効な見学予約がある
+	PetStatusReserved = "reserved"
+	// PetStatusAdopted 成約済み
+	PetStatusAdopted = "adopted"
+	// PetStatusWithdrawn 掲載を終了した
+	PetStatusWithdrawn = "withdrawn"
+)
+
+// IsValidPetStatus ペットのステータスとして有効な値かを判定する
+func IsValidPetStatus(status string) bool {
+	switch status {
+	case PetStatusAvailable, PetStatusReserved, PetStatusAdopted, PetStatusWithdrawn:
+		return true
+	}
+	return false
+}
+
 // Pet ... entity for pet domain model
 type Pet struct {
 	ID               string     `json:"id"`
@@ -17,10 +38,38 @@ type Pet struct {
 	BirthDate        *time.Time `json:"birth_date"`
 	ReferenceNumber  string     `json:"reference_number"`
 	Tags             []string   `json:"tags"`
+	Status           string     `json:"status"`
 	ReservationCount int64      `json:"reservation_count"`
+	CreatedAt        *time.Time `json:"created_at"`
+	UpdatedAt        *time.Time `json:"updated_at"`
 }
 
+// PetChanges ... ペットの部分更新の内容（nilの項目は変更しない）
+type PetChanges struct {
+	Name   *string
+	Breed  *string
+	Gender *string
+	Price  *float64
+	// ImageURL 空文字の場合は画像を削除する
+	ImageURL *string
+	ShopID   *int64
+	// BirthDate ゼロ値の場合は生年月日を削除する
+	BirthDate       *time.Time
+	ReferenceNumber *string
+	// Tags 空のスライスの場合はタグをすべて削除する
+	Tags []string
+	// Likes いいね数（いいねの登録・解除でのみ変更する）
+	Likes *int
+}
+
+// Shop ... ペットを扱う店舗
 type Shop struct {
+	ID       int64  `json:"id"`
 	Name     string `json:"name"`
 	Location string `json:"location"`
 }
+
+// Shops ... 店舗の一覧
+type Shops struct {
+	Data []Shop `json:"data"`
+}
@@ -0,0 +1,36 @@
+package model
+
+import "context"
+
+const (
+	// PrincipalKindUser JWTで認証されたエンドユーザー
+	PrincipalKindUser = "user"
+	// PrincipalKindService APIキーで認証されたサービス（任意のユーザーの代理として操作できる）
+	PrincipalKindService = "service"
+)
+
+// Principal ... 認証済みのリクエスト元
+type Principal struct {
+	// ID ユーザーの場合はユーザーID（JWTのsub）、サービスの場合はAPIキーに対応するサービス名
+	ID string
+	// Kind PrincipalKindUserまたはPrincipalKindService
+	Kind string
+}
+
+// IsService サービス間連携のリクエスト元かを判定する
+func (p *Principal) IsService() bool {
+	return p.Kind == PrincipalKindService
+}
+
+type principalKey struct{}
+
+// WithPrincipal 認証済みのリクエスト元を保持したcontextを返す
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext contextに保持されたリクエスト元を返す（認証が無効の場合はnil）
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
@@ -1,9 +1,65 @@
 package model
 
+import "time"
+
+// 予約ステータス
+const (
+	ReservationStatusPending   = "pending"
+	ReservationStatusConfirmed = "confirmed"
+	ReservationStatusCancelled = "cancelled"
+)
+
+// IsValidReservationStatus 予約ステータスとして有効な値かを判定する
+func IsValidReservationStatus(status string) bool {
+	switch status {
+	case ReservationStatusPending, ReservationStatusConfirmed, ReservationStatusCancelled:
+		return true
+	}
+	return false
+}
+
 type Reservation struct {
-	PetId           string `json:"pet_id"`
-	UserId          string `json:"user_id"`
-	Email           string `json:"email"`
-	FullName        string `json:"full_name"`
-	ReservationDate string `json:"reservation_date"`
+	PetId    string `json:"pet_id"`
+	UserId   string `json:"user_id"`
+	Email    string `json:"email"`
+	FullName string `json:"full_name"`
+	// SlotStartsAt 見学する予約枠の開始日時
+	SlotStartsAt time.Time `json:"slot_starts_at"`
+	// ShopID, SlotTimezone, SlotSeat 予約枠を確保した時に設定する（0・空文字の場合は枠を持たない）
+	ShopID       int64  `json:"shop_id"`
+	SlotTimezone string `json:"slot_timezone"`
+	SlotSeat     int    `json:"-"`
 }
+
+// ReservationSlot ... 見学予約が確保した予約枠と席
+type ReservationSlot struct {
+	ShopID   int64
+	StartsAt time.Time
+	Timezone string
+	Seat     int
+}
+
+type (
+	// ReservationDetail ... entity for reservation db result
+	ReservationDetail struct {
+		ID                  int       `json:"id" db:"id"`
+		PetId               string    `json:"pet_id" db:"pet_id"`
+		UserId              string    `json:"user_id" db:"user_id"`
+		FullName            string    `json:"full_name" db:"user_name"`
+		Email               string    `json:"email" db:"email"`
+		ReservationDateTime time.Time `json:"reservation_date_time" db:"reservation_date_time"`
+		Status              string    `json:"status" db:"status"`
+		// ShopID, SlotStartsAt, SlotTimezone 予約枠（枠を持たない予約はnil）
+		ShopID       *int64     `json:"shop_id" db:"shop_id"`
+		SlotStartsAt *time.Time `json:"slot_starts_at" db:"slot_starts_at"`
+		SlotTimezone *string    `json:"slot_timezone" db:"slot_timezone"`
+		// SlotSeat 予約枠の席番号（キャンセルした予約はnil）
+		SlotSeat  *int       `json:"-" db:"slot_seat"`
+		CreatedAt *time.Time `json:"created_at" db:"created_at"`
+		UpdatedAt *time.Time `json:"updated_at" db:"updated_at"`
+	}
+	// Reservations ... array entity for reservation
+	Reservations struct {
+		Data []ReservationDetail `json:"data"`
+	}
+)
@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+type (
+	// ShopSchedule ... 店舗の見学予約の受付設定
+	ShopSchedule struct {
+		ShopID int64 `json:"shop_id"`
+		// Timezone 営業時間と予約枠を解釈するタイムゾーン（IANAのタイムゾーン名）
+		Timezone string `json:"timezone"`
+		// SlotMinutes 1枠の長さ（分）
+		SlotMinutes int `json:"slot_minutes"`
+		// SlotCapacity 1枠で受け付ける見学予約の数（店舗のすべてのペットで共有する）
+		SlotCapacity int `json:"slot_capacity"`
+		// OpeningHours 曜日ごとの営業時間（登録のない曜日は定休日）
+		OpeningHours []OpeningHours `json:"opening_hours"`
+	}
+	// OpeningHours ... 曜日ごとの営業時間
+	OpeningHours struct {
+		// Weekday 0: 日曜日 〜 6: 土曜日
+		Weekday int `json:"weekday"`
+		// OpensAt, ClosesAt 開店・閉店時刻（hh:mm）
+		OpensAt  string `json:"opens_at"`
+		ClosesAt string `json:"closes_at"`
+	}
+
+	// VisitSlot ... 見学予約の枠
+	VisitSlot struct {
+		StartsAt  time.Time `json:"starts_at"`
+		EndsAt    time.Time `json:"ends_at"`
+		Capacity  int       `json:"capacity"`
+		Remaining int       `json:"remaining"`
+	}
+	// PetAvailability ... ペットの見学予約を受け付けている枠
+	PetAvailability struct {
+		PetID    string      `json:"pet_id"`
+		ShopID   int64       `json:"shop_id"`
+		Timezone string      `json:"timezone"`
+		Slots    []VisitSlot `json:"slots"`
+	}
+)
+
+// AvailabilityFilter ... 見学予約の枠を取得する期間
+type AvailabilityFilter struct {
+	// From 取得を開始する日付（店舗のタイムゾーンの日付。未指定の場合は当日）
+	From string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	// Days 取得する日数（未指定の場合は7日）
+	Days int `query:"days" validate:"omitempty,min=1,max=31"`
+}
@@ -23,6 +23,20 @@ type APIResponse struct {
 	Data interface{} `json:"data"`
 }
 
+// PageInfo ... ページング情報
+type PageInfo struct {
+	// NextCursor 次のページを取得するためのカーソル（最終ページの場合はnull）
+	NextCursor *string `json:"next_cursor"`
+	// TotalCount カーソルを除いた検索条件に一致する件数
+	TotalCount int `json:"total_count"`
+}
+
+// PagedAPIResponse ... ページングに対応したレスポンス
+type PagedAPIResponse struct {
+	APIResponse
+	PageInfo
+}
+
 // PetFilter ... フィルタ
 type PetFilter struct {
 	ID              string  `query:"id"`
@@ -31,4 +45,26 @@ type PetFilter struct {
 	Gender          string  `query:"gender"`
 	Price           float64 `query:"price"`
 	ReferenceNumber string  `query:"reference_number"`
+
+	// Q 名前の部分一致検索（大文字小文字を区別しない）
+	Q string `query:"q" validate:"max=100"`
+	// PriceMin, PriceMax 価格の範囲（両端を含む）
+	PriceMin float64 `query:"price_min" validate:"gte=0"`
+	PriceMax float64 `query:"price_max" validate:"gte=0"`
+	// BirthDateFrom, BirthDateTo 生年月日の範囲（yyyy-mm-dd、両端を含む）
+	BirthDateFrom string `query:"birth_date_from" validate:"omitempty,datetime=2006-01-02"`
+	BirthDateTo   string `query:"birth_date_to" validate:"omitempty,datetime=2006-01-02"`
+	// Tags 指定したタグをすべて含むペットに絞り込む（複数指定またはカンマ区切り）
+	Tags []string `query:"tags" validate:"max=10"`
+	// Status 指定したステータスのいずれかのペットに絞り込む（複数指定またはカンマ区切り）
+	Status []string `query:"status" validate:"max=4"`
+	// ShopID 指定した店舗のペットに絞り込む
+	ShopID int64 `query:"shop_id" validate:"gte=0"`
+
+	// Sort 並び順（price, likes, birth_date, created_at。先頭に-を付けると降順）
+	Sort string `query:"sort" validate:"omitempty,oneof=price -price likes -likes birth_date -birth_date created_at -created_at"`
+	// Limit 1ページの件数（0の場合は全件）
+	Limit int `query:"limit" validate:"gte=0,lte=100"`
+	// Cursor 前のページのレスポンスで返却されたnext_cursor
+	Cursor string `query:"cursor"`
 }
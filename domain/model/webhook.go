@@ -0,0 +1,87 @@
+package model
+
+import "time"
+
+// Webhookの送信状態
+const (
+	WebhookDeliveryStatusPending   = "pending"
+	WebhookDeliveryStatusSucceeded = "succeeded"
+	// WebhookDeliveryStatusDead 再試行の上限に達し、送信を諦めた（リプレイで再送できる）
+	WebhookDeliveryStatusDead = "dead"
+)
+
+// WebhookEventTypes Webhookで購読できるイベントの名前
+var WebhookEventTypes = []string{
+	EventPetLiked,
+	EventPetDeleted,
+	EventReservationCreated,
+	EventReservationStatusChanged,
+}
+
+type (
+	// WebhookSubscription ... entity for webhook_subscriptions db result
+	WebhookSubscription struct {
+		ID        int64  `json:"id" db:"id"`
+		ShopID    int64  `json:"shop_id" db:"shop_id"`
+		EventType string `json:"event_type" db:"event_type"`
+		URL       string `json:"url" db:"url"`
+		// Secret 署名に使う共有鍵（登録時のレスポンスでのみ返す）
+		Secret    string     `json:"secret,omitempty" db:"secret"`
+		Active    bool       `json:"active" db:"active"`
+		CreatedAt *time.Time `json:"created_at" db:"created_at"`
+		UpdatedAt *time.Time `json:"updated_at" db:"updated_at"`
+	}
+	// WebhookSubscriptions ... array entity for webhook subscriptions
+	WebhookSubscriptions struct {
+		Data []WebhookSubscription `json:"data"`
+	}
+
+	// WebhookDelivery ... entity for webhook_deliveries db result
+	WebhookDelivery struct {
+		ID             int64 `json:"id" db:"id"`
+		SubscriptionID int64 `json:"subscription_id" db:"subscription_id"`
+		// EventID 送信するイベントのID（outboxのID）
+		EventID       int64      `json:"event_id" db:"event_id"`
+		EventType     string     `json:"event_type" db:"event_type"`
+		Payload       string     `json:"payload" db:"payload"`
+		Status        string     `json:"status" db:"status"`
+		Attempts      int        `json:"attempts" db:"attempts"`
+		NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+		ResponseCode  *int       `json:"response_code" db:"response_code"`
+		LastError     *string    `json:"last_error" db:"last_error"`
+		DeliveredAt   *time.Time `json:"delivered_at" db:"delivered_at"`
+		CreatedAt     *time.Time `json:"created_at" db:"created_at"`
+	}
+	// WebhookDeliveries ... array entity for webhook deliveries
+	WebhookDeliveries struct {
+		Data []WebhookDelivery `json:"data"`
+	}
+
+	// WebhookDeliveryAttempt ... entity for webhook_delivery_attempts db result
+	WebhookDeliveryAttempt struct {
+		ID         int64 `json:"id" db:"id"`
+		DeliveryID int64 `json:"delivery_id" db:"delivery_id"`
+		Attempt    int   `json:"attempt" db:"attempt"`
+		// ResponseCode 応答ステータスコード（応答がなかった場合はnil）
+		ResponseCode *int       `json:"response_code" db:"response_code"`
+		Error        *string    `json:"error" db:"error"`
+		DurationMs   int64      `json:"duration_ms" db:"duration_ms"`
+		AttemptedAt  *time.Time `json:"attempted_at" db:"attempted_at"`
+	}
+
+	// WebhookDeliveryDetail ... 送信と試行ごとの結果
+	WebhookDeliveryDetail struct {
+		WebhookDelivery
+		History []WebhookDeliveryAttempt `json:"history"`
+	}
+)
+
+// IsWebhookEventType Webhookで購読できるイベントの名前かを判定する
+func IsWebhookEventType(eventType string) bool {
+	for _, t := range WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/horsewin/echo-playground-v2/domain/model"
 	"github.com/horsewin/echo-playground-v2/interface/database"
+	"github.com/lib/pq"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -23,6 +24,7 @@ type favorites struct {
 // FavoriteRepositoryInterface ...
 type FavoriteRepositoryInterface interface {
 	FindByUserId(ctx context.Context, userId string) (favorites map[string]model.Favorite, err error)
+	FindByPetIDs(ctx context.Context, petIDs []string, afterUserID string, limit int) (favorites []model.Favorite, err error)
 	Create(ctx context.Context, input *model.Favorite) (err error)
 	Delete(ctx context.Context, input *model.Favorite) (err error)
 }
@@ -78,6 +80,53 @@ func (f FavoriteRepository) FindByUserId(ctx context.Context, userId string) (fa
 	return
 }
 
+// FindByPetIDs いずれかのペットのお気に入りを、ユーザIDの昇順にafterUserIDより後から最大limit件取得する
+//
+// 前のページの最後のユーザIDをafterUserIDに指定して、お気に入り登録しているユーザをページごとに取得する
+func (f FavoriteRepository) FindByPetIDs(ctx context.Context, petIDs []string, afterUserID string, limit int) (favs []model.Favorite, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("favorite-repository")
+	ctx, span := tracer.Start(ctx, "FavoriteRepository.FindByPetIDs",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int("pet_ids.count", len(petIDs)),
+		attribute.Int("limit", limit),
+	)
+
+	if len(petIDs) == 0 {
+		return
+	}
+
+	var _favorites favorites
+	whereClause := "pet_id = ANY(:pet_ids) AND user_id > :after_user_id"
+	whereArgs := map[string]interface{}{"pet_ids": pq.StringArray(petIDs), "after_user_id": afterUserID}
+	err = f.SQLHandler.Select(ctx, &_favorites.Data, FavoriteTable, whereClause, whereArgs, "user_id, pet_id", limit)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+
+	// 結果の属性を追加
+	span.SetAttributes(
+		attribute.Int("result_count", len(_favorites.Data)),
+	)
+
+	// ドメインモデルに変換
+	for _, _favorite := range _favorites.Data {
+		favs = append(favs, model.Favorite{
+			Id:     _favorite.ID,
+			PetId:  _favorite.PetId,
+			UserId: _favorite.UserId,
+			Value:  true,
+		})
+	}
+	return
+}
+
 // Create ...
 func (f FavoriteRepository) Create(ctx context.Context, input *model.Favorite) (err error) {
 	// スパンを作成
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// IdempotencyRepositoryInterface ...
+type IdempotencyRepositoryInterface interface {
+	Create(ctx context.Context, record *model.IdempotencyRecord) (err error)
+	Find(ctx context.Context, principal string, key string) (record *model.IdempotencyRecord, err error)
+	Complete(ctx context.Context, record *model.IdempotencyRecord, statusCode int, contentType string, body string) (err error)
+	Delete(ctx context.Context, record *model.IdempotencyRecord) (err error)
+	DeleteExpired(ctx context.Context, now time.Time, limit int) (deleted int, err error)
+}
+
+// IdempotencyRepository ...
+type IdempotencyRepository struct {
+	database.SQLHandler
+}
+
+const IdempotencyKeysTable = "idempotency_keys"
+
+// ErrIdempotencyKeyExists 同じリクエスト元・キーの処理結果が既に記録されている場合のエラー
+var ErrIdempotencyKeyExists = errors.New("idempotency key already exists")
+
+// Create 処理中のリクエストとして記録する（既に記録されている場合はErrIdempotencyKeyExists）
+func (repo *IdempotencyRepository) Create(ctx context.Context, record *model.IdempotencyRecord) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("idempotency-repository")
+	ctx, span := tracer.Start(ctx, "IdempotencyRepository.Create",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("principal", record.Principal),
+	)
+
+	in := map[string]interface{}{
+		"principal":       record.Principal,
+		"idempotency_key": record.Key,
+		"fingerprint":     record.Fingerprint,
+		"created_at":      record.CreatedAt,
+		"expires_at":      record.ExpiresAt,
+	}
+	err = repo.SQLHandler.Create(ctx, in, IdempotencyKeysTable)
+	if errors.Is(err, database.ErrUniqueViolation) {
+		err = fmt.Errorf("%w: %v", ErrIdempotencyKeyExists, err)
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	return
+}
+
+// Find リクエスト元・キーの処理結果を取得する（記録されていない場合はnil）
+func (repo *IdempotencyRepository) Find(ctx context.Context, principal string, key string) (record *model.IdempotencyRecord, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("idempotency-repository")
+	ctx, span := tracer.Start(ctx, "IdempotencyRepository.Find",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("principal", principal),
+	)
+
+	var rows []model.IdempotencyRecord
+	whereClause := "principal = :principal AND idempotency_key = :idempotency_key"
+	whereArgs := map[string]interface{}{"principal": principal, "idempotency_key": key}
+	err = repo.SQLHandler.Where(ctx, &rows, IdempotencyKeysTable, whereClause, whereArgs)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if len(rows) > 0 {
+		record = &rows[0]
+	}
+	return
+}
+
+// Complete 処理中のリクエストのレスポンスを保存する
+func (repo *IdempotencyRepository) Complete(ctx context.Context, record *model.IdempotencyRecord, statusCode int, contentType string, body string) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("idempotency-repository")
+	ctx, span := tracer.Start(ctx, "IdempotencyRepository.Complete",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("principal", record.Principal),
+		attribute.Int("status_code", statusCode),
+	)
+
+	setParams := map[string]interface{}{
+		"status_code":   statusCode,
+		"content_type":  contentType,
+		"response_body": body,
+	}
+	err = repo.SQLHandler.Update(ctx, setParams, IdempotencyKeysTable, "principal = :principal AND idempotency_key = :idempotency_key AND created_at = :created_at", recordKey(record))
+	if err != nil {
+		span.RecordError(err)
+	}
+	return
+}
+
+// Delete 処理結果を削除する
+//
+// 削除の間に他のリクエストが同じキーで記録し直した場合に消さないよう、recordと記録した日時が一致する場合のみ削除する
+func (repo *IdempotencyRepository) Delete(ctx context.Context, record *model.IdempotencyRecord) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("idempotency-repository")
+	ctx, span := tracer.Start(ctx, "IdempotencyRepository.Delete",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("principal", record.Principal),
+	)
+
+	err = repo.SQLHandler.Delete(ctx, recordKey(record), IdempotencyKeysTable)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return
+}
+
+// DeleteExpired 期限切れの処理結果を古い順に最大limit件削除し、削除した件数を返す
+func (repo *IdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time, limit int) (deleted int, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("idempotency-repository")
+	ctx, span := tracer.Start(ctx, "IdempotencyRepository.DeleteExpired",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int("limit", limit),
+	)
+
+	var rows []model.IdempotencyRecord
+	err = repo.SQLHandler.Select(ctx, &rows, IdempotencyKeysTable, "expires_at <= :now", map[string]interface{}{"now": now}, "expires_at", limit)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	for i := range rows {
+		if err = repo.SQLHandler.Delete(ctx, recordKey(&rows[i]), IdempotencyKeysTable); err != nil {
+			span.RecordError(err)
+			return
+		}
+		deleted++
+	}
+
+	// 結果の属性を追加
+	span.SetAttributes(
+		attribute.Int("result_count", deleted),
+	)
+	return
+}
+
+// recordKey 処理結果を一意に特定するカラムの値を返す
+func recordKey(record *model.IdempotencyRecord) map[string]interface{} {
+	return map[string]interface{}{
+		"principal":       record.Principal,
+		"idempotency_key": record.Key,
+		"created_at":      record.CreatedAt,
+	}
+}
@@ -2,6 +2,12 @@ package repository
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/horsewin/echo-playground-v2/domain/model"
 	"github.com/horsewin/echo-playground-v2/interface/database"
@@ -12,10 +18,11 @@ import (
 
 // NotificationRepositoryInterface ...
 type NotificationRepositoryInterface interface {
-	Find(ctx context.Context, id string) (account model.Notifications, err error)
-	FindAll(ctx context.Context) (notifications model.Notifications, err error)
+	Find(ctx context.Context, userID string, id string) (account model.Notifications, err error)
+	FindByUserID(ctx context.Context, userID string, filter *model.NotificationFilter) (notifications model.Notifications, page model.PageInfo, err error)
 	Count(ctx context.Context, query string, args map[string]interface{}) (data model.NotificationCount, err error)
 	Update(ctx context.Context, in map[string]interface{}, query string, args map[string]interface{}) (err error)
+	Create(ctx context.Context, input *model.Notification) (err error)
 }
 
 // NotificationRepository ....
@@ -25,8 +32,18 @@ type NotificationRepository struct {
 
 const NotificationTable = "notifications"
 
-// Find ...
-func (repo *NotificationRepository) Find(ctx context.Context, id string) (notifications model.Notifications, err error) {
+const (
+	// DefaultNotificationLimit 件数を指定しない場合の1ページの件数
+	DefaultNotificationLimit = 20
+	// MaxNotificationLimit 1ページで取得できる最大件数
+	MaxNotificationLimit = 100
+)
+
+// ErrInvalidNotificationFilter 検索条件が不正な場合のエラー
+var ErrInvalidNotificationFilter = errors.New("invalid notification filter")
+
+// Find ユーザの通知をIDで取得する（他のユーザの通知は取得しない）
+func (repo *NotificationRepository) Find(ctx context.Context, userID string, id string) (notifications model.Notifications, err error) {
 	// スパンを作成
 	tracer := otel.Tracer("notification-repository")
 	_, span := tracer.Start(ctx, "NotificationRepository.Find",
@@ -36,11 +53,12 @@ func (repo *NotificationRepository) Find(ctx context.Context, id string) (notifi
 
 	// 属性を追加
 	span.SetAttributes(
+		attribute.String("user_id", userID),
 		attribute.String("id", id),
 	)
 
-	whereClause := "id = :id"
-	whereArgs := map[string]interface{}{"id": id}
+	whereClause := "id = :id AND user_id = :user_id"
+	whereArgs := map[string]interface{}{"id": id, "user_id": userID}
 	err = repo.SQLHandler.Where(ctx, &notifications.Data, NotificationTable, whereClause, whereArgs)
 	if err != nil {
 		span.RecordError(err)
@@ -48,20 +66,126 @@ func (repo *NotificationRepository) Find(ctx context.Context, id string) (notifi
 	return
 }
 
-// FindAll ...
-func (repo *NotificationRepository) FindAll(ctx context.Context) (notifications model.Notifications, err error) {
+// FindByUserID ユーザの通知を検索条件で絞り込み、新しい順（id降順）に1ページ分取得する
+func (repo *NotificationRepository) FindByUserID(ctx context.Context, userID string, filter *model.NotificationFilter) (notifications model.Notifications, page model.PageInfo, err error) {
 	// スパンを作成
 	tracer := otel.Tracer("notification-repository")
-	_, span := tracer.Start(ctx, "NotificationRepository.FindAll",
+	ctx, span := tracer.Start(ctx, "NotificationRepository.FindByUserID",
 		trace.WithSpanKind(trace.SpanKindInternal),
 	)
 	defer span.End()
 
-	err = repo.SQLHandler.Scan(ctx, &notifications.Data, NotificationTable, "id desc")
+	if filter == nil {
+		filter = &model.NotificationFilter{}
+	}
+
+	conditions := []string{"user_id = :user_id"}
+	args := map[string]interface{}{"user_id": userID}
+	if filter.IsRead != nil {
+		conditions = append(conditions, "is_read = :is_read")
+		args["is_read"] = *filter.IsRead
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, "type = :type")
+		args["type"] = filter.Type
+	}
+	if filter.Since != "" {
+		since, parseErr := time.Parse(time.RFC3339, filter.Since)
+		if parseErr != nil {
+			err = fmt.Errorf("%w: since must be RFC3339", ErrInvalidNotificationFilter)
+			span.RecordError(err)
+			return
+		}
+		// created_atはタイムゾーンを持たないUTCの日時のため、オフセットを反映してUTCに揃える
+		conditions = append(conditions, "created_at > :since")
+		args["since"] = since.UTC()
+	}
+
+	limit := filter.Limit
+	switch {
+	case limit < 0:
+		err = fmt.Errorf("%w: limit must not be negative", ErrInvalidNotificationFilter)
+		span.RecordError(err)
+		return
+	case limit == 0:
+		limit = DefaultNotificationLimit
+	case limit > MaxNotificationLimit:
+		limit = MaxNotificationLimit
+	}
+
+	// カーソルより古い通知に絞り込む（件数はカーソルを除いた条件で数える）
+	whereClause := strings.Join(conditions, " AND ")
+	pageClause := whereClause
+	if filter.Cursor != "" {
+		cursorID, cursorErr := decodeNotificationCursor(filter.Cursor)
+		if cursorErr != nil {
+			err = cursorErr
+			span.RecordError(err)
+			return
+		}
+		pageClause += " AND id < :cursor_id"
+		args["cursor_id"] = cursorID
+	}
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("user_id", userID),
+		attribute.String("filter.type", filter.Type),
+		attribute.String("filter.since", filter.Since),
+		attribute.Int("filter.limit", limit),
+		attribute.Bool("filter.cursor", filter.Cursor != ""),
+		attribute.String("where_clause", pageClause),
+	)
+
+	// 次のページの有無を判定するため1件多く取得する
+	err = repo.SQLHandler.Select(ctx, &notifications.Data, NotificationTable, pageClause, args, "id desc", limit+1)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if len(notifications.Data) > limit {
+		notifications.Data = notifications.Data[:limit]
+		next := encodeNotificationCursor(notifications.Data[limit-1].ID)
+		page.NextCursor = &next
+	}
+
+	err = repo.SQLHandler.Count(ctx, &page.TotalCount, NotificationTable, whereClause, args)
 	if err != nil {
 		span.RecordError(err)
+		return
 	}
-	return notifications, err
+
+	// 結果の属性を追加
+	span.SetAttributes(
+		attribute.Int("result_count", len(notifications.Data)),
+		attribute.Int("total_count", page.TotalCount),
+	)
+
+	return
+}
+
+// notificationCursor キーセットページング用のカーソル
+type notificationCursor struct {
+	ID int `json:"id"`
+}
+
+// encodeNotificationCursor ページの最後の通知のIDからカーソルを作成する
+func encodeNotificationCursor(id int) string {
+	data, _ := json.Marshal(notificationCursor{ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeNotificationCursor カーソルを解釈して通知のIDを返す
+func decodeNotificationCursor(encoded string) (int, error) {
+	var cursor notificationCursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, fmt.Errorf("%w: malformed cursor", ErrInvalidNotificationFilter)
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 {
+		return 0, fmt.Errorf("%w: malformed cursor", ErrInvalidNotificationFilter)
+	}
+	return cursor.ID, nil
 }
 
 // Count ...
@@ -107,3 +231,37 @@ func (repo *NotificationRepository) Update(ctx context.Context, in map[string]in
 	}
 	return
 }
+
+// Create 通知を作成し、採番したIDと作成日時をinputに反映する
+func (repo *NotificationRepository) Create(ctx context.Context, input *model.Notification) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("notification-repository")
+	ctx, span := tracer.Start(ctx, "NotificationRepository.Create",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("user_id", input.UserId),
+		attribute.String("type", input.Type),
+	)
+
+	in := map[string]interface{}{
+		"user_id": input.UserId,
+		"title":   input.Title,
+		"message": input.Message,
+		"is_read": input.IsRead,
+		"type":    input.Type,
+	}
+
+	var created model.Notification
+	err = repo.SQLHandler.Insert(ctx, &created, in, NotificationTable)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	*input = created
+	span.SetAttributes(attribute.Int("id", created.ID))
+	return
+}
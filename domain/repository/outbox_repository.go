@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OutboxRepositoryInterface ...
+type OutboxRepositoryInterface interface {
+	Add(ctx context.Context, event model.DomainEvent) (err error)
+	ClaimPending(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) (messages []model.OutboxMessage, err error)
+	MarkPublished(ctx context.Context, id int64, attempts int, publishedAt time.Time) (err error)
+	MarkFailed(ctx context.Context, id int64, attempts int, status string, nextAttemptAt time.Time, lastError string) (err error)
+}
+
+// OutboxRepository ...
+type OutboxRepository struct {
+	database.SQLHandler
+}
+
+const OutboxTable = "outbox"
+
+// Add ドメインイベントを配信待ちとして記録する（業務データの変更と同じトランザクション内で呼び出す）
+func (repo *OutboxRepository) Add(ctx context.Context, event model.DomainEvent) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("outbox-repository")
+	ctx, span := tracer.Start(ctx, "OutboxRepository.Add",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("event_type", event.EventName()),
+	)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+
+	in := map[string]interface{}{
+		"event_type": event.EventName(),
+		"payload":    string(payload),
+		"status":     model.OutboxStatusPending,
+	}
+	err = repo.SQLHandler.Create(ctx, in, OutboxTable)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return
+}
+
+// ClaimPending 配信時刻を過ぎた配信待ちのイベントを古い順に取得し、次の配信時刻をleaseUntilまで後ろにずらす
+//
+// 取得時は他のリレーが処理中のイベントを読み飛ばすため、複数のタスクで同時に実行できる。
+// ずらした配信時刻がリースとなり、コミット後は行ロックを保持せずにleaseUntilまで他のリレーに取得されない。
+// 結果を記録する前にリレーが停止した場合は、リースの期限が過ぎると再び配信される
+func (repo *OutboxRepository) ClaimPending(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) (messages []model.OutboxMessage, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("outbox-repository")
+	ctx, span := tracer.Start(ctx, "OutboxRepository.ClaimPending",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int("limit", limit),
+	)
+
+	whereClause := "status = :status AND next_attempt_at <= :now"
+	whereArgs := map[string]interface{}{"status": model.OutboxStatusPending, "now": now}
+	err = repo.SQLHandler.SelectForUpdate(ctx, &messages, OutboxTable, whereClause, whereArgs, "id", limit)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if len(messages) == 0 {
+		return
+	}
+
+	ids := make(pq.Int64Array, len(messages))
+	for i := range messages {
+		ids[i] = messages[i].ID
+		messages[i].NextAttemptAt = leaseUntil
+	}
+	err = repo.SQLHandler.Update(ctx, map[string]interface{}{"next_attempt_at": leaseUntil}, OutboxTable, "id = ANY(:ids)", map[string]interface{}{"ids": ids})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// 結果の属性を追加
+	span.SetAttributes(
+		attribute.Int("result_count", len(messages)),
+	)
+	return
+}
+
+// MarkPublished イベントを配信済みにする
+//
+// リースの期限切れで他のリレーが先に結果を記録した配信待ちでないイベントは変更しない
+func (repo *OutboxRepository) MarkPublished(ctx context.Context, id int64, attempts int, publishedAt time.Time) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("outbox-repository")
+	ctx, span := tracer.Start(ctx, "OutboxRepository.MarkPublished",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("id", id),
+	)
+
+	in := map[string]interface{}{
+		"status":       model.OutboxStatusPublished,
+		"attempts":     attempts,
+		"published_at": publishedAt,
+		// 再試行で配信できた場合は直前の失敗の内容を消す（nilの値は更新対象外となるため型付きのnilでNULLを設定する）
+		"last_error": (*string)(nil),
+	}
+	whereClause := "id = :id AND status = :from_status"
+	whereArgs := map[string]interface{}{"id": id, "from_status": model.OutboxStatusPending}
+	err = repo.SQLHandler.Update(ctx, in, OutboxTable, whereClause, whereArgs)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return
+}
+
+// MarkFailed 配信の失敗を記録する（statusがpendingの場合はnextAttemptAtに再試行する）
+//
+// MarkPublishedと同様に、配信待ちでないイベントは変更しない
+func (repo *OutboxRepository) MarkFailed(ctx context.Context, id int64, attempts int, status string, nextAttemptAt time.Time, lastError string) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("outbox-repository")
+	ctx, span := tracer.Start(ctx, "OutboxRepository.MarkFailed",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("id", id),
+		attribute.Int("attempts", attempts),
+		attribute.String("status", status),
+	)
+
+	in := map[string]interface{}{
+		"status":          status,
+		"attempts":        attempts,
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      lastError,
+	}
+	whereClause := "id = :id AND status = :from_status"
+	whereArgs := map[string]interface{}{"id": id, "from_status": model.OutboxStatusPending}
+	err = repo.SQLHandler.Update(ctx, in, OutboxTable, whereClause, whereArgs)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return
+}
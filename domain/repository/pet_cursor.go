@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// petSortColumns sortパラメータで指定できるキーと並び替えるカラムの対応
+var petSortColumns = map[string]string{
+	"price":      "price",
+	"likes":      "likes",
+	"birth_date": "birth_date",
+	"created_at": "created_at",
+}
+
+// petSort 並び順。同じ値の行はidで並べ、キーセットページングで順序が一意になるようにする
+type petSort struct {
+	key    string
+	column string
+	desc   bool
+}
+
+// parseSort sortパラメータを解釈する（未指定の場合はid順）
+func parseSort(sort string) (petSort, error) {
+	if sort == "" {
+		return petSort{column: "id"}, nil
+	}
+
+	key := strings.TrimPrefix(sort, "-")
+	column, ok := petSortColumns[key]
+	if !ok {
+		return petSort{}, fmt.Errorf("%w: unsupported sort %q", ErrInvalidPetFilter, sort)
+	}
+	return petSort{key: sort, column: column, desc: strings.HasPrefix(sort, "-")}, nil
+}
+
+// orderBy ORDER BY句を返す
+func (s petSort) orderBy() string {
+	direction := "ASC"
+	if s.desc {
+		direction = "DESC"
+	}
+	if s.column == "id" {
+		return "id " + direction
+	}
+	return fmt.Sprintf("%s %s, id %s", s.column, direction, direction)
+}
+
+// value 並び替えに使うカラムの値を返す
+func (s petSort) value(p pet) interface{} {
+	switch s.column {
+	case "price":
+		return p.Price
+	case "likes":
+		return p.Likes
+	case "birth_date":
+		if p.BirthDate != nil {
+			return *p.BirthDate
+		}
+	case "created_at":
+		if p.CreatedAt != nil {
+			return *p.CreatedAt
+		}
+	}
+	return nil
+}
+
+// cursorClause カーソルより後ろの行に絞り込む条件を返す
+//
+// PostgreSQLは昇順ではNULLを末尾、降順では先頭に並べるため、
+// 並び替えるカラムがNULLの行を考慮した条件とする
+func (s petSort) cursorClause(cursor petCursor, args map[string]interface{}) string {
+	args["cursor_id"] = cursor.ID
+	if s.column == "id" {
+		if s.desc {
+			return "id < :cursor_id"
+		}
+		return "id > :cursor_id"
+	}
+
+	column := s.column
+	if cursor.Value == nil {
+		if s.desc {
+			return fmt.Sprintf("((%s IS NULL AND id < :cursor_id) OR %s IS NOT NULL)", column, column)
+		}
+		return fmt.Sprintf("(%s IS NULL AND id > :cursor_id)", column)
+	}
+
+	args["cursor_value"] = cursor.Value
+	if s.desc {
+		return fmt.Sprintf("(%s, id) < (:cursor_value, :cursor_id)", column)
+	}
+	return fmt.Sprintf("((%s, id) > (:cursor_value, :cursor_id) OR %s IS NULL)", column, column)
+}
+
+// petCursor キーセットページング用のカーソル
+type petCursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v"`
+	ID    string      `json:"id"`
+}
+
+// encodePetCursor ページの最後の行からカーソルを作成する
+func encodePetCursor(s petSort, last pet) string {
+	data, _ := json.Marshal(petCursor{Sort: s.key, Value: s.value(last), ID: last.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePetCursor カーソルを解釈する。異なる並び順で作成されたカーソルはエラーとする
+func decodePetCursor(s petSort, encoded string) (petCursor, error) {
+	var cursor petCursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, fmt.Errorf("%w: malformed cursor", ErrInvalidPetFilter)
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return cursor, fmt.Errorf("%w: malformed cursor", ErrInvalidPetFilter)
+	}
+	if cursor.Sort != s.key {
+		return cursor, fmt.Errorf("%w: cursor does not match sort %q", ErrInvalidPetFilter, s.key)
+	}
+
+	// JSONでは文字列となる日時を元の型に戻す
+	switch v := cursor.Value.(type) {
+	case string:
+		if s.column != "birth_date" && s.column != "created_at" {
+			return cursor, fmt.Errorf("%w: malformed cursor", ErrInvalidPetFilter)
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return cursor, fmt.Errorf("%w: malformed cursor", ErrInvalidPetFilter)
+		}
+		cursor.Value = t
+	case float64, nil:
+	default:
+		return cursor, fmt.Errorf("%w: malformed cursor", ErrInvalidPetFilter)
+	}
+	return cursor, nil
+}
@@ -2,6 +2,9 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,32 +18,60 @@ import (
 
 const PetsTable = "pets"
 
+// PetsWithShopView 店舗名と所在地を結合したペットのビュー（参照のみ）
+const PetsWithShopView = "pets_with_shop"
+
+const (
+	// MaxPetLimit 1ページで取得できる最大件数
+	MaxPetLimit = 100
+
+	dateLayout = "2006-01-02"
+)
+
+// ErrInvalidPetFilter 検索条件が不正な場合のエラー
+var ErrInvalidPetFilter = errors.New("invalid pet filter")
+
+// ErrDuplicateReferenceNumber 管理番号が他のペットと重複する場合のエラー
+var ErrDuplicateReferenceNumber = errors.New("duplicate reference number")
+
 // pet... pets テーブルの各カラムと対応する構造体
 type pet struct {
-	ID              string         `db:"id"`
-	Name            string         `db:"name"`
-	Breed           string         `db:"breed"`
-	Gender          string         `db:"gender"`
-	Price           float64        `db:"price"`
-	ImageURL        *string        `db:"image_url"`
-	Likes           int            `db:"likes"`
+	ID       string  `db:"id"`
+	Name     string  `db:"name"`
+	Breed    string  `db:"breed"`
+	Gender   string  `db:"gender"`
+	Price    float64 `db:"price"`
+	ImageURL *string `db:"image_url"`
+	Likes    int     `db:"likes"`
+	ShopID   int64   `db:"shop_id"`
+	// ShopName, ShopLocation PetsWithShopViewから取得した場合のみ設定される
 	ShopName        string         `db:"shop_name"`
 	ShopLocation    string         `db:"shop_location"`
 	BirthDate       *time.Time     `db:"birth_date"`
 	ReferenceNumber string         `db:"reference_number"`
 	Tags            pq.StringArray `db:"tags"`
+	Status          string         `db:"status"`
 	CreatedAt       *time.Time     `db:"created_at"`
 	UpdatedAt       *time.Time     `db:"updated_at"`
 }
 
 type pets struct {
 	Data []pet
+	// NextCursor 次のページが存在する場合のカーソル
+	NextCursor *string
+	// TotalCount カーソルを除いた検索条件に一致する件数
+	TotalCount int
 }
 
 // PetRepositoryInterface ...
 type PetRepositoryInterface interface {
 	Find(ctx context.Context, filter *model.PetFilter) (pets pets, err error)
-	Update(ctx context.Context, input *model.Pet) (err error)
+	FindByID(ctx context.Context, id string) (pet *model.Pet, err error)
+	FindByIDForUpdate(ctx context.Context, id string) (pet *model.Pet, err error)
+	Create(ctx context.Context, input *model.Pet) (err error)
+	Update(ctx context.Context, id string, changes *model.PetChanges) (err error)
+	UpdateStatus(ctx context.Context, id string, fromStatus string, toStatus string) (err error)
+	Delete(ctx context.Context, id string) (err error)
 }
 
 // PetRepository ...
@@ -57,42 +88,161 @@ func (repo *PetRepository) Find(ctx context.Context, filter *model.PetFilter) (p
 	)
 	defer span.End()
 
+	if filter == nil {
+		filter = &model.PetFilter{}
+	}
+
 	// フィルタ条件をリポジトリで解釈する型に変換
-	whereClause, args := parseFilter(filter)
+	whereClause, args, err := parseFilter(filter)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	sort, err := parseSort(filter.Sort)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if filter.Limit < 0 {
+		err = fmt.Errorf("%w: limit must not be negative", ErrInvalidPetFilter)
+		span.RecordError(err)
+		return
+	}
+	limit := filter.Limit
+	if limit > MaxPetLimit {
+		limit = MaxPetLimit
+	}
 
-	// 属性を追加
-	if filter != nil {
-		span.SetAttributes(
-			attribute.String("filter.id", filter.ID),
-			attribute.String("filter.name", filter.Name),
-			attribute.String("filter.gender", filter.Gender),
-			attribute.Float64("filter.price", filter.Price),
-		)
+	// カーソル以降の行に絞り込む（件数はカーソルを除いた条件で数える）
+	conditions := whereClause
+	if filter.Cursor != "" {
+		cursor, cursorErr := decodePetCursor(sort, filter.Cursor)
+		if cursorErr != nil {
+			err = cursorErr
+			span.RecordError(err)
+			return
+		}
+		conditions = append(append([]string{}, whereClause...), sort.cursorClause(cursor, args))
 	}
+
+	// 属性を追加
 	span.SetAttributes(
-		attribute.String("where_clause", strings.Join(whereClause, " and ")),
+		attribute.String("filter.id", filter.ID),
+		attribute.String("filter.name", filter.Name),
+		attribute.String("filter.gender", filter.Gender),
+		attribute.Float64("filter.price", filter.Price),
+		attribute.StringSlice("filter.status", filter.Status),
+		attribute.Int64("filter.shop_id", filter.ShopID),
+		attribute.String("filter.sort", filter.Sort),
+		attribute.Int("filter.limit", limit),
+		attribute.Bool("filter.cursor", filter.Cursor != ""),
+		attribute.String("where_clause", strings.Join(conditions, " and ")),
 	)
 
 	// インフラストラクチャレイヤの処理を実行
-	err = repo.SQLHandler.Where(ctx, &pets.Data, PetsTable, strings.Join(whereClause, " and "), args)
+	// 次のページの有無を判定するため1件多く取得する
+	fetch := 0
+	if limit > 0 {
+		fetch = limit + 1
+	}
+	err = repo.SQLHandler.Select(ctx, &pets.Data, PetsWithShopView, strings.Join(conditions, " and "), args, sort.orderBy(), fetch)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
 
-	// 結果の属性を追加
-	if err == nil {
-		span.SetAttributes(
-			attribute.Int("result_count", len(pets.Data)),
-		)
+	if limit > 0 && len(pets.Data) > limit {
+		pets.Data = pets.Data[:limit]
+		next := encodePetCursor(sort, pets.Data[limit-1])
+		pets.NextCursor = &next
+	}
+
+	if limit > 0 || filter.Cursor != "" {
+		err = repo.SQLHandler.Count(ctx, &pets.TotalCount, PetsWithShopView, strings.Join(whereClause, " and "), args)
+		if err != nil {
+			span.RecordError(err)
+			return
+		}
 	} else {
+		pets.TotalCount = len(pets.Data)
+	}
+
+	// 結果の属性を追加
+	span.SetAttributes(
+		attribute.Int("result_count", len(pets.Data)),
+		attribute.Int("total_count", pets.TotalCount),
+	)
+
+	return
+}
+
+// FindByID ペットを取得する（存在しない場合はnil）
+func (repo *PetRepository) FindByID(ctx context.Context, id string) (result *model.Pet, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("pet-repository")
+	ctx, span := tracer.Start(ctx, "PetRepository.FindByID",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("pet_id", id),
+	)
+
+	var rows []pet
+	err = repo.SQLHandler.Where(ctx, &rows, PetsWithShopView, "id = :id", map[string]interface{}{"id": id})
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if len(rows) > 0 {
+		p := toPetModel(rows[0])
+		result = &p
+	}
+	return
+}
+
+// FindByIDForUpdate ペットを取得し、トランザクションの終了まで行をロックする
+//
+// 他のトランザクションがロックしている場合は解放されるまで待つ（存在しない場合はnil）
+// 同じ店舗のペットを並行して操作できるよう、店舗の行はロックせずにpetsの行のみをロックする
+func (repo *PetRepository) FindByIDForUpdate(ctx context.Context, id string) (result *model.Pet, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("pet-repository")
+	ctx, span := tracer.Start(ctx, "PetRepository.FindByIDForUpdate",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("pet_id", id),
+	)
+
+	var rows []pet
+	err = repo.SQLHandler.SelectForUpdateWait(ctx, &rows, PetsTable, "id = :id", map[string]interface{}{"id": id}, "", 1)
+	if err != nil {
 		span.RecordError(err)
+		return
+	}
+	if len(rows) == 0 {
+		return
 	}
 
+	// ロックした行を店舗とあわせて取得し直す
+	result, err = repo.FindByID(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+	}
 	return
 }
 
-// Update ...
-func (repo *PetRepository) Update(ctx context.Context, input *model.Pet) (err error) {
+// Create ペットを登録し、登録日時などの既定値と店舗名・所在地をinputに反映する
+func (repo *PetRepository) Create(ctx context.Context, input *model.Pet) (err error) {
 	// スパンを作成
 	tracer := otel.Tracer("pet-repository")
-	ctx, span := tracer.Start(ctx, "PetRepository.Update",
+	ctx, span := tracer.Start(ctx, "PetRepository.Create",
 		trace.WithSpanKind(trace.SpanKindInternal),
 	)
 	defer span.End()
@@ -100,43 +250,217 @@ func (repo *PetRepository) Update(ctx context.Context, input *model.Pet) (err er
 	// 属性を追加
 	span.SetAttributes(
 		attribute.String("pet_id", input.ID),
+		attribute.String("reference_number", input.ReferenceNumber),
+		attribute.Int64("shop_id", input.Shop.ID),
 	)
 
 	// Petドメインモデルをリポジトリモデルに変換
-	now := time.Now()
-	setParams := map[string]interface{}{
+	tags := input.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	status := input.Status
+	if status == "" {
+		status = model.PetStatusAvailable
+	}
+	in := map[string]interface{}{
+		"id":               input.ID,
 		"name":             input.Name,
 		"breed":            input.Breed,
 		"gender":           input.Gender,
 		"price":            input.Price,
 		"image_url":        input.ImageURL,
 		"likes":            input.Likes,
-		"shop_name":        input.Shop.Name,
-		"shop_location":    input.Shop.Location,
+		"shop_id":          input.Shop.ID,
 		"birth_date":       input.BirthDate,
 		"reference_number": input.ReferenceNumber,
-		"tags":             pq.StringArray(input.Tags),
-		"updated_at":       &now,
+		"tags":             pq.StringArray(tags),
+		"status":           status,
+	}
+
+	var created pet
+	err = repo.SQLHandler.Insert(ctx, &created, in, PetsTable)
+	if err != nil {
+		err = translatePetError(err)
+		span.RecordError(err)
+		return
 	}
 
-	// WHERE句のパラメータ
-	whereClause := "id = :id"
-	whereParams := map[string]interface{}{
-		"id": input.ID,
+	// 店舗名と所在地を結合して取得し直す
+	var rows []pet
+	err = repo.SQLHandler.Where(ctx, &rows, PetsWithShopView, "id = :id", map[string]interface{}{"id": created.ID})
+	if err != nil {
+		span.RecordError(err)
+		return
 	}
+	if len(rows) == 0 {
+		err = fmt.Errorf("shop not found: %d", input.Shop.ID)
+		span.RecordError(err)
+		return
+	}
+	*input = toPetModel(rows[0])
+	return
+}
+
+// Update ペットのchangesで指定された項目のみを更新する
+func (repo *PetRepository) Update(ctx context.Context, id string, changes *model.PetChanges) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("pet-repository")
+	ctx, span := tracer.Start(ctx, "PetRepository.Update",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// PetChangesをリポジトリモデルのカラムに変換
+	now := time.Now()
+	setParams := map[string]interface{}{
+		"updated_at": &now,
+	}
+	if changes.Name != nil {
+		setParams["name"] = *changes.Name
+	}
+	if changes.Breed != nil {
+		setParams["breed"] = *changes.Breed
+	}
+	if changes.Gender != nil {
+		setParams["gender"] = *changes.Gender
+	}
+	if changes.Price != nil {
+		setParams["price"] = *changes.Price
+	}
+	if changes.ImageURL != nil {
+		var imageURL *string
+		if *changes.ImageURL != "" {
+			imageURL = changes.ImageURL
+		}
+		setParams["image_url"] = imageURL
+	}
+	if changes.ShopID != nil {
+		setParams["shop_id"] = *changes.ShopID
+	}
+	if changes.BirthDate != nil {
+		var birthDate *time.Time
+		if !changes.BirthDate.IsZero() {
+			birthDate = changes.BirthDate
+		}
+		setParams["birth_date"] = birthDate
+	}
+	if changes.ReferenceNumber != nil {
+		setParams["reference_number"] = *changes.ReferenceNumber
+	}
+	if changes.Tags != nil {
+		setParams["tags"] = pq.StringArray(changes.Tags)
+	}
+	if changes.Likes != nil {
+		setParams["likes"] = *changes.Likes
+	}
+
+	// 属性を追加
+	columns := make([]string, 0, len(setParams))
+	for column := range setParams {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	span.SetAttributes(
+		attribute.String("pet_id", id),
+		attribute.StringSlice("columns", columns),
+	)
 
 	// SQLHandlerを呼び出し
-	err = repo.SQLHandler.Update(ctx, setParams, PetsTable, whereClause, whereParams)
+	err = repo.SQLHandler.Update(ctx, setParams, PetsTable, "id = :id", map[string]interface{}{"id": id})
+	if err != nil {
+		err = translatePetError(err)
+		span.RecordError(err)
+	}
+	return
+}
+
+// UpdateStatus ペットのステータスを更新する（現在のステータスがfromStatusの場合のみ）
+func (repo *PetRepository) UpdateStatus(ctx context.Context, id string, fromStatus string, toStatus string) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("pet-repository")
+	ctx, span := tracer.Start(ctx, "PetRepository.UpdateStatus",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
 
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("pet_id", id),
+		attribute.String("from_status", fromStatus),
+		attribute.String("to_status", toStatus),
+	)
+
+	now := time.Now()
+	setParams := map[string]interface{}{
+		"status":     toStatus,
+		"updated_at": &now,
+	}
+	whereClause := "id = :id AND status = :from_status"
+	whereParams := map[string]interface{}{"id": id, "from_status": fromStatus}
+
+	err = repo.SQLHandler.Update(ctx, setParams, PetsTable, whereClause, whereParams)
 	if err != nil {
 		span.RecordError(err)
 	}
+	return
+}
+
+// Delete ペットを削除する（予約・お気に入りは外部キーのON DELETE CASCADEで削除される）
+func (repo *PetRepository) Delete(ctx context.Context, id string) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("pet-repository")
+	ctx, span := tracer.Start(ctx, "PetRepository.Delete",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
 
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("pet_id", id),
+	)
+
+	err = repo.SQLHandler.Delete(ctx, map[string]interface{}{"id": id}, PetsTable)
+	if err != nil {
+		span.RecordError(err)
+	}
 	return
 }
 
+// translatePetError 一意制約の違反を管理番号の重複として返す（IDはUUIDのため重複しない）
+func translatePetError(err error) error {
+	if errors.Is(err, database.ErrUniqueViolation) {
+		return fmt.Errorf("%w: %v", ErrDuplicateReferenceNumber, err)
+	}
+	return err
+}
+
+// toPetModel リポジトリモデルをPetドメインモデルに変換する（予約数は含まない）
+func toPetModel(p pet) model.Pet {
+	return model.Pet{
+		ID:       p.ID,
+		Name:     p.Name,
+		Breed:    p.Breed,
+		Gender:   p.Gender,
+		Price:    p.Price,
+		ImageURL: p.ImageURL,
+		Likes:    p.Likes,
+		Shop: model.Shop{
+			ID:       p.ShopID,
+			Name:     p.ShopName,
+			Location: p.ShopLocation,
+		},
+		BirthDate:       p.BirthDate,
+		ReferenceNumber: p.ReferenceNumber,
+		Tags:            p.Tags,
+		Status:          p.Status,
+		CreatedAt:       p.CreatedAt,
+		UpdatedAt:       p.UpdatedAt,
+	}
+}
+
 // parseFilter ... フィルタ条件を解釈してクエリ条件とバインド変数を返す
-func parseFilter(filter *model.PetFilter) ([]string, map[string]interface{}) {
+func parseFilter(filter *model.PetFilter) ([]string, map[string]interface{}, error) {
 	args := map[string]interface{}{}
 	whereClause := make([]string, 0)
 
@@ -166,10 +490,76 @@ func parseFilter(filter *model.PetFilter) ([]string, map[string]interface{}) {
 			whereClause = append(whereClause, "reference_number = :reference_number")
 			args["reference_number"] = filter.ReferenceNumber
 		}
+		if filter.ShopID != 0 {
+			whereClause = append(whereClause, "shop_id = :shop_id")
+			args["shop_id"] = filter.ShopID
+		}
 		if filter.Breed != "" {
 			whereClause = append(whereClause, "breed = :breed")
 			args["breed"] = filter.Breed
 		}
+		if filter.Q != "" {
+			whereClause = append(whereClause, "name ILIKE :q")
+			args["q"] = "%" + escapeLike(filter.Q) + "%"
+		}
+		if filter.PriceMin != 0 && filter.PriceMax != 0 && filter.PriceMin > filter.PriceMax {
+			return nil, nil, fmt.Errorf("%w: price_min must not exceed price_max", ErrInvalidPetFilter)
+		}
+		if filter.PriceMin != 0 {
+			whereClause = append(whereClause, "price >= :price_min")
+			args["price_min"] = filter.PriceMin
+		}
+		if filter.PriceMax != 0 {
+			whereClause = append(whereClause, "price <= :price_max")
+			args["price_max"] = filter.PriceMax
+		}
+		if filter.BirthDateFrom != "" {
+			from, err := time.Parse(dateLayout, filter.BirthDateFrom)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: invalid birth_date_from %q", ErrInvalidPetFilter, filter.BirthDateFrom)
+			}
+			whereClause = append(whereClause, "birth_date >= :birth_date_from")
+			args["birth_date_from"] = from
+		}
+		if filter.BirthDateTo != "" {
+			to, err := time.Parse(dateLayout, filter.BirthDateTo)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: invalid birth_date_to %q", ErrInvalidPetFilter, filter.BirthDateTo)
+			}
+			whereClause = append(whereClause, "birth_date <= :birth_date_to")
+			args["birth_date_to"] = to
+		}
+		if tags := splitList(filter.Tags); len(tags) > 0 {
+			whereClause = append(whereClause, "tags @> :tags")
+			args["tags"] = pq.StringArray(tags)
+		}
+		if statuses := splitList(filter.Status); len(statuses) > 0 {
+			for _, status := range statuses {
+				if !model.IsValidPetStatus(status) {
+					return nil, nil, fmt.Errorf("%w: unknown status %q", ErrInvalidPetFilter, status)
+				}
+			}
+			whereClause = append(whereClause, "status = ANY(:statuses)")
+			args["statuses"] = pq.StringArray(statuses)
+		}
+	}
+	return whereClause, args, nil
+}
+
+// escapeLike LIKEのワイルドカードとして解釈されないようにエスケープする
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// splitList 複数指定・カンマ区切りの値（タグ・ステータス）を1つのリストにまとめる
+func splitList(values []string) []string {
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				list = append(list, v)
+			}
+		}
 	}
-	return whereClause, args
+	return list
 }
@@ -2,10 +2,13 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/horsewin/echo-playground-v2/domain/model"
 	"github.com/horsewin/echo-playground-v2/interface/database"
+	"github.com/lib/pq"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -15,6 +18,14 @@ import (
 type ReservationRepositoryInterface interface {
 	Create(ctx context.Context, input *model.Reservation) (err error)
 	GetCountByPetID(ctx context.Context, petID string) (count int64, err error)
+	GetCountsByPetIDs(ctx context.Context, petIDs []string) (counts map[string]int64, err error)
+	GetActiveCountByPetID(ctx context.Context, petID string) (count int64, err error)
+	FindByUserID(ctx context.Context, userID string, status string) (reservations model.Reservations, err error)
+	FindByID(ctx context.Context, id int) (reservation *model.ReservationDetail, err error)
+	UpdateStatus(ctx context.Context, id int, fromStatus string, toStatus string) (err error)
+	Reschedule(ctx context.Context, id int, fromStatus string, slot model.ReservationSlot) (err error)
+	FindSeats(ctx context.Context, shopID int64, startsAt time.Time) (seats map[int]int, err error)
+	CountBySlot(ctx context.Context, shopID int64, from time.Time, to time.Time) (counts map[int64]int64, err error)
 }
 
 // ReservationRepository ...
@@ -24,6 +35,9 @@ type ReservationRepository struct {
 
 const ReservationTable = "reservations"
 
+// ErrSlotSeatTaken 予約枠の席が他の予約で確保された場合のエラー
+var ErrSlotSeatTaken = errors.New("slot seat already taken")
+
 // Create ...
 func (repo *ReservationRepository) Create(ctx context.Context, input *model.Reservation) (err error) {
 	// スパンを作成
@@ -37,29 +51,34 @@ func (repo *ReservationRepository) Create(ctx context.Context, input *model.Rese
 	span.SetAttributes(
 		attribute.String("pet_id", input.PetId),
 		attribute.String("user_id", input.UserId),
-		attribute.String("reservation_date", input.ReservationDate),
+		attribute.String("slot_starts_at", input.SlotStartsAt.Format(time.RFC3339)),
+		attribute.Int64("shop_id", input.ShopID),
 	)
 
 	// ドメインモデルをmapに変換
-	rsvDatetime, err := time.Parse("20060102", input.ReservationDate)
-	if err != nil {
-		return
-	}
-
 	in := map[string]interface{}{
 		"pet_id":    input.PetId,
 		"user_id":   input.UserId,
 		"email":     input.Email,
 		"user_name": input.FullName,
-		// yyyymmdd形式をdatetimeに変換
-		"reservation_date_time": rsvDatetime,
-		"status":                "pending", // デフォルトステータスを設定
+		// 見学予定日時は店舗の現地時刻で保存する
+		"reservation_date_time": localDateTime(input.SlotStartsAt),
+		"status":                model.ReservationStatusPending, // デフォルトステータスを設定
+	}
+	for column, value := range slotColumns(model.ReservationSlot{
+		ShopID:   input.ShopID,
+		StartsAt: input.SlotStartsAt,
+		Timezone: input.SlotTimezone,
+		Seat:     input.SlotSeat,
+	}) {
+		in[column] = value
 	}
 
 	// リポジトリモデルをDBに保存
 	err = repo.SQLHandler.Create(ctx, in, ReservationTable)
 
 	if err != nil {
+		err = translateReservationError(err)
 		span.RecordError(err)
 	}
 
@@ -92,3 +111,316 @@ func (repo *ReservationRepository) GetCountByPetID(ctx context.Context, petID st
 
 	return
 }
+
+// GetActiveCountByPetID ペットの有効な予約（確定待ち・確定済み）の数を取得する
+func (repo *ReservationRepository) GetActiveCountByPetID(ctx context.Context, petID string) (count int64, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("reservation-repository")
+	ctx, span := tracer.Start(ctx, "ReservationRepository.GetActiveCountByPetID",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("pet_id", petID),
+	)
+
+	var tmpCount int
+	whereClause := "pet_id = :pet_id AND status = ANY(:statuses)"
+	whereArgs := map[string]interface{}{
+		"pet_id":   petID,
+		"statuses": pq.StringArray{model.ReservationStatusPending, model.ReservationStatusConfirmed},
+	}
+	err = repo.SQLHandler.Count(ctx, &tmpCount, ReservationTable, whereClause, whereArgs)
+	count = int64(tmpCount)
+
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return
+}
+
+// reservationCount ペットごとの予約数の集計結果
+type reservationCount struct {
+	PetID string `db:"pet_id"`
+	Count int64  `db:"count"`
+}
+
+// GetCountsByPetIDs 複数のペットの予約数を1回のクエリで取得する（予約のないペットは結果に含まれない）
+func (repo *ReservationRepository) GetCountsByPetIDs(ctx context.Context, petIDs []string) (counts map[string]int64, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("reservation-repository")
+	ctx, span := tracer.Start(ctx, "ReservationRepository.GetCountsByPetIDs",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int("pet_ids.count", len(petIDs)),
+	)
+
+	counts = make(map[string]int64, len(petIDs))
+	if len(petIDs) == 0 {
+		return
+	}
+
+	var rows []reservationCount
+	whereClause := "pet_id = ANY(:pet_ids)"
+	whereArgs := map[string]interface{}{"pet_ids": pq.StringArray(petIDs)}
+	err = repo.SQLHandler.CountBy(ctx, &rows, ReservationTable, "pet_id", whereClause, whereArgs)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+
+	for _, row := range rows {
+		counts[row.PetID] = row.Count
+	}
+	return
+}
+
+// FindByUserID ユーザの予約を見学予定日時の昇順で取得する（statusが空の場合は全ステータス）
+func (repo *ReservationRepository) FindByUserID(ctx context.Context, userID string, status string) (reservations model.Reservations, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("reservation-repository")
+	ctx, span := tracer.Start(ctx, "ReservationRepository.FindByUserID",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("user_id", userID),
+		attribute.String("status", status),
+	)
+
+	whereClause := "user_id = :user_id"
+	whereArgs := map[string]interface{}{"user_id": userID}
+	if status != "" {
+		whereClause += " AND status = :status"
+		whereArgs["status"] = status
+	}
+
+	reservations.Data = []model.ReservationDetail{}
+	err = repo.SQLHandler.Select(ctx, &reservations.Data, ReservationTable, whereClause, whereArgs, "reservation_date_time ASC, id ASC", 0)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	for i := range reservations.Data {
+		localizeSlot(&reservations.Data[i])
+	}
+	return
+}
+
+// FindByID 予約を取得する（存在しない場合はnil）
+func (repo *ReservationRepository) FindByID(ctx context.Context, id int) (reservation *model.ReservationDetail, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("reservation-repository")
+	ctx, span := tracer.Start(ctx, "ReservationRepository.FindByID",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int("reservation_id", id),
+	)
+
+	var rows []model.ReservationDetail
+	err = repo.SQLHandler.Where(ctx, &rows, ReservationTable, "id = :id", map[string]interface{}{"id": id})
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if len(rows) > 0 {
+		reservation = &rows[0]
+		localizeSlot(reservation)
+	}
+	return
+}
+
+// UpdateStatus 予約のステータスを変更する
+// 他の操作と競合しないよう、ステータスがfromStatusの場合のみ更新する
+func (repo *ReservationRepository) UpdateStatus(ctx context.Context, id int, fromStatus string, toStatus string) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("reservation-repository")
+	ctx, span := tracer.Start(ctx, "ReservationRepository.UpdateStatus",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int("reservation_id", id),
+		attribute.String("from_status", fromStatus),
+		attribute.String("to_status", toStatus),
+	)
+
+	now := time.Now()
+	setParams := map[string]interface{}{
+		"status":     toStatus,
+		"updated_at": &now,
+	}
+	// キャンセルした予約は予約枠の席を空ける（nilの値は更新対象外となるため型付きのnilでNULLを設定する）
+	if toStatus == model.ReservationStatusCancelled {
+		setParams["slot_seat"] = (*int)(nil)
+	}
+	whereClause := "id = :id AND status = :from_status"
+	whereParams := map[string]interface{}{"id": id, "from_status": fromStatus}
+
+	err = repo.SQLHandler.Update(ctx, setParams, ReservationTable, whereClause, whereParams)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return
+}
+
+// Reschedule 予約の予約枠を変更し、再度確定が必要な状態(pending)に戻す
+// 他の操作と競合しないよう、ステータスがfromStatusの場合のみ更新する
+func (repo *ReservationRepository) Reschedule(ctx context.Context, id int, fromStatus string, slot model.ReservationSlot) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("reservation-repository")
+	ctx, span := tracer.Start(ctx, "ReservationRepository.Reschedule",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int("reservation_id", id),
+		attribute.String("from_status", fromStatus),
+		attribute.String("slot_starts_at", slot.StartsAt.Format(time.RFC3339)),
+		attribute.Int64("shop_id", slot.ShopID),
+	)
+
+	now := time.Now()
+	setParams := map[string]interface{}{
+		"reservation_date_time": localDateTime(slot.StartsAt),
+		"status":                model.ReservationStatusPending,
+		"updated_at":            &now,
+	}
+	for column, value := range slotColumns(slot) {
+		setParams[column] = value
+	}
+	whereClause := "id = :id AND status = :from_status"
+	whereParams := map[string]interface{}{"id": id, "from_status": fromStatus}
+
+	err = repo.SQLHandler.Update(ctx, setParams, ReservationTable, whereClause, whereParams)
+	if err != nil {
+		err = translateReservationError(err)
+		span.RecordError(err)
+	}
+	return
+}
+
+// FindSeats 予約枠で確保されている席番号と、席を確保している予約のIDを取得する
+func (repo *ReservationRepository) FindSeats(ctx context.Context, shopID int64, startsAt time.Time) (seats map[int]int, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("reservation-repository")
+	ctx, span := tracer.Start(ctx, "ReservationRepository.FindSeats",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("shop_id", shopID),
+		attribute.String("slot_starts_at", startsAt.Format(time.RFC3339)),
+	)
+
+	var rows []model.ReservationDetail
+	whereClause := "shop_id = :shop_id AND slot_starts_at = :slot_starts_at AND slot_seat IS NOT NULL"
+	whereArgs := map[string]interface{}{"shop_id": shopID, "slot_starts_at": startsAt}
+	err = repo.SQLHandler.Where(ctx, &rows, ReservationTable, whereClause, whereArgs)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+
+	seats = make(map[int]int, len(rows))
+	for _, row := range rows {
+		seats[*row.SlotSeat] = row.ID
+	}
+	return
+}
+
+// slotCount 予約枠ごとの予約数の集計結果
+type slotCount struct {
+	SlotStartsAt time.Time `db:"slot_starts_at"`
+	Count        int64     `db:"count"`
+}
+
+// CountBySlot 店舗の[from, to)に開始する予約枠ごとに、席を確保している予約の数を取得する
+//
+// 結果は予約枠の開始日時（Unix時間の秒）をキーとする（予約のない枠は結果に含まれない）
+func (repo *ReservationRepository) CountBySlot(ctx context.Context, shopID int64, from time.Time, to time.Time) (counts map[int64]int64, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("reservation-repository")
+	ctx, span := tracer.Start(ctx, "ReservationRepository.CountBySlot",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("shop_id", shopID),
+		attribute.String("from", from.Format(time.RFC3339)),
+		attribute.String("to", to.Format(time.RFC3339)),
+	)
+
+	var rows []slotCount
+	whereClause := "shop_id = :shop_id AND slot_starts_at >= :from AND slot_starts_at < :to AND slot_seat IS NOT NULL"
+	whereArgs := map[string]interface{}{"shop_id": shopID, "from": from, "to": to}
+	err = repo.SQLHandler.CountBy(ctx, &rows, ReservationTable, "slot_starts_at", whereClause, whereArgs)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+
+	counts = make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		counts[row.SlotStartsAt.Unix()] += row.Count
+	}
+	return
+}
+
+// slotColumns 予約枠をカラムに変換する（枠を持たない予約の場合はNULL）
+func slotColumns(slot model.ReservationSlot) map[string]interface{} {
+	if slot.ShopID == 0 {
+		return map[string]interface{}{"shop_id": nil, "slot_starts_at": nil, "slot_timezone": nil, "slot_seat": nil}
+	}
+	return map[string]interface{}{
+		"shop_id":        slot.ShopID,
+		"slot_starts_at": slot.StartsAt.UTC(),
+		"slot_timezone":  slot.Timezone,
+		"slot_seat":      slot.Seat,
+	}
+}
+
+// localDateTime タイムゾーンを除いた現地時刻を返す（TIMESTAMP型のカラムに保存するため）
+func localDateTime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// localizeSlot 予約枠の開始日時を予約時の店舗のタイムゾーンで表す
+func localizeSlot(reservation *model.ReservationDetail) {
+	if reservation.SlotStartsAt == nil || reservation.SlotTimezone == nil {
+		return
+	}
+	if loc, err := time.LoadLocation(*reservation.SlotTimezone); err == nil {
+		local := reservation.SlotStartsAt.In(loc)
+		reservation.SlotStartsAt = &local
+	}
+}
+
+// translateReservationError 予約枠の席の一意制約の違反を返す
+func translateReservationError(err error) error {
+	if errors.Is(err, database.ErrUniqueViolation) {
+		return fmt.Errorf("%w: %v", ErrSlotSeatTaken, err)
+	}
+	return err
+}
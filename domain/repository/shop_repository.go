@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const ShopsTable = "shops"
+
+const ShopOpeningHoursTable = "shop_opening_hours"
+
+// shop ... shops テーブルの各カラムと対応する構造体
+type shop struct {
+	ID           int64      `db:"id"`
+	Name         string     `db:"name"`
+	Location     string     `db:"location"`
+	Timezone     string     `db:"timezone"`
+	SlotMinutes  int        `db:"slot_minutes"`
+	SlotCapacity int        `db:"slot_capacity"`
+	CreatedAt    *time.Time `db:"created_at"`
+	UpdatedAt    *time.Time `db:"updated_at"`
+}
+
+// ShopRepositoryInterface ...
+type ShopRepositoryInterface interface {
+	Find(ctx context.Context) (shops model.Shops, err error)
+	FindByID(ctx context.Context, id int64) (shop *model.Shop, err error)
+	FindByIDForUpdate(ctx context.Context, id int64) (shop *model.Shop, err error)
+	FindSchedule(ctx context.Context, id int64) (schedule *model.ShopSchedule, err error)
+	UpdateSchedule(ctx context.Context, schedule *model.ShopSchedule) (err error)
+}
+
+// ShopRepository ...
+type ShopRepository struct {
+	database.SQLHandler
+}
+
+// Find 店舗を登録順に取得する
+func (repo *ShopRepository) Find(ctx context.Context) (shops model.Shops, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("shop-repository")
+	ctx, span := tracer.Start(ctx, "ShopRepository.Find",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	var rows []shop
+	err = repo.SQLHandler.Select(ctx, &rows, ShopsTable, "", map[string]interface{}{}, "id", 0)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+
+	shops.Data = make([]model.Shop, 0, len(rows))
+	for _, row := range rows {
+		shops.Data = append(shops.Data, toShopModel(row))
+	}
+
+	// 結果の属性を追加
+	span.SetAttributes(
+		attribute.Int("result_count", len(shops.Data)),
+	)
+	return
+}
+
+// FindByID 店舗を取得する（存在しない場合はnil）
+func (repo *ShopRepository) FindByID(ctx context.Context, id int64) (result *model.Shop, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("shop-repository")
+	ctx, span := tracer.Start(ctx, "ShopRepository.FindByID",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("shop_id", id),
+	)
+
+	var rows []shop
+	err = repo.SQLHandler.Where(ctx, &rows, ShopsTable, "id = :id", map[string]interface{}{"id": id})
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if len(rows) > 0 {
+		s := toShopModel(rows[0])
+		result = &s
+	}
+	return
+}
+
+// FindByIDForUpdate 店舗を取得し、トランザクションの終了まで行をロックする
+//
+// 他のトランザクションがロックしている場合は解放されるまで待つ（存在しない場合はnil）
+func (repo *ShopRepository) FindByIDForUpdate(ctx context.Context, id int64) (result *model.Shop, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("shop-repository")
+	ctx, span := tracer.Start(ctx, "ShopRepository.FindByIDForUpdate",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("shop_id", id),
+	)
+
+	var rows []shop
+	err = repo.SQLHandler.SelectForUpdateWait(ctx, &rows, ShopsTable, "id = :id", map[string]interface{}{"id": id}, "", 1)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if len(rows) > 0 {
+		s := toShopModel(rows[0])
+		result = &s
+	}
+	return
+}
+
+// openingHours ... shop_opening_hours テーブルの各カラムと対応する構造体
+type openingHours struct {
+	ShopID   int64  `db:"shop_id"`
+	Weekday  int    `db:"weekday"`
+	OpensAt  string `db:"opens_at"`
+	ClosesAt string `db:"closes_at"`
+}
+
+// FindSchedule 店舗の見学予約の受付設定を取得する（店舗が存在しない場合はnil）
+func (repo *ShopRepository) FindSchedule(ctx context.Context, id int64) (schedule *model.ShopSchedule, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("shop-repository")
+	ctx, span := tracer.Start(ctx, "ShopRepository.FindSchedule",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("shop_id", id),
+	)
+
+	var shops []shop
+	err = repo.SQLHandler.Where(ctx, &shops, ShopsTable, "id = :id", map[string]interface{}{"id": id})
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if len(shops) == 0 {
+		return
+	}
+
+	var hours []openingHours
+	err = repo.SQLHandler.Select(ctx, &hours, ShopOpeningHoursTable, "shop_id = :shop_id", map[string]interface{}{"shop_id": id}, "weekday", 0)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+
+	schedule = &model.ShopSchedule{
+		ShopID:       id,
+		Timezone:     shops[0].Timezone,
+		SlotMinutes:  shops[0].SlotMinutes,
+		SlotCapacity: shops[0].SlotCapacity,
+		OpeningHours: make([]model.OpeningHours, 0, len(hours)),
+	}
+	for _, h := range hours {
+		schedule.OpeningHours = append(schedule.OpeningHours, model.OpeningHours{
+			Weekday:  h.Weekday,
+			OpensAt:  h.OpensAt,
+			ClosesAt: h.ClosesAt,
+		})
+	}
+	return
+}
+
+// UpdateSchedule 店舗の見学予約の受付設定を置き換える（営業時間はすべて登録し直す）
+//
+// 営業時間の削除と登録を分けて行うため、トランザクション内で呼び出すこと
+func (repo *ShopRepository) UpdateSchedule(ctx context.Context, schedule *model.ShopSchedule) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("shop-repository")
+	ctx, span := tracer.Start(ctx, "ShopRepository.UpdateSchedule",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("shop_id", schedule.ShopID),
+		attribute.String("timezone", schedule.Timezone),
+		attribute.Int("slot_minutes", schedule.SlotMinutes),
+		attribute.Int("slot_capacity", schedule.SlotCapacity),
+		attribute.Int("opening_hours.count", len(schedule.OpeningHours)),
+	)
+
+	now := time.Now()
+	setParams := map[string]interface{}{
+		"timezone":      schedule.Timezone,
+		"slot_minutes":  schedule.SlotMinutes,
+		"slot_capacity": schedule.SlotCapacity,
+		"updated_at":    &now,
+	}
+	err = repo.SQLHandler.Update(ctx, setParams, ShopsTable, "id = :id", map[string]interface{}{"id": schedule.ShopID})
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+
+	err = repo.SQLHandler.Delete(ctx, map[string]interface{}{"shop_id": schedule.ShopID}, ShopOpeningHoursTable)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	for _, h := range schedule.OpeningHours {
+		in := map[string]interface{}{
+			"shop_id":   schedule.ShopID,
+			"weekday":   h.Weekday,
+			"opens_at":  h.OpensAt,
+			"closes_at": h.ClosesAt,
+		}
+		if err = repo.SQLHandler.Create(ctx, in, ShopOpeningHoursTable); err != nil {
+			span.RecordError(err)
+			return
+		}
+	}
+	return
+}
+
+// toShopModel リポジトリモデルをShopドメインモデルに変換する
+func toShopModel(s shop) model.Shop {
+	return model.Shop{
+		ID:       s.ID,
+		Name:     s.Name,
+		Location: s.Location,
+	}
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/horsewin/echo-playground-v2/interface/database"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TransactionManagerInterface ...
+type TransactionManagerInterface interface {
+	// Do fnを単一トランザクション内で実行する
+	// fnがエラーを返すかpanicした場合はロールバックし、それ以外はコミットする
+	Do(ctx context.Context, fn func(ctx context.Context) error) (err error)
+}
+
+// TransactionManager ...
+type TransactionManager struct {
+	database.SQLHandler
+}
+
+// inTxKey TransactionManager.Doの実行中であることを示すcontextキー
+type inTxKey struct{}
+
+// Do ...
+func (tm *TransactionManager) Do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	// 既にトランザクション内であれば外側のトランザクションに参加する
+	if inTx, _ := ctx.Value(inTxKey{}).(bool); inTx {
+		return fn(ctx)
+	}
+
+	// スパンを作成
+	tracer := otel.Tracer("transaction-manager")
+	ctx, span := tracer.Start(ctx, "TransactionManager.Do",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	txCtx, err := tm.SQLHandler.Begin(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "begin failed")
+		return err
+	}
+	txCtx = context.WithValue(txCtx, inTxKey{}, true)
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tm.SQLHandler.Rollback(txCtx)
+			span.SetStatus(codes.Error, "rolled back by panic")
+			panic(p)
+		}
+	}()
+
+	if err = fn(txCtx); err != nil {
+		span.RecordError(err)
+		if rbErr := tm.SQLHandler.Rollback(txCtx); rbErr != nil {
+			span.RecordError(rbErr)
+			err = fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		span.SetStatus(codes.Error, "rolled back")
+		return err
+	}
+
+	if err = tm.SQLHandler.Commit(txCtx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "commit failed")
+		return err
+	}
+	span.SetStatus(codes.Ok, "committed")
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/horsewin/echo-playground-v2/infrastructure/memory"
+)
+
+// failingCommitHandler コミットに失敗するSQLHandler（トランザクションはロールバックして解放する）
+type failingCommitHandler struct {
+	*memory.SQLHandler
+	err error
+}
+
+func (h *failingCommitHandler) Commit(ctx context.Context) error {
+	_ = h.SQLHandler.Rollback(ctx)
+	return h.err
+}
+
+func newMemoryTransactionManager(t *testing.T) (*TransactionManager, *memory.SQLHandler) {
+	t.Helper()
+	sqlHandler, err := memory.NewSeededSQLHandler()
+	if err != nil {
+		t.Fatalf("failed to create in-memory database: %v", err)
+	}
+	return &TransactionManager{SQLHandler: sqlHandler}, sqlHandler
+}
+
+// countFavorites トランザクション外から見えるお気に入りの件数を返す
+func countFavorites(t *testing.T, sqlHandler *memory.SQLHandler) int {
+	t.Helper()
+	var count int
+	if err := sqlHandler.Count(context.Background(), &count, "favorites", "", nil); err != nil {
+		t.Fatalf("failed to count favorites: %v", err)
+	}
+	return count
+}
+
+// createFavorite トランザクション内でお気に入りを登録する
+func createFavorite(ctx context.Context, tm *TransactionManager, userID string) error {
+	return tm.SQLHandler.Create(ctx, map[string]interface{}{"user_id": userID, "pet_id": "1"}, "favorites")
+}
+
+func TestTransactionManager_Do_Commit(t *testing.T) {
+	tm, sqlHandler := newMemoryTransactionManager(t)
+	before := countFavorites(t, sqlHandler)
+
+	err := tm.Do(context.Background(), func(ctx context.Context) error {
+		return createFavorite(ctx, tm, "tx-user")
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := countFavorites(t, sqlHandler); got != before+1 {
+		t.Errorf("expected committed write to be visible, got %d favorites", got)
+	}
+}
+
+func TestTransactionManager_Do_RollbackOnError(t *testing.T) {
+	tm, sqlHandler := newMemoryTransactionManager(t)
+	before := countFavorites(t, sqlHandler)
+	fnErr := errors.New("fn failed")
+
+	err := tm.Do(context.Background(), func(ctx context.Context) error {
+		if err := createFavorite(ctx, tm, "tx-user"); err != nil {
+			return err
+		}
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Fatalf("expected fn error, got %v", err)
+	}
+	if got := countFavorites(t, sqlHandler); got != before {
+		t.Errorf("expected write to be rolled back, got %d favorites", got)
+	}
+}
+
+func TestTransactionManager_Do_RollbackOnPanic(t *testing.T) {
+	tm, sqlHandler := newMemoryTransactionManager(t)
+	before := countFavorites(t, sqlHandler)
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("expected panic to be re-raised, got %v", p)
+			}
+		}()
+		_ = tm.Do(context.Background(), func(ctx context.Context) error {
+			_ = createFavorite(ctx, tm, "tx-user")
+			panic("boom")
+		})
+	}()
+
+	if got := countFavorites(t, sqlHandler); got != before {
+		t.Errorf("expected write to be rolled back, got %d favorites", got)
+	}
+	// ロールバックによりトランザクションが解放され、次のトランザクションを開始できる
+	if err := tm.Do(context.Background(), func(ctx context.Context) error { return nil }); err != nil {
+		t.Errorf("unexpected error after panic: %v", err)
+	}
+}
+
+func TestTransactionManager_Do_JoinsOuterTransaction(t *testing.T) {
+	tm, sqlHandler := newMemoryTransactionManager(t)
+	before := countFavorites(t, sqlHandler)
+	outerErr := errors.New("outer failed")
+
+	err := tm.Do(context.Background(), func(ctx context.Context) error {
+		// 内側のDoは外側のトランザクションに参加し、単独ではコミットしない
+		if err := tm.Do(ctx, func(ctx context.Context) error {
+			return createFavorite(ctx, tm, "tx-user")
+		}); err != nil {
+			return err
+		}
+		if got := countFavorites(t, sqlHandler); got != before {
+			t.Errorf("expected inner write to be invisible until the outer commit, got %d favorites", got)
+		}
+		return outerErr
+	})
+	if !errors.Is(err, outerErr) {
+		t.Fatalf("expected outer error, got %v", err)
+	}
+	if got := countFavorites(t, sqlHandler); got != before {
+		t.Errorf("expected inner write to be rolled back with the outer transaction, got %d favorites", got)
+	}
+}
+
+func TestTransactionManager_Do_CommitFailure(t *testing.T) {
+	_, sqlHandler := newMemoryTransactionManager(t)
+	commitErr := errors.New("commit failed")
+	tm := &TransactionManager{SQLHandler: &failingCommitHandler{SQLHandler: sqlHandler, err: commitErr}}
+	before := countFavorites(t, sqlHandler)
+
+	err := tm.Do(context.Background(), func(ctx context.Context) error {
+		return createFavorite(ctx, tm, "tx-user")
+	})
+	if !errors.Is(err, commitErr) {
+		t.Fatalf("expected commit error, got %v", err)
+	}
+	if got := countFavorites(t, sqlHandler); got != before {
+		t.Errorf("expected write to be discarded, got %d favorites", got)
+	}
+}
@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WebhookDeliveryRepositoryInterface ...
+type WebhookDeliveryRepositoryInterface interface {
+	FindByID(ctx context.Context, id int64) (delivery *model.WebhookDelivery, err error)
+	FindBySubscriptionID(ctx context.Context, subscriptionID int64, limit int) (deliveries model.WebhookDeliveries, err error)
+	FindByEventID(ctx context.Context, eventID int64) (deliveries []model.WebhookDelivery, err error)
+	FindAttempts(ctx context.Context, deliveryID int64) (attempts []model.WebhookDeliveryAttempt, err error)
+	Create(ctx context.Context, input *model.WebhookDelivery) (err error)
+	ClaimPending(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) (deliveries []model.WebhookDelivery, err error)
+	RecordAttempt(ctx context.Context, input *model.WebhookDeliveryAttempt) (err error)
+	UpdateResult(ctx context.Context, input *model.WebhookDelivery) (err error)
+}
+
+// WebhookDeliveryRepository ...
+type WebhookDeliveryRepository struct {
+	database.SQLHandler
+}
+
+const (
+	WebhookDeliveryTable        = "webhook_deliveries"
+	WebhookDeliveryAttemptTable = "webhook_delivery_attempts"
+)
+
+// FindByID Webhookの送信を取得する（存在しない場合はnil）
+func (repo *WebhookDeliveryRepository) FindByID(ctx context.Context, id int64) (delivery *model.WebhookDelivery, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-delivery-repository")
+	ctx, span := tracer.Start(ctx, "WebhookDeliveryRepository.FindByID",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("delivery_id", id),
+	)
+
+	var rows []model.WebhookDelivery
+	err = repo.SQLHandler.Where(ctx, &rows, WebhookDeliveryTable, "id = :id", map[string]interface{}{"id": id})
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if len(rows) > 0 {
+		delivery = &rows[0]
+	}
+	return
+}
+
+// FindBySubscriptionID 送信先への送信を新しい順にlimit件まで取得する
+func (repo *WebhookDeliveryRepository) FindBySubscriptionID(ctx context.Context, subscriptionID int64, limit int) (deliveries model.WebhookDeliveries, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-delivery-repository")
+	ctx, span := tracer.Start(ctx, "WebhookDeliveryRepository.FindBySubscriptionID",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("subscription_id", subscriptionID),
+		attribute.Int("limit", limit),
+	)
+
+	whereClause := "subscription_id = :subscription_id"
+	whereArgs := map[string]interface{}{"subscription_id": subscriptionID}
+	err = repo.SQLHandler.Select(ctx, &deliveries.Data, WebhookDeliveryTable, whereClause, whereArgs, "id desc", limit)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+
+	// 結果の属性を追加
+	span.SetAttributes(
+		attribute.Int("result_count", len(deliveries.Data)),
+	)
+	return
+}
+
+// FindByEventID イベントの送信を取得する
+func (repo *WebhookDeliveryRepository) FindByEventID(ctx context.Context, eventID int64) (deliveries []model.WebhookDelivery, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-delivery-repository")
+	ctx, span := tracer.Start(ctx, "WebhookDeliveryRepository.FindByEventID",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("event_id", eventID),
+	)
+
+	err = repo.SQLHandler.Where(ctx, &deliveries, WebhookDeliveryTable, "event_id = :event_id", map[string]interface{}{"event_id": eventID})
+	if err != nil {
+		span.RecordError(err)
+	}
+	return
+}
+
+// FindAttempts 送信の試行ごとの結果を古い順に取得する
+func (repo *WebhookDeliveryRepository) FindAttempts(ctx context.Context, deliveryID int64) (attempts []model.WebhookDeliveryAttempt, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-delivery-repository")
+	ctx, span := tracer.Start(ctx, "WebhookDeliveryRepository.FindAttempts",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("delivery_id", deliveryID),
+	)
+
+	whereClause := "delivery_id = :delivery_id"
+	whereArgs := map[string]interface{}{"delivery_id": deliveryID}
+	err = repo.SQLHandler.Select(ctx, &attempts, WebhookDeliveryAttemptTable, whereClause, whereArgs, "attempt, id", 0)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return
+}
+
+// Create 送信待ちの送信を作成し、採番されたIDと既定値をinputに反映する
+func (repo *WebhookDeliveryRepository) Create(ctx context.Context, input *model.WebhookDelivery) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-delivery-repository")
+	ctx, span := tracer.Start(ctx, "WebhookDeliveryRepository.Create",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("subscription_id", input.SubscriptionID),
+		attribute.Int64("event_id", input.EventID),
+		attribute.String("event_type", input.EventType),
+	)
+
+	in := map[string]interface{}{
+		"subscription_id": input.SubscriptionID,
+		"event_id":        input.EventID,
+		"event_type":      input.EventType,
+		"payload":         input.Payload,
+		"status":          model.WebhookDeliveryStatusPending,
+	}
+
+	var created model.WebhookDelivery
+	err = repo.SQLHandler.Insert(ctx, &created, in, WebhookDeliveryTable)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	*input = created
+	span.SetAttributes(attribute.Int64("delivery_id", created.ID))
+	return
+}
+
+// ClaimPending 送信時刻を過ぎた送信待ちを古い順に取得し、次の送信時刻をleaseUntilまで後ろにずらす
+//
+// OutboxRepository.ClaimPendingと同様に、ずらした送信時刻がリースとなり、コミット後はleaseUntilまで他のタスクに取得されない
+func (repo *WebhookDeliveryRepository) ClaimPending(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) (deliveries []model.WebhookDelivery, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-delivery-repository")
+	ctx, span := tracer.Start(ctx, "WebhookDeliveryRepository.ClaimPending",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int("limit", limit),
+	)
+
+	whereClause := "status = :status AND next_attempt_at <= :now"
+	whereArgs := map[string]interface{}{"status": model.WebhookDeliveryStatusPending, "now": now}
+	err = repo.SQLHandler.SelectForUpdate(ctx, &deliveries, WebhookDeliveryTable, whereClause, whereArgs, "id", limit)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+
+	ids := make(pq.Int64Array, len(deliveries))
+	for i := range deliveries {
+		ids[i] = deliveries[i].ID
+		deliveries[i].NextAttemptAt = leaseUntil
+	}
+	err = repo.SQLHandler.Update(ctx, map[string]interface{}{"next_attempt_at": leaseUntil}, WebhookDeliveryTable, "id = ANY(:ids)", map[string]interface{}{"ids": ids})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// 結果の属性を追加
+	span.SetAttributes(
+		attribute.Int("result_count", len(deliveries)),
+	)
+	return
+}
+
+// RecordAttempt 送信の試行の結果を記録する
+func (repo *WebhookDeliveryRepository) RecordAttempt(ctx context.Context, input *model.WebhookDeliveryAttempt) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-delivery-repository")
+	ctx, span := tracer.Start(ctx, "WebhookDeliveryRepository.RecordAttempt",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("delivery_id", input.DeliveryID),
+		attribute.Int("attempt", input.Attempt),
+	)
+
+	in := map[string]interface{}{
+		"delivery_id":   input.DeliveryID,
+		"attempt":       input.Attempt,
+		"response_code": input.ResponseCode,
+		"error":         input.Error,
+		"duration_ms":   input.DurationMs,
+	}
+	err = repo.SQLHandler.Create(ctx, in, WebhookDeliveryAttemptTable)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return
+}
+
+// UpdateResult 送信の状態・試行回数・次の送信時刻・直近の結果を更新する
+//
+// リースの期限切れで他のタスクが先に結果を記録した送信待ちでない送信は変更しない
+func (repo *WebhookDeliveryRepository) UpdateResult(ctx context.Context, input *model.WebhookDelivery) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-delivery-repository")
+	ctx, span := tracer.Start(ctx, "WebhookDeliveryRepository.UpdateResult",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("delivery_id", input.ID),
+		attribute.String("status", input.Status),
+		attribute.Int("attempts", input.Attempts),
+	)
+
+	setParams := map[string]interface{}{
+		"status":          input.Status,
+		"attempts":        input.Attempts,
+		"next_attempt_at": input.NextAttemptAt,
+		"response_code":   input.ResponseCode,
+		"last_error":      input.LastError,
+		"delivered_at":    input.DeliveredAt,
+	}
+	whereClause := "id = :id AND status = :from_status"
+	whereArgs := map[string]interface{}{"id": input.ID, "from_status": model.WebhookDeliveryStatusPending}
+	err = repo.SQLHandler.Update(ctx, setParams, WebhookDeliveryTable, whereClause, whereArgs)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WebhookSubscriptionRepositoryInterface ...
+type WebhookSubscriptionRepositoryInterface interface {
+	FindByShop(ctx context.Context, shopID int64) (subscriptions model.WebhookSubscriptions, err error)
+	FindByID(ctx context.Context, id int64) (subscription *model.WebhookSubscription, err error)
+	FindActive(ctx context.Context, shopID int64, eventType string) (subscriptions []model.WebhookSubscription, err error)
+	Create(ctx context.Context, input *model.WebhookSubscription) (err error)
+	Update(ctx context.Context, input *model.WebhookSubscription) (err error)
+	Delete(ctx context.Context, id int64) (err error)
+}
+
+// WebhookSubscriptionRepository ...
+type WebhookSubscriptionRepository struct {
+	database.SQLHandler
+}
+
+const WebhookSubscriptionTable = "webhook_subscriptions"
+
+// FindByShop 店舗のWebhookの送信先を登録順に取得する（shopIDが0の場合はすべての店舗）
+func (repo *WebhookSubscriptionRepository) FindByShop(ctx context.Context, shopID int64) (subscriptions model.WebhookSubscriptions, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-subscription-repository")
+	ctx, span := tracer.Start(ctx, "WebhookSubscriptionRepository.FindByShop",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("shop_id", shopID),
+	)
+
+	whereClause := ""
+	whereArgs := map[string]interface{}{}
+	if shopID != 0 {
+		whereClause = "shop_id = :shop_id"
+		whereArgs["shop_id"] = shopID
+	}
+	err = repo.SQLHandler.Select(ctx, &subscriptions.Data, WebhookSubscriptionTable, whereClause, whereArgs, "id", 0)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+
+	// 結果の属性を追加
+	span.SetAttributes(
+		attribute.Int("result_count", len(subscriptions.Data)),
+	)
+	return
+}
+
+// FindByID Webhookの送信先を取得する（存在しない場合はnil）
+func (repo *WebhookSubscriptionRepository) FindByID(ctx context.Context, id int64) (subscription *model.WebhookSubscription, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-subscription-repository")
+	ctx, span := tracer.Start(ctx, "WebhookSubscriptionRepository.FindByID",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("subscription_id", id),
+	)
+
+	var rows []model.WebhookSubscription
+	err = repo.SQLHandler.Where(ctx, &rows, WebhookSubscriptionTable, "id = :id", map[string]interface{}{"id": id})
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if len(rows) > 0 {
+		subscription = &rows[0]
+	}
+	return
+}
+
+// FindActive 店舗のイベントを購読している有効な送信先を取得する
+func (repo *WebhookSubscriptionRepository) FindActive(ctx context.Context, shopID int64, eventType string) (subscriptions []model.WebhookSubscription, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-subscription-repository")
+	ctx, span := tracer.Start(ctx, "WebhookSubscriptionRepository.FindActive",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("shop_id", shopID),
+		attribute.String("event_type", eventType),
+	)
+
+	whereClause := "shop_id = :shop_id AND event_type = :event_type AND active = :active"
+	whereArgs := map[string]interface{}{"shop_id": shopID, "event_type": eventType, "active": true}
+	err = repo.SQLHandler.Select(ctx, &subscriptions, WebhookSubscriptionTable, whereClause, whereArgs, "id", 0)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+
+	// 結果の属性を追加
+	span.SetAttributes(
+		attribute.Int("result_count", len(subscriptions)),
+	)
+	return
+}
+
+// Create Webhookの送信先を登録し、採番されたIDと既定値をinputに反映する
+func (repo *WebhookSubscriptionRepository) Create(ctx context.Context, input *model.WebhookSubscription) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-subscription-repository")
+	ctx, span := tracer.Start(ctx, "WebhookSubscriptionRepository.Create",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("shop_id", input.ShopID),
+		attribute.String("event_type", input.EventType),
+	)
+
+	in := map[string]interface{}{
+		"shop_id":    input.ShopID,
+		"event_type": input.EventType,
+		"url":        input.URL,
+		"secret":     input.Secret,
+		"active":     input.Active,
+	}
+
+	var created model.WebhookSubscription
+	err = repo.SQLHandler.Insert(ctx, &created, in, WebhookSubscriptionTable)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	*input = created
+	span.SetAttributes(attribute.Int64("subscription_id", created.ID))
+	return
+}
+
+// Update Webhookの送信先のイベント・URL・有効/無効を更新する
+func (repo *WebhookSubscriptionRepository) Update(ctx context.Context, input *model.WebhookSubscription) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-subscription-repository")
+	ctx, span := tracer.Start(ctx, "WebhookSubscriptionRepository.Update",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("subscription_id", input.ID),
+	)
+
+	now := time.Now()
+	setParams := map[string]interface{}{
+		"event_type": input.EventType,
+		"url":        input.URL,
+		"active":     input.Active,
+		"updated_at": &now,
+	}
+	err = repo.SQLHandler.Update(ctx, setParams, WebhookSubscriptionTable, "id = :id", map[string]interface{}{"id": input.ID})
+	if err != nil {
+		span.RecordError(err)
+	}
+	return
+}
+
+// Delete Webhookの送信先を削除する（送信履歴は外部キーのON DELETE CASCADEで削除される）
+func (repo *WebhookSubscriptionRepository) Delete(ctx context.Context, id int64) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-subscription-repository")
+	ctx, span := tracer.Start(ctx, "WebhookSubscriptionRepository.Delete",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("subscription_id", id),
+	)
+
+	err = repo.SQLHandler.Delete(ctx, map[string]interface{}{"id": id}, WebhookSubscriptionTable)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return
+}
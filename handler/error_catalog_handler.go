@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrorCatalogHandler フロントエンド向けにエラーカタログを公開する
+type ErrorCatalogHandler struct {
+}
+
+// NewErrorCatalogHandler ...
+func NewErrorCatalogHandler() *ErrorCatalogHandler {
+	return &ErrorCatalogHandler{}
+}
+
+// GetErrors クライアントに返却されうるエラー（4xx）のコード・ステータス・メッセージの一覧を返す
+func (handler *ErrorCatalogHandler) GetErrors() echo.HandlerFunc {
+	// カタログは起動時に読み込まれ変更されないため、一度だけ生成する
+	catalog := errors.PublicCatalog()
+
+	return func(c echo.Context) error {
+		// スパンを作成
+		tracer := otel.Tracer("error-catalog-handler")
+		_, span := tracer.Start(c.Request().Context(), "GetErrors",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		span.SetAttributes(
+			attribute.Int("result_count", len(catalog)),
+		)
+
+		c.Response().Header().Set("Cache-Control", "public, max-age=3600")
+		return c.JSON(http.StatusOK, model.APIResponse{
+			Data: catalog,
+		})
+	}
+}
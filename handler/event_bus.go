@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+	"github.com/horsewin/echo-playground-v2/usecase"
+)
+
+// NewEventBus ドメインイベントのバスを作成し、通知を作成する購読者を登録する
+//
+// 通知はバスのRunを実行しているバックグラウンドの処理で作成する
+func NewEventBus(sqlHandler database.SQLHandler, stream NotificationStreamConfig) *usecase.EventBus {
+	bus := usecase.NewEventBus()
+
+	subscriber := &usecase.NotificationSubscriber{
+		Notifications: &usecase.NotificationInteractor{
+			NotificationRepository: &repository.NotificationRepository{
+				SQLHandler: sqlHandler,
+			},
+			Publisher: stream.publisher(),
+		},
+		PetRepository: &repository.PetRepository{
+			SQLHandler: sqlHandler,
+		},
+		FavoriteRepository: &repository.FavoriteRepository{
+			SQLHandler: sqlHandler,
+		},
+	}
+	subscriber.Register(bus)
+
+	return bus
+}
@@ -1,16 +1,44 @@
 package handlers
 
 import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
 	"github.com/labstack/echo/v4"
 )
 
+const (
+	// componentCheckTimeout 1コンポーネントあたりの疎通確認のタイムアウト
+	componentCheckTimeout = 2 * time.Second
+)
+
+// HealthComponent ... readinessで疎通確認を行う依存コンポーネント
+type HealthComponent struct {
+	Name string
+	// Critical falseの場合は失敗してもreadinessを失敗にしない
+	Critical bool
+	Check    func(ctx context.Context) error
+}
+
 // HealthCheckHandler ...
 type HealthCheckHandler struct {
+	components   []HealthComponent
+	shuttingDown func() bool
 }
 
 // NewHealthCheckHandler ...
-func NewHealthCheckHandler() *HealthCheckHandler {
-	return &HealthCheckHandler{}
+// shuttingDownがtrueを返す間はreadinessを失敗させ、ロードバランサーから切り離す
+func NewHealthCheckHandler(shuttingDown func() bool, components ...HealthComponent) *HealthCheckHandler {
+	if shuttingDown == nil {
+		shuttingDown = func() bool { return false }
+	}
+	return &HealthCheckHandler{
+		components:   components,
+		shuttingDown: shuttingDown,
+	}
 }
 
 // HealthCheck ...
@@ -19,3 +47,79 @@ func (handler *HealthCheckHandler) HealthCheck() echo.HandlerFunc {
 		return c.JSON(200, nil)
 	}
 }
+
+// Live ... プロセスが応答可能かのみを返す（依存コンポーネントは確認しない）
+func (handler *HealthCheckHandler) Live() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, model.HealthReport{Status: model.HealthStatusOK})
+	}
+}
+
+// Ready ... 依存コンポーネントの疎通確認結果を返す
+func (handler *HealthCheckHandler) Ready() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if handler.shuttingDown() {
+			return c.JSON(http.StatusServiceUnavailable, model.HealthReport{
+				Status: model.HealthStatusFail,
+				Components: map[string]model.ComponentHealth{
+					"shutdown": {Status: model.HealthStatusFail, Critical: true, Error: "shutting down"},
+				},
+			})
+		}
+
+		report := handler.check(c.Request().Context())
+
+		status := http.StatusOK
+		if report.Status == model.HealthStatusFail {
+			status = http.StatusServiceUnavailable
+		}
+		return c.JSON(status, report)
+	}
+}
+
+// check すべてのコンポーネントを並行して確認する
+func (handler *HealthCheckHandler) check(ctx context.Context) model.HealthReport {
+	report := model.HealthReport{
+		Status:     model.HealthStatusOK,
+		Components: make(map[string]model.ComponentHealth, len(handler.components)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, component := range handler.components {
+		wg.Add(1)
+		go func(component HealthComponent) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, componentCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := component.Check(checkCtx)
+			result := model.ComponentHealth{
+				Status:    model.HealthStatusOK,
+				Critical:  component.Critical,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = model.HealthStatusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[component.Name] = result
+			if err == nil {
+				return
+			}
+			if component.Critical {
+				report.Status = model.HealthStatusFail
+			} else if report.Status == model.HealthStatusOK {
+				report.Status = model.HealthStatusDegraded
+			}
+		}(component)
+	}
+	wg.Wait()
+
+	return report
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/labstack/echo/v4"
+)
+
+func serveReady(t *testing.T, handler *HealthCheckHandler) (int, model.HealthReport) {
+	t.Helper()
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/healthcheck/ready", nil), rec)
+	if err := handler.Ready()(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var report model.HealthReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid response body: %v", err)
+	}
+	return rec.Code, report
+}
+
+func TestHealthCheckHandler_Ready(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	ng := func(ctx context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name           string
+		shuttingDown   bool
+		components     []HealthComponent
+		expectedCode   int
+		expectedStatus string
+	}{
+		{
+			name:           "正常系：すべて成功",
+			components:     []HealthComponent{{Name: "database", Critical: true, Check: ok}},
+			expectedCode:   http.StatusOK,
+			expectedStatus: model.HealthStatusOK,
+		},
+		{
+			name:           "必須コンポーネントの失敗",
+			components:     []HealthComponent{{Name: "database", Critical: true, Check: ng}},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: model.HealthStatusFail,
+		},
+		{
+			name: "非必須コンポーネントの失敗",
+			components: []HealthComponent{
+				{Name: "database", Critical: true, Check: ok},
+				{Name: "otlp_exporter", Critical: false, Check: ng},
+			},
+			expectedCode:   http.StatusOK,
+			expectedStatus: model.HealthStatusDegraded,
+		},
+		{
+			name:           "シャットダウン中",
+			shuttingDown:   true,
+			components:     []HealthComponent{{Name: "database", Critical: true, Check: ok}},
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: model.HealthStatusFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shuttingDown := tt.shuttingDown
+			handler := NewHealthCheckHandler(func() bool { return shuttingDown }, tt.components...)
+
+			code, report := serveReady(t, handler)
+			if code != tt.expectedCode {
+				t.Errorf("expected status code %d but got %d", tt.expectedCode, code)
+			}
+			if report.Status != tt.expectedStatus {
+				t.Errorf("expected status %s but got %s", tt.expectedStatus, report.Status)
+			}
+		})
+	}
+}
+
+func TestHealthCheckHandler_Ready_ReportsComponents(t *testing.T) {
+	handler := NewHealthCheckHandler(nil, HealthComponent{
+		Name:     "database",
+		Critical: true,
+		Check:    func(ctx context.Context) error { return errors.New("connection refused") },
+	})
+
+	_, report := serveReady(t, handler)
+	database, ok := report.Components["database"]
+	if !ok {
+		t.Fatalf("expected database component in report: %+v", report)
+	}
+	if database.Status != model.HealthStatusFail || database.Error != "connection refused" {
+		t.Errorf("unexpected component result: %+v", database)
+	}
+}
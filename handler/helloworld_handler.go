@@ -3,7 +3,6 @@ package handlers
 import (
 	"net/http"
 
-	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -29,9 +28,7 @@ func (handler *HelloWorldHandler) SayHelloWorld() echo.HandlerFunc {
 
 	// ドメインモデルをJSONにして返却
 	return func(c echo.Context) error {
-		// Get logger from context
 		ctx := c.Request().Context()
-		logger := zerolog.Ctx(ctx)
 
 		// スパンを作成
 		tracer := otel.Tracer("helloworld-handler")
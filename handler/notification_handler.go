@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel"
@@ -17,24 +21,79 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-// NotificationReadRequest JSON形式のリクエストボディをバインドするための構造体
+// NotificationReadRequest JSON形式のリクエストボディをバインドするための構造体（user_idの扱いはLikeRequestと同じ）
 type NotificationReadRequest struct {
-	ID string `json:"id" form:"id"`
+	UserId string `json:"user_id" form:"user_id" validate:"omitempty,max=64"`
+	ID     string `json:"id" form:"id" validate:"omitempty,numeric"`
+}
+
+// NotificationsQuery 通知一覧のクエリパラメータ（user_idの扱いはLikeRequestと同じ）
+type NotificationsQuery struct {
+	UserId string `query:"user_id" validate:"omitempty,max=64"`
+	ID     string `query:"id" validate:"omitempty,numeric"`
+	model.NotificationFilter
+}
+
+// UnreadCountQuery 未読件数のクエリパラメータ（user_idの扱いはLikeRequestと同じ）
+type UnreadCountQuery struct {
+	UserId string `query:"user_id" validate:"omitempty,max=64"`
+	Type   string `query:"type" validate:"omitempty,max=32"`
+}
+
+// NotificationStreamQuery 通知ストリームのクエリパラメータ（user_idの扱いはLikeRequestと同じ）
+type NotificationStreamQuery struct {
+	UserId string `query:"user_id" validate:"omitempty,max=64"`
+}
+
+const (
+	// sseRetry 切断されたクライアントが再接続するまでの待機時間（ミリ秒）
+	sseRetry = 3000
+	// sseEventReset Last-Event-IDから再開できないため、一覧の再取得を求めるイベント
+	sseEventReset = "reset"
+	// defaultSSEHeartbeatInterval ハートビートの間隔を指定しない場合の既定値
+	defaultSSEHeartbeatInterval = 15 * time.Second
+)
+
+// NotificationStreamConfig 通知ストリームの設定
+type NotificationStreamConfig struct {
+	// Broker ストリームの購読を受け付けるブローカー（nilの場合はストリームを利用できない）
+	Broker *usecase.NotificationBroker
+	// Publisher 通知の変更の配信先（nilの場合はBrokerに直接配信する）
+	Publisher usecase.NotificationPublisher
+	// HeartbeatInterval 接続を維持するためにハートビートを送る間隔
+	HeartbeatInterval time.Duration
+}
+
+// publisher 通知の変更の配信先を返す
+func (stream NotificationStreamConfig) publisher() usecase.NotificationPublisher {
+	if stream.Publisher == nil && stream.Broker != nil {
+		return stream.Broker
+	}
+	return stream.Publisher
 }
 
 // NotificationHandler ...
 type NotificationHandler struct {
-	Interactor usecase.NotificationInteractor
+	Interactor        usecase.NotificationInteractor
+	HeartbeatInterval time.Duration
 }
 
 // NewNotificationHandler ...
-func NewNotificationHandler(sqlHandler database.SQLHandler) *NotificationHandler {
+func NewNotificationHandler(sqlHandler database.SQLHandler, stream NotificationStreamConfig) *NotificationHandler {
+	heartbeat := stream.HeartbeatInterval
+	if heartbeat <= 0 {
+		heartbeat = defaultSSEHeartbeatInterval
+	}
+
 	return &NotificationHandler{
 		Interactor: usecase.NotificationInteractor{
 			NotificationRepository: &repository.NotificationRepository{
 				SQLHandler: sqlHandler,
 			},
+			Publisher: stream.publisher(),
+			Broker:    stream.Broker,
 		},
+		HeartbeatInterval: heartbeat,
 	}
 }
 
@@ -49,7 +108,12 @@ func (handler *NotificationHandler) GetNotifications() echo.HandlerFunc {
 		)
 		defer span.End()
 
-		id := c.QueryParam("id")
+		var query NotificationsQuery
+		if err = bindAndValidate(c, &query); err != nil {
+			span.RecordError(err)
+			return err
+		}
+		id := query.ID
 
 		// スパンに属性を追加
 		if id != "" {
@@ -57,15 +121,57 @@ func (handler *NotificationHandler) GetNotifications() echo.HandlerFunc {
 				attribute.String("id", id),
 			)
 		}
+		span.SetAttributes(
+			attribute.String("filter.type", query.Type),
+			attribute.String("filter.since", query.Since),
+			attribute.Int("filter.limit", query.Limit),
+		)
 
 		// contextを渡す
-		resJSON, err := handler.Interactor.GetNotifications(ctx, id)
+		res, page, err := handler.Interactor.GetNotifications(ctx, query.UserId, id, &query.NotificationFilter)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusOK, model.PagedAPIResponse{
+			APIResponse: model.APIResponse{
+				Data: res.Data,
+			},
+			PageInfo: page,
+		})
+	}
+}
+
+// GetUnreadCount ...
+func (handler *NotificationHandler) GetUnreadCount() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("notification-handler")
+		ctx, span := tracer.Start(ctx, "GetUnreadCount",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		var query UnreadCountQuery
+		if err = bindAndValidate(c, &query); err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.String("type", query.Type),
+		)
+
+		res, err := handler.Interactor.GetUnreadCount(ctx, query.UserId, query.Type)
 		if err != nil {
 			span.RecordError(err)
 			return errors.NewEchoHTTPError(ctx, err)
 		}
 
-		return c.JSON(http.StatusOK, resJSON)
+		return c.JSON(http.StatusOK, res)
 	}
 }
 
@@ -82,9 +188,9 @@ func (handler *NotificationHandler) PostNotificationsRead() echo.HandlerFunc {
 
 		// JSONリクエストボディからnotificationIdを取得
 		var req NotificationReadRequest
-		if err := c.Bind(&req); err != nil {
+		if err := bindAndValidate(c, &req); err != nil {
 			span.RecordError(err)
-			return errors.NewEchoHTTPError(ctx, err)
+			return err
 		}
 		notificationId := req.ID
 		logger := zerolog.Ctx(ctx)
@@ -96,7 +202,7 @@ func (handler *NotificationHandler) PostNotificationsRead() echo.HandlerFunc {
 		)
 
 		// contextを渡す
-		err = handler.Interactor.MarkNotificationsRead(ctx, notificationId)
+		err = handler.Interactor.MarkNotificationsRead(ctx, req.UserId, notificationId)
 		if err != nil {
 			span.RecordError(err)
 			return errors.NewEchoHTTPError(ctx, err)
@@ -108,3 +214,107 @@ func (handler *NotificationHandler) PostNotificationsRead() echo.HandlerFunc {
 		})
 	}
 }
+
+// StreamNotifications ユーザの通知の作成・既読化をServer-Sent Eventsで配信する
+//
+// 再接続時はLast-Event-IDヘッダー（またはlast_event_idクエリパラメータ）以降のイベントを再送する。
+// 再開できない場合はresetイベントを送り、クライアントはGET /v1/notificationsで一覧を再取得する
+func (handler *NotificationHandler) StreamNotifications() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("notification-handler")
+		ctx, span := tracer.Start(ctx, "StreamNotifications",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		var query NotificationStreamQuery
+		if err = bindAndValidate(c, &query); err != nil {
+			span.RecordError(err)
+			return err
+		}
+		lastEventID := c.Request().Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = c.QueryParam("last_event_id")
+		}
+
+		sub, replay, resumed, err := handler.Interactor.SubscribeNotifications(ctx, query.UserId, lastEventID)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+		defer sub.Close()
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.Bool("resumed", resumed),
+			attribute.Int("replayed", len(replay)),
+		)
+
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.Header().Set(echo.HeaderCacheControl, "no-cache")
+		// リバースプロキシでバッファリングされないようにする
+		res.Header().Set("X-Accel-Buffering", "no")
+		res.WriteHeader(http.StatusOK)
+
+		if _, err = fmt.Fprintf(res, "retry: %d\n\n", sseRetry); err != nil {
+			return nil
+		}
+		if !resumed {
+			if err = writeSSE(res, "", sseEventReset, struct{}{}); err != nil {
+				return nil
+			}
+		}
+		for _, event := range replay {
+			if err = writeSSE(res, event.ID, event.Type, event); err != nil {
+				return nil
+			}
+		}
+		res.Flush()
+
+		heartbeat := time.NewTicker(handler.HeartbeatInterval)
+		defer heartbeat.Stop()
+
+		logger := zerolog.Ctx(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				// クライアントが切断した
+				return nil
+			case event, ok := <-sub.Events():
+				if !ok {
+					// サーバの停止、または配信に追いつけずに切断された
+					logger.Debug().Msg("notification stream closed by broker")
+					return nil
+				}
+				if err = writeSSE(res, event.ID, event.Type, event); err != nil {
+					logger.Debug().Err(err).Msg("failed to write notification event")
+					return nil
+				}
+			case <-heartbeat.C:
+				// コメント行はクライアントに無視されるが、アイドル状態での切断を防ぐ
+				if _, err = io.WriteString(res, ": heartbeat\n\n"); err != nil {
+					return nil
+				}
+			}
+			res.Flush()
+		}
+	}
+}
+
+// writeSSE Server-Sent Eventsの1イベントを書き込む
+func writeSSE(w io.Writer, id string, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err = fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
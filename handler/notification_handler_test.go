@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/infrastructure/memory"
+	"github.com/horsewin/echo-playground-v2/usecase"
+	"github.com/labstack/echo/v4"
+)
+
+// sseFrame はServer-Sent Eventsの1フレーム（コメント行のみのフレームはCommentに入る）
+type sseFrame struct {
+	ID      string
+	Event   string
+	Data    string
+	Retry   string
+	Comment string
+}
+
+// readFrame は空行までを1フレームとして読み込む
+func readFrame(t *testing.T, r *bufio.Reader) sseFrame {
+	t.Helper()
+
+	var frame sseFrame
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return frame
+		}
+		if strings.HasPrefix(line, ":") {
+			frame.Comment = strings.TrimSpace(line[1:])
+			continue
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			frame.ID = value
+		case "event":
+			frame.Event = value
+		case "data":
+			frame.Data = value
+		case "retry":
+			frame.Retry = value
+		}
+	}
+}
+
+// readEvent はハートビートを読み飛ばして次のイベントを読み込む
+func readEvent(t *testing.T, r *bufio.Reader) sseFrame {
+	t.Helper()
+
+	for {
+		if frame := readFrame(t, r); frame.Event != "" {
+			return frame
+		}
+	}
+}
+
+// openStream は通知ストリームに接続し、retryフレームを読み飛ばしたReaderを返す
+func openStream(t *testing.T, url string, lastEventID string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodGet, url+"/v1/notifications/stream?user_id=user1", nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+
+	if res.StatusCode != http.StatusOK || res.Header.Get(echo.HeaderContentType) != "text/event-stream" {
+		t.Fatalf("unexpected response: %d %s", res.StatusCode, res.Header.Get(echo.HeaderContentType))
+	}
+	r := bufio.NewReader(res.Body)
+	if frame := readFrame(t, r); frame.Retry == "" {
+		t.Fatalf("expected retry frame first, got %+v", frame)
+	}
+	return res, r
+}
+
+func TestNotificationHandler_StreamNotifications(t *testing.T) {
+	sqlHandler, err := memory.NewSeededSQLHandler()
+	if err != nil {
+		t.Fatalf("failed to create memory handler: %v", err)
+	}
+	broker := usecase.NewNotificationBroker()
+	handler := NewNotificationHandler(sqlHandler, NotificationStreamConfig{
+		Broker:            broker,
+		HeartbeatInterval: 50 * time.Millisecond,
+	})
+
+	e := echo.New()
+	e.Validator = NewRequestValidator()
+	e.GET("/v1/notifications/stream", handler.StreamNotifications())
+	e.POST("/v1/notifications/read", handler.PostNotificationsRead())
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	markRead := func(body string) {
+		res, err := http.Post(server.URL+"/v1/notifications/read", echo.MIMEApplicationJSON, strings.NewReader(body))
+		if err != nil || res.StatusCode != http.StatusOK {
+			t.Fatalf("failed to mark read: %v %v", res, err)
+		}
+		res.Body.Close()
+	}
+
+	_, stream := openStream(t, server.URL, "")
+
+	// 他のユーザの既読化は配信されない
+	markRead(`{"user_id":"user2","id":"3"}`)
+	markRead(`{"user_id":"user1","id":"1"}`)
+
+	frame := readEvent(t, stream)
+	if frame.Event != "notification.read" || frame.ID == "" || !strings.Contains(frame.Data, `"notification_id":1`) {
+		t.Fatalf("unexpected event: %+v", frame)
+	}
+	lastEventID := frame.ID
+
+	// イベントがない間はハートビートを送る
+	if frame := readFrame(t, stream); frame.Comment != "heartbeat" {
+		t.Errorf("expected heartbeat, got %+v", frame)
+	}
+
+	t.Run("Last-Event-IDから再開する", func(t *testing.T) {
+		markRead(`{"user_id":"user1","id":"2"}`)
+
+		_, resumed := openStream(t, server.URL, lastEventID)
+		frame := readEvent(t, resumed)
+		if frame.Event != "notification.read" || !strings.Contains(frame.Data, `"notification_id":2`) {
+			t.Errorf("expected replayed event for notification 2, got %+v", frame)
+		}
+	})
+
+	t.Run("再開できない場合はresetを送る", func(t *testing.T) {
+		_, reset := openStream(t, server.URL, "unknown-1")
+		if frame := readEvent(t, reset); frame.Event != "reset" {
+			t.Errorf("expected reset event, got %+v", frame)
+		}
+	})
+
+	t.Run("ブローカーを閉じるとストリームが終了する", func(t *testing.T) {
+		broker.Close()
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := io.Copy(io.Discard, stream)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("stream was not closed after broker.Close")
+		}
+	})
+}
@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"context"
+	stderrors "errors"
 	"net/http"
+	"time"
 
 	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
 	"github.com/labstack/echo/v4"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -12,15 +16,66 @@ import (
 	"github.com/horsewin/echo-playground-v2/domain/repository"
 	"github.com/horsewin/echo-playground-v2/interface/database"
 	"github.com/horsewin/echo-playground-v2/usecase"
+	"github.com/horsewin/echo-playground-v2/utils"
 )
 
+// LikeRequest いいね登録/解除のリクエストボディ
+//
+// user_idは認証が無効な場合、またはサービスがユーザーの代理で操作する場合に指定する
+type LikeRequest struct {
+	UserId string `json:"user_id" validate:"omitempty,max=64"`
+	Value  bool   `json:"value"`
+}
+
+// ReservationRequest 見学予約のリクエストボディ（user_idの扱いはLikeRequestと同じ）
+type ReservationRequest struct {
+	UserId   string `json:"user_id" validate:"omitempty,max=64"`
+	Email    string `json:"email" validate:"required,email"`
+	FullName string `json:"full_name" validate:"required,max=100"`
+	// SlotStartsAt 見学する予約枠の開始日時（GET /v1/pets/:id/availabilityのstarts_at）
+	SlotStartsAt string `json:"slot_starts_at" validate:"required,rfc3339"`
+}
+
+// petDateLayout 生年月日の形式
+const petDateLayout = "2006-01-02"
+
+// PetRequest ペットの登録・置き換えのリクエストボディ
+//
+// reference_numberを省略した場合、登録時は採番し、置き換え時は変更しない
+type PetRequest struct {
+	Name            string   `json:"name" validate:"required,max=100"`
+	Breed           string   `json:"breed" validate:"required,max=100"`
+	Gender          string   `json:"gender" validate:"required,oneof=Male Female"`
+	Price           *float64 `json:"price" validate:"required,gte=0"`
+	ImageURL        string   `json:"image_url" validate:"omitempty,http_url,max=2048"`
+	ShopID          int64    `json:"shop_id" validate:"required,gt=0"`
+	BirthDate       string   `json:"birth_date" validate:"omitempty,datetime=2006-01-02"`
+	ReferenceNumber string   `json:"reference_number" validate:"omitempty,max=32"`
+	Tags            []string `json:"tags" validate:"max=10,dive,required,max=30"`
+}
+
+// PetPatchRequest ペットの部分更新のリクエストボディ（指定した項目のみ更新する）
+type PetPatchRequest struct {
+	Name   *string  `json:"name" validate:"omitnil,min=1,max=100"`
+	Breed  *string  `json:"breed" validate:"omitnil,min=1,max=100"`
+	Gender *string  `json:"gender" validate:"omitnil,oneof=Male Female"`
+	Price  *float64 `json:"price" validate:"omitnil,gte=0"`
+	// ImageURL 空文字の場合は画像を削除する
+	ImageURL *string `json:"image_url" validate:"omitzero,http_url,max=2048"`
+	ShopID   *int64  `json:"shop_id" validate:"omitnil,gt=0"`
+	// BirthDate 空文字の場合は生年月日を削除する
+	BirthDate       *string   `json:"birth_date" validate:"omitzero,datetime=2006-01-02"`
+	ReferenceNumber *string   `json:"reference_number" validate:"omitnil,min=1,max=32"`
+	Tags            *[]string `json:"tags" validate:"omitnil,max=10,dive,required,max=30"`
+}
+
 // PetHandler ...
 type PetHandler struct {
 	Interactor usecase.PetInteractor
 }
 
 // NewPetHandler ...
-func NewPetHandler(sqlHandler database.SQLHandler) *PetHandler {
+func NewPetHandler(sqlHandler database.SQLHandler, events usecase.EventPublisher) *PetHandler {
 	return &PetHandler{
 		Interactor: usecase.PetInteractor{
 			PetRepository: &repository.PetRepository{
@@ -32,6 +87,17 @@ func NewPetHandler(sqlHandler database.SQLHandler) *PetHandler {
 			FavoriteRepository: &repository.FavoriteRepository{
 				SQLHandler: sqlHandler,
 			},
+			ShopRepository: &repository.ShopRepository{
+				SQLHandler: sqlHandler,
+			},
+			TransactionManager: &repository.TransactionManager{
+				SQLHandler: sqlHandler,
+			},
+			Outbox: &repository.OutboxRepository{
+				SQLHandler: sqlHandler,
+			},
+			Events:               events,
+			ReservationCountMode: utils.NewAPIConfig().ReservationCountMode,
 		},
 	}
 }
@@ -48,7 +114,7 @@ func (handler *PetHandler) GetPets() echo.HandlerFunc {
 		defer span.End()
 
 		filter := new(model.PetFilter)
-		if err := c.Bind(filter); err != nil {
+		if err := bindAndValidate(c, filter); err != nil {
 			span.RecordError(err)
 			return err
 		}
@@ -59,13 +125,16 @@ func (handler *PetHandler) GetPets() echo.HandlerFunc {
 			attribute.String("filter.name", filter.Name),
 			attribute.String("filter.gender", filter.Gender),
 			attribute.Float64("filter.price", filter.Price),
+			attribute.StringSlice("filter.status", filter.Status),
+			attribute.String("filter.sort", filter.Sort),
+			attribute.Int("filter.limit", filter.Limit),
 		)
 
 		// Pass the context with span to the interactor
-		res, err := handler.Interactor.GetPets(ctx, filter)
+		res, page, err := handler.Interactor.GetPets(ctx, filter)
 		if err != nil {
 			span.RecordError(err)
-			return err
+			return errors.NewEchoHTTPError(ctx, err)
 		}
 
 		// 結果の属性を追加
@@ -74,8 +143,11 @@ func (handler *PetHandler) GetPets() echo.HandlerFunc {
 		)
 
 		// resの中身をJSONにして返却
-		resJSON := model.APIResponse{
-			Data: res,
+		resJSON := model.PagedAPIResponse{
+			APIResponse: model.APIResponse{
+				Data: res,
+			},
+			PageInfo: page,
 		}
 
 		return c.JSON(http.StatusOK, resJSON)
@@ -96,19 +168,14 @@ func (handler *PetHandler) UpdateLike() echo.HandlerFunc {
 		// パスパラメータ "id" の値を取得
 		id := c.Param("id")
 		if id == "" {
-			return c.JSON(http.StatusBadRequest, model.Response{
-				Message: "No id param found",
-			})
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
 		}
 
 		// Bindでリクエストの中身をiに詰める
-		input := new(struct {
-			UserId string `json:"user_id"`
-			Value  bool   `json:"value"`
-		})
-		if err = c.Bind(input); err != nil {
+		input := new(LikeRequest)
+		if err = bindAndValidate(c, input); err != nil {
 			span.RecordError(err)
-			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			return err
 		}
 
 		// スパンに属性を追加
@@ -126,8 +193,16 @@ func (handler *PetHandler) UpdateLike() echo.HandlerFunc {
 		})
 
 		if err != nil {
+			// 登録済みの場合など、処理を継続できる結果は200で返す
+			var be errors.BusinessError
+			if stderrors.As(err, &be) && be.HTTPStatus() < http.StatusBadRequest {
+				return c.JSON(be.HTTPStatus(), model.Response{
+					Code:    be.HTTPStatus(),
+					Message: be.Message(errors.LocaleFromContext(ctx)),
+				})
+			}
 			span.RecordError(err)
-			return err
+			return errors.NewEchoHTTPError(ctx, err)
 		}
 
 		return c.JSON(http.StatusOK, model.Response{
@@ -151,42 +226,38 @@ func (handler *PetHandler) Reservation() echo.HandlerFunc {
 		// パスパラメータ "id" の値を取得
 		petId := c.Param("id")
 		if petId == "" {
-			return c.JSON(http.StatusBadRequest, model.Response{
-				Message: "No id param found",
-			})
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
 		}
 
 		// Bindでリクエストの中身をinputにつめる
-		input := new(struct {
-			UserId          string `json:"user_id"`
-			Email           string `json:"email"`
-			FullName        string `json:"full_name"`
-			ReservationDate string `json:"reservation_date"`
-		})
-		if err = c.Bind(input); err != nil {
+		input := new(ReservationRequest)
+		if err = bindAndValidate(c, input); err != nil {
 			span.RecordError(err)
-			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			return err
 		}
 
 		// スパンに属性を追加
 		span.SetAttributes(
 			attribute.String("pet_id", petId),
 			attribute.String("user_id", input.UserId),
-			attribute.String("reservation_date", input.ReservationDate),
+			attribute.String("slot_starts_at", input.SlotStartsAt),
 		)
 
+		// 入力チェック済みのため変換に失敗することはない
+		slotStartsAt, _ := time.Parse(time.RFC3339, input.SlotStartsAt)
+
 		// UseCaseの実行
 		err = handler.Interactor.CreateReservation(ctx, &model.Reservation{
-			PetId:           petId,
-			UserId:          input.UserId,
-			Email:           input.Email,
-			FullName:        input.FullName,
-			ReservationDate: input.ReservationDate,
+			PetId:        petId,
+			UserId:       input.UserId,
+			Email:        input.Email,
+			FullName:     input.FullName,
+			SlotStartsAt: slotStartsAt,
 		})
 
 		if err != nil {
 			span.RecordError(err)
-			return err
+			return errors.NewEchoHTTPError(ctx, err)
 		}
 
 		return c.JSON(http.StatusOK, model.Response{
@@ -195,3 +266,305 @@ func (handler *PetHandler) Reservation() echo.HandlerFunc {
 		})
 	}
 }
+
+// GetAvailability ...
+func (handler *PetHandler) GetAvailability() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("pet-handler")
+		ctx, span := tracer.Start(ctx, "GetAvailability",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		// パスパラメータ "id" の値を取得
+		petId := c.Param("id")
+		if petId == "" {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		filter := new(model.AvailabilityFilter)
+		if err = bindAndValidate(c, filter); err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.String("pet_id", petId),
+			attribute.String("filter.from", filter.From),
+			attribute.Int("filter.days", filter.Days),
+		)
+
+		res, err := handler.Interactor.GetAvailability(ctx, petId, filter)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		// 結果の属性を追加
+		span.SetAttributes(
+			attribute.Int("result_count", len(res.Slots)),
+		)
+
+		return c.JSON(http.StatusOK, model.APIResponse{Data: res})
+	}
+}
+
+// CreatePet ...
+func (handler *PetHandler) CreatePet() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("pet-handler")
+		ctx, span := tracer.Start(ctx, "CreatePet",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		input := new(PetRequest)
+		if err = bindAndValidate(c, input); err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.String("name", input.Name),
+			attribute.String("reference_number", input.ReferenceNumber),
+		)
+
+		// UseCaseの実行
+		pet := input.toPet()
+		if err = handler.Interactor.CreatePet(ctx, &pet); err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusCreated, model.APIResponse{Data: pet})
+	}
+}
+
+// ReplacePet ...
+func (handler *PetHandler) ReplacePet() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("pet-handler")
+		ctx, span := tracer.Start(ctx, "ReplacePet",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		// パスパラメータ "id" の値を取得
+		id := c.Param("id")
+		if id == "" {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		input := new(PetRequest)
+		if err = bindAndValidate(c, input); err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.String("pet_id", id),
+		)
+
+		// 省略された任意項目は削除する（管理番号は変更しない）
+		pet := input.toPet()
+		imageURL := input.ImageURL
+		birthDate := time.Time{}
+		if pet.BirthDate != nil {
+			birthDate = *pet.BirthDate
+		}
+		changes := &model.PetChanges{
+			Name:      &pet.Name,
+			Breed:     &pet.Breed,
+			Gender:    &pet.Gender,
+			Price:     &pet.Price,
+			ImageURL:  &imageURL,
+			ShopID:    &input.ShopID,
+			BirthDate: &birthDate,
+			Tags:      pet.Tags,
+		}
+		if input.ReferenceNumber != "" {
+			changes.ReferenceNumber = &input.ReferenceNumber
+		}
+
+		// UseCaseの実行
+		res, err := handler.Interactor.UpdatePet(ctx, id, changes)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusOK, model.APIResponse{Data: res})
+	}
+}
+
+// PatchPet ...
+func (handler *PetHandler) PatchPet() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("pet-handler")
+		ctx, span := tracer.Start(ctx, "PatchPet",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		// パスパラメータ "id" の値を取得
+		id := c.Param("id")
+		if id == "" {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		input := new(PetPatchRequest)
+		if err = bindAndValidate(c, input); err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.String("pet_id", id),
+		)
+
+		// UseCaseの実行
+		res, err := handler.Interactor.UpdatePet(ctx, id, input.toChanges())
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusOK, model.APIResponse{Data: res})
+	}
+}
+
+// DeletePet ...
+func (handler *PetHandler) DeletePet() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("pet-handler")
+		ctx, span := tracer.Start(ctx, "DeletePet",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		// パスパラメータ "id" の値を取得
+		id := c.Param("id")
+		if id == "" {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.String("pet_id", id),
+		)
+
+		// UseCaseの実行
+		if err = handler.Interactor.DeletePet(ctx, id); err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// AdoptPet ...
+func (handler *PetHandler) AdoptPet() echo.HandlerFunc {
+	return handler.transition("AdoptPet", handler.Interactor.AdoptPet)
+}
+
+// WithdrawPet ...
+func (handler *PetHandler) WithdrawPet() echo.HandlerFunc {
+	return handler.transition("WithdrawPet", handler.Interactor.WithdrawPet)
+}
+
+// RelistPet ...
+func (handler *PetHandler) RelistPet() echo.HandlerFunc {
+	return handler.transition("RelistPet", handler.Interactor.RelistPet)
+}
+
+// transition ペットのステータスのみを変更する操作のハンドラーを返す
+func (handler *PetHandler) transition(name string, action func(ctx context.Context, id string) (*model.Pet, error)) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("pet-handler")
+		ctx, span := tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		// パスパラメータ "id" の値を取得
+		id := c.Param("id")
+		if id == "" {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.String("pet_id", id),
+		)
+
+		res, err := action(ctx, id)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusOK, model.APIResponse{Data: res})
+	}
+}
+
+// toPet リクエストボディをPetドメインモデルに変換する（入力チェック済みであること）
+func (r *PetRequest) toPet() model.Pet {
+	pet := model.Pet{
+		Name:            r.Name,
+		Breed:           r.Breed,
+		Gender:          r.Gender,
+		Price:           *r.Price,
+		Shop:            model.Shop{ID: r.ShopID},
+		ReferenceNumber: r.ReferenceNumber,
+		Tags:            r.Tags,
+	}
+	if pet.Tags == nil {
+		pet.Tags = []string{}
+	}
+	if r.ImageURL != "" {
+		pet.ImageURL = &r.ImageURL
+	}
+	if birthDate, err := time.Parse(petDateLayout, r.BirthDate); err == nil {
+		pet.BirthDate = &birthDate
+	}
+	return pet
+}
+
+// toChanges リクエストボディをPetChangesに変換する（入力チェック済みであること）
+func (r *PetPatchRequest) toChanges() *model.PetChanges {
+	changes := &model.PetChanges{
+		Name:            r.Name,
+		Breed:           r.Breed,
+		Gender:          r.Gender,
+		Price:           r.Price,
+		ImageURL:        r.ImageURL,
+		ReferenceNumber: r.ReferenceNumber,
+		ShopID:          r.ShopID,
+	}
+	if r.BirthDate != nil {
+		// 空文字の場合はゼロ値（削除）とする
+		birthDate, _ := time.Parse(petDateLayout, *r.BirthDate)
+		changes.BirthDate = &birthDate
+	}
+	if r.Tags != nil {
+		changes.Tags = *r.Tags
+	}
+	return changes
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+	"github.com/horsewin/echo-playground-v2/usecase"
+	"github.com/labstack/echo/v4"
+)
+
+// ReservationsQuery 予約一覧のクエリパラメータ（user_idの扱いはLikeRequestと同じ）
+type ReservationsQuery struct {
+	UserId string `query:"user_id" validate:"omitempty,max=64"`
+	Status string `query:"status" validate:"omitempty,oneof=pending confirmed cancelled"`
+}
+
+// RescheduleRequest JSON形式のリクエストボディをバインドするための構造体
+type RescheduleRequest struct {
+	// SlotStartsAt 変更先の予約枠の開始日時
+	SlotStartsAt string `json:"slot_starts_at" validate:"required,rfc3339"`
+}
+
+// ReservationHandler ...
+type ReservationHandler struct {
+	Interactor usecase.ReservationInteractor
+}
+
+// NewReservationHandler ...
+func NewReservationHandler(sqlHandler database.SQLHandler, events usecase.EventPublisher) *ReservationHandler {
+	return &ReservationHandler{
+		Interactor: usecase.ReservationInteractor{
+			ReservationRepository: &repository.ReservationRepository{
+				SQLHandler: sqlHandler,
+			},
+			PetRepository: &repository.PetRepository{
+				SQLHandler: sqlHandler,
+			},
+			ShopRepository: &repository.ShopRepository{
+				SQLHandler: sqlHandler,
+			},
+			TransactionManager: &repository.TransactionManager{
+				SQLHandler: sqlHandler,
+			},
+			Outbox: &repository.OutboxRepository{
+				SQLHandler: sqlHandler,
+			},
+			Events: events,
+		},
+	}
+}
+
+// GetReservations ...
+func (handler *ReservationHandler) GetReservations() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("reservation-handler")
+		ctx, span := tracer.Start(ctx, "GetReservations",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		var query ReservationsQuery
+		if err = bindAndValidate(c, &query); err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.String("user_id", query.UserId),
+			attribute.String("status", query.Status),
+		)
+
+		res, err := handler.Interactor.GetReservations(ctx, query.UserId, query.Status)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// GetReservation ...
+func (handler *ReservationHandler) GetReservation() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("reservation-handler")
+		ctx, span := tracer.Start(ctx, "GetReservation",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		id, ok := reservationID(c)
+		if !ok {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.Int("reservation_id", id),
+		)
+
+		res, err := handler.Interactor.GetReservation(ctx, id)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusOK, model.APIResponse{Data: res})
+	}
+}
+
+// ConfirmReservation ...
+func (handler *ReservationHandler) ConfirmReservation() echo.HandlerFunc {
+	return handler.transition("ConfirmReservation", handler.Interactor.ConfirmReservation)
+}
+
+// CancelReservation ...
+func (handler *ReservationHandler) CancelReservation() echo.HandlerFunc {
+	return handler.transition("CancelReservation", handler.Interactor.CancelReservation)
+}
+
+// RescheduleReservation ...
+func (handler *ReservationHandler) RescheduleReservation() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("reservation-handler")
+		ctx, span := tracer.Start(ctx, "RescheduleReservation",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		id, ok := reservationID(c)
+		if !ok {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		var req RescheduleRequest
+		if err = bindAndValidate(c, &req); err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.Int("reservation_id", id),
+			attribute.String("slot_starts_at", req.SlotStartsAt),
+		)
+
+		// 入力チェック済みのため変換に失敗することはない
+		slotStartsAt, _ := time.Parse(time.RFC3339, req.SlotStartsAt)
+
+		res, err := handler.Interactor.RescheduleReservation(ctx, id, slotStartsAt)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusOK, model.APIResponse{Data: res})
+	}
+}
+
+// transition ステータスのみを変更する操作のハンドラーを返す
+func (handler *ReservationHandler) transition(name string, action func(ctx context.Context, id int) (*model.ReservationDetail, error)) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("reservation-handler")
+		ctx, span := tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		id, ok := reservationID(c)
+		if !ok {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.Int("reservation_id", id),
+		)
+
+		res, err := action(ctx, id)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusOK, model.APIResponse{Data: res})
+	}
+}
+
+// reservationID パスパラメータ "id" の値を予約IDとして取得する
+func reservationID(c echo.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+	"github.com/horsewin/echo-playground-v2/usecase"
+)
+
+// ScheduleRequest 店舗の見学予約の受付設定のリクエストボディ（opening_hoursにない曜日は定休日）
+type ScheduleRequest struct {
+	Timezone     string                `json:"timezone" validate:"required,timezone"`
+	SlotMinutes  int                   `json:"slot_minutes" validate:"required,min=5,max=480"`
+	SlotCapacity int                   `json:"slot_capacity" validate:"required,min=1,max=100"`
+	OpeningHours []OpeningHoursRequest `json:"opening_hours" validate:"max=7,dive"`
+}
+
+// OpeningHoursRequest 曜日ごとの営業時間（weekdayは0: 日曜日 〜 6: 土曜日）
+type OpeningHoursRequest struct {
+	Weekday  *int   `json:"weekday" validate:"required,min=0,max=6"`
+	OpensAt  string `json:"opens_at" validate:"required,hhmm"`
+	ClosesAt string `json:"closes_at" validate:"required,hhmm"`
+}
+
+// ShopHandler ...
+type ShopHandler struct {
+	Interactor usecase.ShopInteractor
+}
+
+// NewShopHandler ...
+func NewShopHandler(sqlHandler database.SQLHandler) *ShopHandler {
+	// 店舗のペットの一覧は参照のみのため、イベントは配信しない
+	pets := NewPetHandler(sqlHandler, nil)
+	return &ShopHandler{
+		Interactor: usecase.ShopInteractor{
+			ShopRepository: &repository.ShopRepository{
+				SQLHandler: sqlHandler,
+			},
+			TransactionManager: &repository.TransactionManager{
+				SQLHandler: sqlHandler,
+			},
+			Pets: &pets.Interactor,
+		},
+	}
+}
+
+// GetShops ...
+func (handler *ShopHandler) GetShops() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("shop-handler")
+		ctx, span := tracer.Start(ctx, "GetShops",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		res, err := handler.Interactor.GetShops(ctx)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		// 結果の属性を追加
+		span.SetAttributes(
+			attribute.Int("result_count", len(res.Data)),
+		)
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// GetShop ...
+func (handler *ShopHandler) GetShop() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("shop-handler")
+		ctx, span := tracer.Start(ctx, "GetShop",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		id, ok := pathID(c, "id")
+		if !ok {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.Int64("shop_id", id),
+		)
+
+		res, err := handler.Interactor.GetShop(ctx, id)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusOK, model.APIResponse{Data: res})
+	}
+}
+
+// GetShopPets ...
+func (handler *ShopHandler) GetShopPets() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("shop-handler")
+		ctx, span := tracer.Start(ctx, "GetShopPets",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		id, ok := pathID(c, "id")
+		if !ok {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		filter := new(model.PetFilter)
+		if err := bindAndValidate(c, filter); err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.Int64("shop_id", id),
+			attribute.StringSlice("filter.status", filter.Status),
+			attribute.String("filter.sort", filter.Sort),
+			attribute.Int("filter.limit", filter.Limit),
+		)
+
+		res, page, err := handler.Interactor.GetShopPets(ctx, id, filter)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		// 結果の属性を追加
+		span.SetAttributes(
+			attribute.Int("result_count", len(res)),
+		)
+
+		return c.JSON(http.StatusOK, model.PagedAPIResponse{
+			APIResponse: model.APIResponse{Data: res},
+			PageInfo:    page,
+		})
+	}
+}
+
+// GetSchedule ...
+func (handler *ShopHandler) GetSchedule() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("shop-handler")
+		ctx, span := tracer.Start(ctx, "GetSchedule",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		id, ok := pathID(c, "id")
+		if !ok {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.Int64("shop_id", id),
+		)
+
+		res, err := handler.Interactor.GetSchedule(ctx, id)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusOK, model.APIResponse{Data: res})
+	}
+}
+
+// UpdateSchedule ...
+func (handler *ShopHandler) UpdateSchedule() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("shop-handler")
+		ctx, span := tracer.Start(ctx, "UpdateSchedule",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		id, ok := pathID(c, "id")
+		if !ok {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		var req ScheduleRequest
+		if err = bindAndValidate(c, &req); err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.Int64("shop_id", id),
+			attribute.String("timezone", req.Timezone),
+			attribute.Int("slot_minutes", req.SlotMinutes),
+			attribute.Int("slot_capacity", req.SlotCapacity),
+		)
+
+		schedule := &model.ShopSchedule{
+			ShopID:       id,
+			Timezone:     req.Timezone,
+			SlotMinutes:  req.SlotMinutes,
+			SlotCapacity: req.SlotCapacity,
+			OpeningHours: make([]model.OpeningHours, 0, len(req.OpeningHours)),
+		}
+		for _, hours := range req.OpeningHours {
+			schedule.OpeningHours = append(schedule.OpeningHours, model.OpeningHours{
+				Weekday:  *hours.Weekday,
+				OpensAt:  hours.OpensAt,
+				ClosesAt: hours.ClosesAt,
+			})
+		}
+
+		res, err := handler.Interactor.UpdateSchedule(ctx, schedule)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusOK, model.APIResponse{Data: res})
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+)
+
+// RequestValidator echo.Validatorの実装
+type RequestValidator struct {
+	validator *validator.Validate
+}
+
+// NewRequestValidator ...
+func NewRequestValidator() *RequestValidator {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// エラーの項目名にはリクエストで使うjson/queryタグの名前を使う
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "query", "param"} {
+			name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+			if name != "" && name != "-" {
+				return name
+			}
+		}
+		return field.Name
+	})
+
+	// yyyymmdd形式の日付
+	_ = v.RegisterValidation("yyyymmdd", func(fl validator.FieldLevel) bool {
+		_, err := time.Parse("20060102", fl.Field().String())
+		return err == nil
+	})
+	// タイムゾーン付きの日時（RFC 3339形式）
+	_ = v.RegisterValidation("rfc3339", func(fl validator.FieldLevel) bool {
+		_, err := time.Parse(time.RFC3339, fl.Field().String())
+		return err == nil
+	})
+	// hh:mm形式の時刻（00:00〜23:59）
+	_ = v.RegisterValidation("hhmm", func(fl validator.FieldLevel) bool {
+		s := fl.Field().String()
+		_, err := time.Parse("15:04", s)
+		return err == nil && len(s) == len("15:04")
+	})
+	// Webhookで購読できるイベントの名前
+	_ = v.RegisterValidation("webhook_event", func(fl validator.FieldLevel) bool {
+		return model.IsWebhookEventType(fl.Field().String())
+	})
+
+	return &RequestValidator{validator: v}
+}
+
+// Validate ...
+func (rv *RequestValidator) Validate(i interface{}) error {
+	return rv.validator.Struct(i)
+}
+
+// bindAndValidate リクエストをバインドして入力チェックを行う
+// 不正な項目がある場合は項目ごとのエラーを列挙した400エラーを返す
+func bindAndValidate(c echo.Context, req interface{}) error {
+	// Bindのエラーは400のHTTPErrorとして返却される
+	if err := c.Bind(req); err != nil {
+		return err
+	}
+	if err := c.Validate(req); err != nil {
+		return errors.NewValidationHTTPError(c.Request().Context(), err)
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/horsewin/echo-playground-v2/infrastructure/memory"
+	"github.com/labstack/echo/v4"
+)
+
+// serveWithValidator 入力チェックを有効にしたechoでリクエストを処理する
+func serveWithValidator(t *testing.T, method, path, body, lang string, register func(e *echo.Echo)) *httptest.ResponseRecorder {
+	t.Helper()
+
+	e := echo.New()
+	e.Validator = NewRequestValidator()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			locale := errors.NegotiateLocale(c.Request().Header.Get("Accept-Language"), "")
+			c.SetRequest(c.Request().WithContext(errors.WithLocale(c.Request().Context(), locale)))
+			return next(c)
+		}
+	})
+	register(e)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if lang != "" {
+		req.Header.Set("Accept-Language", lang)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRequestValidation_Reservation(t *testing.T) {
+	sqlHandler, err := memory.NewSeededSQLHandler()
+	if err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	petHandler := NewPetHandler(sqlHandler, nil)
+	register := func(e *echo.Echo) { e.POST("/v1/pets/:id/reservation", petHandler.Reservation()) }
+
+	rec := serveWithValidator(t, http.MethodPost, "/v1/pets/1/reservation",
+		`{"user_id":"","email":"not-an-email","full_name":"","slot_starts_at":"2099-12-31 10:00"}`, "ja", register)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 but got %d: %s", rec.Code, rec.Body.String())
+	}
+	var res errors.ValidationErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("invalid response body: %v", err)
+	}
+	if res.Code != "00004E" {
+		t.Errorf("expected code 00004E but got %s", res.Code)
+	}
+
+	expected := map[string]string{
+		"full_name":      "00101E",
+		"email":          "00102E",
+		"slot_starts_at": "00109E",
+	}
+	if len(res.Errors) != len(expected) {
+		t.Fatalf("expected %d field errors but got %+v", len(expected), res.Errors)
+	}
+	for _, fe := range res.Errors {
+		if expected[fe.Field] != fe.Code {
+			t.Errorf("unexpected field error: %+v", fe)
+		}
+		if !strings.HasPrefix(fe.Message, fe.Field+"は") {
+			t.Errorf("expected a localized message but got %q", fe.Message)
+		}
+	}
+
+	// 正しい入力は受け付ける
+	rec = serveWithValidator(t, http.MethodPost, "/v1/pets/1/reservation",
+		`{"user_id":"user1","email":"user1@example.com","full_name":"Test User","slot_starts_at":"2099-12-31T10:00:00+09:00"}`, "", register)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 but got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRequestValidation_PetFilter(t *testing.T) {
+	sqlHandler, err := memory.NewSeededSQLHandler()
+	if err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	petHandler := NewPetHandler(sqlHandler, nil)
+	petHandler.Interactor.DisableLoadSimulation = true
+	register := func(e *echo.Echo) { e.GET("/v1/pets", petHandler.GetPets()) }
+
+	rec := serveWithValidator(t, http.MethodGet, "/v1/pets?sort=name&limit=1000&birth_date_from=20231014", "", "", register)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 but got %d: %s", rec.Code, rec.Body.String())
+	}
+	var res errors.ValidationErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("invalid response body: %v", err)
+	}
+	fields := map[string]errors.FieldError{}
+	for _, fe := range res.Errors {
+		fields[fe.Field] = fe
+	}
+	if fields["sort"].Code != "00104E" || fields["limit"].Code != "00105E" || fields["birth_date_from"].Code != "00106E" {
+		t.Errorf("unexpected field errors: %+v", res.Errors)
+	}
+	if fields["sort"].Message != "sort must be one of the allowed values." {
+		t.Errorf("expected an English message by default but got %q", fields["sort"].Message)
+	}
+
+	rec = serveWithValidator(t, http.MethodGet, "/v1/pets?sort=-price&limit=5", "", "", register)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 but got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRequestValidation_Pet(t *testing.T) {
+	sqlHandler, err := memory.NewSeededSQLHandler()
+	if err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	petHandler := NewPetHandler(sqlHandler, nil)
+	register := func(e *echo.Echo) {
+		e.POST("/v1/pets", petHandler.CreatePet())
+		e.PATCH("/v1/pets/:id", petHandler.PatchPet())
+	}
+
+	fieldErrors := func(rec *httptest.ResponseRecorder) map[string]string {
+		t.Helper()
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 but got %d: %s", rec.Code, rec.Body.String())
+		}
+		var res errors.ValidationErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("invalid response body: %v", err)
+		}
+		fields := map[string]string{}
+		for _, fe := range res.Errors {
+			fields[fe.Field] = fe.Code
+		}
+		return fields
+	}
+
+	fields := fieldErrors(serveWithValidator(t, http.MethodPost, "/v1/pets",
+		`{"name":"Mocha","gender":"unknown","price":-1,"image_url":"ftp://example.com/a.jpg"}`, "", register))
+	expected := map[string]string{"breed": "00101E", "gender": "00104E", "price": "00105E", "image_url": "00108E", "shop_id": "00101E"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("unexpected field errors: %v", fields)
+	}
+
+	// 部分更新では指定した項目のみ確認する（画像・生年月日の空文字は削除を表す）
+	fields = fieldErrors(serveWithValidator(t, http.MethodPatch, "/v1/pets/1",
+		`{"name":"","birth_date":"20231014","tags":[""]}`, "", register))
+	expected = map[string]string{"name": "00105E", "birth_date": "00106E", "tags[0]": "00101E"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("unexpected field errors: %v", fields)
+	}
+
+	rec := serveWithValidator(t, http.MethodPatch, "/v1/pets/1", `{"price":1000,"image_url":"","birth_date":""}`, "", register)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 but got %d: %s", rec.Code, rec.Body.String())
+	}
+	var res struct {
+		Data struct {
+			Name     string  `json:"name"`
+			Price    float64 `json:"price"`
+			ImageURL *string `json:"image_url"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("invalid response body: %v", err)
+	}
+	if res.Data.Name != "cute cat" || res.Data.Price != 1000 || res.Data.ImageURL != nil {
+		t.Errorf("unexpected patched pet: %s", rec.Body.String())
+	}
+}
+
+func TestRequestValidation_Schedule(t *testing.T) {
+	sqlHandler, err := memory.NewSeededSQLHandler()
+	if err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	shopHandler := NewShopHandler(sqlHandler)
+	register := func(e *echo.Echo) { e.PUT("/v1/shops/:id/schedule", shopHandler.UpdateSchedule()) }
+
+	rec := serveWithValidator(t, http.MethodPut, "/v1/shops/1/schedule",
+		`{"timezone":"Asia/Nowhere","slot_minutes":1,"slot_capacity":1,"opening_hours":[{"weekday":7,"opens_at":"9:00","closes_at":"18:00"},{"opens_at":"10:00","closes_at":"24:00"}]}`, "", register)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 but got %d: %s", rec.Code, rec.Body.String())
+	}
+	var res errors.ValidationErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("invalid response body: %v", err)
+	}
+	fields := map[string]string{}
+	for _, fe := range res.Errors {
+		fields[fe.Field] = fe.Code
+	}
+	expected := map[string]string{"timezone": "00111E", "slot_minutes": "00105E", "weekday": "00101E", "opens_at": "00110E", "closes_at": "00110E"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("unexpected field errors: %v", fields)
+	}
+
+	// 正しい入力は受け付ける（日曜日のみ営業）
+	rec = serveWithValidator(t, http.MethodPut, "/v1/shops/1/schedule",
+		`{"timezone":"Europe/London","slot_minutes":30,"slot_capacity":3,"opening_hours":[{"weekday":0,"opens_at":"09:00","closes_at":"12:00"}]}`, "", register)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 but got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRequestValidation_Notifications(t *testing.T) {
+	sqlHandler, err := memory.NewSeededSQLHandler()
+	if err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	notificationHandler := NewNotificationHandler(sqlHandler, NotificationStreamConfig{})
+	register := func(e *echo.Echo) { e.GET("/v1/notifications", notificationHandler.GetNotifications()) }
+
+	rec := serveWithValidator(t, http.MethodGet, "/v1/notifications?user_id=user1&since=2024-01-01&limit=101", "", "", register)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 but got %d: %s", rec.Code, rec.Body.String())
+	}
+	var res errors.ValidationErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("invalid response body: %v", err)
+	}
+	fields := map[string]errors.FieldError{}
+	for _, fe := range res.Errors {
+		fields[fe.Field] = fe
+	}
+	if fields["since"].Code != "00109E" || fields["limit"].Code != "00105E" {
+		t.Errorf("unexpected field errors: %+v", res.Errors)
+	}
+
+	// 検索条件はクエリパラメータから埋め込みの構造体にバインドされる
+	rec = serveWithValidator(t, http.MethodGet, "/v1/notifications?user_id=user1&is_read=false&type=campaign&since=2000-01-01T09:00:00.5%2B09:00", "", "", register)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 but got %d: %s", rec.Code, rec.Body.String())
+	}
+	var page struct {
+		Data       []map[string]interface{} `json:"data"`
+		NextCursor *string                  `json:"next_cursor"`
+		TotalCount int                      `json:"total_count"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("invalid response body: %v", err)
+	}
+	if len(page.Data) != 1 || page.Data[0]["type"] != "campaign" || page.TotalCount != 1 || page.NextCursor != nil {
+		t.Errorf("unexpected response: %s", rec.Body.String())
+	}
+}
+
+func TestRequestValidation_Webhook(t *testing.T) {
+	sqlHandler, err := memory.NewSeededSQLHandler()
+	if err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	webhookHandler := NewWebhookHandler(sqlHandler)
+	register := func(e *echo.Echo) {
+		e.POST("/v1/webhooks", webhookHandler.CreateSubscription())
+		e.PUT("/v1/webhooks/:id", webhookHandler.UpdateSubscription())
+	}
+
+	// Webhookで購読できないイベントは受け付けない
+	for _, tt := range []struct{ method, path, body string }{
+		{http.MethodPost, "/v1/webhooks", `{"shop_id":1,"event_type":"pet.added","url":"https://partner.example.com/hooks"}`},
+		{http.MethodPut, "/v1/webhooks/1", `{"event_type":"pet.added","url":"https://partner.example.com/hooks","active":true}`},
+	} {
+		rec := serveWithValidator(t, tt.method, tt.path, tt.body, "", register)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s %s: expected 400 but got %d: %s", tt.method, tt.path, rec.Code, rec.Body.String())
+		}
+		var res errors.ValidationErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("invalid response body: %v", err)
+		}
+		if len(res.Errors) != 1 || res.Errors[0].Field != "event_type" || res.Errors[0].Code != "00104E" {
+			t.Errorf("%s %s: unexpected field errors: %+v", tt.method, tt.path, res.Errors)
+		}
+	}
+
+	// 購読できるイベントはすべて受け付ける
+	for _, eventType := range model.WebhookEventTypes {
+		rec := serveWithValidator(t, http.MethodPost, "/v1/webhooks",
+			`{"shop_id":1,"event_type":"`+eventType+`","url":"https://partner.example.com/hooks"}`, "", register)
+		if rec.Code != http.StatusCreated {
+			t.Errorf("%s: expected 201 but got %d: %s", eventType, rec.Code, rec.Body.String())
+		}
+	}
+}
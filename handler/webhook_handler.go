@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+	"github.com/horsewin/echo-playground-v2/usecase"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WebhookSubscriptionsQuery Webhookの送信先一覧のクエリパラメータ
+type WebhookSubscriptionsQuery struct {
+	ShopID int64 `query:"shop_id" validate:"omitempty,gt=0"`
+}
+
+// CreateWebhookSubscriptionRequest Webhookの送信先の登録のリクエスト（activeを省略した場合は有効）
+type CreateWebhookSubscriptionRequest struct {
+	ShopID    int64  `json:"shop_id" validate:"required,gt=0"`
+	EventType string `json:"event_type" validate:"required,webhook_event"`
+	URL       string `json:"url" validate:"required,http_url,max=2048"`
+	Active    *bool  `json:"active"`
+}
+
+// UpdateWebhookSubscriptionRequest Webhookの送信先の変更のリクエスト（店舗は変更できない）
+type UpdateWebhookSubscriptionRequest struct {
+	EventType string `json:"event_type" validate:"required,webhook_event"`
+	URL       string `json:"url" validate:"required,http_url,max=2048"`
+	Active    *bool  `json:"active" validate:"required"`
+}
+
+// WebhookHandler ...
+type WebhookHandler struct {
+	Interactor usecase.WebhookInteractor
+}
+
+// NewWebhookHandler ...
+func NewWebhookHandler(sqlHandler database.SQLHandler) *WebhookHandler {
+	return &WebhookHandler{
+		Interactor: usecase.WebhookInteractor{
+			SubscriptionRepository: &repository.WebhookSubscriptionRepository{
+				SQLHandler: sqlHandler,
+			},
+			DeliveryRepository: &repository.WebhookDeliveryRepository{
+				SQLHandler: sqlHandler,
+			},
+			ShopRepository: &repository.ShopRepository{
+				SQLHandler: sqlHandler,
+			},
+		},
+	}
+}
+
+// GetSubscriptions ...
+func (handler *WebhookHandler) GetSubscriptions() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("webhook-handler")
+		ctx, span := tracer.Start(ctx, "GetWebhookSubscriptions",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		var query WebhookSubscriptionsQuery
+		if err = bindAndValidate(c, &query); err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.Int64("shop_id", query.ShopID),
+		)
+
+		res, err := handler.Interactor.GetSubscriptions(ctx, query.ShopID)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// GetSubscription ...
+func (handler *WebhookHandler) GetSubscription() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("webhook-handler")
+		ctx, span := tracer.Start(ctx, "GetWebhookSubscription",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		id, ok := pathID(c, "id")
+		if !ok {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.Int64("subscription_id", id),
+		)
+
+		res, err := handler.Interactor.GetSubscription(ctx, id)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusOK, model.APIResponse{Data: res})
+	}
+}
+
+// CreateSubscription ...
+func (handler *WebhookHandler) CreateSubscription() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("webhook-handler")
+		ctx, span := tracer.Start(ctx, "CreateWebhookSubscription",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		var req CreateWebhookSubscriptionRequest
+		if err = bindAndValidate(c, &req); err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.Int64("shop_id", req.ShopID),
+			attribute.String("event_type", req.EventType),
+		)
+
+		subscription := &model.WebhookSubscription{
+			ShopID:    req.ShopID,
+			EventType: req.EventType,
+			URL:       req.URL,
+			Active:    req.Active == nil || *req.Active,
+		}
+		if err = handler.Interactor.CreateSubscription(ctx, subscription); err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusCreated, model.APIResponse{Data: subscription})
+	}
+}
+
+// UpdateSubscription ...
+func (handler *WebhookHandler) UpdateSubscription() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("webhook-handler")
+		ctx, span := tracer.Start(ctx, "UpdateWebhookSubscription",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		id, ok := pathID(c, "id")
+		if !ok {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		var req UpdateWebhookSubscriptionRequest
+		if err = bindAndValidate(c, &req); err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.Int64("subscription_id", id),
+			attribute.String("event_type", req.EventType),
+		)
+
+		res, err := handler.Interactor.UpdateSubscription(ctx, id, model.WebhookSubscription{
+			EventType: req.EventType,
+			URL:       req.URL,
+			Active:    *req.Active,
+		})
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusOK, model.APIResponse{Data: res})
+	}
+}
+
+// DeleteSubscription ...
+func (handler *WebhookHandler) DeleteSubscription() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("webhook-handler")
+		ctx, span := tracer.Start(ctx, "DeleteWebhookSubscription",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		id, ok := pathID(c, "id")
+		if !ok {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.Int64("subscription_id", id),
+		)
+
+		if err = handler.Interactor.DeleteSubscription(ctx, id); err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// GetDeliveries ...
+func (handler *WebhookHandler) GetDeliveries() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("webhook-handler")
+		ctx, span := tracer.Start(ctx, "GetWebhookDeliveries",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		id, ok := pathID(c, "id")
+		if !ok {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.Int64("subscription_id", id),
+		)
+
+		res, err := handler.Interactor.GetDeliveries(ctx, id)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// GetDelivery ...
+func (handler *WebhookHandler) GetDelivery() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("webhook-handler")
+		ctx, span := tracer.Start(ctx, "GetWebhookDelivery",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		id, ok := pathID(c, "id")
+		deliveryID, deliveryOK := pathID(c, "delivery_id")
+		if !ok || !deliveryOK {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.Int64("subscription_id", id),
+			attribute.Int64("delivery_id", deliveryID),
+		)
+
+		res, err := handler.Interactor.GetDelivery(ctx, id, deliveryID)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		return c.JSON(http.StatusOK, model.APIResponse{Data: res})
+	}
+}
+
+// ReplayDelivery ...
+func (handler *WebhookHandler) ReplayDelivery() echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		// スパンを作成
+		ctx := c.Request().Context()
+		tracer := otel.Tracer("webhook-handler")
+		ctx, span := tracer.Start(ctx, "ReplayWebhookDelivery",
+			trace.WithSpanKind(trace.SpanKindInternal),
+		)
+		defer span.End()
+
+		id, ok := pathID(c, "id")
+		deliveryID, deliveryOK := pathID(c, "delivery_id")
+		if !ok || !deliveryOK {
+			return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", nil))
+		}
+
+		// スパンに属性を追加
+		span.SetAttributes(
+			attribute.Int64("subscription_id", id),
+			attribute.Int64("delivery_id", deliveryID),
+		)
+
+		res, err := handler.Interactor.ReplayDelivery(ctx, id, deliveryID)
+		if err != nil {
+			span.RecordError(err)
+			return errors.NewEchoHTTPError(ctx, err)
+		}
+
+		// 送信はバックグラウンドで行う
+		return c.JSON(http.StatusAccepted, model.APIResponse{Data: res})
+	}
+}
+
+// pathID パスパラメータnameの値を正の整数のIDとして取得する
+func pathID(c echo.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,304 @@
+package infrastructure
+
+import (
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/horsewin/echo-playground-v2/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// AuthModeJWT Authorization: Bearerで渡されたJWTを検証する
+	AuthModeJWT = "jwt"
+	// AuthModeAPIKey X-API-Keyで渡された静的なAPIキーを検証する（サービス間連携用）
+	AuthModeAPIKey = "api_key"
+
+	// headerAPIKey APIキーを渡すヘッダー
+	headerAPIKey = "X-API-Key"
+	// jwtLeeway 発行元とのクロックのずれの許容値
+	jwtLeeway = 30 * time.Second
+)
+
+// errNoCredentials リクエストに認証方式に対応する認証情報が含まれていない
+var errNoCredentials = stderrors.New("no credentials")
+
+// Authenticator リクエストの認証情報を検証してリクエスト元を返す
+type Authenticator interface {
+	// Authenticate 認証情報が含まれていない場合はerrNoCredentialsを返す
+	Authenticate(req *http.Request) (*model.Principal, error)
+	// Challenge 認証に失敗した場合のWWW-Authenticateヘッダーの値（不要な場合は空）
+	Challenge() string
+}
+
+// NewAuthenticators 設定された認証方式のAuthenticatorを返す（認証が無効の場合は空）
+func NewAuthenticators(c *utils.APIConfig) ([]Authenticator, error) {
+	authenticators := make([]Authenticator, 0, len(c.Auth.Modes))
+	for _, mode := range c.Auth.Modes {
+		switch mode {
+		case AuthModeJWT:
+			a, err := newJWTAuthenticator(c)
+			if err != nil {
+				return nil, err
+			}
+			authenticators = append(authenticators, a)
+		case AuthModeAPIKey:
+			authenticators = append(authenticators, &apiKeyAuthenticator{keys: c.Auth.APIKeys})
+		default:
+			return nil, fmt.Errorf("unknown auth mode: %s (expected jwt, api_key or none)", mode)
+		}
+	}
+	return authenticators, nil
+}
+
+// setupAuthMiddleware リクエスト元を認証し、contextに設定するミドルウェアを設定
+//
+// 認証情報が不正な場合は常に401を返す。認証情報がない場合、isPublicがtrueのルートは
+// リクエスト元なしで処理を続け、それ以外は401を返す
+func setupAuthMiddleware(authenticators []Authenticator, isPublic func(c echo.Context) bool) echo.MiddlewareFunc {
+	challenges := make([]string, 0, len(authenticators))
+	for _, a := range authenticators {
+		if challenge := a.Challenge(); challenge != "" {
+			challenges = append(challenges, challenge)
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := req.Context()
+
+			for _, a := range authenticators {
+				principal, err := a.Authenticate(req)
+				if stderrors.Is(err, errNoCredentials) {
+					continue
+				}
+				if err != nil {
+					zerolog.Ctx(ctx).Warn().Err(err).Msg("authentication failed")
+					return unauthorized(c, challenges, errors.NewBusinessError("00006E", err))
+				}
+
+				trace.SpanFromContext(ctx).SetAttributes(
+					attribute.String("enduser.id", principal.ID),
+					attribute.String("enduser.kind", principal.Kind),
+				)
+				c.SetRequest(req.WithContext(model.WithPrincipal(ctx, principal)))
+				return next(c)
+			}
+
+			if isPublic(c) {
+				return next(c)
+			}
+			return unauthorized(c, challenges, errors.NewBusinessError("00005E", nil))
+		}
+	}
+}
+
+// unauthorized WWW-Authenticateヘッダーを設定して401のエラーを返す
+func unauthorized(c echo.Context, challenges []string, err error) error {
+	for _, challenge := range challenges {
+		c.Response().Header().Add(echo.HeaderWWWAuthenticate, challenge)
+	}
+	return errors.NewEchoHTTPError(c.Request().Context(), err)
+}
+
+// jwtAuthenticator 署名・有効期限・発行者を検証したJWTのsubをユーザーIDとする
+type jwtAuthenticator struct {
+	parser     *jwt.Parser
+	hmacSecret []byte
+	rsaKey     *rsa.PublicKey
+	jwks       map[string]*rsa.PublicKey
+}
+
+// newJWTAuthenticator 設定された鍵でJWTを検証するAuthenticatorを返す
+func newJWTAuthenticator(c *utils.APIConfig) (*jwtAuthenticator, error) {
+	a := &jwtAuthenticator{}
+	var methods []string
+
+	if c.Auth.JWTHS256Secret != "" {
+		a.hmacSecret = []byte(c.Auth.JWTHS256Secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if c.Auth.JWTRS256PublicKeyFile != "" {
+		pem, err := os.ReadFile(c.Auth.JWTRS256PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read RS256 public key: %w", err)
+		}
+		a.rsaKey, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RS256 public key: %w", err)
+		}
+	}
+	if c.Auth.JWTJWKSFile != "" {
+		keys, err := loadJWKS(c.Auth.JWTJWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.jwks = keys
+	}
+	if a.rsaKey != nil || len(a.jwks) > 0 {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, stderrors.New("jwt auth mode requires SBCNTR_JWT_HS256_SECRET, SBCNTR_JWT_RS256_PUBLIC_KEY_FILE or SBCNTR_JWT_JWKS_FILE")
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtLeeway),
+	}
+	if c.Auth.JWTIssuer != "" {
+		options = append(options, jwt.WithIssuer(c.Auth.JWTIssuer))
+	}
+	if c.Auth.JWTAudience != "" {
+		options = append(options, jwt.WithAudience(c.Auth.JWTAudience))
+	}
+	a.parser = jwt.NewParser(options...)
+
+	return a, nil
+}
+
+// Authenticate ...
+func (a *jwtAuthenticator) Authenticate(req *http.Request) (*model.Principal, error) {
+	scheme, token, ok := strings.Cut(req.Header.Get(echo.HeaderAuthorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, errNoCredentials
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	if _, err := a.parser.ParseWithClaims(strings.TrimSpace(token), claims, a.keyFunc); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, stderrors.New("token has no sub claim")
+	}
+
+	return &model.Principal{ID: claims.Subject, Kind: model.PrincipalKindUser}, nil
+}
+
+// Challenge ...
+func (a *jwtAuthenticator) Challenge() string {
+	return "Bearer"
+}
+
+// keyFunc 署名方式とkidに応じた検証用の鍵を返す
+func (a *jwtAuthenticator) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if a.hmacSecret != nil {
+			return a.hmacSecret, nil
+		}
+	case *jwt.SigningMethodRSA:
+		if kid, _ := token.Header["kid"].(string); kid != "" {
+			if key, ok := a.jwks[kid]; ok {
+				return key, nil
+			}
+			if a.rsaKey == nil {
+				return nil, fmt.Errorf("unknown kid: %s", kid)
+			}
+		}
+		if a.rsaKey != nil {
+			return a.rsaKey, nil
+		}
+		// kidが指定されていない場合、JWKSの鍵が1つであればその鍵を使う
+		if len(a.jwks) == 1 {
+			for _, key := range a.jwks {
+				return key, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no key for signing method %s", token.Method.Alg())
+}
+
+// loadJWKS JWKSファイルからRSAの公開鍵をkidごとに読み込む
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		// 署名用のRSA鍵のみ対象とする
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS key %s: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS key %s: %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS %s has no RSA signing keys", path)
+	}
+	return keys, nil
+}
+
+// apiKeyAuthenticator 静的なAPIキーに対応するサービスをリクエスト元とする
+type apiKeyAuthenticator struct {
+	// keys APIキーとサービス名の対応
+	keys map[string]string
+}
+
+// Authenticate ...
+func (a *apiKeyAuthenticator) Authenticate(req *http.Request) (*model.Principal, error) {
+	key := req.Header.Get(headerAPIKey)
+	if key == "" {
+		return nil, errNoCredentials
+	}
+
+	// キーの一致判定で処理時間から推測されないよう、全件を定数時間で比較する
+	var service string
+	for candidate, name := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 {
+			service = name
+		}
+	}
+	if service == "" {
+		return nil, stderrors.New("unknown api key")
+	}
+
+	return &model.Principal{ID: service, Kind: model.PrincipalKindService}, nil
+}
+
+// Challenge ...
+func (a *apiKeyAuthenticator) Challenge() string {
+	return ""
+}
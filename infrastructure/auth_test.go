@@ -0,0 +1,178 @@
+package infrastructure
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/utils"
+	"github.com/labstack/echo/v4"
+)
+
+const testHS256Secret = "test-secret"
+
+func signHS256(t *testing.T, claims jwt.RegisteredClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testHS256Secret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+// writeJWKS 公開鍵をkid付きのJWKSファイルとして書き出す
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	t.Helper()
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	data, _ := json.Marshal(jwks)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write JWKS: %v", err)
+	}
+	return path
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	config := &utils.APIConfig{}
+	config.Auth.Modes = []string{AuthModeJWT, AuthModeAPIKey}
+	config.Auth.JWTHS256Secret = testHS256Secret
+	config.Auth.JWTJWKSFile = writeJWKS(t, "key-1", &rsaKey.PublicKey)
+	config.Auth.JWTIssuer = "https://issuer.example.com"
+	config.Auth.APIKeys = map[string]string{"s3cr3t": "batch"}
+
+	authenticators, err := NewAuthenticators(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	e := echo.New()
+	e.HTTPErrorHandler = httpErrorHandler
+	e.Use(setupAuthMiddleware(authenticators, isPublicRoute))
+	whoami := func(c echo.Context) error {
+		p := model.PrincipalFromContext(c.Request().Context())
+		if p == nil {
+			return c.String(http.StatusOK, "anonymous")
+		}
+		return c.String(http.StatusOK, p.Kind+":"+p.ID)
+	}
+	e.GET("/v1/pets", whoami)
+	e.POST("/v1/pets", whoami)
+	e.GET("/v1/shops/:id/pets", whoami)
+	e.GET("/v1/pets/:id/availability", whoami)
+	e.PUT("/v1/shops/:id/schedule", whoami)
+	e.GET("/v1/reservations", whoami)
+
+	now := time.Now()
+	valid := jwt.RegisteredClaims{
+		Subject:   "user1",
+		Issuer:    "https://issuer.example.com",
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Hour))
+	wrongIssuer := valid
+	wrongIssuer.Issuer = "https://evil.example.com"
+	noExpiry := valid
+	noExpiry.ExpiresAt = nil
+
+	signRS256 := func(key *rsa.PrivateKey, kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, valid)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return signed
+	}
+	noneToken, _ := jwt.NewWithClaims(jwt.SigningMethodNone, valid).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		header         string
+		value          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "HS256", path: "/v1/reservations", header: "Authorization", value: "Bearer " + signHS256(t, valid), expectedStatus: http.StatusOK, expectedBody: "user:user1"},
+		{name: "RS256（JWKS）", path: "/v1/reservations", header: "Authorization", value: "Bearer " + signRS256(rsaKey, "key-1"), expectedStatus: http.StatusOK, expectedBody: "user:user1"},
+		{name: "APIキー", path: "/v1/reservations", header: "X-API-Key", value: "s3cr3t", expectedStatus: http.StatusOK, expectedBody: "service:batch"},
+		{name: "公開ルートは認証なしで利用できる", path: "/v1/pets", expectedStatus: http.StatusOK, expectedBody: "anonymous"},
+		{name: "店舗のペットの一覧も公開する", path: "/v1/shops/1/pets", expectedStatus: http.StatusOK, expectedBody: "anonymous"},
+		{name: "見学予約の枠も公開する", path: "/v1/pets/1/availability", expectedStatus: http.StatusOK, expectedBody: "anonymous"},
+		{name: "店舗の受付設定の変更は認証が必要", method: http.MethodPut, path: "/v1/shops/1/schedule", expectedStatus: http.StatusUnauthorized},
+		{name: "公開ルートでも認証情報があれば検証する", path: "/v1/pets", header: "Authorization", value: "Bearer " + signHS256(t, valid), expectedStatus: http.StatusOK, expectedBody: "user:user1"},
+		{name: "認証情報なし", path: "/v1/reservations", expectedStatus: http.StatusUnauthorized},
+		{name: "有効期限切れ", path: "/v1/reservations", header: "Authorization", value: "Bearer " + signHS256(t, expired), expectedStatus: http.StatusUnauthorized},
+		{name: "有効期限なし", path: "/v1/reservations", header: "Authorization", value: "Bearer " + signHS256(t, noExpiry), expectedStatus: http.StatusUnauthorized},
+		{name: "発行者が異なる", path: "/v1/reservations", header: "Authorization", value: "Bearer " + signHS256(t, wrongIssuer), expectedStatus: http.StatusUnauthorized},
+		{name: "署名の鍵が異なる", path: "/v1/reservations", header: "Authorization", value: "Bearer " + signRS256(otherKey, "key-1"), expectedStatus: http.StatusUnauthorized},
+		{name: "未知のkid", path: "/v1/reservations", header: "Authorization", value: "Bearer " + signRS256(rsaKey, "key-2"), expectedStatus: http.StatusUnauthorized},
+		{name: "署名なし", path: "/v1/reservations", header: "Authorization", value: "Bearer " + noneToken, expectedStatus: http.StatusUnauthorized},
+		{name: "未知のAPIキー", path: "/v1/pets", header: "X-API-Key", value: "wrong", expectedStatus: http.StatusUnauthorized},
+		{name: "ペットの登録は公開しない", method: http.MethodPost, path: "/v1/pets", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d but got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q but got %q", tt.expectedBody, rec.Body.String())
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get(echo.HeaderWWWAuthenticate) != "Bearer" {
+				t.Errorf("expected WWW-Authenticate header but got %q", rec.Header().Get(echo.HeaderWWWAuthenticate))
+			}
+		})
+	}
+}
+
+func TestNewAuthenticators_InvalidConfig(t *testing.T) {
+	config := &utils.APIConfig{}
+	config.Auth.Modes = []string{AuthModeJWT}
+	if _, err := NewAuthenticators(config); err == nil {
+		t.Errorf("expected error when no JWT key is configured")
+	}
+
+	config.Auth.Modes = []string{"basic"}
+	if _, err := NewAuthenticators(config); err == nil {
+		t.Errorf("expected error for unknown auth mode")
+	}
+}
@@ -0,0 +1,146 @@
+package infrastructure
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+	"github.com/horsewin/echo-playground-v2/usecase"
+	"github.com/horsewin/echo-playground-v2/utils"
+	zerologlog "github.com/rs/zerolog/log"
+)
+
+// backgroundWorkers リクエストとは別に動かす処理（DBを利用しない場合は空）
+var backgroundWorkers []*backgroundWorker
+
+// backgroundWorker バックグラウンドで動かす処理と、そのgoroutineの停止を管理する
+type backgroundWorker struct {
+	name   string
+	run    func(ctx context.Context)
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// newBackgroundWorkers outboxのリレー、Webhookの送信、期限切れのIdempotency-Keyの削除を作成する
+func newBackgroundWorkers(sqlHandler database.SQLHandler, apiConfig *utils.APIConfig) ([]*backgroundWorker, error) {
+	publisher, err := newOutboxPublisher(apiConfig)
+	if err != nil {
+		return nil, err
+	}
+	transactionManager := &repository.TransactionManager{
+		SQLHandler: sqlHandler,
+	}
+	subscriptions := &repository.WebhookSubscriptionRepository{
+		SQLHandler: sqlHandler,
+	}
+	deliveries := &repository.WebhookDeliveryRepository{
+		SQLHandler: sqlHandler,
+	}
+
+	relay := &usecase.OutboxRelay{
+		Repository: &repository.OutboxRepository{
+			SQLHandler: sqlHandler,
+		},
+		TransactionManager: transactionManager,
+		// Webhookの送信待ちの記録に失敗した場合は、イベントごと再配信する
+		Publisher: outboxPublishers{
+			&usecase.WebhookFanout{
+				PetRepository: &repository.PetRepository{
+					SQLHandler: sqlHandler,
+				},
+				SubscriptionRepository: subscriptions,
+				DeliveryRepository:     deliveries,
+			},
+			publisher,
+		},
+		BatchSize:    apiConfig.Outbox.BatchSize,
+		PollInterval: apiConfig.Outbox.PollInterval,
+		MaxAttempts:  apiConfig.Outbox.MaxAttempts,
+		Lease:        apiConfig.Outbox.Lease,
+	}
+	dispatcher := &usecase.WebhookDispatcher{
+		DeliveryRepository:     deliveries,
+		SubscriptionRepository: subscriptions,
+		TransactionManager:     transactionManager,
+		Sender:                 newHTTPWebhookSender(apiConfig.Webhook.Timeout),
+		PollInterval:           apiConfig.Webhook.PollInterval,
+		MaxAttempts:            apiConfig.Webhook.MaxAttempts,
+		Lease:                  apiConfig.Webhook.Lease,
+	}
+
+	return []*backgroundWorker{
+		{name: "outbox relay", run: relay.Run},
+		{name: "webhook dispatcher", run: dispatcher.Run},
+		{name: "idempotency key purge", run: newIdempotencyInteractor(sqlHandler, apiConfig).Run},
+	}, nil
+}
+
+// outboxPublishers outboxのイベントを順に配信する（いずれかが失敗した場合はイベントごと再試行する）
+type outboxPublishers []usecase.OutboxPublisher
+
+// Publish ...
+func (publishers outboxPublishers) Publish(ctx context.Context, message model.OutboxMessage) error {
+	for _, p := range publishers {
+		if err := p.Publish(ctx, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// start 処理をバックグラウンドで開始する
+func (w *backgroundWorker) start() {
+	ctx, cancel := context.WithCancel(zerologlog.Logger.WithContext(context.Background()))
+	w.cancel = cancel
+	w.done = make(chan struct{})
+
+	go func() {
+		defer close(w.done)
+		w.run(ctx)
+	}()
+}
+
+// stop 処理を停止し、処理中のバッチが完了するまで待つ
+func (w *backgroundWorker) stop(ctx context.Context) error {
+	if w.cancel == nil {
+		return nil
+	}
+	w.cancel()
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", w.name, ctx.Err())
+	}
+}
+
+// StartBackgroundWorkers outboxのイベントの配信とWebhookの送信を開始する（DBを利用しない場合は何もしない）
+//
+// Routerでルートを登録した後に呼び出す
+func StartBackgroundWorkers() {
+	for _, w := range backgroundWorkers {
+		zerologlog.Info().Str("worker", w.name).Msg("Starting background worker")
+		w.start()
+	}
+}
+
+// StopBackgroundWorkers outboxのイベントの配信とWebhookの送信を停止する
+//
+// 処理中のバッチは結果を記録してから停止する（ctxの期限までに完了しない場合はエラーを返す）
+// 未処理のイベント・送信待ちは残したままにし、次に起動したタスクが処理する
+func StopBackgroundWorkers(ctx context.Context) error {
+	// 先にすべて停止を指示し、並行して処理中のバッチを完了させる
+	for _, w := range backgroundWorkers {
+		if w.cancel != nil {
+			w.cancel()
+		}
+	}
+	var errs []error
+	for _, w := range backgroundWorkers {
+		errs = append(errs, w.stop(ctx))
+	}
+	return stderrors.Join(errs...)
+}
@@ -0,0 +1,72 @@
+package infrastructure
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// registerDBStatsMetrics コネクションプールの状態(sql.DBStats)をメトリクスとして公開する
+func registerDBStatsMetrics(conn *sqlx.DB, poolName string) error {
+	meter := otel.Meter("sql-handler")
+
+	openConnections, err := meter.Int64ObservableGauge("db.client.connections.open",
+		metric.WithDescription("Number of established connections, both in use and idle"),
+		metric.WithUnit("{connection}"))
+	if err != nil {
+		return err
+	}
+	inUse, err := meter.Int64ObservableGauge("db.client.connections.in_use",
+		metric.WithDescription("Number of connections currently in use"),
+		metric.WithUnit("{connection}"))
+	if err != nil {
+		return err
+	}
+	idle, err := meter.Int64ObservableGauge("db.client.connections.idle",
+		metric.WithDescription("Number of idle connections"),
+		metric.WithUnit("{connection}"))
+	if err != nil {
+		return err
+	}
+	maxOpen, err := meter.Int64ObservableGauge("db.client.connections.max",
+		metric.WithDescription("Maximum number of open connections allowed"),
+		metric.WithUnit("{connection}"))
+	if err != nil {
+		return err
+	}
+	waitCount, err := meter.Int64ObservableCounter("db.client.connections.wait_count",
+		metric.WithDescription("Total number of connections waited for"),
+		metric.WithUnit("{connection}"))
+	if err != nil {
+		return err
+	}
+	waitDuration, err := meter.Float64ObservableCounter("db.client.connections.wait_duration",
+		metric.WithDescription("Total time blocked waiting for a new connection"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+	closed, err := meter.Int64ObservableCounter("db.client.connections.closed",
+		metric.WithDescription("Total number of connections closed due to SetMaxIdleConns, SetConnMaxIdleTime or SetConnMaxLifetime"),
+		metric.WithUnit("{connection}"))
+	if err != nil {
+		return err
+	}
+
+	attrs := metric.WithAttributes(attribute.String("pool.name", poolName))
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		stats := conn.Stats()
+		o.ObserveInt64(openConnections, int64(stats.OpenConnections), attrs)
+		o.ObserveInt64(inUse, int64(stats.InUse), attrs)
+		o.ObserveInt64(idle, int64(stats.Idle), attrs)
+		o.ObserveInt64(maxOpen, int64(stats.MaxOpenConnections), attrs)
+		o.ObserveInt64(waitCount, stats.WaitCount, attrs)
+		o.ObserveFloat64(waitDuration, stats.WaitDuration.Seconds(), attrs)
+		o.ObserveInt64(closed, stats.MaxIdleClosed+stats.MaxIdleTimeClosed+stats.MaxLifetimeClosed, attrs)
+		return nil
+	}, openConnections, inUse, idle, maxOpen, waitCount, waitDuration, closed)
+	return err
+}
@@ -0,0 +1,40 @@
+package infrastructure
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+// httpErrorHandler ハンドラー・ミドルウェアが返したエラーをapplication/problem+jsonのレスポンスに変換する
+//
+// BusinessError、echo.HTTPError（Bindのエラーを含む）、Recoverミドルウェアが捕捉したpanicを
+// すべて同じ形式で返却する
+func httpErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	ctx := c.Request().Context()
+	var he *echo.HTTPError
+	var be errors.BusinessError
+	if !stderrors.As(err, &he) && !stderrors.As(err, &be) {
+		// 予期せぬエラーはレスポンスに含めないためlogに記録する
+		zerolog.Ctx(ctx).Error().Err(err).Msg("unexpected error")
+	}
+
+	problem := errors.NewProblemDetails(ctx, err, c.Request().URL.Path)
+	c.Response().Header().Set(echo.HeaderContentType, errors.MIMEApplicationProblemJSON)
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(problem.Status)
+	} else {
+		err = c.JSON(problem.Status, problem)
+	}
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("failed to write error response")
+	}
+}
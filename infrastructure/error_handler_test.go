@@ -0,0 +1,118 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	handlers "github.com/horsewin/echo-playground-v2/handler"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	e := echo.New()
+	e.Validator = handlers.NewRequestValidator()
+	e.HTTPErrorHandler = httpErrorHandler
+	e.Use(middleware.RequestID())
+	e.Use(setupLocaleMiddleware())
+	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{DisableErrorHandler: true, DisablePrintStack: true}))
+
+	e.GET("/business", func(c echo.Context) error {
+		return errors.NewBusinessError("20001E", nil)
+	})
+	e.GET("/business/internal", func(c echo.Context) error {
+		return errors.NewBusinessError("10001E", fmt.Errorf("connection refused"))
+	})
+	e.GET("/echo", func(c echo.Context) error {
+		return errors.NewEchoHTTPError(c.Request().Context(), errors.NewBusinessError("20004E", nil))
+	})
+	e.POST("/bind", func(c echo.Context) error {
+		var req struct {
+			Value int `json:"value"`
+		}
+		return c.Bind(&req)
+	})
+	e.POST("/validate", func(c echo.Context) error {
+		req := new(struct {
+			Email string `json:"email" validate:"required,email"`
+		})
+		if err := c.Bind(req); err != nil {
+			return err
+		}
+		if err := c.Validate(req); err != nil {
+			return errors.NewValidationHTTPError(c.Request().Context(), err)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/panic", func(c echo.Context) error {
+		panic("boom")
+	})
+	e.GET("/unexpected", func(c echo.Context) error {
+		return fmt.Errorf("database password is hunter2")
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+		expectedDetail string
+		expectedErrors int
+	}{
+		{name: "BusinessError", method: http.MethodGet, path: "/business", expectedStatus: http.StatusNotFound, expectedCode: "20001E", expectedDetail: "予約が見つかりません。"},
+		{name: "5xxのBusinessError", method: http.MethodGet, path: "/business/internal", expectedStatus: http.StatusInternalServerError, expectedCode: "10001E", expectedDetail: "システムエラーが発生しました。"},
+		{name: "NewEchoHTTPError", method: http.MethodGet, path: "/echo", expectedStatus: http.StatusConflict, expectedCode: "20004E"},
+		{name: "Bindのエラー", method: http.MethodPost, path: "/bind", body: `{"value":"x"}`, expectedStatus: http.StatusBadRequest, expectedCode: "00004E"},
+		{name: "入力チェックのエラー", method: http.MethodPost, path: "/validate", body: `{"email":"x"}`, expectedStatus: http.StatusBadRequest, expectedCode: "00004E", expectedErrors: 1},
+		{name: "panic", method: http.MethodGet, path: "/panic", expectedStatus: http.StatusInternalServerError, expectedCode: "99999E", expectedDetail: "システムエラーが発生しました。"},
+		{name: "予期せぬエラー", method: http.MethodGet, path: "/unexpected", expectedStatus: http.StatusInternalServerError, expectedCode: "99999E", expectedDetail: "システムエラーが発生しました。"},
+		{name: "存在しないルート", method: http.MethodGet, path: "/missing", expectedStatus: http.StatusNotFound, expectedDetail: "Not Found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set("Accept-Language", "ja")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d but got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if ct := rec.Header().Get(echo.HeaderContentType); ct != errors.MIMEApplicationProblemJSON {
+				t.Errorf("unexpected content type: %s", ct)
+			}
+
+			var problem errors.ProblemDetails
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("invalid response body: %v", err)
+			}
+			if problem.Type != "about:blank" || problem.Status != tt.expectedStatus || problem.Title != http.StatusText(tt.expectedStatus) {
+				t.Errorf("unexpected problem: %+v", problem)
+			}
+			if problem.Instance != tt.path || problem.RequestID == "" {
+				t.Errorf("expected instance and request_id: %+v", problem)
+			}
+			if problem.Code != tt.expectedCode {
+				t.Errorf("expected code %q but got %q", tt.expectedCode, problem.Code)
+			}
+			if tt.expectedDetail != "" && problem.Detail != tt.expectedDetail {
+				t.Errorf("expected detail %q but got %q", tt.expectedDetail, problem.Detail)
+			}
+			if len(problem.Errors) != tt.expectedErrors {
+				t.Errorf("expected %d field errors but got %+v", tt.expectedErrors, problem.Errors)
+			}
+			if strings.Contains(rec.Body.String(), "hunter2") || strings.Contains(rec.Body.String(), "connection refused") {
+				t.Errorf("internal error details leaked: %s", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	handlers "github.com/horsewin/echo-playground-v2/handler"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+)
+
+const (
+	// exporterErrorWindow この期間内にエクスポートエラーがあればOTLPエクスポーターを異常とみなす
+	exporterErrorWindow = time.Minute
+)
+
+// shuttingDown SIGTERM受信後にtrueとなり、readinessを失敗させる
+var shuttingDown atomic.Bool
+
+// MarkShuttingDown readinessを失敗させ、ロードバランサーからの切り離しを開始する
+func MarkShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+// IsShuttingDown ...
+func IsShuttingDown() bool {
+	return shuttingDown.Load()
+}
+
+// pinger 疎通確認が可能なSQLHandler
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping コネクションプールの疎通確認を行う
+func (handler *SQLHandler) Ping(ctx context.Context) error {
+	return handler.Conn.PingContext(ctx)
+}
+
+// healthComponents readinessで確認するコンポーネントを返す
+func healthComponents(sqlHandler database.SQLHandler) []handlers.HealthComponent {
+	components := make([]handlers.HealthComponent, 0)
+
+	if p, ok := sqlHandler.(pinger); ok {
+		components = append(components, handlers.HealthComponent{
+			Name:     "database",
+			Critical: true,
+			Check:    p.Ping,
+		})
+	}
+
+	if exporterState.enabled.Load() {
+		// トレースの送出失敗ではリクエストの処理に影響しないため非必須とする
+		components = append(components, handlers.HealthComponent{
+			Name:     "otlp_exporter",
+			Critical: false,
+			Check:    checkExporter,
+		})
+	}
+
+	return components
+}
+
+// checkExporter 直近にOTLPエクスポーターでエラーが発生していないかを確認する
+func checkExporter(_ context.Context) error {
+	lastErrorAt := exporterState.lastErrorAt.Load()
+	if lastErrorAt == 0 {
+		return nil
+	}
+
+	elapsed := time.Since(time.Unix(0, lastErrorAt))
+	if elapsed > exporterErrorWindow {
+		return nil
+	}
+	lastError, _ := exporterState.lastError.Load().(string)
+	return fmt.Errorf("export failed %s ago: %s", elapsed.Round(time.Second), lastError)
+}
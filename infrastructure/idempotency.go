@@ -0,0 +1,122 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+	"github.com/horsewin/echo-playground-v2/usecase"
+	"github.com/horsewin/echo-playground-v2/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// headerIdempotencyKey 再送を識別するキーを渡すヘッダー
+	headerIdempotencyKey = "Idempotency-Key"
+	// headerIdempotentReplayed 保存したレスポンスを返したことを示すヘッダー
+	headerIdempotentReplayed = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength Idempotency-Keyの最大文字数
+	maxIdempotencyKeyLength = 255
+)
+
+// newIdempotencyInteractor 設定に従ってIdempotencyInteractorを作成する
+func newIdempotencyInteractor(sqlHandler database.SQLHandler, apiConfig *utils.APIConfig) *usecase.IdempotencyInteractor {
+	return &usecase.IdempotencyInteractor{
+		Repository: &repository.IdempotencyRepository{
+			SQLHandler: sqlHandler,
+		},
+		TTL:           apiConfig.Idempotency.TTL,
+		PurgeInterval: apiConfig.Idempotency.PurgeInterval,
+	}
+}
+
+// setupIdempotencyMiddleware Idempotency-Keyヘッダーを指定したリクエストの再送に、初回のレスポンスを返すミドルウェアを設定
+//
+// 処理結果はリクエスト元とキーごとに保存し、メソッド・パス・リクエストボディが異なる場合は422を返す。
+// 業務エラー・入力エラー（4xx）も処理結果として保存して再送に返す。サーバーエラーの場合は保存せず、同じキーで再試行できるようにする。
+// 認証ミドルウェアの後（ルート単位）で使うこと
+func setupIdempotencyMiddleware(interactor *usecase.IdempotencyInteractor) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(headerIdempotencyKey)
+			if key == "" {
+				return next(c)
+			}
+			ctx := c.Request().Context()
+			if len(key) > maxIdempotencyKeyLength {
+				return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00009E", nil))
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return errors.NewEchoHTTPError(ctx, errors.NewBusinessError("00004E", err))
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			principal := model.IdempotencyPrincipal(model.PrincipalFromContext(ctx))
+			record, replay, err := interactor.Begin(ctx, principal, key, requestFingerprint(c.Request(), body))
+			if err != nil {
+				return errors.NewEchoHTTPError(ctx, err)
+			}
+			if replay {
+				c.Response().Header().Set(headerIdempotentReplayed, "true")
+				return c.Blob(*record.StatusCode, *record.ContentType, []byte(*record.ResponseBody))
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+			err = next(c)
+			if err != nil {
+				// エラーのレスポンスも保存するため、ここでレスポンスに変換する
+				// errは外側のミドルウェアでログ・トレースに記録するためそのまま返す（HTTPErrorHandlerは書き込み済みのレスポンスを変換し直さない）
+				c.Echo().HTTPErrorHandler(err, c)
+			}
+			c.Response().Writer = recorder.ResponseWriter
+
+			// クライアントの切断で保存をやめないよう、キャンセルされないcontextを使う
+			saveCtx := context.WithoutCancel(ctx)
+			status := c.Response().Status
+			if status >= http.StatusInternalServerError {
+				if releaseErr := interactor.Release(saveCtx, record); releaseErr != nil {
+					zerolog.Ctx(ctx).Error().Err(releaseErr).Msg("failed to release idempotency key")
+				}
+				return err
+			}
+			contentType := c.Response().Header().Get(echo.HeaderContentType)
+			if completeErr := interactor.Complete(saveCtx, record, status, contentType, recorder.body.String()); completeErr != nil {
+				// レスポンスは返却済みのため、記録は処理中のまま残りLockTimeoutの経過後に再実行できる
+				zerolog.Ctx(ctx).Error().Err(completeErr).Msg("failed to save idempotent response")
+			}
+			return err
+		}
+	}
+}
+
+// requestFingerprint 同じキーで異なるリクエストが送られたことを検出するためのハッシュを返す
+func requestFingerprint(req *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, req.URL.Path)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder レスポンスを書き込みながらボディを保持する
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write ...
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
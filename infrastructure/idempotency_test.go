@@ -0,0 +1,105 @@
+package infrastructure
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/horsewin/echo-playground-v2/infrastructure/memory"
+	"github.com/horsewin/echo-playground-v2/utils"
+	"github.com/labstack/echo/v4"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	sqlHandler, err := memory.NewSeededSQLHandler()
+	if err != nil {
+		t.Fatalf("failed to create in-memory database: %v", err)
+	}
+
+	calls := 0
+	e := echo.New()
+	e.HTTPErrorHandler = httpErrorHandler
+	e.POST("/v1/pets/:id/reservation", func(c echo.Context) error {
+		calls++
+		if c.QueryParam("fail") != "" {
+			return echo.NewHTTPError(http.StatusInternalServerError)
+		}
+		if c.QueryParam("full") != "" {
+			return errors.NewEchoHTTPError(c.Request().Context(), errors.NewBusinessError("20010E", nil))
+		}
+		return c.JSON(http.StatusCreated, map[string]int{"call": calls})
+	}, setupIdempotencyMiddleware(newIdempotencyInteractor(sqlHandler, &utils.APIConfig{})))
+
+	post := func(path string, key string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if key != "" {
+			req.Header.Set(headerIdempotencyKey, key)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := post("/v1/pets/1/reservation", "key-1", `{"user_id":"user1"}`)
+	if first.Code != http.StatusCreated || first.Header().Get(headerIdempotentReplayed) != "" {
+		t.Fatalf("unexpected first response: %d %v", first.Code, first.Header())
+	}
+
+	// 再送には初回のレスポンスをそのまま返し、ハンドラーは実行しない
+	retry := post("/v1/pets/1/reservation", "key-1", `{"user_id":"user1"}`)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() ||
+		retry.Header().Get(headerIdempotentReplayed) != "true" ||
+		retry.Header().Get(echo.HeaderContentType) != first.Header().Get(echo.HeaderContentType) {
+		t.Errorf("unexpected replayed response: %d %s %v", retry.Code, retry.Body.String(), retry.Header())
+	}
+	if calls != 1 {
+		t.Errorf("expected handler to run once, got %d", calls)
+	}
+
+	// 同じキーで異なるリクエストは422
+	if rec := post("/v1/pets/2/reservation", "key-1", `{"user_id":"user1"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for another path, got %d", rec.Code)
+	}
+	if rec := post("/v1/pets/1/reservation", "key-1", `{"user_id":"user2"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for another body, got %d", rec.Code)
+	}
+
+	// サーバーエラーは保存せず、同じキーで再試行できる
+	if rec := post("/v1/pets/1/reservation?fail=1", "key-2", `{}`); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+	if rec := post("/v1/pets/1/reservation", "key-2", `{}`); rec.Code != http.StatusCreated || rec.Header().Get(headerIdempotentReplayed) != "" {
+		t.Errorf("expected retry to run the handler, got %d %v", rec.Code, rec.Header())
+	}
+
+	// 業務エラー（4xx）も処理結果として保存し、再送には初回のエラーを返す
+	calls = 0
+	full := post("/v1/pets/1/reservation?full=1", "key-3", `{}`)
+	if full.Code != http.StatusConflict || !strings.Contains(full.Body.String(), "20010E") {
+		t.Fatalf("expected 409, got %d %s", full.Code, full.Body.String())
+	}
+	retry = post("/v1/pets/1/reservation?full=1", "key-3", `{}`)
+	if retry.Code != http.StatusConflict || retry.Body.String() != full.Body.String() ||
+		retry.Header().Get(headerIdempotentReplayed) != "true" ||
+		retry.Header().Get(echo.HeaderContentType) != full.Header().Get(echo.HeaderContentType) {
+		t.Errorf("unexpected replayed error response: %d %s %v", retry.Code, retry.Body.String(), retry.Header())
+	}
+	if calls != 1 {
+		t.Errorf("expected handler to run once for the business error, got %d", calls)
+	}
+
+	// キーを指定しない場合は毎回処理する
+	calls = 0
+	post("/v1/pets/1/reservation", "", `{}`)
+	post("/v1/pets/1/reservation", "", `{}`)
+	if calls != 2 {
+		t.Errorf("expected handler to run for each request without key, got %d", calls)
+	}
+
+	if rec := post("/v1/pets/1/reservation", strings.Repeat("k", maxIdempotencyKeyLength+1), `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for too long key, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// assignValue 格納値をフィールドの型に変換して設定する
+func assignValue(field reflect.Value, value interface{}) error {
+	// pq.StringArrayなどsql.Scannerを実装する型はScanに委ねる
+	if field.CanAddr() && field.Addr().Type().Implements(scannerType) {
+		return field.Addr().Interface().(sql.Scanner).Scan(driverValue(value))
+	}
+
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		if err := assignValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(field.Type()) {
+		field.Set(v)
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		switch t := value.(type) {
+		case time.Time:
+			// database/sqlと同様にRFC3339形式の文字列とする
+			field.SetString(t.Format(time.RFC3339Nano))
+		case int64:
+			field.SetString(strconv.FormatInt(t, 10))
+		case float64:
+			field.SetString(strconv.FormatFloat(t, 'f', -1, 64))
+		default:
+			field.SetString(fmt.Sprint(value))
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch t := value.(type) {
+		case int64:
+			field.SetInt(t)
+			return nil
+		case float64:
+			field.SetInt(int64(t))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch t := value.(type) {
+		case int64:
+			field.SetFloat(float64(t))
+			return nil
+		case float64:
+			field.SetFloat(t)
+			return nil
+		}
+	case reflect.Bool:
+		if b, ok := value.(bool); ok {
+			field.SetBool(b)
+			return nil
+		}
+	case reflect.Struct:
+		if field.Type() == timeType {
+			if t, ok := value.(time.Time); ok {
+				field.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("cannot convert %T to %s", value, field.Type())
+}
+
+// driverValue sql.Scannerに渡せる形式に変換する
+func driverValue(value interface{}) interface{} {
+	if v, ok := value.(driver.Valuer); ok {
+		if dv, err := v.Value(); err == nil {
+			return dv
+		}
+	}
+	return value
+}
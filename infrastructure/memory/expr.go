@@ -0,0 +1,783 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// errNullComparison 行値の比較でNULLが含まれ、結果がNULLとなったことを表す
+var errNullComparison = errors.New("memory: comparison with null")
+
+// expr WHERE句を解釈した評価可能な式
+type expr interface {
+	eval(row map[string]interface{}, args map[string]interface{}) (interface{}, error)
+}
+
+type (
+	// columnExpr カラム参照
+	columnExpr struct{ name string }
+	// paramExpr 名前付きパラメータ（:name）
+	paramExpr struct{ name string }
+	// literalExpr 文字列・数値・真偽値・NULLのリテラル
+	literalExpr struct{ value interface{} }
+	// tupleExpr (a, b) 形式の行値
+	tupleExpr struct{ items []expr }
+	// logicalExpr AND/OR
+	logicalExpr struct {
+		op          string
+		left, right expr
+	}
+	// notExpr NOT
+	notExpr struct{ inner expr }
+	// compareExpr 比較演算子
+	compareExpr struct {
+		op          string
+		left, right expr
+	}
+	// nullCheckExpr IS [NOT] NULL
+	nullCheckExpr struct {
+		inner  expr
+		negate bool
+	}
+	// inExpr [NOT] IN (...)
+	inExpr struct {
+		left   expr
+		items  []expr
+		negate bool
+	}
+	// anyExpr = ANY(:param)
+	anyExpr struct {
+		left, right expr
+	}
+	// likeExpr [NOT] LIKE / ILIKE
+	likeExpr struct {
+		left, right     expr
+		caseInsensitive bool
+		negate          bool
+	}
+	// containsExpr 配列の包含（@>）
+	containsExpr struct{ left, right expr }
+	// funcExpr LOWER/UPPER
+	funcExpr struct {
+		name string
+		arg  expr
+	}
+)
+
+// tokenPattern WHERE句のトークン
+var tokenPattern = regexp.MustCompile(`\s*(::[A-Za-z_\[\]]+|:[A-Za-z_][A-Za-z0-9_]*|'(?:[^']|'')*'|[0-9]+(?:\.[0-9]+)?|[A-Za-z_][A-Za-z0-9_.]*|@>|<>|!=|<=|>=|[=<>(),])`)
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+// parseClause WHERE句を式に変換する
+func parseClause(clause string) (expr, error) {
+	tokens, err := tokenize(clause)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	p := &parser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("memory: unexpected token %q in %q", p.tokens[p.pos], clause)
+	}
+	return e, nil
+}
+
+func tokenize(clause string) ([]string, error) {
+	rest := strings.TrimSpace(clause)
+	tokens := make([]string, 0)
+	for rest != "" {
+		loc := tokenPattern.FindStringSubmatchIndex(rest)
+		if loc == nil || loc[0] != 0 {
+			return nil, fmt.Errorf("memory: cannot parse %q", rest)
+		}
+		tok := rest[loc[2]:loc[3]]
+		// 型キャスト（::text[]など）は無視する
+		if !strings.HasPrefix(tok, "::") {
+			tokens = append(tokens, tok)
+		}
+		rest = strings.TrimSpace(rest[loc[1]:])
+	}
+	return tokens, nil
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) peekKeyword(keyword string) bool {
+	return strings.EqualFold(p.peek(), keyword)
+}
+
+func (p *parser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *parser) expect(tok string) error {
+	if !strings.EqualFold(p.next(), tok) {
+		return fmt.Errorf("memory: expected %q", tok)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpr{op: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("AND") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpr{op: "AND", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (expr, error) {
+	if p.peekKeyword("NOT") {
+		p.next()
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{inner: inner}, nil
+	}
+	return p.parsePredicate()
+}
+
+func (p *parser) parsePredicate() (expr, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	negate := false
+	if p.peekKeyword("NOT") {
+		p.next()
+		negate = true
+	}
+
+	tok := strings.ToUpper(p.peek())
+	switch tok {
+	case "=", "<>", "!=", "<", "<=", ">", ">=":
+		if negate {
+			return nil, fmt.Errorf("memory: unexpected NOT before %s", tok)
+		}
+		p.next()
+		if p.peekKeyword("ANY") {
+			p.next()
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return &anyExpr{left: left, right: right}, nil
+		}
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &compareExpr{op: tok, left: left, right: right}, nil
+	case "@>":
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &containsExpr{left: left, right: right}, nil
+	case "LIKE", "ILIKE":
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &likeExpr{left: left, right: right, caseInsensitive: tok == "ILIKE", negate: negate}, nil
+	case "IN":
+		p.next()
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		items := make([]expr, 0)
+		for {
+			item, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			if p.peek() == "," {
+				p.next()
+				continue
+			}
+			break
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return &inExpr{left: left, items: items, negate: negate}, nil
+	case "IS":
+		p.next()
+		isNot := false
+		if p.peekKeyword("NOT") {
+			p.next()
+			isNot = true
+		}
+		if err := p.expect("NULL"); err != nil {
+			return nil, err
+		}
+		return &nullCheckExpr{inner: left, negate: isNot}, nil
+	}
+
+	if negate {
+		return nil, fmt.Errorf("memory: unexpected NOT")
+	}
+	// 比較演算子を伴わない式（括弧で囲まれた条件式など）
+	return left, nil
+}
+
+func (p *parser) parseOperand() (expr, error) {
+	tok := p.next()
+	upper := strings.ToUpper(tok)
+
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("memory: unexpected end of clause")
+	case tok == "(":
+		first, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != "," {
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return first, nil
+		}
+		items := []expr{first}
+		for p.peek() == "," {
+			p.next()
+			item, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return &tupleExpr{items: items}, nil
+	case strings.HasPrefix(tok, ":"):
+		return &paramExpr{name: tok[1:]}, nil
+	case strings.HasPrefix(tok, "'"):
+		return &literalExpr{value: strings.ReplaceAll(tok[1:len(tok)-1], "''", "'")}, nil
+	case tok[0] >= '0' && tok[0] <= '9':
+		f, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &literalExpr{value: f}, nil
+	case upper == "TRUE":
+		return &literalExpr{value: true}, nil
+	case upper == "FALSE":
+		return &literalExpr{value: false}, nil
+	case upper == "NULL":
+		return &literalExpr{value: nil}, nil
+	case (upper == "LOWER" || upper == "UPPER") && p.peek() == "(":
+		p.next()
+		arg, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return &funcExpr{name: upper, arg: arg}, nil
+	}
+
+	// テーブル修飾（t.column）は外してカラム名のみ扱う
+	if i := strings.LastIndex(tok, "."); i >= 0 {
+		tok = tok[i+1:]
+	}
+	return &columnExpr{name: strings.ToLower(tok)}, nil
+}
+
+func (e *columnExpr) eval(row map[string]interface{}, _ map[string]interface{}) (interface{}, error) {
+	return normalizeValue(row[e.name]), nil
+}
+
+func (e *paramExpr) eval(_ map[string]interface{}, args map[string]interface{}) (interface{}, error) {
+	v, ok := args[e.name]
+	if !ok {
+		return nil, fmt.Errorf("memory: missing parameter :%s", e.name)
+	}
+	return normalizeValue(v), nil
+}
+
+func (e *literalExpr) eval(_ map[string]interface{}, _ map[string]interface{}) (interface{}, error) {
+	return e.value, nil
+}
+
+func (e *tupleExpr) eval(row map[string]interface{}, args map[string]interface{}) (interface{}, error) {
+	values := make([]interface{}, len(e.items))
+	for i, item := range e.items {
+		v, err := item.eval(row, args)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (e *logicalExpr) eval(row map[string]interface{}, args map[string]interface{}) (interface{}, error) {
+	left, err := evalBool(e.left, row, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.op == "AND" && !left {
+		return false, nil
+	}
+	if e.op == "OR" && left {
+		return true, nil
+	}
+	return evalBool(e.right, row, args)
+}
+
+func (e *notExpr) eval(row map[string]interface{}, args map[string]interface{}) (interface{}, error) {
+	v, err := evalBool(e.inner, row, args)
+	if err != nil {
+		return nil, err
+	}
+	return !v, nil
+}
+
+func (e *compareExpr) eval(row map[string]interface{}, args map[string]interface{}) (interface{}, error) {
+	left, err := e.left.eval(row, args)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(row, args)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil || right == nil {
+		// SQLと同様にNULLとの比較は常に偽
+		return false, nil
+	}
+
+	cmp, err := compareValues(left, right)
+	if err == errNullComparison {
+		return false, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "=":
+		return cmp == 0, nil
+	case "<>", "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	}
+	return nil, fmt.Errorf("memory: unsupported operator %s", e.op)
+}
+
+func (e *nullCheckExpr) eval(row map[string]interface{}, args map[string]interface{}) (interface{}, error) {
+	v, err := e.inner.eval(row, args)
+	if err != nil {
+		return nil, err
+	}
+	return (v == nil) != e.negate, nil
+}
+
+func (e *inExpr) eval(row map[string]interface{}, args map[string]interface{}) (interface{}, error) {
+	left, err := e.left.eval(row, args)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil {
+		return false, nil
+	}
+	for _, item := range e.items {
+		v, err := item.eval(row, args)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			continue
+		}
+		if cmp, err := compareValues(left, v); err == nil && cmp == 0 {
+			return !e.negate, nil
+		}
+	}
+	return e.negate, nil
+}
+
+func (e *anyExpr) eval(row map[string]interface{}, args map[string]interface{}) (interface{}, error) {
+	left, err := e.left.eval(row, args)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(row, args)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil {
+		return false, nil
+	}
+	for _, v := range toSlice(right) {
+		if cmp, err := compareValues(left, v); err == nil && cmp == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (e *likeExpr) eval(row map[string]interface{}, args map[string]interface{}) (interface{}, error) {
+	left, err := e.left.eval(row, args)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(row, args)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil || right == nil {
+		return false, nil
+	}
+
+	value, pattern := fmt.Sprint(left), fmt.Sprint(right)
+	if e.caseInsensitive {
+		value, pattern = strings.ToLower(value), strings.ToLower(pattern)
+	}
+	return likeMatch(value, pattern) != e.negate, nil
+}
+
+func (e *containsExpr) eval(row map[string]interface{}, args map[string]interface{}) (interface{}, error) {
+	left, err := e.left.eval(row, args)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(row, args)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil || right == nil {
+		return false, nil
+	}
+
+	have := map[string]bool{}
+	for _, v := range toSlice(left) {
+		have[fmt.Sprint(v)] = true
+	}
+	for _, v := range toSlice(right) {
+		if !have[fmt.Sprint(v)] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (e *funcExpr) eval(row map[string]interface{}, args map[string]interface{}) (interface{}, error) {
+	v, err := e.arg.eval(row, args)
+	if err != nil || v == nil {
+		return v, err
+	}
+	if e.name == "LOWER" {
+		return strings.ToLower(fmt.Sprint(v)), nil
+	}
+	return strings.ToUpper(fmt.Sprint(v)), nil
+}
+
+func evalBool(e expr, row map[string]interface{}, args map[string]interface{}) (bool, error) {
+	v, err := e.eval(row, args)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok && v != nil {
+		return false, fmt.Errorf("memory: expression is not a boolean: %v", v)
+	}
+	return b, nil
+}
+
+// likeMatch LIKEパターン（%と_）に一致するかを判定する
+// PostgreSQLと同様にバックスラッシュを直後の文字のエスケープとして扱う
+func likeMatch(value, pattern string) bool {
+	var b strings.Builder
+	b.WriteString("^")
+	escaped := false
+	for _, r := range pattern {
+		if escaped {
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+			continue
+		}
+		switch r {
+		case '\\':
+			escaped = true
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	matched, _ := regexp.MatchString(b.String(), value)
+	return matched
+}
+
+// normalizeValue 比較しやすいように値の型を揃える
+func normalizeValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case int:
+		return float64(t)
+	case int32:
+		return float64(t)
+	case int64:
+		return float64(t)
+	case float32:
+		return float64(t)
+	case *string:
+		if t == nil {
+			return nil
+		}
+		return *t
+	case *time.Time:
+		if t == nil {
+			return nil
+		}
+		return *t
+	case *int:
+		if t == nil {
+			return nil
+		}
+		return float64(*t)
+	case *int64:
+		if t == nil {
+			return nil
+		}
+		return float64(*t)
+	case *float64:
+		if t == nil {
+			return nil
+		}
+		return *t
+	case *bool:
+		if t == nil {
+			return nil
+		}
+		return *t
+	case pq.StringArray:
+		return []string(t)
+	case *pq.StringArray:
+		if t == nil {
+			return nil
+		}
+		return []string(*t)
+	}
+	return v
+}
+
+// toSlice 配列パラメータを[]interface{}に変換する
+func toSlice(v interface{}) []interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		return t
+	case []string:
+		result := make([]interface{}, len(t))
+		for i, s := range t {
+			result[i] = s
+		}
+		return result
+	case []int:
+		result := make([]interface{}, len(t))
+		for i, n := range t {
+			result[i] = float64(n)
+		}
+		return result
+	case []int64:
+		result := make([]interface{}, len(t))
+		for i, n := range t {
+			result[i] = float64(n)
+		}
+		return result
+	case pq.StringArray:
+		return toSlice([]string(t))
+	case pq.Int64Array:
+		return toSlice([]int64(t))
+	case *pq.StringArray:
+		return toSlice([]string(*t))
+	case *pq.Int64Array:
+		return toSlice([]int64(*t))
+	}
+	return []interface{}{v}
+}
+
+// compareValues 2つの値を比較し、-1, 0, 1のいずれかを返す
+func compareValues(a, b interface{}) (int, error) {
+	a, b = normalizeValue(a), normalizeValue(b)
+
+	// 行値同士の比較（(a, b) > (:a, :b)）
+	if as, ok := a.([]interface{}); ok {
+		bs, ok := b.([]interface{})
+		if !ok || len(as) != len(bs) {
+			return 0, fmt.Errorf("memory: cannot compare row values %v and %v", a, b)
+		}
+		for i := range as {
+			// 大小が決まる前にNULLが現れた場合、SQLと同様に比較結果はNULLとなる
+			if normalizeValue(as[i]) == nil || normalizeValue(bs[i]) == nil {
+				return 0, errNullComparison
+			}
+			cmp, err := compareValues(as[i], bs[i])
+			if err != nil || cmp != 0 {
+				return cmp, err
+			}
+		}
+		return 0, nil
+	}
+
+	switch av := a.(type) {
+	case float64:
+		bv, err := toFloat(b)
+		if err != nil {
+			return 0, err
+		}
+		return compareOrdered(av, bv), nil
+	case string:
+		switch bv := b.(type) {
+		case string:
+			return strings.Compare(av, bv), nil
+		case float64:
+			f, err := strconv.ParseFloat(av, 64)
+			if err != nil {
+				return 0, fmt.Errorf("memory: cannot compare %q with %v", av, bv)
+			}
+			return compareOrdered(f, bv), nil
+		case time.Time:
+			t, err := parseTime(av)
+			if err != nil {
+				return 0, err
+			}
+			return compareTime(t, bv), nil
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			if av == bv {
+				return 0, nil
+			}
+			if !av {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	case time.Time:
+		switch bv := b.(type) {
+		case time.Time:
+			return compareTime(av, bv), nil
+		case string:
+			t, err := parseTime(bv)
+			if err != nil {
+				return 0, err
+			}
+			return compareTime(av, t), nil
+		}
+	}
+
+	return 0, fmt.Errorf("memory: cannot compare %T with %T", a, b)
+}
+
+func compareOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case string:
+		return strconv.ParseFloat(t, 64)
+	}
+	return 0, fmt.Errorf("memory: cannot convert %T to number", v)
+}
+
+// parseTime 日付・日時の文字列を解釈する
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("memory: cannot parse time %q", s)
+}
@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+//go:embed fixtures/*.json
+var fixturesFS embed.FS
+
+// fixtureTables フィクスチャを投入する順序（外部キーの参照元を先に投入する）
+var fixtureTables = []string{"shops", "shop_opening_hours", "pets", "reservations", "favorites", "notifications"}
+
+// NewSeededSQLHandler db/seedsと同じ初期データを投入したSQLHandlerを返す
+func NewSeededSQLHandler() (*SQLHandler, error) {
+	handler := NewSQLHandler()
+	if err := handler.LoadFixtures(context.Background(), fixturesFS, "fixtures"); err != nil {
+		return nil, err
+	}
+	return handler, nil
+}
+
+// LoadFixtures fsysのdirに置かれた{table}.jsonを読み込み、各テーブルに投入する
+func (handler *SQLHandler) LoadFixtures(ctx context.Context, fsys fs.FS, dir string) error {
+	for _, tableName := range fixtureTables {
+		data, err := fs.ReadFile(fsys, fmt.Sprintf("%s/%s.json", dir, tableName))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		var rows []map[string]interface{}
+		if err := json.Unmarshal(data, &rows); err != nil {
+			return fmt.Errorf("memory: invalid fixture %s: %w", tableName, err)
+		}
+		for _, row := range rows {
+			if err := handler.Create(ctx, row, tableName); err != nil {
+				return fmt.Errorf("memory: failed to load fixture %s: %w", tableName, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+package memory
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// columnKind カラムの型
+type columnKind int
+
+const (
+	kindText columnKind = iota
+	kindInt
+	kindNumeric
+	kindBool
+	kindTime
+	kindTextArray
+)
+
+// tableDef インメモリテーブルの定義（db/migrationsのスキーマに対応）
+type tableDef struct {
+	// identity idをGENERATED ALWAYS AS IDENTITYで採番するか
+	identity bool
+	columns  map[string]columnKind
+	// defaults INSERT時に値が指定されなかったカラムの既定値
+	defaults map[string]func() interface{}
+	// uniques 一意制約（複合キーを含む）
+	uniques [][]string
+	// cascades ON DELETE CASCADEの外部キー
+	cascades []foreignKey
+}
+
+// foreignKey 参照先の行が削除された場合に参照元の行も削除する外部キー
+type foreignKey struct {
+	// column 参照元のカラム
+	column string
+	// table, refColumn 参照先のテーブルとカラム
+	table     string
+	refColumn string
+}
+
+func now() interface{} {
+	return time.Now()
+}
+
+// schema db/migrationsで定義しているテーブル
+var schema = map[string]tableDef{
+	"shops": {
+		identity: true,
+		columns: map[string]columnKind{
+			"id":            kindInt,
+			"name":          kindText,
+			"location":      kindText,
+			"timezone":      kindText,
+			"slot_minutes":  kindInt,
+			"slot_capacity": kindInt,
+			"created_at":    kindTime,
+			"updated_at":    kindTime,
+		},
+		defaults: map[string]func() interface{}{
+			"timezone":      func() interface{} { return "Asia/Tokyo" },
+			"slot_minutes":  func() interface{} { return int64(60) },
+			"slot_capacity": func() interface{} { return int64(2) },
+			"created_at":    now,
+			"updated_at":    now,
+		},
+		uniques: [][]string{{"name", "location"}},
+	},
+	"shop_opening_hours": {
+		columns: map[string]columnKind{
+			"shop_id":   kindInt,
+			"weekday":   kindInt,
+			"opens_at":  kindText,
+			"closes_at": kindText,
+		},
+		uniques:  [][]string{{"shop_id", "weekday"}},
+		cascades: []foreignKey{{column: "shop_id", table: "shops", refColumn: "id"}},
+	},
+	"pets": {
+		columns: map[string]columnKind{
+			"id":               kindText,
+			"name":             kindText,
+			"breed":            kindText,
+			"gender":           kindText,
+			"price":            kindNumeric,
+			"image_url":        kindText,
+			"likes":            kindInt,
+			"shop_id":          kindInt,
+			"birth_date":       kindTime,
+			"reference_number": kindText,
+			"tags":             kindTextArray,
+			"status":           kindText,
+			"created_at":       kindTime,
+			"updated_at":       kindTime,
+		},
+		defaults: map[string]func() interface{}{
+			"status":     func() interface{} { return "available" },
+			"created_at": now,
+			"updated_at": now,
+		},
+		uniques: [][]string{{"id"}, {"reference_number"}},
+	},
+	"reservations": {
+		identity: true,
+		columns: map[string]columnKind{
+			"id":                    kindInt,
+			"user_id":               kindText,
+			"user_name":             kindText,
+			"email":                 kindText,
+			"reservation_date_time": kindTime,
+			"status":                kindText,
+			"pet_id":                kindText,
+			"shop_id":               kindInt,
+			"slot_starts_at":        kindTime,
+			"slot_timezone":         kindText,
+			"slot_seat":             kindInt,
+			"created_at":            kindTime,
+			"updated_at":            kindTime,
+		},
+		defaults: map[string]func() interface{}{
+			"created_at": now,
+			"updated_at": now,
+		},
+		uniques:  [][]string{{"shop_id", "slot_starts_at", "slot_seat"}},
+		cascades: []foreignKey{{column: "pet_id", table: "pets", refColumn: "id"}},
+	},
+	"favorites": {
+		identity: true,
+		columns: map[string]columnKind{
+			"id":      kindInt,
+			"user_id": kindText,
+			"pet_id":  kindText,
+		},
+		uniques:  [][]string{{"user_id", "pet_id"}},
+		cascades: []foreignKey{{column: "pet_id", table: "pets", refColumn: "id"}},
+	},
+	"notifications": {
+		identity: true,
+		columns: map[string]columnKind{
+			"id":         kindInt,
+			"user_id":    kindText,
+			"title":      kindText,
+			"message":    kindText,
+			"is_read":    kindBool,
+			"type":       kindText,
+			"created_at": kindTime,
+			"updated_at": kindTime,
+		},
+		defaults: map[string]func() interface{}{
+			"is_read":    func() interface{} { return false },
+			"created_at": now,
+			"updated_at": now,
+		},
+	},
+	"outbox": {
+		identity: true,
+		columns: map[string]columnKind{
+			"id":              kindInt,
+			"event_type":      kindText,
+			"payload":         kindText,
+			"status":          kindText,
+			"attempts":        kindInt,
+			"next_attempt_at": kindTime,
+			"last_error":      kindText,
+			"published_at":    kindTime,
+			"created_at":      kindTime,
+		},
+		defaults: map[string]func() interface{}{
+			"status":          func() interface{} { return "pending" },
+			"attempts":        func() interface{} { return int64(0) },
+			"next_attempt_at": now,
+			"created_at":      now,
+		},
+	},
+	"webhook_subscriptions": {
+		identity: true,
+		columns: map[string]columnKind{
+			"id":         kindInt,
+			"shop_id":    kindInt,
+			"event_type": kindText,
+			"url":        kindText,
+			"secret":     kindText,
+			"active":     kindBool,
+			"created_at": kindTime,
+			"updated_at": kindTime,
+		},
+		defaults: map[string]func() interface{}{
+			"active":     func() interface{} { return true },
+			"created_at": now,
+			"updated_at": now,
+		},
+	},
+	"webhook_deliveries": {
+		identity: true,
+		columns: map[string]columnKind{
+			"id":              kindInt,
+			"subscription_id": kindInt,
+			"event_id":        kindInt,
+			"event_type":      kindText,
+			"payload":         kindText,
+			"status":          kindText,
+			"attempts":        kindInt,
+			"next_attempt_at": kindTime,
+			"response_code":   kindInt,
+			"last_error":      kindText,
+			"delivered_at":    kindTime,
+			"created_at":      kindTime,
+		},
+		defaults: map[string]func() interface{}{
+			"status":          func() interface{} { return "pending" },
+			"attempts":        func() interface{} { return int64(0) },
+			"next_attempt_at": now,
+			"created_at":      now,
+		},
+		cascades: []foreignKey{{column: "subscription_id", table: "webhook_subscriptions", refColumn: "id"}},
+	},
+	"webhook_delivery_attempts": {
+		identity: true,
+		columns: map[string]columnKind{
+			"id":            kindInt,
+			"delivery_id":   kindInt,
+			"attempt":       kindInt,
+			"response_code": kindInt,
+			"error":         kindText,
+			"duration_ms":   kindInt,
+			"attempted_at":  kindTime,
+		},
+		defaults: map[string]func() interface{}{
+			"attempted_at": now,
+		},
+		cascades: []foreignKey{{column: "delivery_id", table: "webhook_deliveries", refColumn: "id"}},
+	},
+	"idempotency_keys": {
+		columns: map[string]columnKind{
+			"principal":       kindText,
+			"idempotency_key": kindText,
+			"fingerprint":     kindText,
+			"status_code":     kindInt,
+			"content_type":    kindText,
+			"response_body":   kindText,
+			"created_at":      kindTime,
+			"expires_at":      kindTime,
+		},
+		defaults: map[string]func() interface{}{
+			"created_at": now,
+		},
+		uniques: [][]string{{"principal", "idempotency_key"}},
+	},
+}
+
+// views db/migrationsで定義しているビュー（参照のたびに元のテーブルから組み立てる）
+var views = map[string]func(tables map[string]*table) *table{
+	"pets_with_shop": petsWithShop,
+}
+
+// petsWithShop petsにshopsの店舗名と所在地を結合する（店舗が存在しないペットは含めない）
+func petsWithShop(tables map[string]*table) *table {
+	shops := map[int64]map[string]interface{}{}
+	for _, shop := range tableRows(tables, "shops") {
+		if id, ok := shop["id"].(int64); ok {
+			shops[id] = shop
+		}
+	}
+
+	view := &table{nextID: 1}
+	for _, pet := range tableRows(tables, "pets") {
+		id, _ := pet["shop_id"].(int64)
+		shop, ok := shops[id]
+		if !ok {
+			continue
+		}
+		row := cloneRow(pet)
+		row["shop_name"] = shop["name"]
+		row["shop_location"] = shop["location"]
+		view.rows = append(view.rows, row)
+	}
+	return view
+}
+
+// tableRows ビューの元になるテーブルの行を返す
+func tableRows(tables map[string]*table, name string) []map[string]interface{} {
+	if t, ok := tables[name]; ok {
+		return t.rows
+	}
+	return nil
+}
+
+// coerce カラムの型に合わせて値を変換する
+func coerce(kind columnKind, v interface{}) (interface{}, error) {
+	v = normalizeValue(v)
+	if v == nil {
+		return nil, nil
+	}
+
+	switch kind {
+	case kindInt:
+		switch t := v.(type) {
+		case float64:
+			return int64(t), nil
+		case string:
+			return strconv.ParseInt(t, 10, 64)
+		}
+	case kindNumeric:
+		switch t := v.(type) {
+		case float64:
+			return t, nil
+		case string:
+			return strconv.ParseFloat(t, 64)
+		}
+	case kindBool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case kindTime:
+		switch t := v.(type) {
+		case time.Time:
+			return t, nil
+		case string:
+			return parseTime(t)
+		}
+	case kindTextArray:
+		switch t := v.(type) {
+		case []string:
+			return pq.StringArray(append([]string{}, t...)), nil
+		case []interface{}:
+			result := make(pq.StringArray, len(t))
+			for i, item := range t {
+				result[i] = fmt.Sprint(item)
+			}
+			return result, nil
+		}
+	case kindText:
+		switch t := v.(type) {
+		case string:
+			return t, nil
+		case float64:
+			return strconv.FormatFloat(t, 'f', -1, 64), nil
+		}
+	}
+
+	return nil, fmt.Errorf("memory: cannot store %T in column", v)
+}
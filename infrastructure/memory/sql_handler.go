@@ -0,0 +1,602 @@
+// Package memory はdatabase.SQLHandlerのインメモリ実装を提供する
+// Postgresを用意せずにリポジトリやユースケースを動かすテストやローカル実行向け
+package memory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/horsewin/echo-playground-v2/interface/database"
+)
+
+// table インメモリテーブル
+type table struct {
+	def    tableDef
+	rows   []map[string]interface{}
+	nextID int64
+}
+
+// SQLHandler ... インメモリのSQL handler struct
+type SQLHandler struct {
+	// mu tablesへのアクセスを保護する
+	mu     sync.RWMutex
+	tables map[string]*table
+	// txMu 書き込み（トランザクションを含む）を直列化する
+	txMu sync.Mutex
+}
+
+// tx インメモリのトランザクション
+// 開始時点のスナップショットに対して変更を行い、コミット時に反映する
+type tx struct {
+	tables map[string]*table
+	done   bool
+}
+
+// txKey contextにトランザクションを保持するためのキー
+type txKey struct{}
+
+// NewSQLHandler 空のテーブルを持つSQLHandlerを返す
+func NewSQLHandler() *SQLHandler {
+	tables := map[string]*table{}
+	for name, def := range schema {
+		tables[name] = &table{def: def, nextID: 1}
+	}
+	return &SQLHandler{tables: tables}
+}
+
+// Begin ...
+func (handler *SQLHandler) Begin(ctx context.Context) (context.Context, error) {
+	if _, ok := ctx.Value(txKey{}).(*tx); ok {
+		return ctx, database.ErrTransactionAlreadyStarted
+	}
+
+	handler.txMu.Lock()
+	handler.mu.RLock()
+	snapshot := cloneTables(handler.tables)
+	handler.mu.RUnlock()
+
+	return context.WithValue(ctx, txKey{}, &tx{tables: snapshot}), nil
+}
+
+// Commit ...
+func (handler *SQLHandler) Commit(ctx context.Context) error {
+	t, ok := ctx.Value(txKey{}).(*tx)
+	if !ok || t.done {
+		return database.ErrNoTransaction
+	}
+	t.done = true
+
+	handler.mu.Lock()
+	handler.tables = t.tables
+	handler.mu.Unlock()
+	handler.txMu.Unlock()
+	return nil
+}
+
+// Rollback ...
+func (handler *SQLHandler) Rollback(ctx context.Context) error {
+	t, ok := ctx.Value(txKey{}).(*tx)
+	if !ok || t.done {
+		return database.ErrNoTransaction
+	}
+	t.done = true
+
+	handler.txMu.Unlock()
+	return nil
+}
+
+// read contextのトランザクションまたは現在のテーブルに対して読み取り処理を行う
+func (handler *SQLHandler) read(ctx context.Context, fn func(tables map[string]*table) error) error {
+	if t, ok := ctx.Value(txKey{}).(*tx); ok && !t.done {
+		return fn(t.tables)
+	}
+
+	handler.mu.RLock()
+	defer handler.mu.RUnlock()
+	return fn(handler.tables)
+}
+
+// write contextのトランザクションまたは現在のテーブルに対して書き込み処理を行う
+// トランザクション外の書き込みは失敗時に変更が残らないようスナップショットに対して行う
+func (handler *SQLHandler) write(ctx context.Context, fn func(tables map[string]*table) error) error {
+	if t, ok := ctx.Value(txKey{}).(*tx); ok && !t.done {
+		return fn(t.tables)
+	}
+
+	handler.txMu.Lock()
+	defer handler.txMu.Unlock()
+
+	handler.mu.RLock()
+	snapshot := cloneTables(handler.tables)
+	handler.mu.RUnlock()
+
+	if err := fn(snapshot); err != nil {
+		return err
+	}
+
+	handler.mu.Lock()
+	handler.tables = snapshot
+	handler.mu.Unlock()
+	return nil
+}
+
+// Where ...
+func (handler *SQLHandler) Where(ctx context.Context, out interface{}, tableName string, clause string, args map[string]interface{}) error {
+	return handler.read(ctx, func(tables map[string]*table) error {
+		rows, err := filterRows(lookup(tables, tableName), clause, args)
+		if err != nil {
+			return err
+		}
+		return scanRows(rows, out)
+	})
+}
+
+// Scan ...
+func (handler *SQLHandler) Scan(ctx context.Context, out interface{}, tableName string, order string) error {
+	return handler.read(ctx, func(tables map[string]*table) error {
+		rows, err := filterRows(lookup(tables, tableName), "", nil)
+		if err != nil {
+			return err
+		}
+		if err := sortRows(rows, order); err != nil {
+			return err
+		}
+		return scanRows(rows, out)
+	})
+}
+
+// Select ...
+func (handler *SQLHandler) Select(ctx context.Context, out interface{}, tableName string, clause string, args map[string]interface{}, order string, limit int) error {
+	return handler.read(ctx, func(tables map[string]*table) error {
+		rows, err := filterRows(lookup(tables, tableName), clause, args)
+		if err != nil {
+			return err
+		}
+		if err := sortRows(rows, order); err != nil {
+			return err
+		}
+		if limit > 0 && len(rows) > limit {
+			rows = rows[:limit]
+		}
+		return scanRows(rows, out)
+	})
+}
+
+// SelectForUpdate ...
+//
+// インメモリのトランザクションは直列に実行されるため、他のトランザクションがロックしている行はない
+func (handler *SQLHandler) SelectForUpdate(ctx context.Context, out interface{}, tableName string, clause string, args map[string]interface{}, order string, limit int) error {
+	if t, ok := ctx.Value(txKey{}).(*tx); !ok || t.done {
+		return database.ErrNoTransaction
+	}
+	return handler.Select(ctx, out, tableName, clause, args, order, limit)
+}
+
+// SelectForUpdateWait ...
+//
+// インメモリのトランザクションは直列に実行されるため、SelectForUpdateと同じく待つことはない
+func (handler *SQLHandler) SelectForUpdateWait(ctx context.Context, out interface{}, tableName string, clause string, args map[string]interface{}, order string, limit int) error {
+	return handler.SelectForUpdate(ctx, out, tableName, clause, args, order, limit)
+}
+
+// Count ...
+func (handler *SQLHandler) Count(ctx context.Context, out *int, tableName string, clause string, args map[string]interface{}) error {
+	return handler.read(ctx, func(tables map[string]*table) error {
+		rows, err := filterRows(lookup(tables, tableName), clause, args)
+		if err != nil {
+			return err
+		}
+		*out = len(rows)
+		return nil
+	})
+}
+
+// CountBy ...
+func (handler *SQLHandler) CountBy(ctx context.Context, out interface{}, tableName string, groupColumn string, clause string, args map[string]interface{}) error {
+	return handler.read(ctx, func(tables map[string]*table) error {
+		rows, err := filterRows(lookup(tables, tableName), clause, args)
+		if err != nil {
+			return err
+		}
+
+		// 出現順を保ったままグループごとに数える
+		column := strings.ToLower(groupColumn)
+		groups := make([]map[string]interface{}, 0)
+		index := map[string]int{}
+		for _, row := range rows {
+			// 配列などmapのキーにできない値もあるため文字列表現で比較する
+			key := fmt.Sprintf("%#v", normalizeValue(row[column]))
+			i, ok := index[key]
+			if !ok {
+				i = len(groups)
+				index[key] = i
+				groups = append(groups, map[string]interface{}{column: row[column], "count": int64(0)})
+			}
+			groups[i]["count"] = groups[i]["count"].(int64) + 1
+		}
+		return scanRows(groups, out)
+	})
+}
+
+// Create ...
+func (handler *SQLHandler) Create(ctx context.Context, in map[string]interface{}, tableName string) error {
+	return handler.write(ctx, func(tables map[string]*table) error {
+		_, err := insertRow(tables, in, tableName)
+		return err
+	})
+}
+
+// Insert ...
+func (handler *SQLHandler) Insert(ctx context.Context, out interface{}, in map[string]interface{}, tableName string) error {
+	dest := reflect.ValueOf(out)
+	if dest.Kind() != reflect.Ptr || dest.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("memory: out must be a pointer to a struct, got %T", out)
+	}
+
+	var inserted map[string]interface{}
+	err := handler.write(ctx, func(tables map[string]*table) error {
+		row, err := insertRow(tables, in, tableName)
+		inserted = cloneRow(row)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return assignRow(dest.Elem(), inserted)
+}
+
+// insertRow 採番・既定値を反映した行を追加し、追加した行を返す
+func insertRow(tables map[string]*table, in map[string]interface{}, tableName string) (map[string]interface{}, error) {
+	t := lookupOrCreate(tables, tableName)
+
+	row := map[string]interface{}{}
+	for key, value := range in {
+		// PostgreSQL実装と同様に採番カラムへの指定は無視する
+		if key == "id" && t.def.identity {
+			continue
+		}
+		v, err := t.coerce(key, value)
+		if err != nil {
+			return nil, fmt.Errorf("%w (%s.%s)", err, tableName, key)
+		}
+		row[key] = v
+	}
+	for key, def := range t.def.defaults {
+		if _, ok := row[key]; !ok {
+			row[key] = def()
+		}
+	}
+	if t.def.identity {
+		row["id"] = t.nextID
+		t.nextID++
+	}
+
+	if err := t.checkUnique(row, -1); err != nil {
+		return nil, err
+	}
+	t.rows = append(t.rows, row)
+	return row, nil
+}
+
+// Update ...
+func (handler *SQLHandler) Update(ctx context.Context, setParams map[string]interface{}, tableName string, whereClause string, whereParams map[string]interface{}) error {
+	return handler.write(ctx, func(tables map[string]*table) error {
+		t := lookupOrCreate(tables, tableName)
+
+		e, err := parseClause(whereClause)
+		if err != nil {
+			return err
+		}
+
+		for i, row := range t.rows {
+			if e != nil {
+				matched, err := evalBool(e, row, whereParams)
+				if err != nil {
+					return err
+				}
+				if !matched {
+					continue
+				}
+			}
+
+			updated := cloneRow(row)
+			for key, value := range setParams {
+				// PostgreSQL実装と同様にnilの値は更新対象外とする
+				if value == nil {
+					continue
+				}
+				v, err := t.coerce(key, value)
+				if err != nil {
+					return fmt.Errorf("%w (%s.%s)", err, tableName, key)
+				}
+				updated[key] = v
+			}
+			if err := t.checkUnique(updated, i); err != nil {
+				return err
+			}
+			t.rows[i] = updated
+		}
+		return nil
+	})
+}
+
+// Delete ...
+//
+// PostgreSQLと同様に、ON DELETE CASCADEの外部キーで参照している行も削除する
+func (handler *SQLHandler) Delete(ctx context.Context, in map[string]interface{}, tableName string) error {
+	return handler.write(ctx, func(tables map[string]*table) error {
+		deleteRows(tables, tableName, func(row map[string]interface{}) bool {
+			for key, value := range in {
+				if value == nil {
+					continue
+				}
+				if cmp, err := compareValues(row[key], value); err != nil || cmp != 0 {
+					return false
+				}
+			}
+			return true
+		})
+		return nil
+	})
+}
+
+// deleteRows matchに一致する行を削除し、削除した行を参照している行を連鎖して削除する
+func deleteRows(tables map[string]*table, tableName string, match func(row map[string]interface{}) bool) {
+	t := lookupOrCreate(tables, tableName)
+
+	kept := make([]map[string]interface{}, 0, len(t.rows))
+	deleted := make([]map[string]interface{}, 0)
+	for _, row := range t.rows {
+		if match(row) {
+			deleted = append(deleted, row)
+		} else {
+			kept = append(kept, row)
+		}
+	}
+	t.rows = kept
+	if len(deleted) == 0 {
+		return
+	}
+
+	for name, def := range schema {
+		for _, fk := range def.cascades {
+			if fk.table != tableName {
+				continue
+			}
+			fk := fk
+			deleteRows(tables, name, func(row map[string]interface{}) bool {
+				for _, parent := range deleted {
+					if cmp, err := compareValues(row[fk.column], parent[fk.refColumn]); err == nil && cmp == 0 {
+						return true
+					}
+				}
+				return false
+			})
+		}
+	}
+}
+
+// lookup テーブルまたはビューを取得する（存在しない場合は空のテーブルを返す）
+func lookup(tables map[string]*table, name string) *table {
+	if view, ok := views[name]; ok {
+		return view(tables)
+	}
+	if t, ok := tables[name]; ok {
+		return t
+	}
+	return &table{nextID: 1}
+}
+
+// lookupOrCreate テーブルを取得し、存在しない場合は作成する
+func lookupOrCreate(tables map[string]*table, name string) *table {
+	t, ok := tables[name]
+	if !ok {
+		t = &table{nextID: 1}
+		tables[name] = t
+	}
+	return t
+}
+
+// coerce スキーマに定義されたカラムであれば型を揃える
+func (t *table) coerce(column string, value interface{}) (interface{}, error) {
+	kind, ok := t.def.columns[column]
+	if !ok {
+		if t.def.columns != nil {
+			return nil, fmt.Errorf("memory: unknown column %s", column)
+		}
+		return normalizeValue(value), nil
+	}
+	return coerce(kind, value)
+}
+
+// checkUnique 一意制約に違反していないかを検証する（skipは更新対象の行番号）
+//
+// PostgreSQLと同様に、NULLを含むキーは他の行と重複しないものとする
+func (t *table) checkUnique(row map[string]interface{}, skip int) error {
+	for _, columns := range t.def.uniques {
+		if hasNull(row, columns) {
+			continue
+		}
+		for i, other := range t.rows {
+			if i == skip {
+				continue
+			}
+			duplicated := true
+			for _, col := range columns {
+				if cmp, err := compareValues(row[col], other[col]); err != nil || cmp != 0 {
+					duplicated = false
+					break
+				}
+			}
+			if duplicated {
+				return fmt.Errorf("%w: (%s)", database.ErrUniqueViolation, strings.Join(columns, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// hasNull columnsのいずれかがNULLかを判定する
+func hasNull(row map[string]interface{}, columns []string) bool {
+	for _, col := range columns {
+		if normalizeValue(row[col]) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// filterRows WHERE句に一致する行を返す
+func filterRows(t *table, clause string, args map[string]interface{}) ([]map[string]interface{}, error) {
+	e, err := parseClause(clause)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]map[string]interface{}, 0, len(t.rows))
+	for _, row := range t.rows {
+		if e != nil {
+			matched, err := evalBool(e, row, args)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				continue
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// sortRows ORDER BY句（"col [ASC|DESC], ..."）に従って並び替える
+// PostgreSQLと同様に昇順ではNULLを末尾、降順では先頭に並べる
+func sortRows(rows []map[string]interface{}, order string) error {
+	type key struct {
+		column string
+		desc   bool
+	}
+
+	keys := make([]key, 0)
+	for _, part := range strings.Split(order, ",") {
+		fields := strings.Fields(strings.TrimSpace(part))
+		if len(fields) == 0 {
+			continue
+		}
+		k := key{column: strings.ToLower(fields[0])}
+		if len(fields) > 1 {
+			switch strings.ToUpper(fields[1]) {
+			case "DESC":
+				k.desc = true
+			case "ASC":
+			default:
+				return fmt.Errorf("memory: unsupported order %q", part)
+			}
+		}
+		keys = append(keys, k)
+	}
+
+	var sortErr error
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, k := range keys {
+			a, b := normalizeValue(rows[i][k.column]), normalizeValue(rows[j][k.column])
+			if a == nil || b == nil {
+				if a == nil && b == nil {
+					continue
+				}
+				// NULLは最大値として扱う
+				return (a == nil) == k.desc
+			}
+			cmp, err := compareValues(a, b)
+			if err != nil {
+				sortErr = err
+				return false
+			}
+			if cmp == 0 {
+				continue
+			}
+			if k.desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+	return sortErr
+}
+
+// scanRows 行を出力先のスライスに詰める
+func scanRows(rows []map[string]interface{}, out interface{}) error {
+	slice := reflect.ValueOf(out)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("memory: out must be a pointer to a slice, got %T", out)
+	}
+	slice = slice.Elem()
+	elemType := slice.Type().Elem()
+
+	result := reflect.MakeSlice(slice.Type(), 0, len(rows))
+	for _, row := range rows {
+		elem := reflect.New(elemType).Elem()
+		if err := assignRow(elem, row); err != nil {
+			return err
+		}
+		result = reflect.Append(result, elem)
+	}
+	slice.Set(result)
+	return nil
+}
+
+// assignRow dbタグを元に構造体のフィールドへ値を設定する
+func assignRow(elem reflect.Value, row map[string]interface{}) error {
+	if elem.Kind() != reflect.Struct {
+		return fmt.Errorf("memory: unsupported destination %s", elem.Type())
+	}
+
+	for i := 0; i < elem.NumField(); i++ {
+		field := elem.Type().Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := assignRow(elem.Field(i), row); err != nil {
+				return err
+			}
+			continue
+		}
+
+		column := field.Tag.Get("db")
+		if column == "" || column == "-" {
+			continue
+		}
+		value, ok := row[column]
+		if !ok || value == nil {
+			continue
+		}
+		if err := assignValue(elem.Field(i), value); err != nil {
+			return fmt.Errorf("memory: cannot assign column %s: %w", column, err)
+		}
+	}
+	return nil
+}
+
+// cloneTables トランザクション用にテーブルを複製する
+func cloneTables(tables map[string]*table) map[string]*table {
+	cloned := make(map[string]*table, len(tables))
+	for name, t := range tables {
+		rows := make([]map[string]interface{}, len(t.rows))
+		for i, row := range t.rows {
+			rows[i] = cloneRow(row)
+		}
+		cloned[name] = &table{def: t.def, rows: rows, nextID: t.nextID}
+	}
+	return cloned
+}
+
+func cloneRow(row map[string]interface{}) map[string]interface{} {
+	cloned := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		cloned[k] = v
+	}
+	return cloned
+}
@@ -0,0 +1,396 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/interface/database"
+	"github.com/lib/pq"
+)
+
+// SQLHandlerがdatabase.SQLHandlerを満たすことをコンパイル時に確認する
+var _ database.SQLHandler = (*SQLHandler)(nil)
+
+type testPet struct {
+	ID        string         `db:"id"`
+	Name      string         `db:"name"`
+	Gender    string         `db:"gender"`
+	Price     float64        `db:"price"`
+	ImageURL  *string        `db:"image_url"`
+	Likes     int            `db:"likes"`
+	BirthDate *time.Time     `db:"birth_date"`
+	Tags      pq.StringArray `db:"tags"`
+}
+
+type testNotification struct {
+	ID        int    `db:"id"`
+	UserId    string `db:"user_id"`
+	IsRead    bool   `db:"is_read"`
+	CreatedAt string `db:"created_at"`
+}
+
+func seededHandler(t *testing.T) *SQLHandler {
+	t.Helper()
+	handler, err := NewSeededSQLHandler()
+	if err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	return handler
+}
+
+func TestSQLHandler_Where(t *testing.T) {
+	handler := seededHandler(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		clause   string
+		args     map[string]interface{}
+		expected int
+	}{
+		{name: "条件なし", clause: "", expected: 12},
+		{name: "等価", clause: "id = :id", args: map[string]interface{}{"id": "77"}, expected: 1},
+		{name: "AND", clause: "gender = :gender and price = :price", args: map[string]interface{}{"gender": "Female", "price": 550000.0}, expected: 4},
+		{name: "範囲", clause: "price >= :min AND price < :max", args: map[string]interface{}{"min": 300000, "max": 450000}, expected: 3},
+		{name: "OR・括弧", clause: "(id = :a OR id = :b) AND gender = :g", args: map[string]interface{}{"a": "1", "b": "2", "g": "Male"}, expected: 1},
+		{name: "ILIKE", clause: "name ILIKE :q", args: map[string]interface{}{"q": "%KITTEN%"}, expected: 6},
+		{name: "LIKEのエスケープ", clause: "name LIKE :q", args: map[string]interface{}{"q": `%\%%`}, expected: 0},
+		{name: "行値の比較", clause: "(price, id) > (:price, :id)", args: map[string]interface{}{"price": 550000.0, "id": "4"}, expected: 5},
+		{name: "行値のNULL比較", clause: "(price, id) > (:price, :id)", args: map[string]interface{}{"price": 550000.0, "id": nil}, expected: 1},
+		{name: "配列の包含", clause: "tags @> :tags", args: map[string]interface{}{"tags": pq.StringArray{"new"}}, expected: 1},
+		{name: "ANY", clause: "id = ANY(:ids)", args: map[string]interface{}{"ids": pq.StringArray{"1", "3", "999"}}, expected: 2},
+		{name: "日付比較", clause: "birth_date >= :from", args: map[string]interface{}{"from": time.Date(2023, 10, 14, 0, 0, 0, 0, time.UTC)}, expected: 12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pets []testPet
+			if err := handler.Where(ctx, &pets, "pets", tt.clause, tt.args); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(pets) != tt.expected {
+				t.Errorf("expected %d rows but got %d", tt.expected, len(pets))
+			}
+		})
+	}
+}
+
+func TestSQLHandler_Where_MapsColumns(t *testing.T) {
+	handler := seededHandler(t)
+
+	var pets []testPet
+	err := handler.Where(context.Background(), &pets, "pets", "id = :id", map[string]interface{}{"id": "1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p := pets[0]
+	if p.Name != "cute cat" || p.Price != 360000 || p.Likes != 10 {
+		t.Errorf("unexpected pet: %+v", p)
+	}
+	if p.ImageURL == nil || p.BirthDate == nil || p.BirthDate.Day() != 14 {
+		t.Errorf("expected pointer columns to be set: %+v", p)
+	}
+	if len(p.Tags) != 4 || p.Tags[3] != "new" {
+		t.Errorf("unexpected tags: %v", p.Tags)
+	}
+}
+
+func TestSQLHandler_Where_MissingParameter(t *testing.T) {
+	handler := seededHandler(t)
+
+	var pets []testPet
+	err := handler.Where(context.Background(), &pets, "pets", "id = :id", nil)
+	if err == nil {
+		t.Errorf("expected error but got nil")
+	}
+}
+
+func TestSQLHandler_Scan_Order(t *testing.T) {
+	handler := seededHandler(t)
+
+	var pets []testPet
+	if err := handler.Scan(context.Background(), &pets, "pets", "price desc, id asc"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if pets[0].ID != "77" {
+		t.Errorf("expected the most expensive pet first, got %s", pets[0].ID)
+	}
+	if pets[1].ID != "10" || pets[2].ID != "4" {
+		t.Errorf("expected ties to be ordered by id, got %s, %s", pets[1].ID, pets[2].ID)
+	}
+	if pets[len(pets)-1].ID != "100" {
+		t.Errorf("expected the cheapest pet last, got %s", pets[len(pets)-1].ID)
+	}
+}
+
+func TestSQLHandler_CreateAndCount(t *testing.T) {
+	handler := seededHandler(t)
+	ctx := context.Background()
+
+	err := handler.Create(ctx, map[string]interface{}{
+		"id": 999, "user_id": "user3", "title": "t", "message": "m", "type": "campaign",
+	}, "notifications")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var notifications []testNotification
+	if err := handler.Where(ctx, &notifications, "notifications", "user_id = :user_id", map[string]interface{}{"user_id": "user3"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(notifications) != 1 {
+		t.Fatalf("expected 1 notification but got %d", len(notifications))
+	}
+	n := notifications[0]
+	if n.ID != 5 {
+		t.Errorf("expected generated id 5 but got %d", n.ID)
+	}
+	if n.IsRead || n.CreatedAt == "" {
+		t.Errorf("expected defaults to be applied: %+v", n)
+	}
+
+	var count int
+	if err := handler.Count(ctx, &count, "notifications", "is_read = :is_read", map[string]interface{}{"is_read": false}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("expected 4 unread notifications but got %d", count)
+	}
+}
+
+func TestSQLHandler_Insert(t *testing.T) {
+	handler := seededHandler(t)
+	ctx := context.Background()
+
+	var created testNotification
+	err := handler.Insert(ctx, &created, map[string]interface{}{
+		"id": 999, "user_id": "user3", "title": "t", "message": "m", "type": "campaign",
+	}, "notifications")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.ID != 5 || created.UserId != "user3" || created.IsRead || created.CreatedAt == "" {
+		t.Errorf("expected inserted row with generated id and defaults: %+v", created)
+	}
+
+	if err := handler.Insert(ctx, created, map[string]interface{}{"user_id": "user3"}, "notifications"); err == nil {
+		t.Error("expected error for non-pointer destination")
+	}
+}
+
+func TestSQLHandler_View(t *testing.T) {
+	handler := seededHandler(t)
+	ctx := context.Background()
+
+	type petWithShop struct {
+		ID           string `db:"id"`
+		ShopID       int64  `db:"shop_id"`
+		ShopName     string `db:"shop_name"`
+		ShopLocation string `db:"shop_location"`
+	}
+
+	// ビューは参照のたびに元のテーブルから組み立てる
+	if err := handler.Update(ctx, map[string]interface{}{"name": "uma-arai shop 3rd"}, "shops", "id = :id", map[string]interface{}{"id": 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var rows []petWithShop
+	if err := handler.Where(ctx, &rows, "pets_with_shop", "id = :id", map[string]interface{}{"id": "1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 1 || rows[0].ShopID != 1 || rows[0].ShopName != "uma-arai shop 3rd" || rows[0].ShopLocation != "Kanagawa" {
+		t.Errorf("unexpected joined rows: %+v", rows)
+	}
+
+	// 店舗が存在しないペットは含めない（内部結合）
+	if err := handler.Update(ctx, map[string]interface{}{"shop_id": 999}, "pets", "id = :id", map[string]interface{}{"id": "1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var count int
+	if err := handler.Count(ctx, &count, "pets_with_shop", "", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 11 {
+		t.Errorf("expected 11 joined rows but got %d", count)
+	}
+}
+
+func TestSQLHandler_CountBy(t *testing.T) {
+	handler := seededHandler(t)
+
+	var counts []struct {
+		Gender string `db:"gender"`
+		Count  int64  `db:"count"`
+	}
+	err := handler.CountBy(context.Background(), &counts, "pets", "gender", "price >= :price", map[string]interface{}{"price": 400000})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actual := map[string]int64{}
+	for _, c := range counts {
+		actual[c.Gender] = c.Count
+	}
+	var total int
+	_ = handler.Count(context.Background(), &total, "pets", "price >= :price", map[string]interface{}{"price": 400000})
+	if len(actual) != 2 || actual["Male"]+actual["Female"] != int64(total) {
+		t.Errorf("unexpected counts: %v (total %d)", actual, total)
+	}
+}
+
+func TestSQLHandler_Create_UniqueViolation(t *testing.T) {
+	handler := NewSQLHandler()
+	ctx := context.Background()
+	in := map[string]interface{}{"user_id": "user1", "pet_id": "1"}
+
+	if err := handler.Create(ctx, in, "favorites"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := handler.Create(ctx, in, "favorites")
+	if !errors.Is(err, database.ErrUniqueViolation) {
+		t.Errorf("expected ErrUniqueViolation but got %v", err)
+	}
+}
+
+func TestSQLHandler_UpdateAndDelete(t *testing.T) {
+	handler := seededHandler(t)
+	ctx := context.Background()
+
+	err := handler.Update(ctx, map[string]interface{}{"is_read": true}, "notifications",
+		"user_id = :user_id AND is_read = :is_read", map[string]interface{}{"user_id": "user1", "is_read": false})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var count int
+	_ = handler.Count(ctx, &count, "notifications", "is_read = :is_read", map[string]interface{}{"is_read": false})
+	if count != 0 {
+		t.Errorf("expected all notifications to be read, got %d unread", count)
+	}
+
+	if err := handler.Delete(ctx, map[string]interface{}{"user_id": "user1"}, "notifications"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = handler.Count(ctx, &count, "notifications", "", nil)
+	if count != 1 {
+		t.Errorf("expected 1 notification left but got %d", count)
+	}
+}
+
+func TestSQLHandler_Delete_Cascade(t *testing.T) {
+	handler := seededHandler(t)
+	ctx := context.Background()
+
+	for _, petID := range []string{"1", "2"} {
+		_ = handler.Create(ctx, map[string]interface{}{"user_id": "user1", "pet_id": petID}, "favorites")
+		_ = handler.Create(ctx, map[string]interface{}{
+			"user_id": "user1", "user_name": "user", "email": "user@example.com",
+			"reservation_date_time": time.Now(), "status": "pending", "pet_id": petID,
+		}, "reservations")
+	}
+	_ = handler.Create(ctx, map[string]interface{}{"shop_id": 1, "event_type": "pet.liked", "url": "http://example.com", "secret": "s"}, "webhook_subscriptions")
+	var delivery struct {
+		ID int64 `db:"id"`
+	}
+	_ = handler.Insert(ctx, &delivery, map[string]interface{}{"subscription_id": 1, "event_id": 1, "event_type": "pet.liked", "payload": "{}"}, "webhook_deliveries")
+	_ = handler.Create(ctx, map[string]interface{}{"delivery_id": delivery.ID, "attempt": 1, "duration_ms": 10}, "webhook_delivery_attempts")
+
+	// 削除したペットを参照している予約・お気に入りも削除される（PostgreSQLのON DELETE CASCADE）
+	if err := handler.Delete(ctx, map[string]interface{}{"id": "1"}, "pets"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, table := range []string{"favorites", "reservations"} {
+		var count int
+		_ = handler.Count(ctx, &count, table, "pet_id = :pet_id", map[string]interface{}{"pet_id": "1"})
+		if count != 0 {
+			t.Errorf("expected %s of the deleted pet to be deleted, got %d", table, count)
+		}
+		_ = handler.Count(ctx, &count, table, "pet_id = :pet_id", map[string]interface{}{"pet_id": "2"})
+		if count != 1 {
+			t.Errorf("expected %s of other pets to be kept, got %d", table, count)
+		}
+	}
+
+	// 連鎖して孫の行も削除される
+	if err := handler.Delete(ctx, map[string]interface{}{"id": 1}, "webhook_subscriptions"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, table := range []string{"webhook_deliveries", "webhook_delivery_attempts"} {
+		var count int
+		_ = handler.Count(ctx, &count, table, "", nil)
+		if count != 0 {
+			t.Errorf("expected %s to be deleted, got %d", table, count)
+		}
+	}
+}
+
+func TestSQLHandler_Transaction(t *testing.T) {
+	handler := seededHandler(t)
+	ctx := context.Background()
+	in := map[string]interface{}{"user_id": "user1", "pet_id": "1"}
+
+	// ロールバックした変更は反映されない
+	txCtx, err := handler.Begin(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := handler.Create(txCtx, in, "favorites"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var count int
+	_ = handler.Count(txCtx, &count, "favorites", "", nil)
+	if count != 1 {
+		t.Errorf("expected the transaction to see its own write")
+	}
+	_ = handler.Count(ctx, &count, "favorites", "", nil)
+	if count != 0 {
+		t.Errorf("expected uncommitted write to be invisible outside the transaction")
+	}
+	if err := handler.Rollback(txCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = handler.Count(ctx, &count, "favorites", "", nil)
+	if count != 0 {
+		t.Errorf("expected rolled back write to be discarded")
+	}
+
+	// コミットした変更は反映される
+	txCtx, _ = handler.Begin(ctx)
+	_ = handler.Create(txCtx, in, "favorites")
+	if err := handler.Commit(txCtx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = handler.Count(ctx, &count, "favorites", "", nil)
+	if count != 1 {
+		t.Errorf("expected committed write to be visible")
+	}
+
+	if err := handler.Commit(txCtx); !errors.Is(err, database.ErrNoTransaction) {
+		t.Errorf("expected ErrNoTransaction on double commit but got %v", err)
+	}
+}
+
+func TestSQLHandler_SelectForUpdateWait(t *testing.T) {
+	handler := seededHandler(t)
+	ctx := context.Background()
+
+	var pets []testPet
+	if err := handler.SelectForUpdateWait(ctx, &pets, "pets", "id = :id", map[string]interface{}{"id": "1"}, "", 1); !errors.Is(err, database.ErrNoTransaction) {
+		t.Errorf("expected ErrNoTransaction outside a transaction but got %v", err)
+	}
+
+	txCtx, err := handler.Begin(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = handler.Rollback(txCtx) }()
+	if err := handler.SelectForUpdateWait(txCtx, &pets, "pets", "id = :id", map[string]interface{}{"id": "1"}, "", 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pets) != 1 || pets[0].ID != "1" {
+		t.Errorf("unexpected rows: %+v", pets)
+	}
+}
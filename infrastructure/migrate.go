@@ -0,0 +1,109 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/horsewin/echo-playground-v2/db"
+	"github.com/horsewin/echo-playground-v2/infrastructure/migration"
+	"github.com/horsewin/echo-playground-v2/utils"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	migrationsDir = "migrations"
+	seedsDir      = "seeds"
+)
+
+// Migrate migrateサブコマンドを実行する
+//
+//	migrate up        未適用のマイグレーションをすべて適用する
+//	migrate down [n]  直近n件（省略時は1件）のマイグレーションをロールバックする
+//	migrate status    マイグレーションの適用状況を表示する
+//	migrate seed      初期データを投入する
+func Migrate(ctx context.Context, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	conn, err := openDB(ctx, utils.NewConfigDB())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	migrator, err := migration.NewMigrator(conn, db.Migrations, migrationsDir)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		count, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Info().Int("applied", count).Msg("migrate up completed")
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+		}
+		count, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		log.Info().Int("reverted", count).Msg("migrate down completed")
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		for _, s := range statuses {
+			event := log.Info().Int64("version", s.Version).Str("name", s.Name).Bool("applied", s.Applied)
+			if s.AppliedAt != nil {
+				event = event.Time("applied_at", *s.AppliedAt)
+			}
+			event.Msg("migration status")
+		}
+		if err != nil {
+			return err
+		}
+	case "seed":
+		if err := migrator.Seed(ctx, db.Seeds, seedsDir); err != nil {
+			return err
+		}
+		log.Info().Msg("seed completed")
+	default:
+		return fmt.Errorf("unknown migrate command: %s (expected up, down, status or seed)", command)
+	}
+
+	return nil
+}
+
+// migrateOnStart 起動時の設定に応じてマイグレーションと初期データ投入を行う
+func migrateOnStart(ctx context.Context, conn *sqlx.DB, c *utils.ConfigDB) error {
+	migrator, err := migration.NewMigrator(conn, db.Migrations, migrationsDir)
+	if err != nil {
+		return err
+	}
+
+	if c.MigrateOnStart {
+		count, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Info().Int("applied", count).Msg("migrations applied on start")
+	}
+
+	if c.SeedOnStart {
+		if err := migrator.Seed(ctx, db.Seeds, seedsDir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package migration
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/horsewin/echo-playground-v2/db"
+)
+
+func TestLoad_SortsByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/000002_second.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"m/000002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"m/000001_first.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+		"m/000001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+	}
+
+	migrations, err := Load(fsys, "m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(migrations) != 2 {
+		t.Fatalf("expected 2 migrations but got %d", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "first" {
+		t.Errorf("expected first migration to be 1_first, got %d_%s", migrations[0].Version, migrations[0].Name)
+	}
+	if migrations[1].Down != "DROP TABLE b;" {
+		t.Errorf("unexpected down script: %s", migrations[1].Down)
+	}
+	if migrations[0].Checksum == "" || migrations[0].Checksum == migrations[1].Checksum {
+		t.Errorf("expected distinct checksums")
+	}
+}
+
+func TestLoad_InvalidFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "不正なファイル名",
+			fsys: fstest.MapFS{"m/first.up.sql": {Data: []byte("")}},
+		},
+		{
+			name: "downファイルがない",
+			fsys: fstest.MapFS{"m/000001_first.up.sql": {Data: []byte("SELECT 1;")}},
+		},
+		{
+			name: "同一バージョンで名前が異なる",
+			fsys: fstest.MapFS{
+				"m/000001_first.up.sql":   {Data: []byte("SELECT 1;")},
+				"m/000001_other.down.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.fsys, "m"); err == nil {
+				t.Errorf("expected error but got nil")
+			}
+		})
+	}
+}
+
+func TestLoad_EmbeddedMigrations(t *testing.T) {
+	migrations, err := Load(db.Migrations, "migrations")
+	if err != nil {
+		t.Fatalf("embedded migrations are invalid: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatalf("expected embedded migrations")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "first", Checksum: "aaa"},
+		{Version: 2, Name: "second", Checksum: "bbb"},
+		{Version: 3, Name: "third", Checksum: "ccc"},
+	}
+
+	tests := []struct {
+		name    string
+		applied []appliedMigration
+		wantErr error
+	}{
+		{
+			name:    "正常系：一部適用済み",
+			applied: []appliedMigration{{Version: 1, Checksum: "aaa"}, {Version: 2, Checksum: "bbb"}},
+		},
+		{
+			name:    "チェックサム不一致",
+			applied: []appliedMigration{{Version: 1, Checksum: "changed"}},
+			wantErr: ErrChecksumMismatch,
+		},
+		{
+			name:    "ファイルが存在しない",
+			applied: []appliedMigration{{Version: 9, Checksum: "zzz"}},
+			wantErr: ErrUnknownMigration,
+		},
+		{
+			name:    "古いバージョンが未適用",
+			applied: []appliedMigration{{Version: 1, Checksum: "aaa"}, {Version: 3, Checksum: "ccc"}},
+			wantErr: ErrOutOfOrder,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verify(tt.applied, migrations)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v but got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPending(t *testing.T) {
+	migrations := []Migration{{Version: 1}, {Version: 2}, {Version: 3}}
+	result := pending([]appliedMigration{{Version: 1}}, migrations)
+
+	if len(result) != 2 || result[0].Version != 2 || result[1].Version != 3 {
+		t.Errorf("unexpected pending migrations: %+v", result)
+	}
+}
@@ -0,0 +1,316 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// migrationsTable 適用済みマイグレーションを管理するテーブル
+	migrationsTable = "schema_migrations"
+	// advisoryLockKey 複数タスクの同時マイグレーションを防ぐためのアドバイザリロックキー
+	advisoryLockKey int64 = 7_262_310_510_042_001
+)
+
+var (
+	// ErrChecksumMismatch 適用済みマイグレーションのファイル内容が変更されている
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	// ErrUnknownMigration DBに適用済みだがファイルが存在しないマイグレーションがある
+	ErrUnknownMigration = errors.New("unknown applied migration")
+	// ErrOutOfOrder 適用済みバージョンより古い未適用のマイグレーションがある
+	ErrOutOfOrder = errors.New("migration out of order")
+)
+
+// Status ... マイグレーションの適用状況
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// appliedMigration schema_migrationsテーブルの各カラムと対応する構造体
+type appliedMigration struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// Migrator ...
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+// NewMigrator fsysのdirに置かれたマイグレーションを読み込んだMigratorを返す
+func NewMigrator(db *sqlx.DB, fsys fs.FS, dir string) (*Migrator, error) {
+	migrations, err := Load(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up 未適用のマイグレーションをすべて適用し、適用した件数を返す
+func (m *Migrator) Up(ctx context.Context) (count int, err error) {
+	err = m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := verify(applied, m.migrations); err != nil {
+			return err
+		}
+
+		for _, mig := range pending(applied, m.migrations) {
+			start := time.Now()
+			if err := m.apply(ctx, conn, mig); err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			log.Info().
+				Int64("version", mig.Version).
+				Str("name", mig.Name).
+				Dur("elapsed", time.Since(start)).
+				Msg("migration applied")
+			count++
+		}
+		return nil
+	})
+	return
+}
+
+// Down 適用済みのマイグレーションを新しい順にsteps件ロールバックする
+func (m *Migrator) Down(ctx context.Context, steps int) (count int, err error) {
+	err = m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := verify(applied, m.migrations); err != nil {
+			return err
+		}
+
+		byVersion := map[int64]Migration{}
+		for _, mig := range m.migrations {
+			byVersion[mig.Version] = mig
+		}
+
+		for i := len(applied) - 1; i >= 0 && count < steps; i-- {
+			mig := byVersion[applied[i].Version]
+			if err := m.revert(ctx, conn, mig); err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			log.Info().
+				Int64("version", mig.Version).
+				Str("name", mig.Name).
+				Msg("migration reverted")
+			count++
+		}
+		return nil
+	})
+	return
+}
+
+// Status 各マイグレーションの適用状況を返す
+func (m *Migrator) Status(ctx context.Context) (statuses []Status, err error) {
+	err = m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		appliedMap := map[int64]appliedMigration{}
+		for _, a := range applied {
+			appliedMap[a.Version] = a
+		}
+		for _, mig := range m.migrations {
+			status := Status{Version: mig.Version, Name: mig.Name}
+			if a, ok := appliedMap[mig.Version]; ok {
+				appliedAt := a.AppliedAt
+				status.Applied = true
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+
+		// ステータス表示時もドリフトは報告する
+		return verify(applied, m.migrations)
+	})
+	return
+}
+
+// Seed fsysのdirに置かれたSQLファイルをファイル名順に実行する
+// シードファイルは何度実行しても同じ結果になるよう記述すること
+func (m *Migrator) Seed(ctx context.Context, fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read seed directory: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && path.Ext(entry.Name()) == ".sql" {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	return m.withLock(ctx, func(conn *sqlx.Conn) error {
+		for _, name := range names {
+			body, err := fs.ReadFile(fsys, path.Join(dir, name))
+			if err != nil {
+				return fmt.Errorf("failed to read seed file %s: %w", name, err)
+			}
+			if err := m.inTx(ctx, conn, func(tx *sqlx.Tx) error {
+				_, err := tx.ExecContext(ctx, string(body))
+				return err
+			}); err != nil {
+				return fmt.Errorf("failed to seed %s: %w", name, err)
+			}
+			log.Info().Str("file", name).Msg("seed applied")
+		}
+		return nil
+	})
+}
+
+// withLock アドバイザリロックを取得したコネクション上でfnを実行する
+// アドバイザリロックはセッション単位のため、同一コネクションを使い続ける必要がある
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sqlx.Conn) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// ctxがキャンセルされていてもロックは解放する
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey); err != nil {
+			log.Error().Err(err).Msg("failed to release migration lock")
+		}
+	}()
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// ensureTable schema_migrationsテーブルが無ければ作成する
+func (m *Migrator) ensureTable(ctx context.Context, conn *sqlx.Conn) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+(
+    version    BIGINT PRIMARY KEY,
+    name       TEXT      NOT NULL,
+    checksum   TEXT      NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`, migrationsTable))
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", migrationsTable, err)
+	}
+	return nil
+}
+
+// applied 適用済みのマイグレーションをバージョン昇順で返す
+func (m *Migrator) applied(ctx context.Context, conn *sqlx.Conn) (applied []appliedMigration, err error) {
+	err = conn.SelectContext(ctx, &applied,
+		fmt.Sprintf("SELECT version, name, checksum, applied_at FROM %s ORDER BY version", migrationsTable))
+	return
+}
+
+// apply マイグレーションと管理テーブルへの記録を1トランザクションで実行する
+func (m *Migrator) apply(ctx context.Context, conn *sqlx.Conn, mig Migration) error {
+	return m.inTx(ctx, conn, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx,
+			fmt.Sprintf("INSERT INTO %s (version, name, checksum) VALUES ($1, $2, $3)", migrationsTable),
+			mig.Version, mig.Name, mig.Checksum)
+		return err
+	})
+}
+
+// revert ロールバックと管理テーブルからの削除を1トランザクションで実行する
+func (m *Migrator) revert(ctx context.Context, conn *sqlx.Conn, mig Migration) error {
+	return m.inTx(ctx, conn, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx,
+			fmt.Sprintf("DELETE FROM %s WHERE version = $1", migrationsTable), mig.Version)
+		return err
+	})
+}
+
+func (m *Migrator) inTx(ctx context.Context, conn *sqlx.Conn, fn func(tx *sqlx.Tx) error) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// verify 適用済みマイグレーションとファイルの整合性を検証する
+func verify(applied []appliedMigration, migrations []Migration) error {
+	byVersion := map[int64]Migration{}
+	for _, mig := range migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	var latest int64
+	appliedSet := map[int64]bool{}
+	for _, a := range applied {
+		mig, ok := byVersion[a.Version]
+		if !ok {
+			return fmt.Errorf("%w: %d_%s", ErrUnknownMigration, a.Version, a.Name)
+		}
+		if mig.Checksum != a.Checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, a.Version, a.Name)
+		}
+		appliedSet[a.Version] = true
+		if a.Version > latest {
+			latest = a.Version
+		}
+	}
+
+	for _, mig := range migrations {
+		if mig.Version < latest && !appliedSet[mig.Version] {
+			return fmt.Errorf("%w: %d_%s is older than applied version %d", ErrOutOfOrder, mig.Version, mig.Name, latest)
+		}
+	}
+
+	return nil
+}
+
+// pending 未適用のマイグレーションをバージョン昇順で返す
+func pending(applied []appliedMigration, migrations []Migration) []Migration {
+	appliedSet := map[int64]bool{}
+	for _, a := range applied {
+		appliedSet[a.Version] = true
+	}
+
+	result := make([]Migration, 0)
+	for _, mig := range migrations {
+		if !appliedSet[mig.Version] {
+			result = append(result, mig)
+		}
+	}
+	return result
+}
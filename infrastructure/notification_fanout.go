@@ -0,0 +1,147 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/usecase"
+	"github.com/horsewin/echo-playground-v2/utils"
+	"github.com/lib/pq"
+	zerologlog "github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// NotificationFanoutLocal 通知イベントをプロセス内の購読者にのみ配信する
+	NotificationFanoutLocal = "local"
+	// NotificationFanoutPostgres 通知イベントをLISTEN/NOTIFYで全タスクの購読者に配信する
+	NotificationFanoutPostgres = "postgres"
+
+	// notificationChannel 通知イベントを送受信するNOTIFYのチャネル
+	notificationChannel = "notification_events"
+	// maxNotifyPayload NOTIFYのペイロードの上限（PostgreSQLの既定は8000バイト未満）
+	maxNotifyPayload = 7999
+	// listenerPingInterval 通知がない間もLISTENの接続断を検知するための疎通確認の間隔
+	listenerPingInterval = 90 * time.Second
+)
+
+// pgNotificationFanout 通知イベントをNOTIFYで送信し、LISTENで受信したイベントをブローカーに配信する
+//
+// 送信したタスク自身もLISTENで受信するため、ブローカーへの配信は受信側でのみ行う
+type pgNotificationFanout struct {
+	handler  *SQLHandler
+	listener *pq.Listener
+	broker   *usecase.NotificationBroker
+	done     chan struct{}
+}
+
+// newPGNotificationFanout LISTENを開始し、受信したイベントをbrokerに配信する
+func newPGNotificationFanout(handler *SQLHandler, c *utils.ConfigDB, broker *usecase.NotificationBroker) (*pgNotificationFanout, error) {
+	f := &pgNotificationFanout{
+		handler: handler,
+		broker:  broker,
+		done:    make(chan struct{}),
+	}
+	f.listener = pq.NewListener(buildDSN(c), time.Second, 30*time.Second, f.onListenerEvent)
+	if err := f.listener.Listen(notificationChannel); err != nil {
+		f.listener.Close()
+		return nil, fmt.Errorf("failed to listen on %s: %w", notificationChannel, err)
+	}
+
+	go f.run()
+	return f, nil
+}
+
+// Publish 通知イベントをNOTIFYで送信する
+//
+// トランザクション内で呼び出した場合、イベントはコミット時に配信される
+func (f *pgNotificationFanout) Publish(ctx context.Context, event model.NotificationEvent) error {
+	// スパンを作成
+	tracer := otel.Tracer("notification-fanout")
+	ctx, span := tracer.Start(ctx, "NotificationFanout.Publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("db.system", dbType),
+		attribute.String("messaging.destination.name", notificationChannel),
+		attribute.String("event.type", event.Type),
+	)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if len(payload) > maxNotifyPayload {
+		err = fmt.Errorf("notification event payload too large: %d bytes", len(payload))
+		span.RecordError(err)
+		return err
+	}
+
+	_, err = f.handler.executor(ctx).NamedExecContext(ctx, "SELECT pg_notify(:channel, :payload)", map[string]interface{}{
+		"channel": notificationChannel,
+		"payload": string(payload),
+	})
+	if err != nil {
+		span.RecordError(err)
+	}
+	return translateError(err)
+}
+
+// run LISTENで受信したイベントをブローカーに配信する
+func (f *pgNotificationFanout) run() {
+	ping := time.NewTicker(listenerPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-f.done:
+			return
+		case n := <-f.listener.Notify:
+			// 再接続時はnilが届く（取りこぼしへの対応はonListenerEventで行う）
+			if n == nil {
+				continue
+			}
+			var event model.NotificationEvent
+			if err := json.Unmarshal([]byte(n.Extra), &event); err != nil {
+				zerologlog.Warn().Err(err).Msg("invalid notification event payload")
+				continue
+			}
+			f.broker.Deliver(event)
+		case <-ping.C:
+			go func() {
+				if err := f.listener.Ping(); err != nil {
+					zerologlog.Warn().Err(err).Msg("notification listener ping failed")
+				}
+			}()
+		}
+	}
+}
+
+// onListenerEvent LISTENの接続状態の変化を記録する
+func (f *pgNotificationFanout) onListenerEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventDisconnected:
+		zerologlog.Warn().Err(err).Msg("notification listener disconnected")
+	case pq.ListenerEventReconnected:
+		// 切断中のイベントを取りこぼした可能性があるため、購読者に再取得を求める
+		zerologlog.Info().Msg("notification listener reconnected")
+		f.broker.Invalidate()
+	case pq.ListenerEventConnectionAttemptFailed:
+		zerologlog.Warn().Err(err).Msg("notification listener reconnect failed")
+	}
+}
+
+// Close LISTENを終了する
+func (f *pgNotificationFanout) Close() error {
+	close(f.done)
+	return f.listener.Close()
+}
@@ -0,0 +1,109 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// metricsServer Prometheusのスクレイプ用管理サーバ（無効の場合はnil）
+var metricsServer *http.Server
+
+// SetupMetrics OpenTelemetryのメータープロバイダーを設定
+//
+// SBCNTR_ENABLE_METRICSが有効な場合はOTLPでメトリクスを送出し、
+// SBCNTR_METRICS_ADDRが指定されている場合は管理ポートで/metricsを公開する。
+// どちらも無効の場合はグローバルのnoopメータープロバイダーのままとする。
+func SetupMetrics(ctx context.Context, serviceName string, serviceVersion string, logger zerolog.Logger, apiConfig *utils.APIConfig) (*sdkmetric.MeterProvider, error) {
+	if !apiConfig.EnableMetrics && apiConfig.MetricsAddr == "" {
+		return nil, nil
+	}
+
+	var options []sdkmetric.Option
+
+	if apiConfig.EnableMetrics {
+		// OTLPエクスポーターのエンドポイント設定（トレースと同じサイドカーに送出する）
+		exporterEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if exporterEndpoint == "" {
+			exporterEndpoint = "127.0.0.1:4318" // デフォルトのOTLP/HTTPエンドポイント
+		}
+
+		exporter, err := otlpmetrichttp.New(ctx,
+			otlpmetrichttp.WithEndpoint(exporterEndpoint),
+			otlpmetrichttp.WithInsecure(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+		}
+		options = append(options, sdkmetric.WithReader(
+			sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(apiConfig.MetricsInterval)),
+		))
+	}
+
+	if apiConfig.MetricsAddr != "" {
+		// デフォルトレジストリにはGoランタイムのメトリクスも含まれるため専用のレジストリを用意する
+		registry := prometheus.NewRegistry()
+		exporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Prometheus exporter: %w", err)
+		}
+		options = append(options, sdkmetric.WithReader(exporter))
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		metricsServer = &http.Server{
+			Addr:              apiConfig.MetricsAddr,
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+	}
+
+	// サービス情報をリソースに追加
+	res := resource.Default()
+	serviceResource, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String(serviceName),
+			semconv.ServiceVersionKey.String(serviceVersion),
+		),
+	)
+	if err == nil {
+		res, _ = resource.Merge(res, serviceResource)
+	}
+	options = append(options, sdkmetric.WithResource(res))
+
+	mp := sdkmetric.NewMeterProvider(options...)
+
+	// グローバルメータープロバイダーを設定
+	otel.SetMeterProvider(mp)
+
+	return mp, nil
+}
+
+// StartMetricsServer /metricsを公開する管理サーバを起動する（無効の場合は何もしない）
+func StartMetricsServer() {
+	if metricsServer == nil {
+		return
+	}
+
+	go func() {
+		logger := zerolog.New(os.Stdout)
+		logger.Info().Str("addr", metricsServer.Addr).Msg("Starting metrics server")
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error().Err(err).Msg("Failed to start metrics server")
+		}
+	}()
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/horsewin/echo-playground-v2/utils"
 	"github.com/rs/zerolog"
 	"os"
+	"sync/atomic"
+	"time"
 
 	awsxray "go.opentelemetry.io/contrib/propagators/aws/xray"
 	"go.opentelemetry.io/otel"
@@ -19,6 +21,13 @@ import (
 
 var Tracer trace.Tracer
 
+// exporterState OTLPエクスポーターの状態（readinessで利用する）
+var exporterState struct {
+	enabled     atomic.Bool
+	lastErrorAt atomic.Int64
+	lastError   atomic.Value
+}
+
 // SetupOpenTelemetry OpenTelemetryのトレーサーを設定
 func SetupOpenTelemetry(ctx context.Context, serviceName string, serviceVersion string, logger zerolog.Logger, apiConfig *utils.APIConfig) (*sdktrace.TracerProvider, error) {
 	if !apiConfig.EnableTracing {
@@ -78,6 +87,14 @@ func SetupOpenTelemetry(ctx context.Context, serviceName string, serviceVersion
 	// グローバルトレーサープロバイダーを設定
 	otel.SetTracerProvider(tp)
 
+	// エクスポート失敗を記録し、readinessで参照できるようにする
+	exporterState.enabled.Store(true)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		exporterState.lastErrorAt.Store(time.Now().UnixNano())
+		exporterState.lastError.Store(err.Error())
+		logger.Warn().Err(err).Msg("OpenTelemetry error")
+	}))
+
 	// X-Ray伝搬を設定（AWSサービスとの互換性のため）
 	otel.SetTextMapPropagator(awsxray.Propagator{})
 
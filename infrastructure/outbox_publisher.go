@@ -0,0 +1,168 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/usecase"
+	"github.com/horsewin/echo-playground-v2/utils"
+	zerologlog "github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// OutboxPublisherLog outboxのイベントをログに出力する（外部システムと連携しない環境向け）
+	OutboxPublisherLog = "log"
+	// OutboxPublisherHTTP outboxのイベントをWebhookとしてPOSTする
+	OutboxPublisherHTTP = "http"
+	// OutboxPublisherFile outboxのイベントをJSON Lines形式でファイルに追記する（ローカルでの動作確認向け）
+	OutboxPublisherFile = "file"
+
+	// outboxWebhookTimeout Webhookの1回の送信のタイムアウト
+	outboxWebhookTimeout = 10 * time.Second
+)
+
+// outboxEnvelope 外部システムに配信するイベントの形式
+type outboxEnvelope struct {
+	ID        int64           `json:"id"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt *time.Time      `json:"created_at,omitempty"`
+}
+
+// newOutboxEnvelope ...
+func newOutboxEnvelope(message model.OutboxMessage) outboxEnvelope {
+	return outboxEnvelope{
+		ID:        message.ID,
+		EventType: message.EventType,
+		Payload:   json.RawMessage(message.Payload),
+		CreatedAt: message.CreatedAt,
+	}
+}
+
+// newOutboxPublisher 設定に応じたoutboxの配信先を返す
+func newOutboxPublisher(apiConfig *utils.APIConfig) (usecase.OutboxPublisher, error) {
+	switch apiConfig.Outbox.Publisher {
+	case OutboxPublisherLog:
+		return logOutboxPublisher{}, nil
+	case OutboxPublisherHTTP:
+		if apiConfig.Outbox.WebhookURL == "" {
+			return nil, fmt.Errorf("SBCNTR_OUTBOX_WEBHOOK_URL is required for the %s outbox publisher", OutboxPublisherHTTP)
+		}
+		return &httpOutboxPublisher{
+			url:    apiConfig.Outbox.WebhookURL,
+			client: &http.Client{Timeout: outboxWebhookTimeout},
+		}, nil
+	case OutboxPublisherFile:
+		if apiConfig.Outbox.File == "" {
+			return nil, fmt.Errorf("SBCNTR_OUTBOX_FILE is required for the %s outbox publisher", OutboxPublisherFile)
+		}
+		return &fileOutboxPublisher{path: apiConfig.Outbox.File}, nil
+	}
+	return nil, fmt.Errorf("unknown outbox publisher: %s", apiConfig.Outbox.Publisher)
+}
+
+// logOutboxPublisher outboxのイベントをログに出力する
+type logOutboxPublisher struct{}
+
+// Publish ...
+func (logOutboxPublisher) Publish(ctx context.Context, message model.OutboxMessage) error {
+	zerologlog.Info().
+		Int64("id", message.ID).
+		Str("event_type", message.EventType).
+		RawJSON("payload", []byte(message.Payload)).
+		Msg("outbox event published")
+	return nil
+}
+
+// httpOutboxPublisher outboxのイベントをWebhookとしてPOSTする（2xx以外の応答は失敗とする）
+type httpOutboxPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// Publish ...
+func (p *httpOutboxPublisher) Publish(ctx context.Context, message model.OutboxMessage) error {
+	// スパンを作成
+	tracer := otel.Tracer("outbox-publisher")
+	ctx, span := tracer.Start(ctx, "httpOutboxPublisher.Publish",
+		trace.WithSpanKind(trace.SpanKindClient),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("outbox.id", message.ID),
+		attribute.String("outbox.event_type", message.EventType),
+	)
+
+	body, err := json.Marshal(newOutboxEnvelope(message))
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Outbox-Event-Type", message.EventType)
+	// 受信側で再配信を重複として除外できるようにする
+	req.Header.Set("X-Outbox-Message-Id", strconv.FormatInt(message.ID, 10))
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	defer res.Body.Close()
+	// 接続を再利用するため応答を読み切る
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	span.SetAttributes(attribute.Int("http.status_code", res.StatusCode))
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		err = fmt.Errorf("webhook responded with status %d", res.StatusCode)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// fileOutboxPublisher outboxのイベントをJSON Lines形式でファイルに追記する
+type fileOutboxPublisher struct {
+	mu   sync.Mutex
+	path string
+}
+
+// Publish ...
+func (p *fileOutboxPublisher) Publish(ctx context.Context, message model.OutboxMessage) error {
+	line, err := json.Marshal(newOutboxEnvelope(message))
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f, err := os.OpenFile(p.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,112 @@
+package infrastructure
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/utils"
+)
+
+func TestNewOutboxPublisher(t *testing.T) {
+	apiConfig := &utils.APIConfig{}
+	for _, publisher := range []string{OutboxPublisherHTTP, OutboxPublisherFile, "kafka"} {
+		apiConfig.Outbox.Publisher = publisher
+		if _, err := newOutboxPublisher(apiConfig); err == nil {
+			t.Errorf("%s: expected error for incomplete configuration", publisher)
+		}
+	}
+
+	apiConfig.Outbox.Publisher = OutboxPublisherLog
+	publisher, err := newOutboxPublisher(apiConfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := publisher.Publish(context.Background(), model.OutboxMessage{ID: 1, EventType: model.EventPetLiked, Payload: `{}`}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHTTPOutboxPublisher(t *testing.T) {
+	status := http.StatusNoContent
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	apiConfig := &utils.APIConfig{}
+	apiConfig.Outbox.Publisher = OutboxPublisherHTTP
+	apiConfig.Outbox.WebhookURL = server.URL
+	publisher, err := newOutboxPublisher(apiConfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	message := model.OutboxMessage{ID: 42, EventType: model.EventPetLiked, Payload: `{"pet_id":"1"}`}
+	if err := publisher.Publish(context.Background(), message); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if received.Method != http.MethodPost || received.Header.Get("X-Outbox-Event-Type") != model.EventPetLiked ||
+		received.Header.Get("X-Outbox-Message-Id") != "42" {
+		t.Errorf("unexpected request: %s %v", received.Method, received.Header)
+	}
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if envelope["id"] != float64(42) || envelope["payload"].(map[string]interface{})["pet_id"] != "1" {
+		t.Errorf("unexpected body: %s", body)
+	}
+
+	// 2xx以外の応答は失敗として再試行させる
+	status = http.StatusServiceUnavailable
+	if err := publisher.Publish(context.Background(), message); err == nil {
+		t.Error("expected error for 503 response")
+	}
+}
+
+func TestFileOutboxPublisher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	apiConfig := &utils.APIConfig{}
+	apiConfig.Outbox.Publisher = OutboxPublisherFile
+	apiConfig.Outbox.File = path
+	publisher, err := newOutboxPublisher(apiConfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, id := range []int64{1, 2} {
+		if err := publisher.Publish(context.Background(), model.OutboxMessage{ID: id, EventType: model.EventPetLiked, Payload: `{}`}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open sink: %v", err)
+	}
+	defer f.Close()
+	var ids []int64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var envelope outboxEnvelope
+		if err := json.Unmarshal(scanner.Bytes(), &envelope); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, envelope.ID)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("expected messages 1 and 2 appended, got %v", ids)
+	}
+}
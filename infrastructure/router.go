@@ -2,10 +2,17 @@ package infrastructure
 
 import (
 	"context"
+	stderrors "errors"
+	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
 	handlers "github.com/horsewin/echo-playground-v2/handler"
+	"github.com/horsewin/echo-playground-v2/infrastructure/memory"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+	"github.com/horsewin/echo-playground-v2/usecase"
 	"github.com/horsewin/echo-playground-v2/utils"
 
 	"github.com/labstack/echo/v4"
@@ -15,6 +22,9 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -23,6 +33,12 @@ const (
 	projectName = "echo-playground-v2"
 )
 
+// テレメトリのプロバイダー（無効の場合はnil）。終了時にバッファをフラッシュする
+var (
+	tracerProvider *sdktrace.TracerProvider
+	meterProvider  *sdkmetric.MeterProvider
+)
+
 // configureOpenTelemetry OpenTelemetryの設定を行う
 func configureOpenTelemetry(apiConfig *utils.APIConfig, logger zerolog.Logger) {
 	logger.Info().Msgf("configureOpenTelemetry start : %v", apiConfig.EnableTracing)
@@ -31,14 +47,37 @@ func configureOpenTelemetry(apiConfig *utils.APIConfig, logger zerolog.Logger) {
 	tp, err := SetupOpenTelemetry(ctx, projectName, "2.14.0", logger, apiConfig)
 	if err != nil {
 		logger.Error().Err(err).Msg("Failed to configure OpenTelemetry")
-		return
-	}
-
-	if tp != nil {
+	} else if tp != nil {
+		tracerProvider = tp
 		logger.Info().Msg("OpenTelemetry configured successfully")
 	} else {
 		logger.Info().Msg("OpenTelemetry is disabled")
 	}
+
+	mp, err := SetupMetrics(ctx, projectName, "2.14.0", logger, apiConfig)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to configure OpenTelemetry metrics")
+	} else if mp != nil {
+		meterProvider = mp
+		logger.Info().Msg("OpenTelemetry metrics configured successfully")
+	} else {
+		logger.Info().Msg("OpenTelemetry metrics is disabled")
+	}
+}
+
+// ShutdownTelemetry メトリクスサーバを停止し、未送信のトレース・メトリクスをフラッシュする
+func ShutdownTelemetry(ctx context.Context) error {
+	var errs []error
+	if metricsServer != nil {
+		errs = append(errs, metricsServer.Shutdown(ctx))
+	}
+	if meterProvider != nil {
+		errs = append(errs, meterProvider.Shutdown(ctx))
+	}
+	if tracerProvider != nil {
+		errs = append(errs, tracerProvider.Shutdown(ctx))
+	}
+	return stderrors.Join(errs...)
 }
 
 // setupRequestLogger リクエストロガーミドルウェアを設定
@@ -84,7 +123,7 @@ func setupRequestLogger(logger zerolog.Logger) echo.MiddlewareFunc {
 			return nil
 		},
 		Skipper: func(c echo.Context) bool {
-			return c.Path() == "/healthcheck"
+			return isHealthCheckPath(c.Path())
 		},
 	})
 }
@@ -102,7 +141,7 @@ func setupOpenTelemetryMiddleware() echo.MiddlewareFunc {
 				Logger()
 
 			// healthcheckはトレース作成を行わない
-			if c.Path() == "/healthcheck" {
+			if isHealthCheckPath(c.Path()) {
 				return next(c)
 			}
 
@@ -154,30 +193,238 @@ func setupOpenTelemetryMiddleware() echo.MiddlewareFunc {
 	}
 }
 
+// setupLocaleMiddleware エラーメッセージのロケールとリクエストIDをcontextに設定するミドルウェアを設定
+//
+// ロケールはlangクエリパラメータ、Accept-Languageヘッダーの順に決定する
+func setupLocaleMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			locale := errors.NegotiateLocale(req.Header.Get("Accept-Language"), c.QueryParam("lang"))
+			c.Response().Header().Set("Content-Language", locale)
+
+			ctx := errors.WithLocale(req.Context(), locale)
+			ctx = errors.WithRequestID(ctx, c.Response().Header().Get(echo.HeaderXRequestID))
+			c.SetRequest(req.WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
+
+// setupMetricsMiddleware ルートテンプレートごとのRED（リクエスト数・エラー・処理時間）メトリクスを記録するミドルウェアを設定
+func setupMetricsMiddleware() echo.MiddlewareFunc {
+	meter := otel.Meter(projectName)
+	duration, _ := meter.Float64Histogram("http.server.request.duration",
+		metric.WithDescription("Duration of HTTP server requests"),
+		metric.WithUnit("s"),
+	)
+	activeRequests, _ := meter.Int64UpDownCounter("http.server.active_requests",
+		metric.WithDescription("Number of in-flight HTTP server requests"),
+		metric.WithUnit("{request}"),
+	)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// healthcheckは計測しない
+			if isHealthCheckPath(c.Path()) {
+				return next(c)
+			}
+
+			// パスパラメータでカーディナリティが増えないよう、実パスではなくルートテンプレートを使う
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			ctx := c.Request().Context()
+			method := attribute.String("http.request.method", c.Request().Method)
+			routeAttr := attribute.String("http.route", route)
+
+			activeRequests.Add(ctx, 1, metric.WithAttributes(method, routeAttr))
+			start := time.Now()
+
+			err := next(c)
+
+			activeRequests.Add(ctx, -1, metric.WithAttributes(method, routeAttr))
+			status := responseStatus(c, err)
+			duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+				method,
+				routeAttr,
+				attribute.Int("http.response.status_code", status),
+				attribute.Bool("error", status >= 500),
+			))
+
+			return err
+		}
+	}
+}
+
+// responseStatus ハンドラーの戻り値を考慮したレスポンスステータスを返す
+//
+// エラーはミドルウェアの外側でHTTPErrorHandlerがレスポンスに変換するため、
+// この時点ではまだステータスが書き込まれていない
+func responseStatus(c echo.Context, err error) int {
+	if err == nil {
+		return c.Response().Status
+	}
+	return errors.StatusCode(err)
+}
+
+// newSQLHandler 環境変数DB_CONNに応じたSQLHandlerを返す
+//
+//	DB_CONN=1      PostgreSQLに接続する
+//	DB_CONN=memory 初期データを投入したインメモリDBを使う
+//
+// それ以外の場合はDBを利用しないためnilを返す
+func newSQLHandler() database.SQLHandler {
+	switch os.Getenv("DB_CONN") {
+	case "1":
+		handler, err := NewSQLHandler()
+		if err != nil {
+			zerologlog.Fatal().Err(err).Msg("Failed to connect to database")
+		}
+		return handler
+	case "memory":
+		handler, err := memory.NewSeededSQLHandler()
+		if err != nil {
+			zerologlog.Fatal().Err(err).Msg("Failed to initialize in-memory database")
+		}
+		zerologlog.Info().Msg("Using in-memory database")
+		return handler
+	}
+	return nil
+}
+
+// isHealthCheckPath ヘルスチェック用のパスかを判定する
+func isHealthCheckPath(path string) bool {
+	return path == "/healthcheck" || strings.HasPrefix(path, "/healthcheck/")
+}
+
+// isPublicRoute 認証なしで利用できるルートかを判定する
+func isPublicRoute(c echo.Context) bool {
+	path := c.Path()
+	if isHealthCheckPath(path) {
+		return true
+	}
+	switch path {
+	case "/", "/v1/helloworld", "/v1/helloworld/error", "/v1/errors":
+		return true
+	case "/v1/pets", "/v1/pets/:id/availability", "/v1/shops", "/v1/shops/:id", "/v1/shops/:id/pets", "/v1/shops/:id/schedule":
+		return c.Request().Method == http.MethodGet
+	}
+	return false
+}
+
+// newNotificationStream 通知ストリームのブローカーと配信先を作成する
+//
+// ブローカーはサーバの停止時に閉じ、接続中のストリームを終了させる
+// （Shutdownは処理中のリクエストの完了を待つため、閉じないと停止がタイムアウトする）
+func newNotificationStream(e *echo.Echo, sqlHandler database.SQLHandler, apiConfig *utils.APIConfig) handlers.NotificationStreamConfig {
+	broker := usecase.NewNotificationBroker()
+	e.Server.RegisterOnShutdown(broker.Close)
+	stream := handlers.NotificationStreamConfig{
+		Broker:            broker,
+		HeartbeatInterval: apiConfig.SSEHeartbeatInterval,
+	}
+
+	switch apiConfig.NotificationFanout {
+	case NotificationFanoutLocal:
+	case NotificationFanoutPostgres:
+		pgHandler, ok := sqlHandler.(*SQLHandler)
+		if !ok {
+			zerologlog.Warn().Msg("SBCNTR_NOTIFICATION_FANOUT=postgres requires DB_CONN=1; delivering notification events locally")
+			break
+		}
+		fanout, err := newPGNotificationFanout(pgHandler, utils.NewConfigDB(), broker)
+		if err != nil {
+			zerologlog.Fatal().Err(err).Msg("Failed to start notification fanout")
+		}
+		e.Server.RegisterOnShutdown(func() {
+			if err := fanout.Close(); err != nil {
+				zerologlog.Error().Err(err).Msg("Failed to close notification listener")
+			}
+		})
+		stream.Publisher = fanout
+	default:
+		zerologlog.Warn().Str("fanout", apiConfig.NotificationFanout).Msg("Unknown SBCNTR_NOTIFICATION_FANOUT; delivering notification events locally")
+	}
+
+	return stream
+}
+
 // registerRoutes ルートを登録する
-func registerRoutes(e *echo.Echo) {
-	healthCheckHandler := handlers.NewHealthCheckHandler()
+func registerRoutes(e *echo.Echo, apiConfig *utils.APIConfig) {
+	sqlHandler := newSQLHandler()
+	healthCheckHandler := handlers.NewHealthCheckHandler(IsShuttingDown, healthComponents(sqlHandler)...)
 	helloWorldHandler := handlers.NewHelloWorldHandler()
+	errorCatalogHandler := handlers.NewErrorCatalogHandler()
 
 	// ---------------------------
 	// APIルートの定義
 	// ---------------------------
 	e.GET("/", healthCheckHandler.HealthCheck())
 	e.GET("/healthcheck", healthCheckHandler.HealthCheck())
+	e.GET("/healthcheck/live", healthCheckHandler.Live())
+	e.GET("/healthcheck/ready", healthCheckHandler.Ready())
 	e.GET("/v1/helloworld", helloWorldHandler.SayHelloWorld())
 	e.GET("/v1/helloworld/error", helloWorldHandler.SayError())
-	if os.Getenv("DB_CONN") == "1" {
-		sqlHandler := NewSQLHandler()
-		petHandler := handlers.NewPetHandler(sqlHandler)
-		notificationHandler := handlers.NewNotificationHandler(sqlHandler)
+	e.GET("/v1/errors", errorCatalogHandler.GetErrors())
+	if sqlHandler != nil {
+		stream := newNotificationStream(e, sqlHandler, apiConfig)
+		events := handlers.NewEventBus(sqlHandler, stream)
+
+		workers, err := newBackgroundWorkers(sqlHandler, apiConfig)
+		if err != nil {
+			zerologlog.Fatal().Err(err).Msg("Failed to configure background workers")
+		}
+		// 通知の作成をリクエストの処理から切り離す
+		backgroundWorkers = append(workers, &backgroundWorker{name: "event bus", run: events.Run})
+
+		petHandler := handlers.NewPetHandler(sqlHandler, events)
+		notificationHandler := handlers.NewNotificationHandler(sqlHandler, stream)
+		reservationHandler := handlers.NewReservationHandler(sqlHandler, events)
+		webhookHandler := handlers.NewWebhookHandler(sqlHandler)
+		shopHandler := handlers.NewShopHandler(sqlHandler)
+		idempotent := setupIdempotencyMiddleware(newIdempotencyInteractor(sqlHandler, apiConfig))
 
 		e.GET("/v1/pets", petHandler.GetPets())
-		e.POST("/v1/pets/:id/like", petHandler.UpdateLike())
-		e.POST("/v1/pets/:id/reservation", petHandler.Reservation())
+		e.POST("/v1/pets", petHandler.CreatePet())
+		e.PUT("/v1/pets/:id", petHandler.ReplacePet())
+		e.PATCH("/v1/pets/:id", petHandler.PatchPet())
+		e.DELETE("/v1/pets/:id", petHandler.DeletePet())
+		e.POST("/v1/pets/:id/adopt", petHandler.AdoptPet())
+		e.POST("/v1/pets/:id/withdraw", petHandler.WithdrawPet())
+		e.POST("/v1/pets/:id/relist", petHandler.RelistPet())
+		e.POST("/v1/pets/:id/like", petHandler.UpdateLike(), idempotent)
+		e.POST("/v1/pets/:id/reservation", petHandler.Reservation(), idempotent)
+		e.GET("/v1/pets/:id/availability", petHandler.GetAvailability())
+
+		e.GET("/v1/shops", shopHandler.GetShops())
+		e.GET("/v1/shops/:id", shopHandler.GetShop())
+		e.GET("/v1/shops/:id/pets", shopHandler.GetShopPets())
+		e.GET("/v1/shops/:id/schedule", shopHandler.GetSchedule())
+		e.PUT("/v1/shops/:id/schedule", shopHandler.UpdateSchedule())
+
+		e.GET("/v1/reservations", reservationHandler.GetReservations())
+		e.GET("/v1/reservations/:id", reservationHandler.GetReservation())
+		e.POST("/v1/reservations/:id/confirm", reservationHandler.ConfirmReservation())
+		e.POST("/v1/reservations/:id/cancel", reservationHandler.CancelReservation())
+		e.POST("/v1/reservations/:id/reschedule", reservationHandler.RescheduleReservation())
 
 		e.GET("/v1/notifications", notificationHandler.GetNotifications())
+		e.GET("/v1/notifications/unread_count", notificationHandler.GetUnreadCount())
+		e.GET("/v1/notifications/stream", notificationHandler.StreamNotifications())
 		e.POST("/v1/notifications/read", notificationHandler.PostNotificationsRead())
 
+		e.GET("/v1/webhooks", webhookHandler.GetSubscriptions())
+		e.POST("/v1/webhooks", webhookHandler.CreateSubscription())
+		e.GET("/v1/webhooks/:id", webhookHandler.GetSubscription())
+		e.PUT("/v1/webhooks/:id", webhookHandler.UpdateSubscription())
+		e.DELETE("/v1/webhooks/:id", webhookHandler.DeleteSubscription())
+		e.GET("/v1/webhooks/:id/deliveries", webhookHandler.GetDeliveries())
+		e.GET("/v1/webhooks/:id/deliveries/:delivery_id", webhookHandler.GetDelivery())
+		e.POST("/v1/webhooks/:id/deliveries/:delivery_id/replay", webhookHandler.ReplayDelivery())
+
 	}
 }
 
@@ -194,27 +441,49 @@ func Router() *echo.Echo {
 	// Configure Echo settings
 	e.HideBanner = true
 	e.HidePort = false
+	e.Validator = handlers.NewRequestValidator()
+	e.HTTPErrorHandler = httpErrorHandler
 
 	// Setup middlewares
-	setupMiddlewares(e, logger)
+	setupMiddlewares(e, logger, apiConfig)
 
 	// Register routes
-	registerRoutes(e)
+	registerRoutes(e, apiConfig)
 
 	return e
 }
 
 // setupMiddlewares ミドルウェアを設定
-func setupMiddlewares(e *echo.Echo, logger zerolog.Logger) {
+func setupMiddlewares(e *echo.Echo, logger zerolog.Logger, apiConfig *utils.APIConfig) {
 	// リクエストIDの生成
 	e.Use(middleware.RequestID())
 
+	// ロケールの決定
+	e.Use(setupLocaleMiddleware())
+
 	// ログ出力設定
 	e.Use(setupRequestLogger(logger))
 
 	// OpenTelemetryミドルウェア
 	e.Use(setupOpenTelemetryMiddleware())
 
+	// メトリクスミドルウェア
+	e.Use(setupMetricsMiddleware())
+
+	// 認証ミドルウェア（認証が無効の場合は設定しない）
+	authenticators, err := NewAuthenticators(apiConfig)
+	if err != nil {
+		zerologlog.Fatal().Err(err).Msg("Failed to configure authentication")
+	}
+	if len(authenticators) > 0 {
+		e.Use(setupAuthMiddleware(authenticators, isPublicRoute))
+	} else {
+		logger.Warn().Msg("Authentication is disabled; user_id in requests is trusted as is")
+	}
+
 	// recoveryミドルウェアの設定
-	e.Use(middleware.Recover())
+	// panicはエラーとして外側のミドルウェアに返し、ログ・トレース・メトリクスに記録してからHTTPErrorHandlerで変換する
+	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
+		DisableErrorHandler: true,
+	}))
 }
@@ -0,0 +1,63 @@
+package infrastructure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	// 他のテストに影響しないよう、グローバルのMeterProviderを元に戻す
+	previous := otel.GetMeterProvider()
+	t.Cleanup(func() { otel.SetMeterProvider(previous) })
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	e := echo.New()
+	e.Use(setupMetricsMiddleware())
+	e.GET("/v1/pets/:id", func(c echo.Context) error {
+		if c.Param("id") == "boom" {
+			return echo.NewHTTPError(http.StatusInternalServerError)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/healthcheck", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	for _, path := range []string{"/v1/pets/1", "/v1/pets/2", "/v1/pets/boom", "/healthcheck"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	counts := map[int64]uint64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "http.server.request.duration" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Histogram[float64]).DataPoints {
+				// パスパラメータではなくルートテンプレートで集計される
+				route, _ := dp.Attributes.Value(attribute.Key("http.route"))
+				if route.AsString() != "/v1/pets/:id" {
+					t.Errorf("unexpected route: %s", route.AsString())
+				}
+				status, _ := dp.Attributes.Value(attribute.Key("http.response.status_code"))
+				counts[status.AsInt64()] += dp.Count
+			}
+		}
+	}
+
+	if counts[http.StatusOK] != 2 || counts[http.StatusInternalServerError] != 1 {
+		t.Errorf("unexpected request counts: %v", counts)
+	}
+}
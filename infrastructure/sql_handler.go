@@ -3,15 +3,18 @@ package infrastructure
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
-	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/horsewin/echo-playground-v2/interface/database"
 	"github.com/horsewin/echo-playground-v2/utils"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -31,57 +34,196 @@ type SQLHandler struct {
 	Conn *sqlx.DB
 }
 
+// sqlExecutor *sqlx.DBと*sqlx.Txの共通インターフェース
+type sqlExecutor interface {
+	PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error)
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// txKey contextにトランザクションを保持するためのキー
+type txKey struct{}
+
 var (
 	sqlHandlerInstance *SQLHandler
+	sqlHandlerErr      error
 	once               sync.Once
 )
 
 const (
 	dbType = "postgres"
+	// defaultConnectTimeout 接続試行のタイムアウトを指定しない（0以下の）場合の既定値
+	defaultConnectTimeout = 5 * time.Second
 )
 
 // NewSQLHandler ...
-func NewSQLHandler() *SQLHandler {
+func NewSQLHandler() (*SQLHandler, error) {
 	once.Do(func() {
 		c := utils.NewConfigDB()
-		USER := c.Postgres.Username
-		PASS := c.Postgres.Password
-		DBNAME := c.Postgres.DBName
-		dbPort := os.Getenv("DB_PORT")
-		if dbPort == "" {
-			dbPort = "5432"
+		conn, err := openDB(context.Background(), c)
+		if err != nil {
+			sqlHandlerErr = err
+			return
 		}
-		PROTOCOL := "host=" + os.Getenv("DB_HOST") + " port=" + dbPort
 
-		// localhostのDBの場合はSSLを無効化
-		var sslModeValue string
-		if os.Getenv("DB_HOST") == "localhost" {
-			sslModeValue = "disable"
-		} else {
-			sslModeValue = "require" // 本番環境ではSSLを有効にする
+		// コネクションを払い出す前にスキーマを最新化する
+		if c.MigrateOnStart || c.SeedOnStart {
+			if err := migrateOnStart(context.Background(), conn, c); err != nil {
+				conn.Close()
+				sqlHandlerErr = fmt.Errorf("database migration failed: %w", err)
+				return
+			}
+		}
+
+		// メトリクスはDB接続の可否に影響させない
+		if err := registerDBStatsMetrics(conn, c.Postgres.DBName); err != nil {
+			log.Printf("[WARN] failed to register database pool metrics: %v", err)
 		}
 
-		CONNECT := "user=" + USER + " password=" + PASS + " " + PROTOCOL + " dbname=" + DBNAME + " sslmode=" + sslModeValue
+		sqlHandlerInstance = &SQLHandler{Conn: conn}
+	})
+
+	return sqlHandlerInstance, sqlHandlerErr
+}
 
-		// 標準のSQLコネクションを作成
-		db, err := sql.Open(dbType, CONNECT)
-		if err != nil {
-			log.Fatalf("Error: No database connection established: %v", err)
+// openDB DBへ接続し、疎通確認済みのコネクションプールを返す
+// DBの起動が遅れている場合に備え、疎通確認は指数バックオフでリトライする
+func openDB(ctx context.Context, c *utils.ConfigDB) (*sqlx.DB, error) {
+	// 標準のSQLコネクションを作成
+	db, err := sql.Open(dbType, buildDSN(c))
+	if err != nil {
+		return nil, fmt.Errorf("no database connection established: %w", err)
+	}
+
+	// コネクションプールを設定
+	db.SetMaxOpenConns(c.Pool.MaxOpenConns)
+	db.SetMaxIdleConns(c.Pool.MaxIdleConns)
+	db.SetConnMaxLifetime(c.Pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(c.Pool.ConnMaxIdleTime)
+
+	conn := sqlx.NewDb(db, dbType)
+
+	attempts := c.Retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, connectTimeout(c))
+		err = conn.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			break
 		}
-		conn := sqlx.NewDb(db, dbType)
-		err = conn.Ping()
-		if err != nil {
+		if attempt >= attempts {
 			db.Close()
-			log.Fatalf("Error: No database connection established: %v", err)
+			return nil, fmt.Errorf("no database connection established after %d attempts: %w", attempt, err)
 		}
 
-		// 接続成功
-		log.Println("DB connected successfully")
+		wait := utils.ExponentialBackoff(c.Retry.InitialInterval, c.Retry.MaxInterval, attempt)
+		log.Printf("DB is not ready (attempt %d/%d): %v, retrying in %s", attempt, attempts, err, wait)
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
 
-		sqlHandlerInstance = &SQLHandler{Conn: conn}
-	})
+	// 接続成功
+	log.Println("DB connected successfully")
+
+	return conn, nil
+}
 
-	return sqlHandlerInstance
+// connectTimeout 1回の接続試行のタイムアウトを返す（0以下の場合は既定値）
+func connectTimeout(c *utils.ConfigDB) time.Duration {
+	if c.Postgres.ConnectTimeout > 0 {
+		return c.Postgres.ConnectTimeout
+	}
+	return defaultConnectTimeout
+}
+
+// buildDSN 設定からlib/pq形式の接続文字列を組み立てる
+func buildDSN(c *utils.ConfigDB) string {
+	params := [][2]string{
+		{"host", c.Postgres.Host},
+		{"port", c.Postgres.Port},
+		{"user", c.Postgres.Username},
+		{"password", c.Postgres.Password},
+		{"dbname", c.Postgres.DBName},
+		{"sslmode", c.Postgres.SSLMode},
+		{"sslrootcert", c.Postgres.SSLRootCert},
+		{"application_name", c.Postgres.ApplicationName},
+	}
+	if c.Postgres.ConnectTimeout > 0 {
+		// connect_timeoutは秒単位（最小1秒）
+		seconds := int(c.Postgres.ConnectTimeout.Round(time.Second) / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		params = append(params, [2]string{"connect_timeout", strconv.Itoa(seconds)})
+	}
+	if c.Postgres.StatementTimeout > 0 {
+		// lib/pqは未知のキーをランタイムパラメータとしてサーバへ送る
+		params = append(params, [2]string{"statement_timeout", strconv.FormatInt(c.Postgres.StatementTimeout.Milliseconds(), 10)})
+	}
+
+	pairs := make([]string, 0, len(params))
+	for _, p := range params {
+		if p[1] == "" {
+			continue
+		}
+		pairs = append(pairs, p[0]+"="+quoteDSNValue(p[1]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// quoteDSNValue 空白や引用符を含む値をシングルクォートで囲みエスケープする
+func quoteDSNValue(value string) string {
+	if !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + replacer.Replace(value) + "'"
+}
+
+// executor contextにトランザクションが存在すればそれを、なければコネクションプールを返す
+func (handler *SQLHandler) executor(ctx context.Context) sqlExecutor {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+	return handler.Conn
+}
+
+// Begin ...
+func (handler *SQLHandler) Begin(ctx context.Context) (context.Context, error) {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return ctx, database.ErrTransactionAlreadyStarted
+	}
+
+	tx, err := handler.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, txKey{}, tx), nil
+}
+
+// Commit ...
+func (handler *SQLHandler) Commit(ctx context.Context) error {
+	tx, ok := ctx.Value(txKey{}).(*sqlx.Tx)
+	if !ok {
+		return database.ErrNoTransaction
+	}
+	return tx.Commit()
+}
+
+// Rollback ...
+func (handler *SQLHandler) Rollback(ctx context.Context) error {
+	tx, ok := ctx.Value(txKey{}).(*sqlx.Tx)
+	if !ok {
+		return database.ErrNoTransaction
+	}
+	return tx.Rollback()
 }
 
 // Where ...
@@ -106,11 +248,12 @@ func (handler *SQLHandler) Where(ctx context.Context, out interface{}, table str
 		attribute.String("db.sql.table", table),
 	)
 
-	stmt, err := handler.Conn.PrepareNamed(query)
+	stmt, err := handler.executor(ctx).PrepareNamedContext(ctx, query)
 	if err != nil {
 		span.RecordError(err)
 		return err
 	}
+	defer stmt.Close()
 
 	err = stmt.SelectContext(ctx, out, whereArgs)
 	if err != nil {
@@ -138,7 +281,98 @@ func (handler *SQLHandler) Scan(ctx context.Context, out interface{}, table stri
 		attribute.String("db.sql.table", table),
 	)
 
-	err := handler.Conn.SelectContext(ctx, out, query)
+	err := handler.executor(ctx).SelectContext(ctx, out, query)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
+// Select ...
+func (handler *SQLHandler) Select(ctx context.Context, out interface{}, table string, whereClause string, whereArgs map[string]interface{}, order string, limit int) error {
+	// スパンを作成
+	tracer := otel.Tracer("sql-handler")
+	ctx, span := tracer.Start(ctx, "SQLHandler.Select",
+		trace.WithSpanKind(trace.SpanKindClient),
+	)
+	defer span.End()
+
+	query := buildSelectQuery(table, whereClause, order, limit)
+	return handler.selectContext(ctx, span, out, table, query, whereArgs)
+}
+
+// SelectForUpdate ...
+func (handler *SQLHandler) SelectForUpdate(ctx context.Context, out interface{}, table string, whereClause string, whereArgs map[string]interface{}, order string, limit int) error {
+	// スパンを作成
+	tracer := otel.Tracer("sql-handler")
+	ctx, span := tracer.Start(ctx, "SQLHandler.SelectForUpdate",
+		trace.WithSpanKind(trace.SpanKindClient),
+	)
+	defer span.End()
+
+	return handler.selectForUpdate(ctx, span, out, table, whereClause, whereArgs, order, limit, "FOR UPDATE SKIP LOCKED")
+}
+
+// SelectForUpdateWait ...
+func (handler *SQLHandler) SelectForUpdateWait(ctx context.Context, out interface{}, table string, whereClause string, whereArgs map[string]interface{}, order string, limit int) error {
+	// スパンを作成
+	tracer := otel.Tracer("sql-handler")
+	ctx, span := tracer.Start(ctx, "SQLHandler.SelectForUpdateWait",
+		trace.WithSpanKind(trace.SpanKindClient),
+	)
+	defer span.End()
+
+	return handler.selectForUpdate(ctx, span, out, table, whereClause, whereArgs, order, limit, "FOR UPDATE")
+}
+
+// selectForUpdate ロック句を付けたSELECT文をトランザクション内で実行する
+func (handler *SQLHandler) selectForUpdate(ctx context.Context, span trace.Span, out interface{}, table string, whereClause string, whereArgs map[string]interface{}, order string, limit int, lockClause string) error {
+	// トランザクション外ではロックがすぐに解放されるため意味がない
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); !ok {
+		span.RecordError(database.ErrNoTransaction)
+		return database.ErrNoTransaction
+	}
+
+	query := buildSelectQuery(table, whereClause, order, limit) + " " + lockClause
+	return handler.selectContext(ctx, span, out, table, query, whereArgs)
+}
+
+// buildSelectQuery SELECT文を組み立てる
+func buildSelectQuery(table string, whereClause string, order string, limit int) string {
+	query := fmt.Sprintf("SELECT * FROM %s", table)
+	if whereClause != "" {
+		query += fmt.Sprintf(" WHERE %s", whereClause)
+	}
+	if order != "" {
+		query += fmt.Sprintf(" ORDER BY %s", order)
+	}
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	return query
+}
+
+// selectContext SELECT文を実行して結果をoutに格納する
+func (handler *SQLHandler) selectContext(ctx context.Context, span trace.Span, out interface{}, table string, query string, whereArgs map[string]interface{}) error {
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("db.system", dbType),
+		attribute.String("db.statement", query),
+		attribute.String("db.operation", "SELECT"),
+		attribute.String("db.sql.table", table),
+	)
+
+	stmt, err := handler.executor(ctx).PrepareNamedContext(ctx, query)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	defer stmt.Close()
+
+	if whereArgs == nil {
+		whereArgs = map[string]interface{}{}
+	}
+	err = stmt.SelectContext(ctx, out, whereArgs)
 	if err != nil {
 		span.RecordError(err)
 	}
@@ -168,11 +402,12 @@ func (handler *SQLHandler) Count(ctx context.Context, out *int, table string, wh
 	)
 
 	var count int
-	stmt, err := handler.Conn.PrepareNamed(query)
+	stmt, err := handler.executor(ctx).PrepareNamedContext(ctx, query)
 	if err != nil {
 		span.RecordError(err)
 		return err
 	}
+	defer stmt.Close()
 
 	err = stmt.GetContext(ctx, &count, whereArgs)
 	*out = count
@@ -182,6 +417,46 @@ func (handler *SQLHandler) Count(ctx context.Context, out *int, table string, wh
 	return err
 }
 
+// CountBy ...
+func (handler *SQLHandler) CountBy(ctx context.Context, out interface{}, table string, groupColumn string, whereClause string, whereArgs map[string]interface{}) error {
+	// スパンを作成
+	tracer := otel.Tracer("sql-handler")
+	ctx, span := tracer.Start(ctx, "SQLHandler.CountBy",
+		trace.WithSpanKind(trace.SpanKindClient),
+	)
+	defer span.End()
+
+	query := fmt.Sprintf("SELECT %s, COUNT(*) AS count FROM %s", groupColumn, table)
+	if whereClause != "" {
+		query += fmt.Sprintf(" WHERE %s", whereClause)
+	}
+	query += fmt.Sprintf(" GROUP BY %s", groupColumn)
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("db.system", dbType),
+		attribute.String("db.statement", query),
+		attribute.String("db.operation", "SELECT"),
+		attribute.String("db.sql.table", table),
+	)
+
+	stmt, err := handler.executor(ctx).PrepareNamedContext(ctx, query)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	defer stmt.Close()
+
+	if whereArgs == nil {
+		whereArgs = map[string]interface{}{}
+	}
+	err = stmt.SelectContext(ctx, out, whereArgs)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
+
 // Create ...
 func (handler *SQLHandler) Create(ctx context.Context, input map[string]interface{}, table string) error {
 	// スパンを作成
@@ -217,11 +492,56 @@ func (handler *SQLHandler) Create(ctx context.Context, input map[string]interfac
 		attribute.String("db.sql.table", table),
 	)
 
-	_, err := handler.Conn.NamedExecContext(ctx, query, input)
+	_, err := handler.executor(ctx).NamedExecContext(ctx, query, input)
 	if err != nil {
 		span.RecordError(err)
 	}
-	return err
+	return translateError(err)
+}
+
+// Insert ...
+func (handler *SQLHandler) Insert(ctx context.Context, out interface{}, input map[string]interface{}, table string) error {
+	// スパンを作成
+	tracer := otel.Tracer("sql-handler")
+	ctx, span := tracer.Start(ctx, "SQLHandler.Insert",
+		trace.WithSpanKind(trace.SpanKindClient),
+	)
+	defer span.End()
+
+	// カラム名とプレースホルダーを構築（Createと同様にIDは採番に任せる）
+	columns := make([]string, 0, len(input))
+	placeholders := make([]string, 0, len(input))
+	for key := range input {
+		if key == "id" {
+			continue
+		}
+		columns = append(columns, key)
+		placeholders = append(placeholders, fmt.Sprintf(":%s", key))
+	}
+
+	// クエリを構築
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING *", table, strings.Join(columns, ","), strings.Join(placeholders, ","))
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("db.system", dbType),
+		attribute.String("db.statement", query),
+		attribute.String("db.operation", "INSERT"),
+		attribute.String("db.sql.table", table),
+	)
+
+	stmt, err := handler.executor(ctx).PrepareNamedContext(ctx, query)
+	if err != nil {
+		span.RecordError(err)
+		return translateError(err)
+	}
+	defer stmt.Close()
+
+	err = stmt.GetContext(ctx, out, input)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return translateError(err)
 }
 
 // Update ...
@@ -275,13 +595,13 @@ func (handler *SQLHandler) Update(ctx context.Context, setParams map[string]inte
 		attribute.String("db.sql.table", table),
 	)
 
-	_, err := handler.Conn.NamedExecContext(ctx, query, allParams)
+	_, err := handler.executor(ctx).NamedExecContext(ctx, query, allParams)
 
 	if err != nil {
 		span.RecordError(err)
 	}
 
-	return err
+	return translateError(err)
 }
 
 // Delete ...
@@ -293,14 +613,15 @@ func (handler *SQLHandler) Delete(ctx context.Context, in map[string]interface{}
 	)
 	defer span.End()
 
-	columns, _, values := buildNamedParameters(in)
+	columns, placeholders, values := buildNamedParameters(in)
 
+	// 値はSQLに埋め込まずプレースホルダーでバインドする
 	whereClauses := make([]string, len(columns))
 	for i, col := range columns {
-		whereClauses[i] = fmt.Sprintf("%s = %v", col, values[col])
+		whereClauses[i] = fmt.Sprintf("%s = %s", col, placeholders[i])
 	}
 
-	query := fmt.Sprintf("DELETE FROM %s WHERE %s", table, strings.Join(whereClauses, ","))
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s", table, strings.Join(whereClauses, " AND "))
 
 	// 属性を追加
 	span.SetAttributes(
@@ -310,7 +631,7 @@ func (handler *SQLHandler) Delete(ctx context.Context, in map[string]interface{}
 		attribute.String("db.sql.table", table),
 	)
 
-	_, err := handler.Conn.NamedExecContext(ctx, query, values)
+	_, err := handler.executor(ctx).NamedExecContext(ctx, query, values)
 	if err != nil {
 		span.RecordError(err)
 	}
@@ -318,6 +639,15 @@ func (handler *SQLHandler) Delete(ctx context.Context, in map[string]interface{}
 	return err
 }
 
+// translateError PostgreSQLのエラーをdatabaseパッケージのエラーに変換する
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return fmt.Errorf("%w: %s", database.ErrUniqueViolation, pqErr.Message)
+	}
+	return err
+}
+
 func buildNamedParameters(input map[string]interface{}) (columns []string, placeholderNames []string, values map[string]interface{}) {
 	columns = []string{}
 	placeholderNames = []string{}
@@ -0,0 +1,64 @@
+package infrastructure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/utils"
+	"github.com/lib/pq"
+)
+
+func TestBuildDSN(t *testing.T) {
+	c := new(utils.ConfigDB)
+	c.Postgres.Host = "db.example.com"
+	c.Postgres.Port = "5432"
+	c.Postgres.Username = "app"
+	c.Postgres.Password = `p@ss word'\x`
+	c.Postgres.DBName = "sbcntrapp"
+	c.Postgres.SSLMode = "verify-full"
+	c.Postgres.SSLRootCert = "/etc/ssl/rds.pem"
+	c.Postgres.ApplicationName = "echo-playground-v2"
+	c.Postgres.ConnectTimeout = 1500 * time.Millisecond
+	c.Postgres.StatementTimeout = 30 * time.Second
+
+	dsn := buildDSN(c)
+
+	expected := `host=db.example.com port=5432 user=app password='p@ss word\'\\x' dbname=sbcntrapp ` +
+		`sslmode=verify-full sslrootcert=/etc/ssl/rds.pem application_name=echo-playground-v2 ` +
+		`connect_timeout=2 statement_timeout=30000`
+	if dsn != expected {
+		t.Errorf("unexpected dsn:\n got: %s\nwant: %s", dsn, expected)
+	}
+
+	// lib/pqが解釈できる形式であることを確認
+	if _, err := pq.NewConnector(dsn); err != nil {
+		t.Errorf("dsn is not parsable by lib/pq: %v", err)
+	}
+}
+
+func TestBuildDSN_OmitsEmptyValues(t *testing.T) {
+	c := new(utils.ConfigDB)
+	c.Postgres.Host = "localhost"
+	c.Postgres.Port = "5432"
+	c.Postgres.SSLMode = "disable"
+
+	dsn := buildDSN(c)
+	if dsn != "host=localhost port=5432 sslmode=disable" {
+		t.Errorf("unexpected dsn: %s", dsn)
+	}
+}
+
+func TestConnectTimeout(t *testing.T) {
+	c := new(utils.ConfigDB)
+	for _, d := range []time.Duration{0, -time.Second} {
+		c.Postgres.ConnectTimeout = d
+		if got := connectTimeout(c); got != defaultConnectTimeout {
+			t.Errorf("%s: expected default %s but got %s", d, defaultConnectTimeout, got)
+		}
+	}
+
+	c.Postgres.ConnectTimeout = 2 * time.Second
+	if got := connectTimeout(c); got != 2*time.Second {
+		t.Errorf("expected 2s but got %s", got)
+	}
+}
@@ -0,0 +1,128 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Webhookのリクエストヘッダー
+const (
+	// WebhookHeaderSignature "sha256="に続けて、"タイムスタンプ.リクエストボディ"のHMAC-SHA256を16進数で表した値
+	WebhookHeaderSignature = "X-Webhook-Signature"
+	// WebhookHeaderTimestamp 署名した時刻（Unix秒）。受信側はリプレイ攻撃を防ぐため古すぎる場合は拒否する
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	// WebhookHeaderDeliveryID 送信のID
+	WebhookHeaderDeliveryID = "X-Webhook-Delivery-Id"
+	// WebhookHeaderEventID イベントのID（リプレイでも変わらないため、受信側の重複排除に使う）
+	WebhookHeaderEventID = "X-Webhook-Event-Id"
+	// WebhookHeaderEventType イベントの名前
+	WebhookHeaderEventType = "X-Webhook-Event-Type"
+
+	// webhookSignaturePrefix 署名の方式を示す接頭辞
+	webhookSignaturePrefix = "sha256="
+)
+
+// webhookEnvelope 送信先にPOSTするイベントの形式
+type webhookEnvelope struct {
+	ID         int64           `json:"id"`
+	EventID    int64           `json:"event_id"`
+	EventType  string          `json:"event_type"`
+	Payload    json.RawMessage `json:"payload"`
+	OccurredAt *time.Time      `json:"occurred_at,omitempty"`
+}
+
+// SignWebhookPayload Webhookの署名を返す（受信側は同じ計算結果とX-Webhook-Signatureを比較して検証する）
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return webhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// httpWebhookSender 署名したイベントを送信先にPOSTする
+type httpWebhookSender struct {
+	client *http.Client
+	// now 署名する時刻を返す関数
+	now func() time.Time
+}
+
+// newHTTPWebhookSender ...
+func newHTTPWebhookSender(timeout time.Duration) *httpWebhookSender {
+	return &httpWebhookSender{
+		client: &http.Client{Timeout: timeout},
+		now:    time.Now,
+	}
+}
+
+// Send ...
+func (s *httpWebhookSender) Send(ctx context.Context, subscription model.WebhookSubscription, delivery model.WebhookDelivery) (int, error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-sender")
+	ctx, span := tracer.Start(ctx, "httpWebhookSender.Send",
+		trace.WithSpanKind(trace.SpanKindClient),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("webhook.subscription_id", subscription.ID),
+		attribute.Int64("webhook.delivery_id", delivery.ID),
+		attribute.String("webhook.event_type", delivery.EventType),
+	)
+
+	body, err := json.Marshal(webhookEnvelope{
+		ID:         delivery.ID,
+		EventID:    delivery.EventID,
+		EventType:  delivery.EventType,
+		Payload:    json.RawMessage(delivery.Payload),
+		OccurredAt: delivery.CreatedAt,
+	})
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+	timestamp := s.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookHeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookHeaderSignature, SignWebhookPayload(subscription.Secret, timestamp, body))
+	req.Header.Set(WebhookHeaderDeliveryID, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(WebhookHeaderEventID, strconv.FormatInt(delivery.EventID, 10))
+	req.Header.Set(WebhookHeaderEventType, delivery.EventType)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+	defer res.Body.Close()
+	// 接続を再利用するため応答を読み切る
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	span.SetAttributes(attribute.Int("http.status_code", res.StatusCode))
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		err = fmt.Errorf("webhook responded with status %d", res.StatusCode)
+		span.RecordError(err)
+		return res.StatusCode, err
+	}
+	return res.StatusCode, nil
+}
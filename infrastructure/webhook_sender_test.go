@@ -0,0 +1,81 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	// 受信側が同じ計算をして検証できるよう、署名の形式を固定する
+	// （printf '1700000000.{"id":1}' | openssl dgst -sha256 -hmac whsec_test と一致する）
+	got := SignWebhookPayload("whsec_test", 1700000000, []byte(`{"id":1}`))
+	want := "sha256=2f441ba4b3b2d50d28a9ab9d9fd8880376ecd1eb5d0435401553f5d8d0a5dcf8"
+	if got != want {
+		t.Errorf("expected %s but got %s", want, got)
+	}
+	if got == SignWebhookPayload("whsec_test", 1700000001, []byte(`{"id":1}`)) {
+		t.Error("expected signature to depend on timestamp")
+	}
+}
+
+func TestHTTPWebhookSender(t *testing.T) {
+	status := http.StatusOK
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sender := newHTTPWebhookSender(time.Second)
+	sender.now = func() time.Time { return time.Unix(1700000000, 0) }
+	subscription := model.WebhookSubscription{ID: 1, URL: server.URL, Secret: "whsec_test"}
+	delivery := model.WebhookDelivery{ID: 7, SubscriptionID: 1, EventID: 42, EventType: model.EventPetLiked, Payload: `{"pet_id":"1"}`}
+
+	code, err := sender.Send(context.Background(), subscription, delivery)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%v)", code, err)
+	}
+	if received.Method != http.MethodPost ||
+		received.Header.Get(WebhookHeaderTimestamp) != "1700000000" ||
+		received.Header.Get(WebhookHeaderDeliveryID) != "7" ||
+		received.Header.Get(WebhookHeaderEventID) != "42" ||
+		received.Header.Get(WebhookHeaderEventType) != model.EventPetLiked {
+		t.Errorf("unexpected request headers: %v", received.Header)
+	}
+
+	// 受信側の手順で署名を検証できる
+	timestamp, _ := strconv.ParseInt(received.Header.Get(WebhookHeaderTimestamp), 10, 64)
+	if received.Header.Get(WebhookHeaderSignature) != SignWebhookPayload(subscription.Secret, timestamp, body) {
+		t.Errorf("signature mismatch: %s", received.Header.Get(WebhookHeaderSignature))
+	}
+	var envelope webhookEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if envelope.ID != 7 || envelope.EventID != 42 || string(envelope.Payload) != delivery.Payload {
+		t.Errorf("unexpected body: %s", body)
+	}
+
+	// 2xx以外の応答はステータスコードとエラーを返す
+	status = http.StatusServiceUnavailable
+	if code, err := sender.Send(context.Background(), subscription, delivery); err == nil || code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 error, got %d (%v)", code, err)
+	}
+
+	// 応答がない場合は0とエラーを返す
+	server.Close()
+	if code, err := sender.Send(context.Background(), subscription, delivery); err == nil || code != 0 {
+		t.Errorf("expected connection error, got %d (%v)", code, err)
+	}
+}
@@ -0,0 +1,12 @@
+package database
+
+import "errors"
+
+var (
+	// ErrNoTransaction contextにトランザクションが存在しない
+	ErrNoTransaction = errors.New("database: no transaction in context")
+	// ErrTransactionAlreadyStarted contextに既にトランザクションが存在する
+	ErrTransactionAlreadyStarted = errors.New("database: transaction already started")
+	// ErrUniqueViolation 一意制約に違反した
+	ErrUniqueViolation = errors.New("database: unique constraint violation")
+)
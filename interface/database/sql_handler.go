@@ -6,8 +6,29 @@ import "context"
 type SQLHandler interface {
 	Where(ctx context.Context, out interface{}, tableName string, clause string, args map[string]interface{}) error
 	Scan(ctx context.Context, out interface{}, tableName string, order string) error
+	// Select 条件に一致する行を並び順と件数を指定して取得する（limitが0以下の場合は件数を制限しない）
+	Select(ctx context.Context, out interface{}, tableName string, clause string, args map[string]interface{}, order string, limit int) error
+	// SelectForUpdate Selectと同様に取得した行をトランザクションの終了までロックする
+	// 他のトランザクションがロックしている行は待たずに読み飛ばす（トランザクション内でのみ利用できる）
+	SelectForUpdate(ctx context.Context, out interface{}, tableName string, clause string, args map[string]interface{}, order string, limit int) error
+	// SelectForUpdateWait SelectForUpdateと同様に行をロックする
+	// 他のトランザクションがロックしている行はロックが解放されるまで待つ（トランザクション内でのみ利用できる）
+	SelectForUpdateWait(ctx context.Context, out interface{}, tableName string, clause string, args map[string]interface{}, order string, limit int) error
 	Count(ctx context.Context, out *int, tableName string, clause string, args map[string]interface{}) error
+	// CountBy 条件に一致する行をgroupColumnの値ごとに数える
+	// outには`db:"<groupColumn>"`と`db:"count"`のタグを持つ構造体のスライスを渡す
+	CountBy(ctx context.Context, out interface{}, tableName string, groupColumn string, clause string, args map[string]interface{}) error
 	Create(ctx context.Context, in map[string]interface{}, tableName string) error
+	// Insert 行を追加し、採番・既定値を反映した行をout（構造体へのポインタ）に格納する
+	Insert(ctx context.Context, out interface{}, in map[string]interface{}, tableName string) error
 	Update(ctx context.Context, setParams map[string]interface{}, tableName string, whereClause string, whereParams map[string]interface{}) error
 	Delete(ctx context.Context, in map[string]interface{}, tableName string) error
+
+	// Begin トランザクションを開始し、トランザクションを保持したcontextを返す
+	// 返却されたcontextを渡した処理はすべて同一トランザクション内で実行される
+	Begin(ctx context.Context) (context.Context, error)
+	// Commit contextに保持されたトランザクションをコミットする
+	Commit(ctx context.Context) error
+	// Rollback contextに保持されたトランザクションをロールバックする
+	Rollback(ctx context.Context) error
 }
@@ -6,8 +6,11 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	// 店舗のタイムゾーンを解釈するため、タイムゾーンデータベースを持たない実行環境でも利用できるよう埋め込む
+	_ "time/tzdata"
 
 	"github.com/horsewin/echo-playground-v2/infrastructure"
+	"github.com/horsewin/echo-playground-v2/utils"
 	"github.com/rs/zerolog/log"
 )
 
@@ -17,6 +20,14 @@ const (
 )
 
 func main() {
+	// migrateサブコマンド: スキーマのマイグレーションを実行して終了する
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := infrastructure.Migrate(context.Background(), os.Args[2:]); err != nil {
+			log.Fatal().Err(err).Msg("Failed to migrate database")
+		}
+		return
+	}
+
 	// Wait for interrupt signal to gracefully shutdown the server with a timeout of 10 seconds.
 	// Use a buffered channel to avoid missing signals as recommended for signal.Notify
 	quit := make(chan os.Signal, 1)
@@ -24,6 +35,12 @@ func main() {
 
 	router := infrastructure.Router()
 
+	// Prometheus用の管理サーバを起動（SBCNTR_METRICS_ADDR指定時のみ）
+	infrastructure.StartMetricsServer()
+
+	// outboxに記録したドメインイベントの配信とWebhookの送信を開始（DB利用時のみ）
+	infrastructure.StartBackgroundWorkers()
+
 	// Start server
 	go func() {
 		if os.Getenv(envTLSCert) == "" || os.Getenv(envTLSKey) == "" {
@@ -42,11 +59,25 @@ func main() {
 
 	<-quit
 	log.Info().Msg("Caught SIGTERM, shutting down")
+
+	// readinessを失敗させ、ロードバランサーがタスクを切り離すまで待機する
+	infrastructure.MarkShuttingDown()
+	drainDelay := utils.NewAPIConfig().ShutdownDrainDelay
+	log.Info().Dur("drain_delay", drainDelay).Msg("Waiting for load balancer to drain connections")
+	time.Sleep(drainDelay)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	if err := router.Shutdown(ctx); err != nil {
 		log.Fatal().Err(err).Msg("Error during server shutdown")
 	}
+	// リクエストの処理が完了してから停止する。処理中のバッチは最後まで処理して結果を記録し、未配信のイベントは次のタスクが配信する
+	if err := infrastructure.StopBackgroundWorkers(ctx); err != nil {
+		log.Error().Err(err).Msg("Error during background worker shutdown")
+	}
+	if err := infrastructure.ShutdownTelemetry(ctx); err != nil {
+		log.Error().Err(err).Msg("Error during telemetry shutdown")
+	}
 	log.Info().Msg("Exited app")
 }
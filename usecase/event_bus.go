@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EventHandler ドメインイベントを処理する
+type EventHandler func(ctx context.Context, event model.DomainEvent) error
+
+// EventPublisher ドメインイベントを購読者に配信する
+type EventPublisher interface {
+	// Publish 業務処理のコミット後に呼び出す
+	Publish(ctx context.Context, events ...model.DomainEvent)
+}
+
+// defaultEventQueueSize バックグラウンドで配信を待つイベントの上限
+const defaultEventQueueSize = 1024
+
+// EventBus ドメインイベントを購読しているハンドラーに配信する
+//
+// Runの実行中はイベントをキューに積んでバックグラウンドで配信し、リクエストの処理を待たせない。
+// Runを実行していない場合（テストやRunの終了後）とキューが満杯の場合は、呼び出し元で同期的に配信する。
+// 業務処理はコミット済みのため、ハンドラーのエラーは呼び出し元に返さずlogとメトリクスに記録する
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]EventHandler
+
+	// runMu キューへの追加とRunの終了を排他し、終了時に積まれたイベントを取りこぼさないようにする
+	runMu   sync.RWMutex
+	running bool
+	queue   chan queuedEvent
+}
+
+// queuedEvent 配信を待つイベントと、配信時に引き継ぐcontext（ロガー・トレース）
+type queuedEvent struct {
+	ctx   context.Context
+	event model.DomainEvent
+}
+
+// NewEventBus ...
+func NewEventBus() *EventBus {
+	return &EventBus{
+		handlers: make(map[string][]EventHandler),
+		queue:    make(chan queuedEvent, defaultEventQueueSize),
+	}
+}
+
+// Subscribe eventNameのイベントを購読する（登録順に呼び出す）
+func (b *EventBus) Subscribe(eventName string, handler EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventName] = append(b.handlers[eventName], handler)
+}
+
+// Publish ...
+func (b *EventBus) Publish(ctx context.Context, events ...model.DomainEvent) {
+	// リクエストが切断されても購読者の処理は最後まで行う
+	ctx = context.WithoutCancel(ctx)
+
+	for _, event := range events {
+		if !b.enqueue(ctx, event) {
+			b.deliver(ctx, event)
+		}
+	}
+}
+
+// Run ctxがキャンセルされるまでキューに積まれたイベントを配信する（同時に1つだけ実行する）
+//
+// キャンセル時はキューに残っているイベントを配信してから戻る
+func (b *EventBus) Run(ctx context.Context) {
+	b.runMu.Lock()
+	b.running = true
+	b.runMu.Unlock()
+
+	for {
+		select {
+		case q := <-b.queue:
+			b.deliver(q.ctx, q.event)
+		case <-ctx.Done():
+			// 以降のPublishは同期的に配信するため、残っているイベントを配信すれば取りこぼしはない
+			b.runMu.Lock()
+			b.running = false
+			b.runMu.Unlock()
+			for {
+				select {
+				case q := <-b.queue:
+					b.deliver(q.ctx, q.event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// enqueue Runの実行中であればイベントをキューに積む（積めなかった場合はfalse）
+func (b *EventBus) enqueue(ctx context.Context, event model.DomainEvent) bool {
+	b.runMu.RLock()
+	defer b.runMu.RUnlock()
+	if !b.running {
+		return false
+	}
+	select {
+	case b.queue <- queuedEvent{ctx: ctx, event: event}:
+		return true
+	default:
+		zerolog.Ctx(ctx).Warn().Str("event", event.EventName()).Msg("event queue is full; delivering synchronously")
+		return false
+	}
+}
+
+// deliver イベントを購読しているハンドラーに順に配信する
+func (b *EventBus) deliver(ctx context.Context, event model.DomainEvent) {
+	b.mu.RLock()
+	handlers := b.handlers[event.EventName()]
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		b.dispatch(ctx, event, handler)
+	}
+}
+
+// dispatch 1つのハンドラーにイベントを配信する
+func (b *EventBus) dispatch(ctx context.Context, event model.DomainEvent, handler EventHandler) {
+	// スパンを作成
+	tracer := otel.Tracer("event-bus")
+	ctx, span := tracer.Start(ctx, "EventBus.dispatch",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("event.name", event.EventName()),
+	)
+
+	err := func() (err error) {
+		// ハンドラーのpanicで業務処理のレスポンスを失わないようにする
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("event handler panicked: %v", r)
+			}
+		}()
+		return handler(ctx, event)
+	}()
+	if err != nil {
+		span.RecordError(err)
+		eventHandlerFailuresCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("event.name", event.EventName())))
+		zerolog.Ctx(ctx).Error().Err(err).Str("event", event.EventName()).Msg("failed to handle domain event")
+	}
+}
+
+// publishEvents ドメインイベントを配信する（publisherがnilの場合は何もしない）
+func publishEvents(ctx context.Context, publisher EventPublisher, events ...model.DomainEvent) {
+	if publisher == nil {
+		return
+	}
+	publisher.Publish(ctx, events...)
+}
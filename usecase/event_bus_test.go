@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+)
+
+func TestEventBus_Publish(t *testing.T) {
+	bus := NewEventBus()
+
+	var received []string
+	bus.Subscribe(model.EventPetAdded, func(ctx context.Context, event model.DomainEvent) error {
+		received = append(received, "failing")
+		return errors.New("handler failed")
+	})
+	bus.Subscribe(model.EventPetAdded, func(ctx context.Context, event model.DomainEvent) error {
+		panic("handler panicked")
+	})
+	bus.Subscribe(model.EventPetAdded, func(ctx context.Context, event model.DomainEvent) error {
+		received = append(received, "ok:"+event.(model.PetAdded).Pet.ID)
+		return nil
+	})
+	bus.Subscribe(model.EventPetPriceChanged, func(ctx context.Context, event model.DomainEvent) error {
+		received = append(received, "price")
+		return nil
+	})
+
+	// 呼び出し元のcontextがキャンセルされていても最後まで配信する
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bus.Publish(ctx, model.PetAdded{Pet: model.Pet{ID: "1"}})
+
+	// 失敗・panicしたハンドラーがあっても後続のハンドラーに配信し、購読していないイベントは呼び出さない
+	if len(received) != 2 || received[0] != "failing" || received[1] != "ok:1" {
+		t.Errorf("unexpected handler calls: %v", received)
+	}
+
+	// Publisherがnilの場合は何もしない
+	publishEvents(ctx, nil, model.PetAdded{})
+}
+
+func TestEventBus_Run(t *testing.T) {
+	bus := NewEventBus()
+
+	release := make(chan struct{})
+	handled := make(chan string, 10)
+	bus.Subscribe(model.EventPetAdded, func(ctx context.Context, event model.DomainEvent) error {
+		<-release
+		handled <- event.(model.PetAdded).Pet.ID
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bus.Run(ctx)
+	}()
+	// Runが開始してキューに積むようになるまで待つ
+	for !bus.enqueue(context.Background(), model.PetAdded{Pet: model.Pet{ID: "0"}}) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// ハンドラーの完了を待たずに戻る
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(context.Background(), model.PetAdded{Pet: model.Pet{ID: "1"}}, model.PetAdded{Pet: model.Pet{ID: "2"}})
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("expected Publish to return without waiting for handlers")
+	}
+
+	// 停止時はキューに残っているイベントを配信してから戻る
+	cancel()
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Run to return after draining the queue")
+	}
+	if len(handled) != 3 {
+		t.Errorf("expected all queued events to be handled, got %d", len(handled))
+	}
+
+	// Runの終了後は同期的に配信する
+	bus.Publish(context.Background(), model.PetAdded{Pet: model.Pet{ID: "3"}})
+	if len(handled) != 4 {
+		t.Errorf("expected synchronous delivery after Run returned, got %d", len(handled))
+	}
+}
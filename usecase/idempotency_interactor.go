@@ -0,0 +1,182 @@
+package usecase
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// IdempotencyInteractorのデフォルト値
+const (
+	defaultIdempotencyTTL           = 24 * time.Hour
+	defaultIdempotencyLockTimeout   = time.Minute
+	defaultIdempotencyPurgeInterval = 10 * time.Minute
+	defaultIdempotencyBatchSize     = 100
+)
+
+// IdempotencyInteractor Idempotency-Keyを指定したリクエストの処理結果を記録し、再送時に同じレスポンスを返す
+type IdempotencyInteractor struct {
+	Repository repository.IdempotencyRepositoryInterface
+	// TTL 処理結果を保持する期間（経過後は同じキーを新しいリクエストとして扱う）
+	TTL time.Duration
+	// LockTimeout 処理中のまま完了しないリクエストを中断したとみなすまでの時間
+	LockTimeout time.Duration
+	// PurgeInterval 期限切れの処理結果を削除する間隔
+	PurgeInterval time.Duration
+	// BatchSize 1回に削除する期限切れの処理結果の上限
+	BatchSize int
+	// Now 現在時刻を返す関数（未指定の場合はtime.Now）
+	Now func() time.Time
+}
+
+// Begin リクエストの処理を開始する
+//
+// 初めてのキーの場合は処理中として記録したrecordを返し、処理後にCompleteまたはReleaseを呼び出すこと。
+// 同じリクエストの処理が完了している場合はreplayをtrueにして保存したレスポンスを持つrecordを返す。
+// 異なる内容のリクエストで使用済みの場合は00010E、同じキーのリクエストが処理中の場合は00011Eを返す
+func (interactor *IdempotencyInteractor) Begin(ctx context.Context, principal string, key string, fingerprint string) (record *model.IdempotencyRecord, replay bool, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("idempotency-interactor")
+	ctx, span := tracer.Start(ctx, "IdempotencyInteractor.Begin",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("principal", principal),
+	)
+
+	// 期限切れ・中断した記録を削除した直後に他のリクエストが記録した場合に備えて1回だけやり直す
+	for attempt := 0; attempt < 2; attempt++ {
+		now := interactor.now()
+		record = &model.IdempotencyRecord{
+			Principal:   principal,
+			Key:         key,
+			Fingerprint: fingerprint,
+			// DBの精度に合わせて条件付きの削除で一致させる
+			CreatedAt: now.Truncate(time.Microsecond),
+			ExpiresAt: now.Add(interactor.ttl()),
+		}
+		err = interactor.Repository.Create(ctx, record)
+		if err == nil {
+			return record, false, nil
+		}
+		if !stderrors.Is(err, repository.ErrIdempotencyKeyExists) {
+			span.RecordError(err)
+			return nil, false, errors.NewBusinessError("10003E", err)
+		}
+
+		var existing *model.IdempotencyRecord
+		existing, err = interactor.Repository.Find(ctx, principal, key)
+		if err != nil {
+			span.RecordError(err)
+			return nil, false, errors.NewBusinessError("10001E", err)
+		}
+		if existing == nil {
+			// 記録が削除された直後のためやり直す
+			continue
+		}
+		stale := !existing.Completed() && !now.Before(existing.CreatedAt.Add(interactor.lockTimeout()))
+		if !now.Before(existing.ExpiresAt) || stale {
+			if err = interactor.Repository.Delete(ctx, existing); err != nil {
+				span.RecordError(err)
+				return nil, false, errors.NewBusinessError("10003E", err)
+			}
+			continue
+		}
+
+		if existing.Fingerprint != fingerprint {
+			err = errors.NewBusinessError("00010E", fmt.Errorf("idempotency key reused with a different request"))
+			span.RecordError(err)
+			return nil, false, err
+		}
+		if !existing.Completed() {
+			err = errors.NewBusinessError("00011E", fmt.Errorf("idempotency key in progress"))
+			span.RecordError(err)
+			return nil, false, err
+		}
+		span.SetAttributes(attribute.Bool("replay", true))
+		return existing, true, nil
+	}
+
+	err = errors.NewBusinessError("00011E", fmt.Errorf("idempotency key contended"))
+	span.RecordError(err)
+	return nil, false, err
+}
+
+// Complete Beginで記録したリクエストのレスポンスを保存する
+func (interactor *IdempotencyInteractor) Complete(ctx context.Context, record *model.IdempotencyRecord, statusCode int, contentType string, body string) error {
+	if err := interactor.Repository.Complete(ctx, record, statusCode, contentType, body); err != nil {
+		return errors.NewBusinessError("10003E", err)
+	}
+	return nil
+}
+
+// Release Beginで記録したリクエストを削除し、同じキーで再試行できるようにする
+//
+// サーバーエラーなどレスポンスを保存しない場合に呼び出す
+func (interactor *IdempotencyInteractor) Release(ctx context.Context, record *model.IdempotencyRecord) error {
+	if err := interactor.Repository.Delete(ctx, record); err != nil {
+		return errors.NewBusinessError("10003E", err)
+	}
+	return nil
+}
+
+// Run ctxがキャンセルされるまでPurgeIntervalごとに期限切れの処理結果を削除する
+func (interactor *IdempotencyInteractor) Run(ctx context.Context) {
+	pollBatches(ctx, interactor.purgeInterval(), interactor.batchSize(), "expired idempotency keys", interactor.PurgeExpired)
+}
+
+// PurgeExpired 期限切れの処理結果を1バッチ分削除し、削除した件数を返す
+func (interactor *IdempotencyInteractor) PurgeExpired(ctx context.Context) (int, error) {
+	return interactor.Repository.DeleteExpired(ctx, interactor.now(), interactor.batchSize())
+}
+
+// now 現在時刻を返す
+func (interactor *IdempotencyInteractor) now() time.Time {
+	if interactor.Now != nil {
+		return interactor.Now()
+	}
+	return time.Now()
+}
+
+// ttl 処理結果を保持する期間を返す
+func (interactor *IdempotencyInteractor) ttl() time.Duration {
+	if interactor.TTL > 0 {
+		return interactor.TTL
+	}
+	return defaultIdempotencyTTL
+}
+
+// lockTimeout 処理中のリクエストを中断したとみなすまでの時間を返す
+func (interactor *IdempotencyInteractor) lockTimeout() time.Duration {
+	if interactor.LockTimeout > 0 {
+		return interactor.LockTimeout
+	}
+	return defaultIdempotencyLockTimeout
+}
+
+// purgeInterval 期限切れの処理結果を削除する間隔を返す
+func (interactor *IdempotencyInteractor) purgeInterval() time.Duration {
+	if interactor.PurgeInterval > 0 {
+		return interactor.PurgeInterval
+	}
+	return defaultIdempotencyPurgeInterval
+}
+
+// batchSize 1回に削除する期限切れの処理結果の上限を返す
+func (interactor *IdempotencyInteractor) batchSize() int {
+	if interactor.BatchSize > 0 {
+		return interactor.BatchSize
+	}
+	return defaultIdempotencyBatchSize
+}
@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+)
+
+// newMemoryIdempotencyInteractor インメモリDBを使うIdempotencyInteractorと、現在時刻を進める関数を作成する
+func newMemoryIdempotencyInteractor(t *testing.T) (*IdempotencyInteractor, func(d time.Duration)) {
+	t.Helper()
+	sqlHandler := newSeededSQLHandler(t)
+	now := time.Date(2099, 6, 15, 12, 0, 0, 0, time.UTC)
+	interactor := &IdempotencyInteractor{
+		Repository:  &repository.IdempotencyRepository{SQLHandler: sqlHandler},
+		TTL:         time.Hour,
+		LockTimeout: time.Minute,
+		Now:         func() time.Time { return now },
+	}
+	return interactor, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestIdempotencyInteractor_ReplaysCompletedResponse(t *testing.T) {
+	interactor, _ := newMemoryIdempotencyInteractor(t)
+	ctx := testContext()
+
+	record, replay, err := interactor.Begin(ctx, "user:user1", "key-1", "fp")
+	if err != nil || replay {
+		t.Fatalf("unexpected result: replay=%v err=%v", replay, err)
+	}
+	if err := interactor.Complete(ctx, record, 201, "application/json", `{"ok":true}`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stored, replay, err := interactor.Begin(ctx, "user:user1", "key-1", "fp")
+	if err != nil || !replay {
+		t.Fatalf("expected replay, got replay=%v err=%v", replay, err)
+	}
+	if *stored.StatusCode != 201 || *stored.ContentType != "application/json" || *stored.ResponseBody != `{"ok":true}` {
+		t.Errorf("unexpected stored response: %+v", stored)
+	}
+
+	// キーはリクエスト元ごとに区別する
+	if _, replay, err := interactor.Begin(ctx, "user:user2", "key-1", "fp"); err != nil || replay {
+		t.Errorf("expected new request for another principal, got replay=%v err=%v", replay, err)
+	}
+}
+
+func TestIdempotencyInteractor_RejectsMismatchedRequest(t *testing.T) {
+	interactor, _ := newMemoryIdempotencyInteractor(t)
+	ctx := testContext()
+
+	record, _, err := interactor.Begin(ctx, "user:user1", "key-1", "fp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := interactor.Complete(ctx, record, 200, "application/json", "{}"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, _, err = interactor.Begin(ctx, "user:user1", "key-1", "other")
+	assertBusinessError(t, err, "00010E")
+}
+
+func TestIdempotencyInteractor_InProgress(t *testing.T) {
+	interactor, advance := newMemoryIdempotencyInteractor(t)
+	ctx := testContext()
+
+	if _, _, err := interactor.Begin(ctx, "user:user1", "key-1", "fp"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _, err := interactor.Begin(ctx, "user:user1", "key-1", "fp")
+	assertBusinessError(t, err, "00011E")
+
+	// LockTimeoutを過ぎても完了しない場合は中断したとみなして処理し直す
+	advance(time.Minute)
+	if _, replay, err := interactor.Begin(ctx, "user:user1", "key-1", "fp"); err != nil || replay {
+		t.Errorf("expected new request after lock timeout, got replay=%v err=%v", replay, err)
+	}
+}
+
+func TestIdempotencyInteractor_Release(t *testing.T) {
+	interactor, _ := newMemoryIdempotencyInteractor(t)
+	ctx := testContext()
+
+	record, _, err := interactor.Begin(ctx, "user:user1", "key-1", "fp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := interactor.Release(ctx, record); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, replay, err := interactor.Begin(ctx, "user:user1", "key-1", "other"); err != nil || replay {
+		t.Errorf("expected new request after release, got replay=%v err=%v", replay, err)
+	}
+}
+
+func TestIdempotencyInteractor_Expiry(t *testing.T) {
+	interactor, advance := newMemoryIdempotencyInteractor(t)
+	ctx := testContext()
+
+	for _, key := range []string{"key-1", "key-2"} {
+		record, _, err := interactor.Begin(ctx, "user:user1", key, "fp")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := interactor.Complete(ctx, record, 200, "application/json", "{}"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	advance(time.Hour)
+
+	// TTLを過ぎたキーは異なる内容でも新しいリクエストとして扱う
+	if _, replay, err := interactor.Begin(ctx, "user:user1", "key-1", "other"); err != nil || replay {
+		t.Errorf("expected new request after expiry, got replay=%v err=%v", replay, err)
+	}
+
+	deleted, err := interactor.PurgeExpired(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 expired key purged, got %d", deleted)
+	}
+}
@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+)
+
+// resolveUserID 操作対象のユーザーIDを認証済みのリクエスト元から決定する
+//
+//   - ユーザー: 本人として操作する。リクエストでユーザーIDが指定された場合は本人と一致しなければならない
+//   - サービス: リクエストで指定されたユーザーの代理として操作する
+//   - 認証が無効（リクエスト元なし）: リクエストで指定されたユーザーIDをそのまま使う
+func resolveUserID(ctx context.Context, requested string) (string, error) {
+	principal := model.PrincipalFromContext(ctx)
+	if principal != nil && !principal.IsService() {
+		if requested != "" && requested != principal.ID {
+			return "", errors.NewBusinessError("00007E", fmt.Errorf("user %s requested user %s", principal.ID, requested))
+		}
+		return principal.ID, nil
+	}
+
+	if requested == "" {
+		return "", errors.NewBusinessError("00004E", fmt.Errorf("user_id is required"))
+	}
+	return requested, nil
+}
+
+// canAccessUserResource リクエスト元がユーザーのリソースにアクセスできるかを判定する
+//
+// エンドユーザーは本人のリソースのみ、サービスと認証が無効の場合はすべてのリソースにアクセスできる
+func canAccessUserResource(ctx context.Context, ownerID string) bool {
+	principal := model.PrincipalFromContext(ctx)
+	return principal == nil || principal.IsService() || principal.ID == ownerID
+}
+
+// requireService サービス間連携のための操作をリクエスト元が行えるかを確認する
+//
+// エンドユーザーは操作できず、サービスと認証が無効の場合のみ操作できる
+func requireService(ctx context.Context) error {
+	principal := model.PrincipalFromContext(ctx)
+	if principal != nil && !principal.IsService() {
+		return errors.NewBusinessError("00008E", fmt.Errorf("user %s is not a service", principal.ID))
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+)
+
+func TestResolveUserID(t *testing.T) {
+	user := &model.Principal{ID: "user1", Kind: model.PrincipalKindUser}
+	service := &model.Principal{ID: "batch", Kind: model.PrincipalKindService}
+
+	tests := []struct {
+		name          string
+		principal     *model.Principal
+		requested     string
+		expected      string
+		expectedError string
+	}{
+		{name: "ユーザー：指定なし", principal: user, expected: "user1"},
+		{name: "ユーザー：本人を指定", principal: user, requested: "user1", expected: "user1"},
+		{name: "ユーザー：他人を指定", principal: user, requested: "user2", expectedError: "00007E"},
+		{name: "サービス：代理で操作", principal: service, requested: "user2", expected: "user2"},
+		{name: "サービス：指定なし", principal: service, expectedError: "00004E"},
+		{name: "認証なし：指定あり", requested: "user2", expected: "user2"},
+		{name: "認証なし：指定なし", expectedError: "00004E"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := testContext()
+			if tt.principal != nil {
+				ctx = model.WithPrincipal(ctx, tt.principal)
+			}
+
+			actual, err := resolveUserID(ctx, tt.requested)
+			if tt.expectedError != "" {
+				assertBusinessError(t, err, tt.expectedError)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("expected %s but got %s", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ビジネスイベントのカウンター
+//
+// グローバルのメータープロバイダーは後から設定されたものに委譲されるため、
+// パッケージ初期化時に作成しても問題ない
+var (
+	meter = otel.Meter("usecase")
+
+	likesCounter, _ = meter.Int64Counter("pets.likes",
+		metric.WithDescription("Number of pets liked"),
+		metric.WithUnit("{like}"))
+	unlikesCounter, _ = meter.Int64Counter("pets.unlikes",
+		metric.WithDescription("Number of pets unliked"),
+		metric.WithUnit("{like}"))
+	petStatusTransitionsCounter, _ = meter.Int64Counter("pets.status_transitions",
+		metric.WithDescription("Number of pet status transitions by action"),
+		metric.WithUnit("{pet}"))
+	reservationsCreatedCounter, _ = meter.Int64Counter("reservations.created",
+		metric.WithDescription("Number of reservations created"),
+		metric.WithUnit("{reservation}"))
+	reservationTransitionsCounter, _ = meter.Int64Counter("reservations.transitions",
+		metric.WithDescription("Number of reservation status transitions by action"),
+		metric.WithUnit("{reservation}"))
+	notificationsReadCounter, _ = meter.Int64Counter("notifications.marked_read",
+		metric.WithDescription("Number of requests marking notifications as read"),
+		metric.WithUnit("{request}"))
+	notificationsCreatedCounter, _ = meter.Int64Counter("notifications.created",
+		metric.WithDescription("Number of notifications created from domain events by type"),
+		metric.WithUnit("{notification}"))
+	eventHandlerFailuresCounter, _ = meter.Int64Counter("events.handler_failures",
+		metric.WithDescription("Number of domain event handlers that failed by event name"),
+		metric.WithUnit("{failure}"))
+	notificationStreamsGauge, _ = meter.Int64UpDownCounter("notifications.stream.subscribers",
+		metric.WithDescription("Number of open notification stream subscriptions"),
+		metric.WithUnit("{subscription}"))
+	notificationStreamDroppedCounter, _ = meter.Int64Counter("notifications.stream.dropped",
+		metric.WithDescription("Number of notification stream subscriptions dropped for falling behind"),
+		metric.WithUnit("{subscription}"))
+	outboxPublishedCounter, _ = meter.Int64Counter("outbox.published",
+		metric.WithDescription("Number of outbox messages published by event type"),
+		metric.WithUnit("{message}"))
+	outboxFailuresCounter, _ = meter.Int64Counter("outbox.failures",
+		metric.WithDescription("Number of failed outbox publish attempts by event type"),
+		metric.WithUnit("{attempt}"))
+	outboxDeadLetteredCounter, _ = meter.Int64Counter("outbox.dead_lettered",
+		metric.WithDescription("Number of outbox messages moved to the dead-letter state by event type"),
+		metric.WithUnit("{message}"))
+	webhookDeliveriesCounter, _ = meter.Int64Counter("webhooks.deliveries",
+		metric.WithDescription("Number of webhook delivery attempts by event type and result"),
+		metric.WithUnit("{attempt}"))
+	webhookDeadLetteredCounter, _ = meter.Int64Counter("webhooks.dead_lettered",
+		metric.WithDescription("Number of webhook deliveries moved to the dead-letter state by event type"),
+		metric.WithUnit("{delivery}"))
+)
@@ -0,0 +1,213 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+)
+
+const (
+	// notificationHistorySize Last-Event-IDでの再開に備えて保持する直近のイベント数
+	notificationHistorySize = 256
+	// notificationSubscriberBuffer 購読者ごとの未送信イベントの上限（超えた購読者は切断する）
+	notificationSubscriberBuffer = 64
+)
+
+// ErrNotificationBrokerClosed 停止したブローカーを購読しようとした場合のエラー
+var ErrNotificationBrokerClosed = stderrors.New("notification broker is closed")
+
+// NotificationPublisher 通知のイベントをストリームの購読者に配信する
+type NotificationPublisher interface {
+	Publish(ctx context.Context, event model.NotificationEvent) error
+}
+
+// NotificationBroker プロセス内で通知のイベントをユーザごとの購読者に配信する
+//
+// イベントIDは"<エポック>-<連番>"の形式で、直近のイベントを保持してLast-Event-IDからの再開に使う。
+// エポックはプロセスの起動ごと（およびInvalidateのたび）に変わるため、別のタスクや
+// 取りこぼしの可能性がある時点のIDでは再開せず、クライアントに再取得を求める
+type NotificationBroker struct {
+	mu          sync.Mutex
+	epoch       string
+	seq         uint64
+	history     []brokerEntry
+	subscribers map[*NotificationSubscription]struct{}
+	closed      bool
+}
+
+// brokerEntry 採番済みのイベント
+type brokerEntry struct {
+	seq   uint64
+	event model.NotificationEvent
+}
+
+// NewNotificationBroker ...
+func NewNotificationBroker() *NotificationBroker {
+	return &NotificationBroker{
+		epoch:       newBrokerEpoch(),
+		subscribers: make(map[*NotificationSubscription]struct{}),
+	}
+}
+
+// newBrokerEpoch イベントIDの接頭辞にするランダムな値を返す
+func newBrokerEpoch() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Publish プロセス内の購読者にのみ配信する（LISTEN/NOTIFYを使わない構成のNotificationPublisher）
+func (b *NotificationBroker) Publish(_ context.Context, event model.NotificationEvent) error {
+	b.Deliver(event)
+	return nil
+}
+
+// Deliver イベントを採番して対象ユーザの購読者に配信する
+//
+// 購読者のバッファが一杯の場合、配信を待たずにその購読者を切断する。
+// クライアントはLast-Event-IDを指定して再接続し、保持しているイベントから再開する
+func (b *NotificationBroker) Deliver(event model.NotificationEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.seq++
+	event.ID = fmt.Sprintf("%s-%d", b.epoch, b.seq)
+	b.history = append(b.history, brokerEntry{seq: b.seq, event: event})
+	if len(b.history) > notificationHistorySize {
+		b.history = b.history[len(b.history)-notificationHistorySize:]
+	}
+
+	for sub := range b.subscribers {
+		if sub.userID != event.UserId {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			b.unsubscribe(sub)
+			notificationStreamDroppedCounter.Add(context.Background(), 1)
+		}
+	}
+}
+
+// Subscribe ユーザのイベントを購読する
+//
+// lastEventIDを指定した場合、そのイベントより後に配信された対象ユーザのイベントを返す。
+// lastEventIDから再開できない場合（別のタスクのID、保持期間外など）はresumedがfalseになる
+func (b *NotificationBroker) Subscribe(userID string, lastEventID string) (sub *NotificationSubscription, replay []model.NotificationEvent, resumed bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, nil, false, ErrNotificationBrokerClosed
+	}
+
+	resumed = true
+	if lastEventID != "" {
+		replay, resumed = b.replay(userID, lastEventID)
+	}
+
+	// 再開するイベントの取得と登録を同じロック内で行い、間のイベントを取りこぼさない
+	sub = &NotificationSubscription{
+		broker: b,
+		userID: userID,
+		events: make(chan model.NotificationEvent, notificationSubscriberBuffer),
+	}
+	b.subscribers[sub] = struct{}{}
+	notificationStreamsGauge.Add(context.Background(), 1)
+
+	return sub, replay, resumed, nil
+}
+
+// replay lastEventIDより後の対象ユーザのイベントを返す（ロックを取得した状態で呼び出す）
+func (b *NotificationBroker) replay(userID string, lastEventID string) ([]model.NotificationEvent, bool) {
+	epoch, seqStr, ok := strings.Cut(lastEventID, "-")
+	if !ok || epoch != b.epoch {
+		return nil, false
+	}
+	lastSeq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil || lastSeq > b.seq {
+		return nil, false
+	}
+	// 保持しているイベントより前のIDの場合、間のイベントが失われている
+	oldest := b.seq - uint64(len(b.history)) + 1
+	if lastSeq+1 < oldest {
+		return nil, false
+	}
+
+	var events []model.NotificationEvent
+	for _, entry := range b.history {
+		if entry.seq > lastSeq && entry.event.UserId == userID {
+			events = append(events, entry.event)
+		}
+	}
+	return events, true
+}
+
+// Invalidate 保持しているイベントを破棄し、すべての購読者を切断する
+//
+// LISTEN/NOTIFYの再接続などでイベントを取りこぼした可能性がある場合に呼び出す。
+// エポックを変えるため、再接続したクライアントは再開せずに再取得する
+func (b *NotificationBroker) Invalidate() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.epoch = newBrokerEpoch()
+	b.history = nil
+	for sub := range b.subscribers {
+		b.unsubscribe(sub)
+	}
+}
+
+// Close すべての購読者を切断し、以降の購読・配信を受け付けない
+//
+// サーバの停止時に呼び出し、ストリームのハンドラーを終了させる
+func (b *NotificationBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		b.unsubscribe(sub)
+	}
+}
+
+// unsubscribe 購読者を登録解除してチャネルを閉じる（ロックを取得した状態で呼び出す）
+func (b *NotificationBroker) unsubscribe(sub *NotificationSubscription) {
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+	delete(b.subscribers, sub)
+	close(sub.events)
+	notificationStreamsGauge.Add(context.Background(), -1)
+}
+
+// NotificationSubscription ユーザのイベントの購読
+type NotificationSubscription struct {
+	broker *NotificationBroker
+	userID string
+	events chan model.NotificationEvent
+}
+
+// Events 配信されたイベント（購読が終了するとcloseされる）
+func (s *NotificationSubscription) Events() <-chan model.NotificationEvent {
+	return s.events
+}
+
+// Close 購読を終了する
+func (s *NotificationSubscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.unsubscribe(s)
+}
@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+)
+
+// receive はバッファ済みのイベントをすべて取り出す
+func receive(sub *NotificationSubscription) []model.NotificationEvent {
+	var events []model.NotificationEvent
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestNotificationBroker_DeliversToUser(t *testing.T) {
+	broker := NewNotificationBroker()
+	sub1, _, _, err := broker.Subscribe("user1", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer sub1.Close()
+	sub2, _, _, _ := broker.Subscribe("user2", "")
+	defer sub2.Close()
+
+	broker.Deliver(model.NotificationEvent{Type: model.NotificationEventRead, UserId: "user1", NotificationID: 1})
+	broker.Deliver(model.NotificationEvent{Type: model.NotificationEventRead, UserId: "user2", NotificationID: 3})
+
+	events := receive(sub1)
+	if len(events) != 1 || events[0].NotificationID != 1 || events[0].ID == "" {
+		t.Fatalf("expected only user1's event with an id, got %+v", events)
+	}
+	if events := receive(sub2); len(events) != 1 || events[0].NotificationID != 3 {
+		t.Fatalf("expected only user2's event, got %+v", events)
+	}
+}
+
+func TestNotificationBroker_Resume(t *testing.T) {
+	broker := NewNotificationBroker()
+	sub, _, _, _ := broker.Subscribe("user1", "")
+	broker.Deliver(model.NotificationEvent{Type: model.NotificationEventRead, UserId: "user1", NotificationID: 1})
+	lastEventID := receive(sub)[0].ID
+	sub.Close()
+
+	// 切断中のイベント
+	broker.Deliver(model.NotificationEvent{Type: model.NotificationEventRead, UserId: "user1", NotificationID: 2})
+	broker.Deliver(model.NotificationEvent{Type: model.NotificationEventRead, UserId: "user2", NotificationID: 3})
+
+	t.Run("Last-Event-ID以降のイベントを再送する", func(t *testing.T) {
+		sub, replay, resumed, err := broker.Subscribe("user1", lastEventID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer sub.Close()
+		if !resumed || len(replay) != 1 || replay[0].NotificationID != 2 {
+			t.Fatalf("expected to resume with notification 2, got resumed=%v %+v", resumed, replay)
+		}
+	})
+
+	t.Run("別のタスク・不正なIDからは再開しない", func(t *testing.T) {
+		for _, id := range []string{"000000000000-1", "invalid", lastEventID + "0"} {
+			sub, replay, resumed, _ := broker.Subscribe("user1", id)
+			sub.Close()
+			if resumed || len(replay) != 0 {
+				t.Errorf("%s: expected reset, got resumed=%v %+v", id, resumed, replay)
+			}
+		}
+	})
+
+	t.Run("保持期間外のIDからは再開しない", func(t *testing.T) {
+		for i := 0; i < notificationHistorySize; i++ {
+			broker.Deliver(model.NotificationEvent{Type: model.NotificationEventRead, UserId: "user2"})
+		}
+		sub, _, resumed, _ := broker.Subscribe("user1", lastEventID)
+		sub.Close()
+		if resumed {
+			t.Error("expected events older than the history to require a reset")
+		}
+	})
+
+	t.Run("Invalidate後は再開しない", func(t *testing.T) {
+		sub, _, _, _ := broker.Subscribe("user1", "")
+		broker.Deliver(model.NotificationEvent{Type: model.NotificationEventRead, UserId: "user1"})
+		id := receive(sub)[0].ID
+		broker.Invalidate()
+		if _, ok := <-sub.Events(); ok {
+			t.Error("expected subscription to be closed by Invalidate")
+		}
+		sub, _, resumed, _ := broker.Subscribe("user1", id)
+		sub.Close()
+		if resumed {
+			t.Error("expected a reset after Invalidate")
+		}
+	})
+}
+
+func TestNotificationBroker_DropsSlowSubscriber(t *testing.T) {
+	broker := NewNotificationBroker()
+	sub, _, _, _ := broker.Subscribe("user1", "")
+	defer sub.Close()
+
+	for i := 0; i <= notificationSubscriberBuffer; i++ {
+		broker.Deliver(model.NotificationEvent{Type: model.NotificationEventRead, UserId: "user1"})
+	}
+
+	// バッファ分のイベントを受け取った後、チャネルが閉じられる
+	if events := receive(sub); len(events) != notificationSubscriberBuffer {
+		t.Errorf("expected %d buffered events, got %d", notificationSubscriberBuffer, len(events))
+	}
+	if _, ok := <-sub.Events(); ok {
+		t.Error("expected slow subscriber to be dropped")
+	}
+}
+
+func TestNotificationBroker_Close(t *testing.T) {
+	broker := NewNotificationBroker()
+	sub, _, _, _ := broker.Subscribe("user1", "")
+
+	broker.Close()
+	if _, ok := <-sub.Events(); ok {
+		t.Error("expected subscription to be closed")
+	}
+	// 閉じた購読のCloseは何もしない
+	sub.Close()
+
+	if _, _, _, err := broker.Subscribe("user1", ""); !errors.Is(err, ErrNotificationBrokerClosed) {
+		t.Errorf("expected ErrNotificationBrokerClosed, got %v", err)
+	}
+}
@@ -2,51 +2,100 @@ package usecase
 
 import (
 	"context"
+	stderrors "errors"
+	"strconv"
 
 	"github.com/horsewin/echo-playground-v2/domain/model"
 	"github.com/horsewin/echo-playground-v2/domain/model/errors"
 	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 )
 
 // NotificationInteractor ...
 type NotificationInteractor struct {
 	NotificationRepository repository.NotificationRepositoryInterface
+	// Publisher 通知の変更をストリームの購読者に配信する（nilの場合は配信しない）
+	Publisher NotificationPublisher
+	// Broker 通知ストリームの購読を受け付ける（nilの場合はストリームを利用できない）
+	Broker *NotificationBroker
 }
 
-// GetNotifications ...
-func (interactor *NotificationInteractor) GetNotifications(ctx context.Context, id string) (app model.Notifications, err error) {
+// GetNotifications ユーザの通知を新しい順に1ページ分取得する（idを指定した場合はその通知のみ）
+func (interactor *NotificationInteractor) GetNotifications(ctx context.Context, userID string, id string, filter *model.NotificationFilter) (app model.Notifications, page model.PageInfo, err error) {
+	// 取得対象のユーザーは認証済みのリクエスト元から決定する
+	userID, err = resolveUserID(ctx, userID)
+	if err != nil {
+		return app, page, err
+	}
+
 	if id == "" {
-		app, err = interactor.NotificationRepository.FindAll(ctx)
+		app, page, err = interactor.NotificationRepository.FindByUserID(ctx, userID, filter)
+		if stderrors.Is(err, repository.ErrInvalidNotificationFilter) {
+			return app, page, errors.NewBusinessError("00003E", err)
+		}
 		if err != nil {
-			return app, errors.NewBusinessError("10001E", err)
+			return app, page, errors.NewBusinessError("10001E", err)
 		}
 
 	} else {
-		app, err = interactor.NotificationRepository.Find(ctx, id)
+		app, err = interactor.NotificationRepository.Find(ctx, userID, id)
 		if err != nil {
-			return app, errors.NewBusinessError("10001E", err)
+			return app, page, errors.NewBusinessError("10001E", err)
 		}
+		page.TotalCount = len(app.Data)
+	}
+
+	return
+}
+
+// GetUnreadCount ユーザの未読通知の件数を返す（notificationTypeを指定した場合はその種類のみ）
+func (interactor *NotificationInteractor) GetUnreadCount(ctx context.Context, userID string, notificationType string) (count model.NotificationCount, err error) {
+	// 取得対象のユーザーは認証済みのリクエスト元から決定する
+	userID, err = resolveUserID(ctx, userID)
+	if err != nil {
+		return count, err
+	}
+
+	clause := "user_id = :user_id AND is_read = :is_read"
+	args := map[string]interface{}{
+		"user_id": userID,
+		"is_read": false,
+	}
+	if notificationType != "" {
+		clause += " AND type = :type"
+		args["type"] = notificationType
 	}
 
+	count, err = interactor.NotificationRepository.Count(ctx, clause, args)
+	if err != nil {
+		return count, errors.NewBusinessError("10001E", err)
+	}
 	return
 }
 
-// MarkNotificationsRead ...
-func (interactor *NotificationInteractor) MarkNotificationsRead(ctx context.Context, notificationId string) (err error) {
-	var clause string
-	var args map[string]interface{}
+// MarkNotificationsRead ユーザの通知を既読にする（notificationIdを省略した場合はユーザの未読通知すべて）
+func (interactor *NotificationInteractor) MarkNotificationsRead(ctx context.Context, userID string, notificationId string) (err error) {
+	// 更新対象のユーザーは認証済みのリクエスト元から決定する
+	userID, err = resolveUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// 他のユーザの通知を更新しないよう、常にuser_idで絞り込む
+	clause := "user_id = :user_id AND is_read = :is_read"
+	args := map[string]interface{}{
+		"user_id": userID,
+		"is_read": false,
+	}
+	scope := "all"
 
 	if notificationId != "" {
 		// 特定の通知のみを既読にする
-		clause = "id = :id AND is_read = :is_read"
-		args = map[string]interface{}{
-			"id":      notificationId,
-			"is_read": false,
-		}
-	} else {
-		// 全ての未読通知を既読にする（従来の動作）
-		clause = "is_read = :is_read"
-		args = map[string]interface{}{"is_read": false}
+		scope = "single"
+		clause = "id = :id AND " + clause
+		args["id"] = notificationId
 	}
 
 	err = interactor.NotificationRepository.Update(ctx, map[string]interface{}{"is_read": true}, clause, args)
@@ -54,6 +103,63 @@ func (interactor *NotificationInteractor) MarkNotificationsRead(ctx context.Cont
 	if err != nil {
 		return errors.NewBusinessError("10001E", err)
 	}
+	notificationsReadCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("scope", scope)))
+
+	event := model.NotificationEvent{Type: model.NotificationEventRead, UserId: userID}
+	if notificationId != "" {
+		event.NotificationID, _ = strconv.Atoi(notificationId)
+	}
+	interactor.publish(ctx, event)
 
 	return
 }
+
+// CreateNotification 通知を作成し、ストリームの購読者に配信する
+func (interactor *NotificationInteractor) CreateNotification(ctx context.Context, notification *model.Notification) (err error) {
+	err = interactor.NotificationRepository.Create(ctx, notification)
+	if err != nil {
+		return errors.NewBusinessError("10006E", err)
+	}
+	notificationsCreatedCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("type", notification.Type)))
+
+	created := *notification
+	interactor.publish(ctx, model.NotificationEvent{
+		Type:         model.NotificationEventCreated,
+		UserId:       notification.UserId,
+		Notification: &created,
+	})
+	return
+}
+
+// SubscribeNotifications ユーザの通知のイベントを購読する
+//
+// lastEventIDから再開できない場合はresumedがfalseになるため、クライアントに一覧の再取得を求める
+func (interactor *NotificationInteractor) SubscribeNotifications(ctx context.Context, userID string, lastEventID string) (sub *NotificationSubscription, replay []model.NotificationEvent, resumed bool, err error) {
+	// 購読するユーザーは認証済みのリクエスト元から決定する
+	userID, err = resolveUserID(ctx, userID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	if interactor.Broker == nil {
+		return nil, nil, false, errors.NewBusinessError("10001E", stderrors.New("notification stream is not configured"))
+	}
+	sub, replay, resumed, err = interactor.Broker.Subscribe(userID, lastEventID)
+	if err != nil {
+		return nil, nil, false, errors.NewBusinessError("10001E", err)
+	}
+	return
+}
+
+// publish 通知の変更を配信する
+//
+// 通知の更新はコミット済みのため、配信に失敗してもエラーにはせずlogに記録する
+// （ストリームの購読者は一覧の再取得で追いつく）
+func (interactor *NotificationInteractor) publish(ctx context.Context, event model.NotificationEvent) {
+	if interactor.Publisher == nil {
+		return
+	}
+	if err := interactor.Publisher.Publish(ctx, event); err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Str("type", event.Type).Msg("failed to publish notification event")
+	}
+}
@@ -3,10 +3,12 @@ package usecase
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/horsewin/echo-playground-v2/domain/model"
-	business_errors "github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
 )
 
 // MockNotificationRepository はテスト用のモックリポジトリ
@@ -16,21 +18,47 @@ type MockNotificationRepository struct {
 	FindAllResult model.Notifications
 	FindAllError  error
 	UpdateError   error
+	CountResult   model.NotificationCount
+	CountError    error
+	CreateError   error
+
+	// 呼び出し時の引数を記録する
+	FindUserID   string
+	UpdateClause string
+	UpdateArgs   map[string]interface{}
+	CountClause  string
+	CountArgs    map[string]interface{}
+	Created      []model.Notification
 }
 
-func (m *MockNotificationRepository) Find(ctx context.Context, id string) (model.Notifications, error) {
+func (m *MockNotificationRepository) Find(ctx context.Context, userID string, id string) (model.Notifications, error) {
+	m.FindUserID = userID
 	return m.FindResult, m.FindError
 }
 
-func (m *MockNotificationRepository) FindAll(ctx context.Context) (model.Notifications, error) {
-	return m.FindAllResult, m.FindAllError
+func (m *MockNotificationRepository) FindByUserID(ctx context.Context, userID string, filter *model.NotificationFilter) (model.Notifications, model.PageInfo, error) {
+	m.FindUserID = userID
+	return m.FindAllResult, model.PageInfo{TotalCount: len(m.FindAllResult.Data)}, m.FindAllError
 }
 
 func (m *MockNotificationRepository) Count(ctx context.Context, query string, args map[string]interface{}) (model.NotificationCount, error) {
-	return model.NotificationCount{}, nil
+	m.CountClause = query
+	m.CountArgs = args
+	return m.CountResult, m.CountError
+}
+
+func (m *MockNotificationRepository) Create(ctx context.Context, input *model.Notification) error {
+	if m.CreateError != nil {
+		return m.CreateError
+	}
+	input.ID = len(m.Created) + 1
+	m.Created = append(m.Created, *input)
+	return nil
 }
 
 func (m *MockNotificationRepository) Update(ctx context.Context, in map[string]interface{}, query string, args map[string]interface{}) error {
+	m.UpdateClause = query
+	m.UpdateArgs = args
 	return m.UpdateError
 }
 
@@ -49,7 +77,7 @@ func TestNotificationInteractor_GetNotifications_WithID(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	result, err := interactor.GetNotifications(ctx, "1")
+	result, _, err := interactor.GetNotifications(ctx, "user1", "1", nil)
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -69,8 +97,8 @@ func TestNotificationInteractor_GetNotifications_WithoutID(t *testing.T) {
 	mockRepo := &MockNotificationRepository{
 		FindAllResult: model.Notifications{
 			Data: []model.Notification{
+				{ID: 2, UserId: "user1", Title: "Test Notification 2", Message: "Test message 2", IsRead: true},
 				{ID: 1, UserId: "user1", Title: "Test Notification 1", Message: "Test message 1", IsRead: false},
-				{ID: 2, UserId: "user2", Title: "Test Notification 2", Message: "Test message 2", IsRead: true},
 			},
 		},
 		FindAllError: nil,
@@ -81,7 +109,7 @@ func TestNotificationInteractor_GetNotifications_WithoutID(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	result, err := interactor.GetNotifications(ctx, "")
+	result, _, err := interactor.GetNotifications(ctx, "user1", "", nil)
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -91,6 +119,9 @@ func TestNotificationInteractor_GetNotifications_WithoutID(t *testing.T) {
 	if len(result.Data) != 2 {
 		t.Errorf("expected 2 notifications but got %d", len(result.Data))
 	}
+	if mockRepo.FindUserID != "user1" {
+		t.Errorf("expected notifications to be filtered by user1 but got %q", mockRepo.FindUserID)
+	}
 }
 
 func TestNotificationInteractor_GetNotifications_FindError(t *testing.T) {
@@ -103,22 +134,9 @@ func TestNotificationInteractor_GetNotifications_FindError(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	_, err := interactor.GetNotifications(ctx, "1")
-
-	if err == nil {
-		t.Errorf("expected error but got nil")
-		return
-	}
+	_, _, err := interactor.GetNotifications(ctx, "user1", "1", nil)
 
-	var be business_errors.BusinessError
-	if !errors.As(err, &be) {
-		t.Errorf("expected BusinessError but got %T", err)
-		return
-	}
-
-	if be.Code() != "10001E" {
-		t.Errorf("expected error code 10001E but got %s", be.Code())
-	}
+	assertBusinessError(t, err, "10001E")
 }
 
 func TestNotificationInteractor_MarkNotificationsRead_WithID(t *testing.T) {
@@ -131,7 +149,7 @@ func TestNotificationInteractor_MarkNotificationsRead_WithID(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err := interactor.MarkNotificationsRead(ctx, "1")
+	err := interactor.MarkNotificationsRead(ctx, "user1", "1")
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -148,11 +166,49 @@ func TestNotificationInteractor_MarkNotificationsRead_WithoutID(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err := interactor.MarkNotificationsRead(ctx, "")
+	err := interactor.MarkNotificationsRead(ctx, "user1", "")
 
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
+
+	// 全件既読の場合もリクエスト元のユーザの通知のみを更新する
+	if !strings.Contains(mockRepo.UpdateClause, "user_id = :user_id") || mockRepo.UpdateArgs["user_id"] != "user1" {
+		t.Errorf("expected update to be scoped to user1: %s %v", mockRepo.UpdateClause, mockRepo.UpdateArgs)
+	}
+}
+
+func TestNotificationInteractor_MarkNotificationsRead_AuthenticatedUser(t *testing.T) {
+	mockRepo := &MockNotificationRepository{}
+	interactor := &NotificationInteractor{
+		NotificationRepository: mockRepo,
+	}
+
+	ctx := model.WithPrincipal(context.Background(), &model.Principal{ID: "user2", Kind: model.PrincipalKindUser})
+	if err := interactor.MarkNotificationsRead(ctx, "", "3"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mockRepo.UpdateArgs["user_id"] != "user2" || mockRepo.UpdateArgs["id"] != "3" {
+		t.Errorf("expected update to be scoped to the authenticated user: %v", mockRepo.UpdateArgs)
+	}
+
+	// 他のユーザの通知は操作できない
+	err := interactor.MarkNotificationsRead(ctx, "user1", "")
+	assertBusinessError(t, err, "00007E")
+	_, _, err = interactor.GetNotifications(ctx, "user1", "", nil)
+	assertBusinessError(t, err, "00007E")
+}
+
+func TestNotificationInteractor_RequiresUser(t *testing.T) {
+	interactor := &NotificationInteractor{
+		NotificationRepository: &MockNotificationRepository{},
+	}
+
+	// 認証が無効の場合はuser_idの指定が必須
+	_, _, err := interactor.GetNotifications(context.Background(), "", "", nil)
+	assertBusinessError(t, err, "00004E")
+	err = interactor.MarkNotificationsRead(context.Background(), "", "")
+	assertBusinessError(t, err, "00004E")
 }
 
 func TestNotificationInteractor_MarkNotificationsRead_UpdateError(t *testing.T) {
@@ -165,20 +221,217 @@ func TestNotificationInteractor_MarkNotificationsRead_UpdateError(t *testing.T)
 	}
 
 	ctx := context.Background()
-	err := interactor.MarkNotificationsRead(ctx, "1")
+	err := interactor.MarkNotificationsRead(ctx, "user1", "1")
 
-	if err == nil {
-		t.Errorf("expected error but got nil")
-		return
+	assertBusinessError(t, err, "10001E")
+}
+
+func TestNotificationInteractor_WithMemoryDB(t *testing.T) {
+	sqlHandler := newSeededSQLHandler(t)
+
+	interactor := &NotificationInteractor{
+		NotificationRepository: &repository.NotificationRepository{SQLHandler: sqlHandler},
 	}
 
-	var be business_errors.BusinessError
-	if !errors.As(err, &be) {
-		t.Errorf("expected BusinessError but got %T", err)
-		return
+	ctx := context.Background()
+	if err := interactor.MarkNotificationsRead(ctx, "user1", "1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, _, err := interactor.GetNotifications(ctx, "user1", "1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Data) != 1 || !result.Data[0].IsRead {
+		t.Errorf("expected notification 1 to be read: %+v", result.Data)
+	}
+
+	result, _, err = interactor.GetNotifications(ctx, "user1", "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Data) != 3 || result.Data[0].ID != 4 {
+		t.Errorf("expected user1's 3 notifications ordered by id desc: %+v", result.Data)
+	}
+
+	// 他のユーザの通知はIDを指定しても取得できない
+	result, _, err = interactor.GetNotifications(ctx, "user2", "1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Data) != 0 {
+		t.Errorf("expected no notifications but got %+v", result.Data)
+	}
+}
+
+func TestNotificationInteractor_MarkAllRead_DoesNotTouchOtherUsers(t *testing.T) {
+	sqlHandler := newSeededSQLHandler(t)
+	repo := &repository.NotificationRepository{SQLHandler: sqlHandler}
+	interactor := &NotificationInteractor{NotificationRepository: repo}
+	ctx := context.Background()
+
+	// user2の未読通知を用意する
+	if err := sqlHandler.Update(ctx, map[string]interface{}{"is_read": false}, repository.NotificationTable,
+		"user_id = :user_id", map[string]interface{}{"user_id": "user2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if be.Code() != "10001E" {
-		t.Errorf("expected error code 10001E but got %s", be.Code())
+	if err := interactor.MarkNotificationsRead(ctx, "user1", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
+
+	unread := func(userID string) int {
+		count, err := repo.Count(ctx, "user_id = :user_id AND is_read = :is_read", map[string]interface{}{"user_id": userID, "is_read": false})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return count.Data
+	}
+	if unread("user1") != 0 {
+		t.Errorf("expected all of user1's notifications to be read")
+	}
+	if unread("user2") != 1 {
+		t.Errorf("expected user2's notification to stay unread")
+	}
+}
+
+func TestNotificationInteractor_GetNotifications_PaginationAndFilters(t *testing.T) {
+	sqlHandler := newSeededSQLHandler(t)
+	interactor := &NotificationInteractor{
+		NotificationRepository: &repository.NotificationRepository{SQLHandler: sqlHandler},
+	}
+	ctx := context.Background()
+
+	// user1の通知（id: 4, 2, 1）を2件ずつ新しい順に取得する
+	first, page, err := interactor.GetNotifications(ctx, "user1", "", &model.NotificationFilter{Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(first.Data) != 2 || first.Data[0].ID != 4 || first.Data[1].ID != 2 {
+		t.Errorf("unexpected first page: %+v", first.Data)
+	}
+	if page.NextCursor == nil || page.TotalCount != 3 {
+		t.Fatalf("unexpected page info: %+v", page)
+	}
+
+	second, page, err := interactor.GetNotifications(ctx, "user1", "", &model.NotificationFilter{Limit: 2, Cursor: *page.NextCursor})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(second.Data) != 1 || second.Data[0].ID != 1 || page.NextCursor != nil {
+		t.Errorf("unexpected last page: %+v %+v", second.Data, page)
+	}
+
+	if err := interactor.MarkNotificationsRead(ctx, "user1", "2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	unread := false
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name     string
+		filter   model.NotificationFilter
+		expected []int
+	}{
+		{name: "未読のみ", filter: model.NotificationFilter{IsRead: &unread}, expected: []int{4, 1}},
+		{name: "種類", filter: model.NotificationFilter{Type: "campaign"}, expected: []int{4}},
+		{name: "指定日時より後", filter: model.NotificationFilter{Since: past}, expected: []int{4, 2, 1}},
+		{name: "指定日時より後（該当なし）", filter: model.NotificationFilter{Since: future}, expected: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, page, err := interactor.GetNotifications(ctx, "user1", "", &tt.filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ids := make([]int, 0, len(result.Data))
+			for _, n := range result.Data {
+				ids = append(ids, n.ID)
+			}
+			if len(ids) != len(tt.expected) || page.TotalCount != len(tt.expected) {
+				t.Fatalf("expected %v but got %v (total %d)", tt.expected, ids, page.TotalCount)
+			}
+			for i := range ids {
+				if ids[i] != tt.expected[i] {
+					t.Errorf("expected %v but got %v", tt.expected, ids)
+				}
+			}
+		})
+	}
+
+	_, _, err = interactor.GetNotifications(ctx, "user1", "", &model.NotificationFilter{Cursor: "not-a-cursor"})
+	assertBusinessError(t, err, "00003E")
+}
+
+func TestNotificationInteractor_GetUnreadCount(t *testing.T) {
+	sqlHandler := newSeededSQLHandler(t)
+	interactor := &NotificationInteractor{
+		NotificationRepository: &repository.NotificationRepository{SQLHandler: sqlHandler},
+	}
+	ctx := context.Background()
+
+	count, err := interactor.GetUnreadCount(ctx, "user1", "")
+	if err != nil || count.Data != 3 {
+		t.Errorf("expected 3 unread notifications but got %d (%v)", count.Data, err)
+	}
+	count, err = interactor.GetUnreadCount(ctx, "user1", "campaign")
+	if err != nil || count.Data != 1 {
+		t.Errorf("expected 1 unread campaign notification but got %d (%v)", count.Data, err)
+	}
+	count, err = interactor.GetUnreadCount(ctx, "user2", "")
+	if err != nil || count.Data != 0 {
+		t.Errorf("expected no unread notifications for user2 but got %d (%v)", count.Data, err)
+	}
+
+	// DBのエラーはBusinessErrorとして返す
+	interactor.NotificationRepository = &MockNotificationRepository{CountError: errors.New("database error")}
+	_, err = interactor.GetUnreadCount(ctx, "user1", "")
+	assertBusinessError(t, err, "10001E")
+}
+
+func TestNotificationInteractor_MarkNotificationsRead_Publishes(t *testing.T) {
+	broker := NewNotificationBroker()
+	interactor := &NotificationInteractor{
+		NotificationRepository: &MockNotificationRepository{},
+		Publisher:              broker,
+		Broker:                 broker,
+	}
+
+	ctx := model.WithPrincipal(context.Background(), &model.Principal{ID: "user1", Kind: model.PrincipalKindUser})
+	sub, _, resumed, err := interactor.SubscribeNotifications(ctx, "", "")
+	if err != nil || !resumed {
+		t.Fatalf("unexpected subscribe result: resumed=%v err=%v", resumed, err)
+	}
+	defer sub.Close()
+
+	if err := interactor.MarkNotificationsRead(ctx, "", "2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := interactor.MarkNotificationsRead(ctx, "", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	events := receive(sub)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", events)
+	}
+	if events[0].Type != model.NotificationEventRead || events[0].UserId != "user1" || events[0].NotificationID != 2 {
+		t.Errorf("unexpected event for single read: %+v", events[0])
+	}
+	if events[1].NotificationID != 0 {
+		t.Errorf("expected mark-all-read event without notification id, got %+v", events[1])
+	}
+
+	// 他のユーザのストリームは購読できない
+	if _, _, _, err := interactor.SubscribeNotifications(ctx, "user2", ""); err == nil {
+		t.Error("expected error when subscribing to another user's stream")
+	}
+}
+
+func TestNotificationInteractor_SubscribeNotifications_NoBroker(t *testing.T) {
+	interactor := &NotificationInteractor{NotificationRepository: &MockNotificationRepository{}}
+
+	_, _, _, err := interactor.SubscribeNotifications(context.Background(), "user1", "")
+	assertBusinessError(t, err, "10001E")
 }
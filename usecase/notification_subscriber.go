@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+)
+
+// notificationPageSize 通知の対象のペット・お気に入りを取得する1ページの件数
+const notificationPageSize = 100
+
+// NotificationSubscriber ドメインイベントから影響を受けるユーザの通知を作成する
+type NotificationSubscriber struct {
+	Notifications      *NotificationInteractor
+	PetRepository      repository.PetRepositoryInterface
+	FavoriteRepository repository.FavoriteRepositoryInterface
+}
+
+// Register 通知を作成するドメインイベントを購読する
+func (s *NotificationSubscriber) Register(bus *EventBus) {
+	bus.Subscribe(model.EventPetAdded, s.onPetAdded)
+	bus.Subscribe(model.EventPetPriceChanged, s.onPetPriceChanged)
+	bus.Subscribe(model.EventPetStatusChanged, s.onPetStatusChanged)
+	bus.Subscribe(model.EventPetDeleted, s.onPetDeleted)
+	bus.Subscribe(model.EventReservationCreated, s.onReservationCreated)
+	bus.Subscribe(model.EventReservationStatusChanged, s.onReservationStatusChanged)
+}
+
+// onPetAdded 同じ犬種のペットをお気に入り登録しているユーザに新しいペットを知らせる
+func (s *NotificationSubscriber) onPetAdded(ctx context.Context, event model.DomainEvent) error {
+	e, ok := event.(model.PetAdded)
+	if !ok {
+		return unexpectedEvent(event)
+	}
+
+	notification := model.Notification{
+		Type:    model.NotificationTypeNewPet,
+		Title:   "新しい子が増えました",
+		Message: fmt.Sprintf("可愛い「%s」が新しく増えました。ぜひチェックしてください！", e.Pet.Name),
+	}
+
+	// 同じ犬種のペットはページごとに取得し、複数のペットをお気に入り登録しているユーザには1件だけ通知する
+	seen := make(map[string]bool)
+	filter := &model.PetFilter{Breed: e.Pet.Breed, Limit: notificationPageSize}
+	var errs []error
+	for {
+		sameBreed, err := s.PetRepository.Find(ctx, filter)
+		if err != nil {
+			return stderrors.Join(append(errs, err)...)
+		}
+		petIDs := make([]string, 0, len(sameBreed.Data))
+		for _, p := range sameBreed.Data {
+			if p.ID != e.Pet.ID {
+				petIDs = append(petIDs, p.ID)
+			}
+		}
+		if err := s.notifyFavoriteUsers(ctx, petIDs, seen, notification); err != nil {
+			errs = append(errs, err)
+		}
+
+		if sameBreed.NextCursor == nil {
+			return stderrors.Join(errs...)
+		}
+		filter.Cursor = *sameBreed.NextCursor
+	}
+}
+
+// onPetPriceChanged ペットをお気に入り登録しているユーザに価格の変更を知らせる
+func (s *NotificationSubscriber) onPetPriceChanged(ctx context.Context, event model.DomainEvent) error {
+	e, ok := event.(model.PetPriceChanged)
+	if !ok {
+		return unexpectedEvent(event)
+	}
+
+	return s.notifyFavoriteUsers(ctx, []string{e.Pet.ID}, make(map[string]bool), model.Notification{
+		Type:  model.NotificationTypePriceChange,
+		Title: "お気に入りペットの価格変更",
+		Message: fmt.Sprintf("お気に入り登録している「%s」の価格が%s円から%s円に変更されました。",
+			e.Pet.Name, formatPrice(e.OldPrice), formatPrice(e.NewPrice)),
+	})
+}
+
+// onPetStatusChanged ペットをお気に入り登録しているユーザにステータスの変更を知らせる
+func (s *NotificationSubscriber) onPetStatusChanged(ctx context.Context, event model.DomainEvent) error {
+	e, ok := event.(model.PetStatusChanged)
+	if !ok {
+		return unexpectedEvent(event)
+	}
+
+	notification := model.Notification{Type: model.NotificationTypePetStatus}
+	switch e.To {
+	case model.PetStatusAvailable:
+		notification.Title = "お気に入りペットの見学予約を再開しました"
+		notification.Message = fmt.Sprintf("お気に入り登録している「%s」の見学予約を再び受け付けています。", e.Pet.Name)
+	case model.PetStatusReserved:
+		notification.Title = "お気に入りペットに見学予約が入りました"
+		notification.Message = fmt.Sprintf("お気に入り登録している「%s」に見学予約が入りました。", e.Pet.Name)
+	case model.PetStatusAdopted:
+		notification.Title = "お気に入りペットの家族が決まりました"
+		notification.Message = fmt.Sprintf("お気に入り登録している「%s」の新しい家族が決まりました。", e.Pet.Name)
+	case model.PetStatusWithdrawn:
+		notification.Title = "お気に入りペットの掲載が終了しました"
+		notification.Message = fmt.Sprintf("お気に入り登録している「%s」の掲載が終了しました。", e.Pet.Name)
+	default:
+		return fmt.Errorf("unknown pet status: %s", e.To)
+	}
+
+	return s.notifyFavoriteUsers(ctx, []string{e.Pet.ID}, make(map[string]bool), notification)
+}
+
+// onPetDeleted ペットをお気に入り登録していたユーザに掲載の終了を知らせる
+//
+// お気に入りはペットとともに削除されているため、イベントに含まれるユーザに通知する
+func (s *NotificationSubscriber) onPetDeleted(ctx context.Context, event model.DomainEvent) error {
+	e, ok := event.(model.PetDeleted)
+	if !ok {
+		return unexpectedEvent(event)
+	}
+
+	return s.notify(ctx, e.FavoriteUserIDs, model.Notification{
+		Type:    model.NotificationTypePetStatus,
+		Title:   "お気に入りペットの掲載が終了しました",
+		Message: fmt.Sprintf("お気に入り登録している「%s」の掲載が終了しました。", e.Pet.Name),
+	})
+}
+
+// onReservationCreated 予約したユーザに予約の受付を知らせる
+func (s *NotificationSubscriber) onReservationCreated(ctx context.Context, event model.DomainEvent) error {
+	e, ok := event.(model.ReservationCreated)
+	if !ok {
+		return unexpectedEvent(event)
+	}
+
+	date := formatReservationDate(e.Reservation.SlotStartsAt)
+	return s.notify(ctx, []string{e.Reservation.UserId}, model.Notification{
+		Type:    model.NotificationTypeReservation,
+		Title:   "見学予約を受け付けました",
+		Message: fmt.Sprintf("「%s」の見学予約（%s）を受け付けました。確定までお待ちください。", s.petName(ctx, e.Reservation.PetId), date),
+	})
+}
+
+// onReservationStatusChanged 予約したユーザに予約の確定・キャンセル・日程変更を知らせる
+func (s *NotificationSubscriber) onReservationStatusChanged(ctx context.Context, event model.DomainEvent) error {
+	e, ok := event.(model.ReservationStatusChanged)
+	if !ok {
+		return unexpectedEvent(event)
+	}
+
+	name := s.petName(ctx, e.Reservation.PetId)
+	date := formatReservationDate(e.Reservation.ReservationDateTime)
+	notification := model.Notification{Type: model.NotificationTypeReservation}
+	switch e.Action {
+	case ReservationActionConfirm:
+		notification.Title = "見学予約が確定しました"
+		notification.Message = fmt.Sprintf("「%s」の見学予約（%s）が確定しました。", name, date)
+	case ReservationActionCancel:
+		notification.Title = "見学予約がキャンセルされました"
+		notification.Message = fmt.Sprintf("「%s」の見学予約（%s）がキャンセルされました。", name, date)
+	case ReservationActionReschedule:
+		notification.Title = "見学予約の日程を変更しました"
+		notification.Message = fmt.Sprintf("「%s」の見学予約を%sに変更しました。確定までお待ちください。", name, date)
+	default:
+		return fmt.Errorf("unknown reservation action: %s", e.Action)
+	}
+
+	return s.notify(ctx, []string{e.Reservation.UserId}, notification)
+}
+
+// notifyFavoriteUsers いずれかのペットをお気に入り登録しているユーザに通知を作成する
+//
+// ユーザはページごとに取得して通知し、seenに含まれる（通知済みの）ユーザは除く
+func (s *NotificationSubscriber) notifyFavoriteUsers(ctx context.Context, petIDs []string, seen map[string]bool, template model.Notification) error {
+	var errs []error
+	afterUserID := ""
+	for len(petIDs) > 0 {
+		favorites, err := s.FavoriteRepository.FindByPetIDs(ctx, petIDs, afterUserID, notificationPageSize)
+		if err != nil {
+			return stderrors.Join(append(errs, err)...)
+		}
+
+		userIDs := make([]string, 0, len(favorites))
+		for _, f := range favorites {
+			if !seen[f.UserId] {
+				seen[f.UserId] = true
+				userIDs = append(userIDs, f.UserId)
+			}
+		}
+		if err := s.notify(ctx, userIDs, template); err != nil {
+			errs = append(errs, err)
+		}
+
+		if len(favorites) < notificationPageSize {
+			break
+		}
+		afterUserID = favorites[len(favorites)-1].UserId
+	}
+	return stderrors.Join(errs...)
+}
+
+// petName 通知に表示するペットの名前を返す（取得できない場合はペットID）
+func (s *NotificationSubscriber) petName(ctx context.Context, petID string) string {
+	pets, err := s.PetRepository.Find(ctx, &model.PetFilter{ID: petID})
+	if err != nil || len(pets.Data) == 0 {
+		return petID
+	}
+	return pets.Data[0].Name
+}
+
+// notify ユーザごとに通知を作成する（一部のユーザで失敗しても残りのユーザには作成する）
+func (s *NotificationSubscriber) notify(ctx context.Context, userIDs []string, template model.Notification) error {
+	var errs []error
+	for _, userID := range userIDs {
+		notification := template
+		notification.UserId = userID
+		if err := s.Notifications.CreateNotification(ctx, &notification); err != nil {
+			errs = append(errs, fmt.Errorf("user %s: %w", userID, err))
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// unexpectedEvent 購読したイベント名と型が一致しない場合のエラー
+func unexpectedEvent(event model.DomainEvent) error {
+	return fmt.Errorf("unexpected event type %T for %s", event, event.EventName())
+}
+
+// formatPrice 価格を通知の表示用に整形する
+func formatPrice(price float64) string {
+	return strconv.FormatFloat(price, 'f', -1, 64)
+}
+
+// formatReservationDate 見学予定日時を店舗の現地時刻で通知の表示用に整形する
+func formatReservationDate(t time.Time) string {
+	return t.Format("2006/01/02 15:04")
+}
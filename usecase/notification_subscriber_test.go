@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+)
+
+// newSubscriberFixture 通知を作成する購読者を登録したイベントバスとストリームのブローカーを返す
+func newSubscriberFixture(t *testing.T) (database.SQLHandler, *EventBus, *NotificationBroker) {
+	t.Helper()
+	sqlHandler := newSeededSQLHandler(t)
+
+	broker := NewNotificationBroker()
+	bus := NewEventBus()
+	subscriber := &NotificationSubscriber{
+		Notifications: &NotificationInteractor{
+			NotificationRepository: &repository.NotificationRepository{SQLHandler: sqlHandler},
+			Publisher:              broker,
+		},
+		PetRepository:      &repository.PetRepository{SQLHandler: sqlHandler},
+		FavoriteRepository: repository.FavoriteRepository{SQLHandler: sqlHandler},
+	}
+	subscriber.Register(bus)
+	return sqlHandler, bus, broker
+}
+
+// notificationsOf ユーザの通知を新しい順に返す
+func notificationsOf(t *testing.T, sqlHandler database.SQLHandler, userID string) []model.Notification {
+	t.Helper()
+	repo := &repository.NotificationRepository{SQLHandler: sqlHandler}
+	notifications, _, err := repo.FindByUserID(testContext(), userID, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return notifications.Data
+}
+
+func TestNotificationSubscriber_PetEvents(t *testing.T) {
+	sqlHandler, bus, broker := newSubscriberFixture(t)
+	ctx := testContext()
+
+	favorites := repository.FavoriteRepository{SQLHandler: sqlHandler}
+	for _, f := range []model.Favorite{
+		{PetId: "1", UserId: "fan1"},
+		{PetId: "2", UserId: "fan1"},
+		{PetId: "2", UserId: "fan2"},
+	} {
+		if err := favorites.Create(ctx, &f); err != nil {
+			t.Fatalf("failed to create favorite: %v", err)
+		}
+	}
+	sub, _, _, _ := broker.Subscribe("fan2", "")
+	defer sub.Close()
+
+	pets := &repository.PetRepository{SQLHandler: sqlHandler}
+	found, err := pets.Find(ctx, &model.PetFilter{ID: "2"})
+	if err != nil || len(found.Data) != 1 {
+		t.Fatalf("failed to find pet: %v", err)
+	}
+	pet := model.Pet{ID: "2", Name: found.Data[0].Name, Breed: found.Data[0].Breed}
+
+	t.Run("価格変更はお気に入り登録しているユーザに通知する", func(t *testing.T) {
+		bus.Publish(ctx, model.PetPriceChanged{Pet: pet, OldPrice: 30000, NewPrice: 25000})
+
+		for _, userID := range []string{"fan1", "fan2"} {
+			notifications := notificationsOf(t, sqlHandler, userID)
+			if len(notifications) != 1 || notifications[0].Type != model.NotificationTypePriceChange ||
+				!strings.Contains(notifications[0].Message, "30000円から25000円") {
+				t.Errorf("%s: unexpected notifications %+v", userID, notifications)
+			}
+		}
+
+		// 作成した通知はストリームにも配信する
+		events := receive(sub)
+		if len(events) != 1 || events[0].Type != model.NotificationEventCreated || events[0].Notification.ID == 0 {
+			t.Errorf("expected created event with the stored notification, got %+v", events)
+		}
+	})
+
+	t.Run("新しいペットは同じ犬種をお気に入り登録しているユーザに1件だけ通知する", func(t *testing.T) {
+		bus.Publish(ctx, model.PetAdded{Pet: model.Pet{ID: "100", Name: "新入り", Breed: pet.Breed}})
+
+		notifications := notificationsOf(t, sqlHandler, "fan2")
+		if len(notifications) != 2 || notifications[0].Type != model.NotificationTypeNewPet ||
+			!strings.Contains(notifications[0].Message, "新入り") {
+			t.Errorf("unexpected notifications %+v", notifications)
+		}
+		newPet := 0
+		for _, n := range notificationsOf(t, sqlHandler, "fan1") {
+			if n.Type == model.NotificationTypeNewPet {
+				newPet++
+			}
+		}
+		if newPet != 1 {
+			t.Errorf("expected exactly one new_pet notification for fan1, got %d", newPet)
+		}
+	})
+
+	t.Run("ステータスの変更はお気に入り登録しているユーザに通知する", func(t *testing.T) {
+		bus.Publish(ctx, model.PetStatusChanged{Pet: pet, Action: PetActionAdopt, From: model.PetStatusReserved, To: model.PetStatusAdopted})
+
+		for _, userID := range []string{"fan1", "fan2"} {
+			notifications := notificationsOf(t, sqlHandler, userID)
+			if len(notifications) == 0 || notifications[0].Type != model.NotificationTypePetStatus ||
+				!strings.Contains(notifications[0].Message, "新しい家族が決まりました") {
+				t.Errorf("%s: unexpected notifications %+v", userID, notifications)
+			}
+		}
+	})
+
+	t.Run("削除はイベントに含まれるお気に入り登録していたユーザに通知する", func(t *testing.T) {
+		before := len(notificationsOf(t, sqlHandler, "fan2"))
+		bus.Publish(ctx, model.PetDeleted{Pet: pet, FavoriteUserIDs: []string{"fan1"}})
+
+		notifications := notificationsOf(t, sqlHandler, "fan1")
+		if len(notifications) == 0 || notifications[0].Type != model.NotificationTypePetStatus ||
+			!strings.Contains(notifications[0].Message, "掲載が終了しました") {
+			t.Errorf("unexpected notifications %+v", notifications)
+		}
+		if after := len(notificationsOf(t, sqlHandler, "fan2")); after != before {
+			t.Errorf("expected no notification for users not in the event, got %d more", after-before)
+		}
+	})
+}
+
+func TestNotificationSubscriber_PagesFavorites(t *testing.T) {
+	sqlHandler, bus, _ := newSubscriberFixture(t)
+	ctx := testContext()
+
+	// 1ページの件数を超えるユーザがお気に入り登録している
+	favorites := repository.FavoriteRepository{SQLHandler: sqlHandler}
+	users := notificationPageSize*2 + 1
+	for i := 0; i < users; i++ {
+		if err := favorites.Create(ctx, &model.Favorite{PetId: "1", UserId: fmt.Sprintf("fan%03d", i)}); err != nil {
+			t.Fatalf("failed to create favorite: %v", err)
+		}
+	}
+
+	bus.Publish(ctx, model.PetPriceChanged{Pet: model.Pet{ID: "1", Name: "ポチ"}, OldPrice: 30000, NewPrice: 25000})
+
+	for _, userID := range []string{"fan000", fmt.Sprintf("fan%03d", notificationPageSize), fmt.Sprintf("fan%03d", users-1)} {
+		if notifications := notificationsOf(t, sqlHandler, userID); len(notifications) != 1 {
+			t.Errorf("%s: expected one notification, got %d", userID, len(notifications))
+		}
+	}
+}
+
+func TestNotificationSubscriber_ReservationEvents(t *testing.T) {
+	sqlHandler, bus, _ := newSubscriberFixture(t)
+	ctx := testContext()
+
+	petInteractor := &PetInteractor{
+		PetRepository:         &repository.PetRepository{SQLHandler: sqlHandler},
+		ReservationRepository: &repository.ReservationRepository{SQLHandler: sqlHandler},
+		ShopRepository:        &repository.ShopRepository{SQLHandler: sqlHandler},
+		TransactionManager:    &repository.TransactionManager{SQLHandler: sqlHandler},
+		Events:                bus,
+	}
+	err := petInteractor.CreateReservation(ctx, &model.Reservation{
+		PetId:        "1",
+		UserId:       "visitor",
+		Email:        "visitor@example.com",
+		FullName:     "Visitor",
+		SlotStartsAt: testSlotStartsAt(6, 20, 14),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	notifications := notificationsOf(t, sqlHandler, "visitor")
+	if len(notifications) != 1 || notifications[0].Type != model.NotificationTypeReservation ||
+		!strings.Contains(notifications[0].Message, "2099/06/20 14:00") {
+		t.Fatalf("unexpected notifications after reservation: %+v", notifications)
+	}
+
+	reservationInteractor := &ReservationInteractor{
+		ReservationRepository: &repository.ReservationRepository{SQLHandler: sqlHandler},
+		TransactionManager:    &repository.TransactionManager{SQLHandler: sqlHandler},
+		Events:                bus,
+	}
+	reservations, _ := reservationInteractor.GetReservations(ctx, "visitor", "")
+	if _, err := reservationInteractor.ConfirmReservation(ctx, reservations.Data[0].ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	notifications = notificationsOf(t, sqlHandler, "visitor")
+	if len(notifications) != 2 || notifications[0].Title != "見学予約が確定しました" {
+		t.Errorf("unexpected notifications after confirmation: %+v", notifications)
+	}
+
+	// 遷移できなかった場合は通知しない
+	if _, err := reservationInteractor.ConfirmReservation(ctx, reservations.Data[0].ID); err == nil {
+		t.Fatal("expected error confirming twice")
+	}
+	if notifications := notificationsOf(t, sqlHandler, "visitor"); len(notifications) != 2 {
+		t.Errorf("expected no notification for a failed transition, got %d", len(notifications))
+	}
+}
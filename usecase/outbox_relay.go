@@ -0,0 +1,211 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"github.com/horsewin/echo-playground-v2/utils"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OutboxRelayのデフォルト値
+const (
+	defaultOutboxBatchSize      = 50
+	defaultOutboxPollInterval   = time.Second
+	defaultOutboxMaxAttempts    = 10
+	defaultOutboxInitialBackoff = time.Second
+	defaultOutboxMaxBackoff     = 5 * time.Minute
+	defaultOutboxLease          = 15 * time.Minute
+)
+
+// OutboxPublisher outboxに記録したイベントを外部システムに配信する
+//
+// Publishはトランザクションの外で呼び出す。配信後の記録に失敗した場合はリースの期限後に再配信するため、
+// 受信側はMessage IDで重複を除外すること
+type OutboxPublisher interface {
+	Publish(ctx context.Context, message model.OutboxMessage) error
+}
+
+// OutboxRelay outboxに記録されたイベントを定期的に取り出して配信する
+type OutboxRelay struct {
+	Repository         repository.OutboxRepositoryInterface
+	TransactionManager repository.TransactionManagerInterface
+	Publisher          OutboxPublisher
+	// BatchSize 1回に取得して配信するイベントの上限
+	BatchSize int
+	// PollInterval 配信待ちのイベントを確認する間隔
+	PollInterval time.Duration
+	// MaxAttempts 配信を試行する回数の上限（超えた場合はdeadにする）
+	MaxAttempts int
+	// InitialBackoff 初回の失敗から再試行までの待ち時間（失敗するたびに倍にする）
+	InitialBackoff time.Duration
+	// MaxBackoff 再試行までの待ち時間の上限
+	MaxBackoff time.Duration
+	// Lease 取得したイベントを他のリレーに取得させない期間（1バッチの配信にかかる時間より長くする）
+	Lease time.Duration
+	// Now 現在時刻を返す関数（未指定の場合はtime.Now）
+	Now func() time.Time
+}
+
+// Run ctxがキャンセルされるまでPollIntervalごとにイベントを配信する
+//
+// キャンセル時に処理中のバッチは最後まで配信して結果を記録してから戻る
+func (r *OutboxRelay) Run(ctx context.Context) {
+	pollBatches(ctx, r.pollInterval(), r.batchSize(), "outbox messages", r.RelayOnce)
+}
+
+// RelayOnce 配信待ちのイベントを1バッチ分配信し、処理したイベントの数を返す
+//
+// 配信中にトランザクションと行ロックを保持しないよう、取得と結果の記録はそれぞれ短いトランザクションで行い、
+// 配信はその間にトランザクションの外で行う
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (processed int, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("outbox-relay")
+	ctx, span := tracer.Start(ctx, "OutboxRelay.RelayOnce",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 取得したイベントはリースの期限まで他のリレーに取得されない
+	var messages []model.OutboxMessage
+	err = r.TransactionManager.Do(ctx, func(ctx context.Context) error {
+		now := r.now()
+		claimed, err := r.Repository.ClaimPending(ctx, now, now.Add(r.lease()), r.batchSize())
+		if err != nil {
+			return errors.NewBusinessError("10001E", err)
+		}
+		messages = claimed
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	publishErrs := make([]error, len(messages))
+	for i, message := range messages {
+		publishErrs[i] = r.Publisher.Publish(ctx, message)
+	}
+
+	// 記録に失敗した場合はリースの期限後に再配信される
+	err = r.TransactionManager.Do(ctx, func(ctx context.Context) error {
+		for i, message := range messages {
+			if err := r.record(ctx, message, publishErrs[i]); err != nil {
+				return errors.NewBusinessError("10003E", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+	processed = len(messages)
+
+	// 結果の属性を追加
+	span.SetAttributes(
+		attribute.Int("result_count", processed),
+	)
+	return processed, nil
+}
+
+// record 1件のイベントの配信の結果を記録する（publishErrがnilの場合は配信済みにする）
+func (r *OutboxRelay) record(ctx context.Context, message model.OutboxMessage, publishErr error) error {
+	attempts := message.Attempts + 1
+	eventType := metric.WithAttributes(attribute.String("event_type", message.EventType))
+
+	if publishErr == nil {
+		outboxPublishedCounter.Add(ctx, 1, eventType)
+		return r.Repository.MarkPublished(ctx, message.ID, attempts, r.now())
+	}
+
+	outboxFailuresCounter.Add(ctx, 1, eventType)
+	status := model.OutboxStatusPending
+	if attempts >= r.maxAttempts() {
+		status = model.OutboxStatusDead
+		outboxDeadLetteredCounter.Add(ctx, 1, eventType)
+		zerolog.Ctx(ctx).Error().Err(publishErr).Int64("id", message.ID).Str("event_type", message.EventType).
+			Int("attempts", attempts).Msg("outbox message moved to dead letter")
+	} else {
+		zerolog.Ctx(ctx).Warn().Err(publishErr).Int64("id", message.ID).Str("event_type", message.EventType).
+			Int("attempts", attempts).Msg("failed to publish outbox message")
+	}
+	return r.Repository.MarkFailed(ctx, message.ID, attempts, status, r.now().Add(r.backoff(attempts)), publishErr.Error())
+}
+
+// backoff attempts回目の失敗から再試行までの待ち時間を返す
+func (r *OutboxRelay) backoff(attempts int) time.Duration {
+	initial, max := r.InitialBackoff, r.MaxBackoff
+	if initial <= 0 {
+		initial = defaultOutboxInitialBackoff
+	}
+	if max <= 0 {
+		max = defaultOutboxMaxBackoff
+	}
+	return utils.ExponentialBackoff(initial, max, attempts)
+}
+
+// now 現在時刻を返す
+func (r *OutboxRelay) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// batchSize 1回に取得して配信するイベントの上限を返す
+func (r *OutboxRelay) batchSize() int {
+	if r.BatchSize > 0 {
+		return r.BatchSize
+	}
+	return defaultOutboxBatchSize
+}
+
+// pollInterval 配信待ちのイベントを確認する間隔を返す
+func (r *OutboxRelay) pollInterval() time.Duration {
+	if r.PollInterval > 0 {
+		return r.PollInterval
+	}
+	return defaultOutboxPollInterval
+}
+
+// lease 取得したイベントを他のリレーに取得させない期間を返す
+func (r *OutboxRelay) lease() time.Duration {
+	if r.Lease > 0 {
+		return r.Lease
+	}
+	return defaultOutboxLease
+}
+
+// maxAttempts 配信を試行する回数の上限を返す
+func (r *OutboxRelay) maxAttempts() int {
+	if r.MaxAttempts > 0 {
+		return r.MaxAttempts
+	}
+	return defaultOutboxMaxAttempts
+}
+
+// recordOutbox 外部システムに配信するイベントをoutboxに記録する（outboxがnilの場合は何もしない）
+//
+// 業務データの変更と同じトランザクション内で呼び出す
+func recordOutbox(ctx context.Context, outbox repository.OutboxRepositoryInterface, events ...model.DomainEvent) error {
+	if outbox == nil {
+		return nil
+	}
+	for _, event := range events {
+		if err := outbox.Add(ctx, event); err != nil {
+			return errors.NewBusinessError("10003E", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+)
+
+// MockOutboxPublisher はOutboxPublisherのモック実装
+// Errが設定されている場合は配信に失敗する
+type MockOutboxPublisher struct {
+	Err       error
+	Published []model.OutboxMessage
+}
+
+func (m *MockOutboxPublisher) Publish(ctx context.Context, message model.OutboxMessage) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	m.Published = append(m.Published, message)
+	return nil
+}
+
+// MockOutboxRepository はOutboxRepositoryInterfaceのモック実装
+// AddErrorが設定されている場合は記録に失敗する
+type MockOutboxRepository struct {
+	repository.OutboxRepositoryInterface
+	AddError error
+}
+
+func (m *MockOutboxRepository) Add(ctx context.Context, event model.DomainEvent) error {
+	return m.AddError
+}
+
+// outboxMessages outboxに記録されたイベントを古い順に返す
+func outboxMessages(t *testing.T, sqlHandler database.SQLHandler) []model.OutboxMessage {
+	t.Helper()
+	var messages []model.OutboxMessage
+	if err := sqlHandler.Select(testContext(), &messages, repository.OutboxTable, "", nil, "id", 0); err != nil {
+		t.Fatalf("failed to select outbox: %v", err)
+	}
+	return messages
+}
+
+func TestPetInteractor_RecordsOutbox(t *testing.T) {
+	interactor, sqlHandler := newMemoryPetInteractor(t)
+	interactor.Outbox = &repository.OutboxRepository{SQLHandler: sqlHandler}
+	ctx := testContext()
+
+	if err := interactor.UpdateLikeCount(ctx, &model.InputUpdateLikeRequest{PetId: "1", UserId: "user456", Value: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := interactor.CreateReservation(ctx, &model.Reservation{PetId: "1", UserId: "user456", SlotStartsAt: testSlotStartsAt(12, 31, 10)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages := outboxMessages(t, sqlHandler)
+	// 予約の作成ではペットのステータスの変更も記録する
+	if len(messages) != 3 || messages[0].EventType != model.EventPetLiked || messages[1].EventType != model.EventReservationCreated ||
+		messages[2].EventType != model.EventPetStatusChanged {
+		t.Fatalf("unexpected outbox messages: %+v", messages)
+	}
+	var liked model.PetLiked
+	if err := json.Unmarshal([]byte(messages[0].Payload), &liked); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if liked.PetId != "1" || liked.UserId != "user456" || !liked.Value || liked.Likes != 11 {
+		t.Errorf("unexpected payload: %+v", liked)
+	}
+	if messages[0].Status != model.OutboxStatusPending || messages[0].Attempts != 0 {
+		t.Errorf("expected pending message, got %+v", messages[0])
+	}
+
+	// 記録に失敗した場合は業務データの変更もロールバックする
+	interactor.Outbox = &MockOutboxRepository{AddError: errors.New("database error")}
+	err = interactor.UpdateLikeCount(ctx, &model.InputUpdateLikeRequest{PetId: "2", UserId: "user456", Value: true})
+	assertBusinessError(t, err, "10003E")
+	favMap, _ := interactor.FavoriteRepository.FindByUserId(ctx, "user456")
+	if _, ok := favMap["2"]; ok {
+		t.Errorf("expected favorite to be rolled back")
+	}
+}
+
+func TestReservationInteractor_RecordsOutbox(t *testing.T) {
+	sqlHandler := newSeededSQLHandler(t)
+	ctx := testContext()
+	reservations := &repository.ReservationRepository{SQLHandler: sqlHandler}
+	if err := reservations.Create(ctx, &model.Reservation{PetId: "1", UserId: "user1", SlotStartsAt: testSlotStartsAt(12, 31, 10)}); err != nil {
+		t.Fatalf("failed to create reservation: %v", err)
+	}
+	created, _ := reservations.FindByUserID(ctx, "user1", "")
+
+	interactor := &ReservationInteractor{
+		ReservationRepository: reservations,
+		TransactionManager:    &repository.TransactionManager{SQLHandler: sqlHandler},
+		Outbox:                &repository.OutboxRepository{SQLHandler: sqlHandler},
+	}
+	if _, err := interactor.ConfirmReservation(ctx, created.Data[0].ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages := outboxMessages(t, sqlHandler)
+	if len(messages) != 1 || messages[0].EventType != model.EventReservationStatusChanged {
+		t.Fatalf("unexpected outbox messages: %+v", messages)
+	}
+	var changed model.ReservationStatusChanged
+	if err := json.Unmarshal([]byte(messages[0].Payload), &changed); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if changed.Action != ReservationActionConfirm || changed.From != model.ReservationStatusPending || changed.To != model.ReservationStatusConfirmed {
+		t.Errorf("unexpected payload: %+v", changed)
+	}
+}
+
+func TestOutboxRelay_RelayOnce(t *testing.T) {
+	sqlHandler := newSeededSQLHandler(t)
+	ctx := testContext()
+	outbox := &repository.OutboxRepository{SQLHandler: sqlHandler}
+	for _, petID := range []string{"1", "2"} {
+		if err := outbox.Add(ctx, model.PetLiked{PetId: petID, UserId: "user1", Value: true}); err != nil {
+			t.Fatalf("failed to add outbox message: %v", err)
+		}
+	}
+
+	now := time.Now().Add(time.Minute)
+	publisher := &MockOutboxPublisher{}
+	relay := &OutboxRelay{
+		Repository:         outbox,
+		TransactionManager: &repository.TransactionManager{SQLHandler: sqlHandler},
+		Publisher:          publisher,
+		BatchSize:          1,
+		MaxAttempts:        2,
+		InitialBackoff:     time.Second,
+		Now:                func() time.Time { return now },
+	}
+
+	t.Run("古い順にBatchSizeずつ配信して配信済みにする", func(t *testing.T) {
+		n, err := relay.RelayOnce(ctx)
+		if err != nil || n != 1 {
+			t.Fatalf("expected 1 message relayed, got %d (%v)", n, err)
+		}
+		messages := outboxMessages(t, sqlHandler)
+		if len(publisher.Published) != 1 || publisher.Published[0].ID != messages[0].ID {
+			t.Fatalf("unexpected published messages: %+v", publisher.Published)
+		}
+		if messages[0].Status != model.OutboxStatusPublished || messages[0].Attempts != 1 || messages[0].PublishedAt == nil {
+			t.Errorf("expected first message to be published, got %+v", messages[0])
+		}
+		if messages[1].Status != model.OutboxStatusPending {
+			t.Errorf("expected second message to stay pending, got %+v", messages[1])
+		}
+	})
+
+	t.Run("失敗したイベントはバックオフ後に再試行し、上限に達したらdeadにする", func(t *testing.T) {
+		publisher.Err = errors.New("connection refused")
+
+		if n, err := relay.RelayOnce(ctx); err != nil || n != 1 {
+			t.Fatalf("expected 1 message relayed, got %d (%v)", n, err)
+		}
+		failed := outboxMessages(t, sqlHandler)[1]
+		if failed.Status != model.OutboxStatusPending || failed.Attempts != 1 ||
+			failed.LastError == nil || *failed.LastError != "connection refused" ||
+			!failed.NextAttemptAt.Equal(now.Add(time.Second)) {
+			t.Fatalf("expected message to be retried after backoff, got %+v", failed)
+		}
+
+		// バックオフ中は配信しない
+		if n, err := relay.RelayOnce(ctx); err != nil || n != 0 {
+			t.Fatalf("expected no message relayed during backoff, got %d (%v)", n, err)
+		}
+
+		now = now.Add(time.Second)
+		if n, err := relay.RelayOnce(ctx); err != nil || n != 1 {
+			t.Fatalf("expected 1 message relayed, got %d (%v)", n, err)
+		}
+		if dead := outboxMessages(t, sqlHandler)[1]; dead.Status != model.OutboxStatusDead || dead.Attempts != 2 {
+			t.Errorf("expected message to be dead lettered, got %+v", dead)
+		}
+
+		// deadのイベントは配信しない
+		now = now.Add(time.Hour)
+		if n, err := relay.RelayOnce(ctx); err != nil || n != 0 {
+			t.Errorf("expected dead message not to be relayed, got %d (%v)", n, err)
+		}
+	})
+
+	t.Run("再試行で配信できた場合は直前の失敗の内容を消す", func(t *testing.T) {
+		if err := outbox.Add(ctx, model.PetLiked{PetId: "3", UserId: "user1", Value: true}); err != nil {
+			t.Fatalf("failed to add outbox message: %v", err)
+		}
+		now = now.Add(time.Minute)
+
+		publisher.Err = errors.New("connection refused")
+		if n, err := relay.RelayOnce(ctx); err != nil || n != 1 {
+			t.Fatalf("expected 1 message relayed, got %d (%v)", n, err)
+		}
+		if failed := outboxMessages(t, sqlHandler)[2]; failed.Status != model.OutboxStatusPending || failed.LastError == nil {
+			t.Fatalf("expected message to be retried, got %+v", failed)
+		}
+
+		publisher.Err = nil
+		now = now.Add(time.Second)
+		if n, err := relay.RelayOnce(ctx); err != nil || n != 1 {
+			t.Fatalf("expected 1 message relayed, got %d (%v)", n, err)
+		}
+		published := outboxMessages(t, sqlHandler)[2]
+		if published.Status != model.OutboxStatusPublished || published.Attempts != 2 || published.PublishedAt == nil {
+			t.Fatalf("expected message to be published, got %+v", published)
+		}
+		if published.LastError != nil {
+			t.Errorf("expected last error to be cleared, got %q", *published.LastError)
+		}
+	})
+}
+
+// outboxPublisherFunc 関数をOutboxPublisherとして使う
+type outboxPublisherFunc func(ctx context.Context, message model.OutboxMessage) error
+
+func (f outboxPublisherFunc) Publish(ctx context.Context, message model.OutboxMessage) error {
+	return f(ctx, message)
+}
+
+func TestOutboxRelay_Lease(t *testing.T) {
+	sqlHandler := newSeededSQLHandler(t)
+	ctx := testContext()
+	outbox := &repository.OutboxRepository{SQLHandler: sqlHandler}
+	transactionManager := &repository.TransactionManager{SQLHandler: sqlHandler}
+	for _, petID := range []string{"1", "2"} {
+		if err := outbox.Add(ctx, model.PetLiked{PetId: petID, UserId: "user1", Value: true}); err != nil {
+			t.Fatalf("failed to add outbox message: %v", err)
+		}
+	}
+
+	now := time.Now().Add(time.Minute)
+	relay := &OutboxRelay{
+		Repository:         outbox,
+		TransactionManager: transactionManager,
+		BatchSize:          1,
+		Lease:              time.Minute,
+		Now:                func() time.Time { return now },
+	}
+
+	t.Run("配信はトランザクションの外で行い、取得したイベントはリースの期限まで取得されない", func(t *testing.T) {
+		relay.Publisher = outboxPublisherFunc(func(ctx context.Context, message model.OutboxMessage) error {
+			var locked []model.OutboxMessage
+			if err := sqlHandler.SelectForUpdate(ctx, &locked, repository.OutboxTable, "", nil, "id", 0); !errors.Is(err, database.ErrNoTransaction) {
+				t.Errorf("expected to publish outside of a transaction, got %v", err)
+			}
+			if claimed := outboxMessages(t, sqlHandler)[0]; !claimed.NextAttemptAt.Equal(now.Add(time.Minute)) {
+				t.Errorf("expected the lease to be committed before publishing, got %+v", claimed)
+			}
+			return nil
+		})
+		if n, err := relay.RelayOnce(ctx); err != nil || n != 1 {
+			t.Fatalf("expected 1 message relayed, got %d (%v)", n, err)
+		}
+		if published := outboxMessages(t, sqlHandler)[0]; published.Status != model.OutboxStatusPublished {
+			t.Errorf("expected message to be published, got %+v", published)
+		}
+	})
+
+	t.Run("結果を記録する前に停止したイベントはリースの期限後に再び配信する", func(t *testing.T) {
+		err := transactionManager.Do(ctx, func(ctx context.Context) error {
+			_, err := outbox.ClaimPending(ctx, now, now.Add(time.Minute), 1)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("failed to claim: %v", err)
+		}
+
+		publisher := &MockOutboxPublisher{}
+		relay.Publisher = publisher
+		if n, err := relay.RelayOnce(ctx); err != nil || n != 0 {
+			t.Fatalf("expected leased message not to be relayed, got %d (%v)", n, err)
+		}
+
+		now = now.Add(time.Minute)
+		if n, err := relay.RelayOnce(ctx); err != nil || n != 1 {
+			t.Fatalf("expected 1 message relayed after the lease expired, got %d (%v)", n, err)
+		}
+		if len(publisher.Published) != 1 || publisher.Published[0].ID != outboxMessages(t, sqlHandler)[1].ID {
+			t.Errorf("unexpected published messages: %+v", publisher.Published)
+		}
+	})
+
+	t.Run("他のリレーが記録した結果は上書きしない", func(t *testing.T) {
+		published := outboxMessages(t, sqlHandler)[1]
+		if err := outbox.MarkFailed(ctx, published.ID, 5, model.OutboxStatusPending, now, "stale"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if m := outboxMessages(t, sqlHandler)[1]; m.Status != model.OutboxStatusPublished || m.LastError != nil {
+			t.Errorf("expected published message to be kept, got %+v", m)
+		}
+	})
+}
+
+func TestOutboxRelay_Backoff(t *testing.T) {
+	relay := &OutboxRelay{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempts, expected := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		if d := relay.backoff(attempts); d != expected {
+			t.Errorf("attempts %d: expected %s but got %s", attempts, expected, d)
+		}
+	}
+}
+
+func TestOutboxRelay_Run(t *testing.T) {
+	sqlHandler := newSeededSQLHandler(t)
+	outbox := &repository.OutboxRepository{SQLHandler: sqlHandler}
+	if err := outbox.Add(testContext(), model.PetLiked{PetId: "1", UserId: "user1", Value: true}); err != nil {
+		t.Fatalf("failed to add outbox message: %v", err)
+	}
+
+	relay := &OutboxRelay{
+		Repository:         outbox,
+		TransactionManager: &repository.TransactionManager{SQLHandler: sqlHandler},
+		Publisher:          &MockOutboxPublisher{},
+		PollInterval:       10 * time.Millisecond,
+		Now:                func() time.Time { return time.Now().Add(time.Minute) },
+	}
+	ctx, cancel := context.WithCancel(testContext())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		relay.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for outboxMessages(t, sqlHandler)[0].Status != model.OutboxStatusPublished {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the relay to publish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// キャンセルすると停止する
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("relay did not stop after cancel")
+	}
+}
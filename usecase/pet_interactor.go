@@ -2,28 +2,68 @@ package usecase
 
 import (
 	"context"
+	cryptorand "crypto/rand"
+	stderrors "errors"
+	"fmt"
+	"math/big"
 	"math/rand"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/horsewin/echo-playground-v2/domain/model"
 	"github.com/horsewin/echo-playground-v2/domain/model/errors"
 	"github.com/horsewin/echo-playground-v2/domain/repository"
 	"github.com/horsewin/echo-playground-v2/utils"
-	"github.com/jinzhu/copier"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// 予約数の取得方法
+const (
+	// ReservationCountModeBatch ペットIDをまとめて1回のクエリで予約数を取得する（デフォルト）
+	ReservationCountModeBatch = "batch"
+	// ReservationCountModeNPlusOne ペットごとに予約数を取得する。N+1問題をOpenTelemetryで確認するためのデモ用
+	ReservationCountModeNPlusOne = "n_plus_one"
+)
+
+const (
+	// referenceNumberSpace 採番する管理番号の範囲（7桁）
+	referenceNumberSpace = 10000000
+	// referenceNumberAttempts 使われていない管理番号を探す回数の上限
+	referenceNumberAttempts = 5
+)
+
 // PetInteractor ...
 type PetInteractor struct {
 	PetRepository         repository.PetRepositoryInterface
 	ReservationRepository repository.ReservationRepositoryInterface
 	FavoriteRepository    repository.FavoriteRepositoryInterface
+	ShopRepository        repository.ShopRepositoryInterface
+	TransactionManager    repository.TransactionManagerInterface
+	// Events 業務上の変更をドメインイベントとして配信する（nilの場合は配信しない）
+	Events EventPublisher
+	// Outbox 外部システムに配信するドメインイベントを業務データと同じトランザクションで記録する（nilの場合は記録しない）
+	Outbox repository.OutboxRepositoryInterface
+	// DisableLoadSimulation 性能テスト用のCPU負荷・レイテンシの発生を無効化する
+	DisableLoadSimulation bool
+	// ReservationCountMode 予約数の取得方法（未指定の場合はReservationCountModeBatch）
+	ReservationCountMode string
+	// Now 現在時刻を返す関数（未指定の場合はtime.Now）
+	Now func() time.Time
+}
+
+// now 現在時刻を返す
+func (interactor *PetInteractor) now() time.Time {
+	if interactor.Now != nil {
+		return interactor.Now()
+	}
+	return time.Now()
 }
 
 // GetPets ...
-func (interactor *PetInteractor) GetPets(ctx context.Context, filter *model.PetFilter) (pets []model.Pet, err error) {
+func (interactor *PetInteractor) GetPets(ctx context.Context, filter *model.PetFilter) (pets []model.Pet, page model.PageInfo, err error) {
 	// スパンを作成
 	tracer := otel.Tracer("pet-interactor")
 	ctx, span := tracer.Start(ctx, "PetInteractor.GetPets",
@@ -41,33 +81,36 @@ func (interactor *PetInteractor) GetPets(ctx context.Context, filter *model.PetF
 		)
 	}
 
-	// 性能テスト用：3回に1回だけCPU負荷を発生させる
-	if rand.Intn(3) == 0 {
-		induceCpuLoad()
-	}
+	if !interactor.DisableLoadSimulation {
+		// 性能テスト用：3回に1回だけCPU負荷を発生させる
+		if rand.Intn(3) == 0 {
+			induceCpuLoad()
+		}
 
-	// 性能テスト用：3回に1回だけレイテンシを発生させる
-	if rand.Intn(3) == 0 {
-		induceLatency()
+		// 性能テスト用：3回に1回だけレイテンシを発生させる
+		if rand.Intn(3) == 0 {
+			induceLatency()
+		}
 	}
 
 	// Repository層からデータを取得
 	_app, err := interactor.PetRepository.Find(ctx, filter)
 	if err != nil {
-		return pets, errors.NewBusinessError("10001E", err)
+		if stderrors.Is(err, repository.ErrInvalidPetFilter) {
+			return pets, page, errors.NewBusinessError("00003E", err)
+		}
+		return pets, page, errors.NewBusinessError("10001E", err)
 	}
 
-	// ドメインモデルに変換
+	// 予約数を取得
+	petIDs := make([]string, 0, len(_app.Data))
 	for _, p := range _app.Data {
-		// 予約数を取得
-		// Note: 意図的にN+1問題を起こしている箇所。OpenTelemetryで確認するため。
-		reservationCount, countErr := interactor.ReservationRepository.GetCountByPetID(ctx, p.ID)
-		if countErr != nil {
-			utils.LogError("Failed to get reservation count: %v", countErr)
-			// エラーが発生しても処理は継続
-			reservationCount = 0
-		}
+		petIDs = append(petIDs, p.ID)
+	}
+	reservationCounts := interactor.getReservationCounts(ctx, petIDs)
 
+	// ドメインモデルに変換
+	for _, p := range _app.Data {
 		pets = append(pets, model.Pet{
 			ID:       p.ID,
 			Name:     p.Name,
@@ -77,22 +120,81 @@ func (interactor *PetInteractor) GetPets(ctx context.Context, filter *model.PetF
 			ImageURL: p.ImageURL,
 			Likes:    p.Likes,
 			Shop: model.Shop{
+				ID:       p.ShopID,
 				Name:     p.ShopName,
 				Location: p.ShopLocation,
 			},
 			BirthDate:        p.BirthDate,
 			ReferenceNumber:  p.ReferenceNumber,
 			Tags:             p.Tags,
-			ReservationCount: reservationCount,
+			Status:           p.Status,
+			ReservationCount: reservationCounts[p.ID],
+			CreatedAt:        p.CreatedAt,
+			UpdatedAt:        p.UpdatedAt,
 		})
 	}
 
+	page = model.PageInfo{
+		NextCursor: _app.NextCursor,
+		TotalCount: _app.TotalCount,
+	}
+
 	// 結果の属性を追加
 	span.SetAttributes(
 		attribute.Int("result_count", len(pets)),
+		attribute.Int("total_count", page.TotalCount),
 	)
 
-	return pets, nil
+	return pets, page, nil
+}
+
+// getReservationCounts ペットごとの予約数を取得する
+// 取得に失敗しても一覧の表示は継続するため、その場合の予約数は0とする
+func (interactor *PetInteractor) getReservationCounts(ctx context.Context, petIDs []string) map[string]int64 {
+	mode := interactor.ReservationCountMode
+	if mode != ReservationCountModeNPlusOne {
+		mode = ReservationCountModeBatch
+	}
+
+	// スパンを作成（モードごとのクエリ数をX-Rayで比較できるようにする）
+	tracer := otel.Tracer("pet-interactor")
+	ctx, span := tracer.Start(ctx, "PetInteractor.getReservationCounts",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("reservation_count.mode", mode),
+			attribute.Int("reservation_count.pet_count", len(petIDs)),
+		),
+	)
+	defer span.End()
+
+	if mode == ReservationCountModeBatch {
+		counts, err := interactor.ReservationRepository.GetCountsByPetIDs(ctx, petIDs)
+		queries := 1
+		if len(petIDs) == 0 {
+			queries = 0
+		}
+		span.SetAttributes(attribute.Int("reservation_count.queries", queries))
+		if err != nil {
+			span.RecordError(err)
+			utils.LogError("Failed to get reservation counts: %v", err)
+			return map[string]int64{}
+		}
+		return counts
+	}
+
+	// Note: 意図的にN+1問題を起こしている箇所。OpenTelemetryで確認するため。
+	counts := make(map[string]int64, len(petIDs))
+	for _, petID := range petIDs {
+		count, err := interactor.ReservationRepository.GetCountByPetID(ctx, petID)
+		if err != nil {
+			span.RecordError(err)
+			utils.LogError("Failed to get reservation count: %v", err)
+			continue
+		}
+		counts[petID] = count
+	}
+	span.SetAttributes(attribute.Int("reservation_count.queries", len(petIDs)))
+	return counts
 }
 
 // UpdateLikeCount ...
@@ -104,103 +206,573 @@ func (interactor *PetInteractor) UpdateLikeCount(ctx context.Context, input *mod
 	)
 	defer span.End()
 
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("input.pet_id", input.PetId),
+		attribute.String("input.user_id", input.UserId),
+		attribute.Bool("input.value", input.Value),
+	)
+
+	// 操作するユーザーは認証済みのリクエスト元から決定する
+	input.UserId, err = resolveUserID(ctx, input.UserId)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	span.SetAttributes(attribute.String("input.user_id", input.UserId))
+
+	// いいね数の更新とお気に入りの登録/解除を単一トランザクションで実行する
+	var liked model.PetLiked
+	err = interactor.TransactionManager.Do(ctx, func(ctx context.Context) error {
+		// 同じペットへのいいねを直列化するため、いいね数を読む前にペットの行をロックする
+		pet, err := lockPet(ctx, interactor.PetRepository, input.PetId)
+		if err != nil {
+			return err
+		}
+
+		// like状態を取得
+		favMap, err := interactor.FavoriteRepository.FindByUserId(ctx, input.UserId)
+		if err != nil {
+			return errors.NewBusinessError("10001E", err)
+		}
+		if favMap[input.PetId].Value == input.Value {
+			return errors.NewBusinessError("00001I", nil)
+		}
+
+		// Like数を更新
+		if input.Value {
+			pet.Likes = pet.Likes + 1
+		} else {
+			pet.Likes = pet.Likes - 1
+		}
+
+		// いいね数のみを更新し、同時に行われた他の項目の変更を上書きしない
+		err = interactor.PetRepository.Update(ctx, pet.ID, &model.PetChanges{Likes: &pet.Likes})
+		if err != nil {
+			return errors.NewBusinessError("10003E", err)
+		}
+
+		if input.Value {
+			err = interactor.FavoriteRepository.Create(ctx, &model.Favorite{
+				PetId:  input.PetId,
+				UserId: input.UserId,
+				Value:  input.Value,
+			})
+			if err != nil {
+				return errors.NewBusinessError("10004E", err)
+			}
+		} else {
+			err = interactor.FavoriteRepository.Delete(ctx, &model.Favorite{
+				PetId:  input.PetId,
+				UserId: input.UserId,
+			})
+			if err != nil {
+				return errors.NewBusinessError("10005E", err)
+			}
+		}
+
+		liked = model.PetLiked{PetId: input.PetId, UserId: input.UserId, Value: input.Value, Likes: pet.Likes}
+		return recordOutbox(ctx, interactor.Outbox, liked)
+	})
+	if err != nil {
+		return err
+	}
+
+	// コミットされた場合のみ計上する
+	if input.Value {
+		likesCounter.Add(ctx, 1)
+	} else {
+		unlikesCounter.Add(ctx, 1)
+	}
+
+	publishEvents(ctx, interactor.Events, liked)
+	return nil
+}
+
+// CreateReservation ...
+func (interactor *PetInteractor) CreateReservation(ctx context.Context, input *model.Reservation) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("pet-interactor")
+	ctx, span := tracer.Start(ctx, "PetInteractor.CreateReservation",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
 	// 属性を追加
 	if input != nil {
 		span.SetAttributes(
 			attribute.String("input.pet_id", input.PetId),
 			attribute.String("input.user_id", input.UserId),
-			attribute.Bool("input.value", input.Value),
+			attribute.String("input.slot_starts_at", input.SlotStartsAt.Format(time.RFC3339)),
 		)
 	}
 
-	// like状態を取得
-	favMap, err := interactor.FavoriteRepository.FindByUserId(ctx, input.UserId)
+	// 予約するユーザーは認証済みのリクエスト元から決定する
+	input.UserId, err = resolveUserID(ctx, input.UserId)
 	if err != nil {
-		return errors.NewBusinessError("10001E", err)
+		span.RecordError(err)
+		return err
+	}
+	span.SetAttributes(attribute.String("input.user_id", input.UserId))
+
+	// ペットの予約中への変更・予約枠の確保・予約の作成・配信するイベントの記録を単一トランザクションで実行する
+	// 予約を受け付けられるのは予約可能なペットのみで、同時に予約された場合は一方のみ成功する
+	// 予約枠はペットの店舗のすべてのペットで共有し、満席の場合は受け付けない
+	var events []model.DomainEvent
+	err = interactor.TransactionManager.Do(ctx, func(ctx context.Context) error {
+		pet, err := lockPet(ctx, interactor.PetRepository, input.PetId)
+		if err != nil {
+			return err
+		}
+		changed, err := changePetStatus(ctx, interactor.PetRepository, pet, PetActionReserve)
+		if err != nil {
+			return err
+		}
+
+		slot, err := bookVisitSlot(ctx, interactor.ShopRepository, interactor.ReservationRepository, pet.Shop.ID, input.SlotStartsAt, interactor.now(), 0)
+		if err != nil {
+			return err
+		}
+		input.ShopID = slot.ShopID
+		input.SlotStartsAt = slot.StartsAt
+		input.SlotTimezone = slot.Timezone
+		input.SlotSeat = slot.Seat
+
+		if err := interactor.ReservationRepository.Create(ctx, input); err != nil {
+			return visitSlotWriteError(err)
+		}
+		events = []model.DomainEvent{model.ReservationCreated{Reservation: *input}, *changed}
+		return recordOutbox(ctx, interactor.Outbox, events...)
+	})
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	reservationsCreatedCounter.Add(ctx, 1)
+	petStatusTransitionsCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("action", PetActionReserve)))
+
+	publishEvents(ctx, interactor.Events, events...)
+	return
+}
+
+// GetAvailability ペットの見学予約を受け付けている枠を取得する
+//
+// 期間は店舗のタイムゾーンの日付で指定し、開始前で空きのある枠のみを返す（予約可能でないペットは空の一覧）
+func (interactor *PetInteractor) GetAvailability(ctx context.Context, id string, filter *model.AvailabilityFilter) (*model.PetAvailability, error) {
+	// スパンを作成
+	tracer := otel.Tracer("pet-interactor")
+	ctx, span := tracer.Start(ctx, "PetInteractor.GetAvailability",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	if filter == nil {
+		filter = &model.AvailabilityFilter{}
 	}
-	if favMap[input.PetId].Value == input.Value {
-		return errors.NewBusinessError("00001I", nil)
+	days := filter.Days
+	if days <= 0 {
+		days = 7
 	}
 
-	// 現在のペットモデルを取得
-	petData, err := interactor.PetRepository.Find(ctx, &model.PetFilter{ID: input.PetId})
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("pet_id", id),
+		attribute.String("filter.from", filter.From),
+		attribute.Int("filter.days", days),
+	)
+
+	pet, err := interactor.findPet(ctx, id)
 	if err != nil {
-		return errors.NewBusinessError("10001E", err)
+		span.RecordError(err)
+		return nil, err
 	}
-	var pet model.Pet
-	for _, p := range petData.Data {
-		if p.ID == input.PetId {
-			err = copier.Copy(&pet, &p)
-			if err != nil {
-				return errors.NewBusinessError("10002E", err)
+	schedule, loc, err := findSchedule(ctx, interactor.ShopRepository, pet.Shop.ID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	now := interactor.now()
+	from := now.In(loc)
+	if filter.From != "" {
+		if from, err = time.ParseInLocation("2006-01-02", filter.From, loc); err != nil {
+			span.RecordError(err)
+			return nil, errors.NewBusinessError("20007E", err)
+		}
+	}
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 0, days)
+
+	availability := &model.PetAvailability{
+		PetID:    pet.ID,
+		ShopID:   pet.Shop.ID,
+		Timezone: schedule.Timezone,
+		Slots:    []model.VisitSlot{},
+	}
+	if pet.Status != model.PetStatusAvailable {
+		return availability, nil
+	}
+
+	counts, err := interactor.ReservationRepository.CountBySlot(ctx, pet.Shop.ID, start, end)
+	if err != nil {
+		span.RecordError(err)
+		return nil, errors.NewBusinessError("10001E", err)
+	}
+	for day := 0; day < days; day++ {
+		for _, slot := range visitSlotsOn(schedule, loc, start.AddDate(0, 0, day)) {
+			if !slot.StartsAt.After(now) {
+				continue
 			}
+			slot.Remaining = slot.Capacity - int(counts[slot.StartsAt.Unix()])
+			if slot.Remaining <= 0 {
+				continue
+			}
+			availability.Slots = append(availability.Slots, slot)
+		}
+	}
 
-			// マッピングしきれない構造は手動でコピー
-			pet.Shop = model.Shop{
-				Name:     p.ShopName,
-				Location: p.ShopLocation,
+	span.SetAttributes(attribute.Int("result_count", len(availability.Slots)))
+	return availability, nil
+}
+
+// CreatePet ペットを登録する
+//
+// IDはサーバで採番し、管理番号は指定されなかった場合に採番する
+func (interactor *PetInteractor) CreatePet(ctx context.Context, input *model.Pet) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("pet-interactor")
+	ctx, span := tracer.Start(ctx, "PetInteractor.CreatePet",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("input.name", input.Name),
+		attribute.String("input.reference_number", input.ReferenceNumber),
+		attribute.Int64("input.shop_id", input.Shop.ID),
+	)
+
+	if err = requireService(ctx); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	input.ID = uuid.NewString()
+	input.Likes = 0
+	input.Status = model.PetStatusAvailable
+	err = interactor.TransactionManager.Do(ctx, func(ctx context.Context) error {
+		if err := interactor.checkShop(ctx, input.Shop.ID); err != nil {
+			return err
+		}
+		if input.ReferenceNumber == "" {
+			referenceNumber, err := interactor.newReferenceNumber(ctx)
+			if err != nil {
+				return err
 			}
+			input.ReferenceNumber = referenceNumber
+		} else if err := interactor.checkReferenceNumber(ctx, input.ReferenceNumber, ""); err != nil {
+			return err
+		}
+
+		if err := interactor.PetRepository.Create(ctx, input); err != nil {
+			return petWriteError(err)
 		}
+		return recordOutbox(ctx, interactor.Outbox, model.PetAdded{Pet: *input})
+	})
+	if err != nil {
+		span.RecordError(err)
+		return err
 	}
+	span.SetAttributes(attribute.String("pet_id", input.ID))
 
-	// Like数を更新
-	if input.Value {
-		pet.Likes = pet.Likes + 1
-	} else {
-		pet.Likes = pet.Likes - 1
+	publishEvents(ctx, interactor.Events, model.PetAdded{Pet: *input})
+	return nil
+}
+
+// UpdatePet ペットのchangesで指定された項目のみを更新し、更新後のペットを返す
+//
+// 価格が変わった場合はお気に入り登録しているユーザに通知するため、価格変更のイベントを配信する
+func (interactor *PetInteractor) UpdatePet(ctx context.Context, id string, changes *model.PetChanges) (*model.Pet, error) {
+	// スパンを作成
+	tracer := otel.Tracer("pet-interactor")
+	ctx, span := tracer.Start(ctx, "PetInteractor.UpdatePet",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("pet_id", id),
+	)
+
+	if err := requireService(ctx); err != nil {
+		span.RecordError(err)
+		return nil, err
 	}
 
-	// TODO: トランザクションを使って更新処理を行う
-	err = interactor.PetRepository.Update(ctx, &pet)
+	// いいね数は管理APIからは変更できない
+	changes.Likes = nil
+
+	var updated *model.Pet
+	var events []model.DomainEvent
+	err := interactor.TransactionManager.Do(ctx, func(ctx context.Context) error {
+		// 同時に行われた変更と変更前の価格・管理番号の確認が競合しないよう、ペットの行をロックする
+		current, err := lockPet(ctx, interactor.PetRepository, id)
+		if err != nil {
+			return err
+		}
+		if changes.ShopID != nil && *changes.ShopID != current.Shop.ID {
+			if err := interactor.checkShop(ctx, *changes.ShopID); err != nil {
+				return err
+			}
+		}
+		if changes.ReferenceNumber != nil && *changes.ReferenceNumber != current.ReferenceNumber {
+			if err := interactor.checkReferenceNumber(ctx, *changes.ReferenceNumber, id); err != nil {
+				return err
+			}
+		}
+
+		if err := interactor.PetRepository.Update(ctx, id, changes); err != nil {
+			return petWriteError(err)
+		}
+		if updated, err = interactor.findPet(ctx, id); err != nil {
+			return err
+		}
+
+		if updated.Price != current.Price {
+			events = append(events, model.PetPriceChanged{Pet: *updated, OldPrice: current.Price, NewPrice: updated.Price})
+		}
+		return recordOutbox(ctx, interactor.Outbox, events...)
+	})
 	if err != nil {
-		return errors.NewBusinessError("10003E", err)
+		span.RecordError(err)
+		return nil, err
 	}
 
-	if input.Value {
-		err = interactor.FavoriteRepository.Create(ctx, &model.Favorite{
-			PetId:  input.PetId,
-			UserId: input.UserId,
-			Value:  input.Value,
-		})
+	publishEvents(ctx, interactor.Events, events...)
+	return updated, nil
+}
+
+// DeletePet ペットを削除する
+//
+// 有効な見学予約があるペットは削除できない（40005E）。いいね・終了した見学予約は外部キーのON DELETE CASCADEで削除される
+func (interactor *PetInteractor) DeletePet(ctx context.Context, id string) error {
+	// スパンを作成
+	tracer := otel.Tracer("pet-interactor")
+	ctx, span := tracer.Start(ctx, "PetInteractor.DeletePet",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("pet_id", id),
+	)
+
+	if err := requireService(ctx); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	var deleted model.PetDeleted
+	err := interactor.TransactionManager.Do(ctx, func(ctx context.Context) error {
+		// 削除の確認中に見学予約が作成されないよう、ペットの行をロックする
+		pet, err := lockPet(ctx, interactor.PetRepository, id)
 		if err != nil {
-			return errors.NewBusinessError("10004E", err)
+			return err
 		}
-	} else {
-		err = interactor.FavoriteRepository.Delete(ctx, &model.Favorite{
-			PetId:  input.PetId,
-			UserId: input.UserId,
-		})
+		active, err := interactor.ReservationRepository.GetActiveCountByPetID(ctx, id)
+		if err != nil {
+			return errors.NewBusinessError("10001E", err)
+		}
+		if pet.Status == model.PetStatusReserved || active > 0 {
+			return errors.NewBusinessError("40005E", fmt.Errorf("pet %s has %d active reservations", id, active))
+		}
+
+		// お気に入りは削除とともに消えるため、通知の対象のユーザを先に取得する
+		userIDs, err := interactor.favoriteUserIDs(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := interactor.PetRepository.Delete(ctx, id); err != nil {
+			return errors.NewBusinessError("10003E", err)
+		}
+
+		deleted = model.PetDeleted{Pet: *pet, FavoriteUserIDs: userIDs}
+		return recordOutbox(ctx, interactor.Outbox, deleted)
+	})
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	publishEvents(ctx, interactor.Events, deleted)
+	return nil
+}
+
+// favoriteUserIDs ペットをお気に入り登録しているユーザをすべて取得する
+func (interactor *PetInteractor) favoriteUserIDs(ctx context.Context, petID string) ([]string, error) {
+	userIDs := make([]string, 0)
+	afterUserID := ""
+	for {
+		favorites, err := interactor.FavoriteRepository.FindByPetIDs(ctx, []string{petID}, afterUserID, notificationPageSize)
 		if err != nil {
-			return errors.NewBusinessError("10005E", err)
+			return nil, errors.NewBusinessError("10001E", err)
+		}
+		for _, f := range favorites {
+			userIDs = append(userIDs, f.UserId)
 		}
+		if len(favorites) < notificationPageSize {
+			return userIDs, nil
+		}
+		afterUserID = favorites[len(favorites)-1].UserId
 	}
+}
 
-	return
+// AdoptPet ペットを成約済みにする
+func (interactor *PetInteractor) AdoptPet(ctx context.Context, id string) (*model.Pet, error) {
+	return interactor.changeStatus(ctx, id, PetActionAdopt)
 }
 
-// CreateReservation ...
-func (interactor *PetInteractor) CreateReservation(ctx context.Context, input *model.Reservation) (err error) {
+// WithdrawPet ペットの掲載を終了する
+func (interactor *PetInteractor) WithdrawPet(ctx context.Context, id string) (*model.Pet, error) {
+	return interactor.changeStatus(ctx, id, PetActionWithdraw)
+}
+
+// RelistPet 掲載を終了したペットを再び予約可能にする
+func (interactor *PetInteractor) RelistPet(ctx context.Context, id string) (*model.Pet, error) {
+	return interactor.changeStatus(ctx, id, PetActionRelist)
+}
+
+// changeStatus 管理者の操作でペットのステータスを変更し、変更後のペットを返す
+func (interactor *PetInteractor) changeStatus(ctx context.Context, id string, action string) (*model.Pet, error) {
 	// スパンを作成
 	tracer := otel.Tracer("pet-interactor")
-	ctx, span := tracer.Start(ctx, "PetInteractor.CreateReservation",
+	ctx, span := tracer.Start(ctx, "PetInteractor.changeStatus",
 		trace.WithSpanKind(trace.SpanKindInternal),
 	)
 	defer span.End()
 
 	// 属性を追加
-	if input != nil {
+	span.SetAttributes(
+		attribute.String("pet_id", id),
+		attribute.String("action", action),
+	)
+
+	if err := requireService(ctx); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	var updated *model.Pet
+	var changed *model.PetStatusChanged
+	err := interactor.TransactionManager.Do(ctx, func(ctx context.Context) error {
+		pet, err := lockPet(ctx, interactor.PetRepository, id)
+		if err != nil {
+			return err
+		}
+		if changed, err = changePetStatus(ctx, interactor.PetRepository, pet, action); err != nil {
+			return err
+		}
 		span.SetAttributes(
-			attribute.String("input.pet_id", input.PetId),
-			attribute.String("input.user_id", input.UserId),
-			attribute.String("input.reservation_date", input.ReservationDate),
+			attribute.String("from_status", changed.From),
+			attribute.String("to_status", changed.To),
 		)
+
+		if updated, err = interactor.findPet(ctx, id); err != nil {
+			return err
+		}
+		changed.Pet = *updated
+		return recordOutbox(ctx, interactor.Outbox, *changed)
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
 	}
+	petStatusTransitionsCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("action", action)))
 
-	err = interactor.ReservationRepository.Create(ctx, input)
+	publishEvents(ctx, interactor.Events, *changed)
+	return updated, nil
+}
+
+// findPet ペットを取得する（存在しない場合は40001E）
+func (interactor *PetInteractor) findPet(ctx context.Context, id string) (*model.Pet, error) {
+	pet, err := interactor.PetRepository.FindByID(ctx, id)
 	if err != nil {
-		return errors.NewBusinessError("10003E", err)
+		return nil, errors.NewBusinessError("10001E", err)
 	}
-	return
+	if pet == nil {
+		return nil, errors.NewBusinessError("40001E", fmt.Errorf("pet not found: %s", id))
+	}
+	return pet, nil
+}
+
+// checkShop ペットに設定する店舗が存在するかを確認する（存在しない場合は50002E）
+func (interactor *PetInteractor) checkShop(ctx context.Context, shopID int64) error {
+	shop, err := interactor.ShopRepository.FindByID(ctx, shopID)
+	if err != nil {
+		return errors.NewBusinessError("10001E", err)
+	}
+	if shop == nil {
+		return errors.NewBusinessError("50002E", fmt.Errorf("shop not found: %d", shopID))
+	}
+	return nil
+}
+
+// checkReferenceNumber 管理番号が他のペット（exceptID以外）で使われていないかを確認する（使われている場合は40002E）
+func (interactor *PetInteractor) checkReferenceNumber(ctx context.Context, referenceNumber string, exceptID string) error {
+	taken, err := interactor.referenceNumberTaken(ctx, referenceNumber, exceptID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return errors.NewBusinessError("40002E", fmt.Errorf("reference number %s is already used", referenceNumber))
+	}
+	return nil
+}
+
+// referenceNumberTaken 管理番号が他のペット（exceptID以外）で使われているかを判定する
+func (interactor *PetInteractor) referenceNumberTaken(ctx context.Context, referenceNumber string, exceptID string) (bool, error) {
+	pets, err := interactor.PetRepository.Find(ctx, &model.PetFilter{ReferenceNumber: referenceNumber})
+	if err != nil {
+		return false, errors.NewBusinessError("10001E", err)
+	}
+	for _, p := range pets.Data {
+		if p.ID != exceptID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// newReferenceNumber 使われていない7桁の管理番号を採番する
+func (interactor *PetInteractor) newReferenceNumber(ctx context.Context) (string, error) {
+	for i := 0; i < referenceNumberAttempts; i++ {
+		n, err := cryptorand.Int(cryptorand.Reader, big.NewInt(referenceNumberSpace))
+		if err != nil {
+			return "", errors.NewBusinessError("99999E", err)
+		}
+		referenceNumber := fmt.Sprintf("%07d", n.Int64())
+		taken, err := interactor.referenceNumberTaken(ctx, referenceNumber, "")
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return referenceNumber, nil
+		}
+	}
+	return "", errors.NewBusinessError("99999E", fmt.Errorf("failed to allocate reference number"))
+}
+
+// petWriteError ペットの登録・更新のエラーを業務エラーに変換する
+//
+// 確認後に他のリクエストが同じ管理番号を登録した場合は一意制約の違反になる
+func petWriteError(err error) error {
+	if stderrors.Is(err, repository.ErrDuplicateReferenceNumber) {
+		return errors.NewBusinessError("40002E", err)
+	}
+	return errors.NewBusinessError("10003E", err)
 }
 
 // induceCpuLoad ... 意図的にテスト用のCPU負荷を発生させる関数
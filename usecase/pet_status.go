@@ -0,0 +1,106 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+)
+
+// ペットに対する操作
+const (
+	// PetActionReserve 見学予約の作成
+	PetActionReserve = "reserve"
+	// PetActionRelease 有効な見学予約がすべてキャンセルされた
+	PetActionRelease = "release"
+	// PetActionAdopt 成約（管理者のみ）
+	PetActionAdopt = "adopt"
+	// PetActionWithdraw 掲載の終了（管理者のみ）
+	PetActionWithdraw = "withdraw"
+	// PetActionRelist 掲載の再開（管理者のみ）
+	PetActionRelist = "relist"
+)
+
+// petTransition 遷移先のステータス、または遷移できない場合のエラーコード
+type petTransition struct {
+	to        string
+	errorCode string
+}
+
+// petTransitions ペットのステータスの状態遷移表（操作 -> 現在のステータス -> 遷移）
+var petTransitions = map[string]map[string]petTransition{
+	PetActionReserve: {
+		model.PetStatusAvailable: {to: model.PetStatusReserved},
+		model.PetStatusReserved:  {errorCode: "40003E"},
+		model.PetStatusAdopted:   {errorCode: "40003E"},
+		model.PetStatusWithdrawn: {errorCode: "40003E"},
+	},
+	PetActionRelease: {
+		model.PetStatusAvailable: {errorCode: "40004E"},
+		model.PetStatusReserved:  {to: model.PetStatusAvailable},
+		model.PetStatusAdopted:   {errorCode: "40004E"},
+		model.PetStatusWithdrawn: {errorCode: "40004E"},
+	},
+	// 見学予約の有無にかかわらず成約・掲載終了できる
+	PetActionAdopt: {
+		model.PetStatusAvailable: {to: model.PetStatusAdopted},
+		model.PetStatusReserved:  {to: model.PetStatusAdopted},
+		model.PetStatusAdopted:   {errorCode: "40004E"},
+		model.PetStatusWithdrawn: {errorCode: "40004E"},
+	},
+	PetActionWithdraw: {
+		model.PetStatusAvailable: {to: model.PetStatusWithdrawn},
+		model.PetStatusReserved:  {to: model.PetStatusWithdrawn},
+		model.PetStatusAdopted:   {errorCode: "40004E"},
+		model.PetStatusWithdrawn: {errorCode: "40004E"},
+	},
+	PetActionRelist: {
+		model.PetStatusAvailable: {errorCode: "40004E"},
+		model.PetStatusReserved:  {errorCode: "40004E"},
+		model.PetStatusAdopted:   {errorCode: "40004E"},
+		model.PetStatusWithdrawn: {to: model.PetStatusAvailable},
+	},
+}
+
+// nextPetStatus 操作後のペットのステータスを返す。遷移できない場合はBusinessErrorを返す
+func nextPetStatus(action string, current string) (string, error) {
+	transition, ok := petTransitions[action][current]
+	if !ok {
+		return "", errors.NewBusinessError("10002E", fmt.Errorf("unknown pet transition: %s from %s", action, current))
+	}
+	if transition.errorCode != "" {
+		return "", errors.NewBusinessError(transition.errorCode, nil)
+	}
+	return transition.to, nil
+}
+
+// lockPet ペットを取得し、トランザクションの終了まで他の操作によるステータスの変更を防ぐ
+//
+// 他のトランザクションがロックしている場合は解放されるまで待ち、存在しない場合は40001Eを返す
+func lockPet(ctx context.Context, pets repository.PetRepositoryInterface, id string) (*model.Pet, error) {
+	pet, err := pets.FindByIDForUpdate(ctx, id)
+	if err != nil {
+		return nil, errors.NewBusinessError("10001E", err)
+	}
+	if pet == nil {
+		return nil, errors.NewBusinessError("40001E", fmt.Errorf("pet not found: %s", id))
+	}
+	return pet, nil
+}
+
+// changePetStatus lockPetでロックしたペットのステータスを状態遷移表に従って変更し、変更のイベントを返す
+func changePetStatus(ctx context.Context, pets repository.PetRepositoryInterface, pet *model.Pet, action string) (*model.PetStatusChanged, error) {
+	to, err := nextPetStatus(action, pet.Status)
+	if err != nil {
+		return nil, err
+	}
+	if err := pets.UpdateStatus(ctx, pet.ID, pet.Status, to); err != nil {
+		return nil, errors.NewBusinessError("10003E", err)
+	}
+
+	event := &model.PetStatusChanged{Pet: *pet, Action: action, From: pet.Status, To: to}
+	event.Pet.Status = to
+	return event, nil
+}
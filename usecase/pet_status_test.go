@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+)
+
+func TestNextPetStatus(t *testing.T) {
+	tests := []struct {
+		action    string
+		current   string
+		expected  string
+		errorCode string
+	}{
+		{action: PetActionReserve, current: model.PetStatusAvailable, expected: model.PetStatusReserved},
+		{action: PetActionReserve, current: model.PetStatusReserved, errorCode: "40003E"},
+		{action: PetActionReserve, current: model.PetStatusAdopted, errorCode: "40003E"},
+		{action: PetActionReserve, current: model.PetStatusWithdrawn, errorCode: "40003E"},
+		{action: PetActionRelease, current: model.PetStatusReserved, expected: model.PetStatusAvailable},
+		{action: PetActionRelease, current: model.PetStatusAvailable, errorCode: "40004E"},
+		{action: PetActionAdopt, current: model.PetStatusAvailable, expected: model.PetStatusAdopted},
+		{action: PetActionAdopt, current: model.PetStatusReserved, expected: model.PetStatusAdopted},
+		{action: PetActionAdopt, current: model.PetStatusWithdrawn, errorCode: "40004E"},
+		{action: PetActionWithdraw, current: model.PetStatusReserved, expected: model.PetStatusWithdrawn},
+		{action: PetActionWithdraw, current: model.PetStatusAdopted, errorCode: "40004E"},
+		{action: PetActionRelist, current: model.PetStatusWithdrawn, expected: model.PetStatusAvailable},
+		{action: PetActionRelist, current: model.PetStatusAdopted, errorCode: "40004E"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action+"/"+tt.current, func(t *testing.T) {
+			next, err := nextPetStatus(tt.action, tt.current)
+			if tt.errorCode != "" {
+				assertBusinessError(t, err, tt.errorCode)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if next != tt.expected {
+				t.Errorf("expected %s but got %s", tt.expected, next)
+			}
+		})
+	}
+
+	// 未知のステータスは遷移できない
+	_, err := nextPetStatus(PetActionAdopt, "sold")
+	assertBusinessError(t, err, "10002E")
+}
@@ -0,0 +1,306 @@
+package usecase
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 予約に対する操作
+const (
+	ReservationActionConfirm    = "confirm"
+	ReservationActionCancel     = "cancel"
+	ReservationActionReschedule = "reschedule"
+)
+
+// reservationTransition 遷移先のステータス、または遷移できない場合のエラーコード
+type reservationTransition struct {
+	to        string
+	errorCode string
+}
+
+// reservationTransitions 予約ステータスの状態遷移表（操作 -> 現在のステータス -> 遷移）
+var reservationTransitions = map[string]map[string]reservationTransition{
+	ReservationActionConfirm: {
+		model.ReservationStatusPending:   {to: model.ReservationStatusConfirmed},
+		model.ReservationStatusConfirmed: {errorCode: "20003E"},
+		model.ReservationStatusCancelled: {errorCode: "20002E"},
+	},
+	ReservationActionCancel: {
+		model.ReservationStatusPending:   {to: model.ReservationStatusCancelled},
+		model.ReservationStatusConfirmed: {to: model.ReservationStatusCancelled},
+		model.ReservationStatusCancelled: {errorCode: "20004E"},
+	},
+	// 日程を変更した予約は再度確定が必要なためpendingに戻す
+	ReservationActionReschedule: {
+		model.ReservationStatusPending:   {to: model.ReservationStatusPending},
+		model.ReservationStatusConfirmed: {to: model.ReservationStatusPending},
+		model.ReservationStatusCancelled: {errorCode: "20005E"},
+	},
+}
+
+// nextReservationStatus 操作後のステータスを返す。遷移できない場合はBusinessErrorを返す
+func nextReservationStatus(action string, current string) (string, error) {
+	transition, ok := reservationTransitions[action][current]
+	if !ok {
+		return "", errors.NewBusinessError("10002E", fmt.Errorf("unknown reservation transition: %s from %s", action, current))
+	}
+	if transition.errorCode != "" {
+		return "", errors.NewBusinessError(transition.errorCode, nil)
+	}
+	return transition.to, nil
+}
+
+// ReservationInteractor ...
+type ReservationInteractor struct {
+	ReservationRepository repository.ReservationRepositoryInterface
+	// PetRepository 有効な予約がすべてキャンセルされたペットを予約可能に戻す
+	PetRepository repository.PetRepositoryInterface
+	// ShopRepository 日程の変更先の予約枠を店舗の受付設定から確認する
+	ShopRepository     repository.ShopRepositoryInterface
+	TransactionManager repository.TransactionManagerInterface
+	// Events 業務上の変更をドメインイベントとして配信する（nilの場合は配信しない）
+	Events EventPublisher
+	// Outbox 外部システムに配信するドメインイベントを業務データと同じトランザクションで記録する（nilの場合は記録しない）
+	Outbox repository.OutboxRepositoryInterface
+	// Now 現在時刻を返す関数（未指定の場合はtime.Now）
+	Now func() time.Time
+}
+
+// now 現在時刻を返す
+func (interactor *ReservationInteractor) now() time.Time {
+	if interactor.Now != nil {
+		return interactor.Now()
+	}
+	return time.Now()
+}
+
+// GetReservations ユーザの予約一覧を取得する
+func (interactor *ReservationInteractor) GetReservations(ctx context.Context, userID string, status string) (reservations model.Reservations, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("reservation-interactor")
+	ctx, span := tracer.Start(ctx, "ReservationInteractor.GetReservations",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.String("user_id", userID),
+		attribute.String("status", status),
+	)
+
+	// 取得対象のユーザーは認証済みのリクエスト元から決定する
+	userID, err = resolveUserID(ctx, userID)
+	if err != nil {
+		return reservations, err
+	}
+	span.SetAttributes(attribute.String("user_id", userID))
+
+	if status != "" && !model.IsValidReservationStatus(status) {
+		return reservations, errors.NewBusinessError("00003E", fmt.Errorf("unknown reservation status: %s", status))
+	}
+
+	reservations, err = interactor.ReservationRepository.FindByUserID(ctx, userID, status)
+	if err != nil {
+		return reservations, errors.NewBusinessError("10001E", err)
+	}
+	return
+}
+
+// GetReservation 予約を取得する
+func (interactor *ReservationInteractor) GetReservation(ctx context.Context, id int) (reservation *model.ReservationDetail, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("reservation-interactor")
+	ctx, span := tracer.Start(ctx, "ReservationInteractor.GetReservation",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int("reservation_id", id),
+	)
+
+	reservation, err = interactor.ReservationRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, errors.NewBusinessError("10001E", err)
+	}
+	// 他のユーザーの予約は存在を明かさないよう、見つからない場合と同じエラーとする
+	if reservation == nil || !canAccessUserResource(ctx, reservation.UserId) {
+		return nil, errors.NewBusinessError("20001E", nil)
+	}
+	return
+}
+
+// ConfirmReservation 予約を確定する
+//
+// 予約の確定は店舗が行うため、サービス間連携（APIキー）でのみ行える（予約したユーザーは確定できない）
+func (interactor *ReservationInteractor) ConfirmReservation(ctx context.Context, id int) (*model.ReservationDetail, error) {
+	if err := requireService(ctx); err != nil {
+		return nil, err
+	}
+	return interactor.transition(ctx, id, ReservationActionConfirm,
+		func(ctx context.Context, current *model.ReservationDetail, to string) error {
+			return interactor.ReservationRepository.UpdateStatus(ctx, id, current.Status, to)
+		})
+}
+
+// CancelReservation 予約をキャンセルする
+func (interactor *ReservationInteractor) CancelReservation(ctx context.Context, id int) (*model.ReservationDetail, error) {
+	return interactor.transition(ctx, id, ReservationActionCancel,
+		func(ctx context.Context, current *model.ReservationDetail, to string) error {
+			return interactor.ReservationRepository.UpdateStatus(ctx, id, current.Status, to)
+		})
+}
+
+// RescheduleReservation 予約の予約枠をslotStartsAtに開始する枠に変更する
+//
+// 変更先は予約した店舗（予約枠を持たない予約の場合はペットの店舗）の開始前で空きのある枠とする
+func (interactor *ReservationInteractor) RescheduleReservation(ctx context.Context, id int, slotStartsAt time.Time) (*model.ReservationDetail, error) {
+	return interactor.transition(ctx, id, ReservationActionReschedule,
+		func(ctx context.Context, current *model.ReservationDetail, _ string) error {
+			shopID, err := interactor.reservationShopID(ctx, current)
+			if err != nil {
+				return err
+			}
+			slot, err := bookVisitSlot(ctx, interactor.ShopRepository, interactor.ReservationRepository, shopID, slotStartsAt, interactor.now(), id)
+			if err != nil {
+				return err
+			}
+			if err := interactor.ReservationRepository.Reschedule(ctx, id, current.Status, slot); err != nil {
+				return visitSlotWriteError(err)
+			}
+			return nil
+		})
+}
+
+// reservationShopID 予約の予約枠の店舗を返す（予約枠を持たない予約の場合はペットの店舗）
+func (interactor *ReservationInteractor) reservationShopID(ctx context.Context, reservation *model.ReservationDetail) (int64, error) {
+	if reservation.ShopID != nil {
+		return *reservation.ShopID, nil
+	}
+	pet, err := interactor.PetRepository.FindByID(ctx, reservation.PetId)
+	if err != nil {
+		return 0, errors.NewBusinessError("10001E", err)
+	}
+	if pet == nil {
+		return 0, errors.NewBusinessError("40001E", fmt.Errorf("pet not found: %s", reservation.PetId))
+	}
+	return pet.Shop.ID, nil
+}
+
+// transition 状態遷移表に従って予約を更新し、更新後の予約を返す
+func (interactor *ReservationInteractor) transition(ctx context.Context, id int, action string, apply func(ctx context.Context, current *model.ReservationDetail, to string) error) (result *model.ReservationDetail, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("reservation-interactor")
+	ctx, span := tracer.Start(ctx, "ReservationInteractor.transition",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int("reservation_id", id),
+		attribute.String("action", action),
+	)
+
+	var events []model.DomainEvent
+	var released *model.PetStatusChanged
+	err = interactor.TransactionManager.Do(ctx, func(ctx context.Context) error {
+		current, err := interactor.ReservationRepository.FindByID(ctx, id)
+		if err != nil {
+			return errors.NewBusinessError("10001E", err)
+		}
+		if current == nil || !canAccessUserResource(ctx, current.UserId) {
+			return errors.NewBusinessError("20001E", nil)
+		}
+
+		to, err := nextReservationStatus(action, current.Status)
+		if err != nil {
+			return err
+		}
+		span.SetAttributes(
+			attribute.String("from_status", current.Status),
+			attribute.String("to_status", to),
+		)
+
+		if err := apply(ctx, current, to); err != nil {
+			var be errors.BusinessError
+			if stderrors.As(err, &be) {
+				return err
+			}
+			return errors.NewBusinessError("10003E", err)
+		}
+
+		// 更新は遷移元のステータスを条件に行うため、他の操作と競合した場合はステータスが一致しない
+		updated, err := interactor.ReservationRepository.FindByID(ctx, id)
+		if err != nil {
+			return errors.NewBusinessError("10001E", err)
+		}
+		if updated == nil || updated.Status != to {
+			return errors.NewBusinessError("20008E", nil)
+		}
+		result = updated
+		events = []model.DomainEvent{model.ReservationStatusChanged{
+			Reservation: *updated,
+			Action:      action,
+			From:        current.Status,
+			To:          updated.Status,
+		}}
+
+		if to == model.ReservationStatusCancelled {
+			if released, err = interactor.releasePet(ctx, updated.PetId); err != nil {
+				return err
+			}
+			if released != nil {
+				events = append(events, *released)
+			}
+		}
+		return recordOutbox(ctx, interactor.Outbox, events...)
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	reservationTransitionsCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("action", action)))
+	if released != nil {
+		petStatusTransitionsCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("action", PetActionRelease)))
+	}
+
+	// コミット後に配信する
+	publishEvents(ctx, interactor.Events, events...)
+	return result, nil
+}
+
+// releasePet 有効な予約が残っていない予約中のペットを予約可能に戻し、変更のイベントを返す
+//
+// 成約済み・掲載終了のペットは変更しない（変更しない場合はnilを返す）
+func (interactor *ReservationInteractor) releasePet(ctx context.Context, petID string) (*model.PetStatusChanged, error) {
+	active, err := interactor.ReservationRepository.GetActiveCountByPetID(ctx, petID)
+	if err != nil {
+		return nil, errors.NewBusinessError("10001E", err)
+	}
+	if active > 0 {
+		return nil, nil
+	}
+
+	pet, err := lockPet(ctx, interactor.PetRepository, petID)
+	if err != nil {
+		return nil, err
+	}
+	if pet.Status != model.PetStatusReserved {
+		return nil, nil
+	}
+	return changePetStatus(ctx, interactor.PetRepository, pet, PetActionRelease)
+}
@@ -0,0 +1,377 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+)
+
+// newMemoryReservationInteractor 初期データを投入したインメモリDBを利用するReservationInteractorを返す
+// 現在時刻は2099/06/15 21:00（Asia/Tokyo）に固定する
+func newMemoryReservationInteractor(t *testing.T) *ReservationInteractor {
+	t.Helper()
+	sqlHandler := newSeededSQLHandler(t)
+	return &ReservationInteractor{
+		ReservationRepository: &repository.ReservationRepository{SQLHandler: sqlHandler},
+		PetRepository:         &repository.PetRepository{SQLHandler: sqlHandler},
+		ShopRepository:        &repository.ShopRepository{SQLHandler: sqlHandler},
+		TransactionManager:    &repository.TransactionManager{SQLHandler: sqlHandler},
+		Now: func() time.Time {
+			return time.Date(2099, 6, 15, 12, 0, 0, 0, time.UTC)
+		},
+	}
+}
+
+// createReservation テスト用の予約（予約枠を持たない予約）を作成し、そのIDを返す
+func createReservation(t *testing.T, interactor *ReservationInteractor, userID string) int {
+	t.Helper()
+	ctx := testContext()
+	err := interactor.ReservationRepository.Create(ctx, &model.Reservation{
+		PetId:        "1",
+		UserId:       userID,
+		Email:        userID + "@example.com",
+		FullName:     "Test User",
+		SlotStartsAt: time.Date(2099, 6, 20, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reservations, err := interactor.ReservationRepository.FindByUserID(ctx, userID, "")
+	if err != nil || len(reservations.Data) == 0 {
+		t.Fatalf("failed to find created reservation: %v", err)
+	}
+	return reservations.Data[len(reservations.Data)-1].ID
+}
+
+func TestNextReservationStatus(t *testing.T) {
+	tests := []struct {
+		action    string
+		current   string
+		expected  string
+		errorCode string
+	}{
+		{action: ReservationActionConfirm, current: model.ReservationStatusPending, expected: model.ReservationStatusConfirmed},
+		{action: ReservationActionConfirm, current: model.ReservationStatusConfirmed, errorCode: "20003E"},
+		{action: ReservationActionConfirm, current: model.ReservationStatusCancelled, errorCode: "20002E"},
+		{action: ReservationActionCancel, current: model.ReservationStatusPending, expected: model.ReservationStatusCancelled},
+		{action: ReservationActionCancel, current: model.ReservationStatusConfirmed, expected: model.ReservationStatusCancelled},
+		{action: ReservationActionCancel, current: model.ReservationStatusCancelled, errorCode: "20004E"},
+		{action: ReservationActionReschedule, current: model.ReservationStatusConfirmed, expected: model.ReservationStatusPending},
+		{action: ReservationActionReschedule, current: model.ReservationStatusCancelled, errorCode: "20005E"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action+"/"+tt.current, func(t *testing.T) {
+			next, err := nextReservationStatus(tt.action, tt.current)
+			if tt.errorCode != "" {
+				assertBusinessError(t, err, tt.errorCode)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if next != tt.expected {
+				t.Errorf("expected %s but got %s", tt.expected, next)
+			}
+		})
+	}
+}
+
+func TestReservationInteractor_Lifecycle(t *testing.T) {
+	interactor := newMemoryReservationInteractor(t)
+	ctx := testContext()
+	id := createReservation(t, interactor, "user1")
+
+	before, err := interactor.GetReservation(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if before.Status != model.ReservationStatusPending {
+		t.Fatalf("expected pending but got %s", before.Status)
+	}
+
+	confirmed, err := interactor.ConfirmReservation(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if confirmed.Status != model.ReservationStatusConfirmed {
+		t.Errorf("expected confirmed but got %s", confirmed.Status)
+	}
+	if confirmed.UpdatedAt == nil || !confirmed.UpdatedAt.After(*before.UpdatedAt) {
+		t.Errorf("expected updated_at to be refreshed")
+	}
+
+	// 確定済みの予約を再度確定することはできない
+	_, err = interactor.ConfirmReservation(ctx, id)
+	assertBusinessError(t, err, "20003E")
+
+	// 日程を変更すると再度確定が必要になる
+	rescheduled, err := interactor.RescheduleReservation(ctx, id, testSlotStartsAt(7, 1, 10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rescheduled.Status != model.ReservationStatusPending || rescheduled.ReservationDateTime.Day() != 1 || rescheduled.ReservationDateTime.Hour() != 10 {
+		t.Errorf("unexpected reservation after reschedule: %+v", rescheduled)
+	}
+	// 予約枠は店舗のタイムゾーンで返す
+	if rescheduled.ShopID == nil || *rescheduled.ShopID != 1 || rescheduled.SlotTimezone == nil || *rescheduled.SlotTimezone != "Asia/Tokyo" ||
+		rescheduled.SlotStartsAt == nil || rescheduled.SlotStartsAt.Format(time.RFC3339) != "2099-07-01T10:00:00+09:00" {
+		t.Errorf("unexpected slot after reschedule: %+v", rescheduled)
+	}
+
+	cancelled, err := interactor.CancelReservation(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cancelled.Status != model.ReservationStatusCancelled {
+		t.Errorf("expected cancelled but got %s", cancelled.Status)
+	}
+
+	// キャンセル済みの予約は確定・再キャンセル・日程変更できない
+	_, err = interactor.ConfirmReservation(ctx, id)
+	assertBusinessError(t, err, "20002E")
+	_, err = interactor.CancelReservation(ctx, id)
+	assertBusinessError(t, err, "20004E")
+	_, err = interactor.RescheduleReservation(ctx, id, testSlotStartsAt(7, 1, 10))
+	assertBusinessError(t, err, "20005E")
+}
+
+func TestReservationInteractor_Reschedule_InvalidSlot(t *testing.T) {
+	interactor := newMemoryReservationInteractor(t)
+	ctx := testContext()
+	id := createReservation(t, interactor, "user1")
+
+	// 開始済みの枠には変更できない
+	_, err := interactor.RescheduleReservation(ctx, id, testSlotStartsAt(6, 15, 17))
+	assertBusinessError(t, err, "20006E")
+
+	// 枠の開始日時でない・営業時間外の日時には変更できない
+	_, err = interactor.RescheduleReservation(ctx, id, testSlotStartsAt(7, 1, 10).Add(30*time.Minute))
+	assertBusinessError(t, err, "20009E")
+	_, err = interactor.RescheduleReservation(ctx, id, testSlotStartsAt(7, 1, 9))
+	assertBusinessError(t, err, "20009E")
+	_, err = interactor.RescheduleReservation(ctx, id, testSlotStartsAt(7, 1, 18))
+	assertBusinessError(t, err, "20009E")
+
+	// 同じ日時であれば異なるオフセットで指定してもよい
+	if _, err := interactor.RescheduleReservation(ctx, id, testSlotStartsAt(6, 16, 10).UTC()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestReservationInteractor_Reschedule_Capacity(t *testing.T) {
+	interactor := newMemoryReservationInteractor(t)
+	ctx := testContext()
+	slot := testSlotStartsAt(7, 1, 10)
+	first := createReservation(t, interactor, "user1")
+	second := createReservation(t, interactor, "user2")
+	third := createReservation(t, interactor, "user3")
+
+	for _, id := range []int{first, second} {
+		if _, err := interactor.RescheduleReservation(ctx, id, slot); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// 1枠2席のため3件目は受け付けない
+	_, err := interactor.RescheduleReservation(ctx, third, slot)
+	assertBusinessError(t, err, "20010E")
+
+	// 自身が確保している席は空席として扱う
+	if _, err := interactor.RescheduleReservation(ctx, first, slot); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// キャンセルすると席が空く
+	if _, err := interactor.CancelReservation(ctx, second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := interactor.RescheduleReservation(ctx, third, slot); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// staleSeatsRepository 予約枠の席の確認後に他の予約が同じ席を確保した状況を再現する
+type staleSeatsRepository struct {
+	*repository.ReservationRepository
+}
+
+func (r *staleSeatsRepository) FindSeats(ctx context.Context, shopID int64, startsAt time.Time) (map[int]int, error) {
+	return map[int]int{}, nil
+}
+
+func TestReservationInteractor_Reschedule_SeatConflict(t *testing.T) {
+	interactor := newMemoryReservationInteractor(t)
+	ctx := testContext()
+	slot := testSlotStartsAt(7, 1, 10)
+	first := createReservation(t, interactor, "user1")
+	second := createReservation(t, interactor, "user2")
+	if _, err := interactor.RescheduleReservation(ctx, first, slot); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 同じ席を選んだ予約は一意制約で失敗し、再試行を促すエラーとなる
+	interactor.ReservationRepository = &staleSeatsRepository{interactor.ReservationRepository.(*repository.ReservationRepository)}
+	_, err := interactor.RescheduleReservation(ctx, second, slot)
+	assertBusinessError(t, err, "20011E")
+
+	if reservation, _ := interactor.GetReservation(ctx, second); reservation.SlotStartsAt != nil {
+		t.Errorf("expected reschedule to be rolled back, got %+v", reservation)
+	}
+}
+
+func TestReservationInteractor_NotFound(t *testing.T) {
+	interactor := newMemoryReservationInteractor(t)
+	ctx := testContext()
+
+	_, err := interactor.GetReservation(ctx, 999)
+	assertBusinessError(t, err, "20001E")
+	_, err = interactor.ConfirmReservation(ctx, 999)
+	assertBusinessError(t, err, "20001E")
+}
+
+func TestReservationInteractor_OtherUsersReservation(t *testing.T) {
+	interactor := newMemoryReservationInteractor(t)
+	id := createReservation(t, interactor, "user2")
+	ctx := model.WithPrincipal(testContext(), &model.Principal{ID: "user1", Kind: model.PrincipalKindUser})
+
+	// 他のユーザーの予約は存在しないものとして扱う
+	_, err := interactor.GetReservation(ctx, id)
+	assertBusinessError(t, err, "20001E")
+	_, err = interactor.CancelReservation(ctx, id)
+	assertBusinessError(t, err, "20001E")
+
+	_, err = interactor.GetReservations(ctx, "user2", "")
+	assertBusinessError(t, err, "00007E")
+
+	// 確定は店舗（サービス）のみ行え、予約した本人でも確定できない
+	ctx = model.WithPrincipal(testContext(), &model.Principal{ID: "user2", Kind: model.PrincipalKindUser})
+	_, err = interactor.ConfirmReservation(ctx, id)
+	assertBusinessError(t, err, "00008E")
+	if _, err := interactor.ConfirmReservation(serviceContext(), id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 本人であればキャンセルできる
+	if _, err := interactor.CancelReservation(ctx, id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reservations, err := interactor.GetReservations(ctx, "", "")
+	if err != nil || len(reservations.Data) != 1 {
+		t.Errorf("expected own reservations but got %+v, %v", reservations, err)
+	}
+}
+
+func TestReservationInteractor_GetReservations(t *testing.T) {
+	interactor := newMemoryReservationInteractor(t)
+	ctx := testContext()
+	first := createReservation(t, interactor, "user1")
+	createReservation(t, interactor, "user1")
+	createReservation(t, interactor, "user2")
+
+	if _, err := interactor.CancelReservation(ctx, first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	all, err := interactor.GetReservations(ctx, "user1", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(all.Data) != 2 {
+		t.Errorf("expected 2 reservations but got %d", len(all.Data))
+	}
+
+	pending, err := interactor.GetReservations(ctx, "user1", model.ReservationStatusPending)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pending.Data) != 1 {
+		t.Errorf("expected 1 pending reservation but got %d", len(pending.Data))
+	}
+
+	_, err = interactor.GetReservations(ctx, "user1", "unknown")
+	assertBusinessError(t, err, "00003E")
+}
+
+func TestReservationInteractor_ConcurrentUpdate(t *testing.T) {
+	// 他の操作で先にキャンセルされ、条件付き更新が反映されなかった場合を再現する
+	mockRepo := &MockReservationRepository{
+		FindByIDFunc: func(ctx context.Context, id int) (*model.ReservationDetail, error) {
+			return &model.ReservationDetail{ID: id, Status: model.ReservationStatusPending}, nil
+		},
+	}
+	mockTxManager := &MockTransactionManager{}
+	interactor := &ReservationInteractor{
+		ReservationRepository: mockRepo,
+		TransactionManager:    mockTxManager,
+	}
+
+	_, err := interactor.ConfirmReservation(testContext(), 1)
+	assertBusinessError(t, err, "20008E")
+	if !mockTxManager.RolledBack {
+		t.Errorf("expected transaction to be rolled back")
+	}
+}
+
+func TestReservationInteractor_CancelReleasesPet(t *testing.T) {
+	interactor := newMemoryReservationInteractor(t)
+	bus := NewEventBus()
+	interactor.Events = bus
+	events := recordEvents(bus, model.EventPetStatusChanged)
+	pets := &PetInteractor{
+		PetRepository:         interactor.PetRepository,
+		ReservationRepository: interactor.ReservationRepository,
+		ShopRepository:        interactor.ShopRepository,
+		TransactionManager:    interactor.TransactionManager,
+		Now:                   interactor.Now,
+	}
+	ctx := testContext()
+	petStatus := func() string {
+		pet, _ := interactor.PetRepository.FindByID(ctx, "1")
+		return pet.Status
+	}
+
+	if err := pets.CreateReservation(ctx, &model.Reservation{PetId: "1", UserId: "user1", SlotStartsAt: testSlotStartsAt(6, 20, 10)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first, _ := interactor.GetReservations(ctx, "user1", "")
+	// ステータスの導入前に作成された予約を再現する
+	second := createReservation(t, interactor, "user2")
+
+	// 有効な予約が残っている間は予約中のまま
+	if _, err := interactor.CancelReservation(ctx, first.Data[0].ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := petStatus(); status != model.PetStatusReserved || len(*events) != 0 {
+		t.Fatalf("expected pet to stay reserved, got %s (%+v)", status, *events)
+	}
+
+	// 最後の予約がキャンセルされると予約可能に戻る
+	if _, err := interactor.CancelReservation(ctx, second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := petStatus(); status != model.PetStatusAvailable {
+		t.Fatalf("expected pet to be available, got %s", status)
+	}
+	if len(*events) != 1 || (*events)[0].(model.PetStatusChanged).Action != PetActionRelease {
+		t.Errorf("expected release event, got %+v", *events)
+	}
+
+	// 成約済みのペットは予約をキャンセルしても変更しない
+	if err := pets.CreateReservation(ctx, &model.Reservation{PetId: "1", UserId: "user3", SlotStartsAt: testSlotStartsAt(6, 20, 10)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := pets.AdoptPet(serviceContext(), "1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	third, _ := interactor.GetReservations(ctx, "user3", "")
+	if _, err := interactor.CancelReservation(ctx, third.Data[0].ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := petStatus(); status != model.PetStatusAdopted {
+		t.Errorf("expected pet to stay adopted, got %s", status)
+	}
+}
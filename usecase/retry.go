@@ -0,0 +1,35 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// pollBatches ctxがキャンセルされるまでintervalごとにprocessを呼び出す
+//
+// processがbatchSize件を処理した場合は残りがあるとみなして待たずに呼び出す。
+// processにはキャンセルされないcontextを渡すため、処理中のバッチは最後まで完了してから戻る
+func pollBatches(ctx context.Context, interval time.Duration, batchSize int, name string, process func(ctx context.Context) (int, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := process(context.WithoutCancel(ctx))
+			if err != nil {
+				zerolog.Ctx(ctx).Error().Err(err).Msgf("failed to process %s", name)
+			}
+			if err != nil || n < batchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ShopInteractor ペットを扱う店舗を参照する
+type ShopInteractor struct {
+	ShopRepository     repository.ShopRepositoryInterface
+	TransactionManager repository.TransactionManagerInterface
+	// Pets 店舗のペットの一覧をGET /v1/petsと同じ形式で返すために利用する
+	Pets *PetInteractor
+}
+
+// GetShops 店舗を登録順に取得する
+func (interactor *ShopInteractor) GetShops(ctx context.Context) (shops model.Shops, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("shop-interactor")
+	ctx, span := tracer.Start(ctx, "ShopInteractor.GetShops",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	shops, err = interactor.ShopRepository.Find(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return shops, errors.NewBusinessError("10001E", err)
+	}
+	return
+}
+
+// GetShop 店舗を取得する
+func (interactor *ShopInteractor) GetShop(ctx context.Context, id int64) (*model.Shop, error) {
+	// スパンを作成
+	tracer := otel.Tracer("shop-interactor")
+	ctx, span := tracer.Start(ctx, "ShopInteractor.GetShop",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("shop_id", id),
+	)
+
+	shop, err := interactor.findShop(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return shop, nil
+}
+
+// GetShopPets 店舗のペットを取得する（絞り込み・並び替え・ページングはGET /v1/petsと同じ）
+func (interactor *ShopInteractor) GetShopPets(ctx context.Context, id int64, filter *model.PetFilter) ([]model.Pet, model.PageInfo, error) {
+	// スパンを作成
+	tracer := otel.Tracer("shop-interactor")
+	ctx, span := tracer.Start(ctx, "ShopInteractor.GetShopPets",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("shop_id", id),
+	)
+
+	if _, err := interactor.findShop(ctx, id); err != nil {
+		span.RecordError(err)
+		return nil, model.PageInfo{}, err
+	}
+
+	if filter == nil {
+		filter = &model.PetFilter{}
+	}
+	filter.ShopID = id
+	return interactor.Pets.GetPets(ctx, filter)
+}
+
+// GetSchedule 店舗の見学予約の受付設定を取得する
+func (interactor *ShopInteractor) GetSchedule(ctx context.Context, id int64) (*model.ShopSchedule, error) {
+	// スパンを作成
+	tracer := otel.Tracer("shop-interactor")
+	ctx, span := tracer.Start(ctx, "ShopInteractor.GetSchedule",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("shop_id", id),
+	)
+
+	schedule, _, err := findSchedule(ctx, interactor.ShopRepository, id)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// UpdateSchedule 店舗の見学予約の受付設定を置き換え、更新後の設定を返す
+//
+// 確保済みの予約枠は変更しない（枠の長さや営業時間を変えても既存の予約はそのまま有効）
+func (interactor *ShopInteractor) UpdateSchedule(ctx context.Context, schedule *model.ShopSchedule) (*model.ShopSchedule, error) {
+	// スパンを作成
+	tracer := otel.Tracer("shop-interactor")
+	ctx, span := tracer.Start(ctx, "ShopInteractor.UpdateSchedule",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("shop_id", schedule.ShopID),
+		attribute.String("timezone", schedule.Timezone),
+	)
+
+	if err := requireService(ctx); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := validateSchedule(schedule); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	var updated *model.ShopSchedule
+	err := interactor.TransactionManager.Do(ctx, func(ctx context.Context) error {
+		if _, err := interactor.findShop(ctx, schedule.ShopID); err != nil {
+			return err
+		}
+		if err := interactor.ShopRepository.UpdateSchedule(ctx, schedule); err != nil {
+			return errors.NewBusinessError("10003E", err)
+		}
+		var err error
+		updated, _, err = findSchedule(ctx, interactor.ShopRepository, schedule.ShopID)
+		return err
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+// findShop 店舗を取得する（存在しない場合は50001E）
+func (interactor *ShopInteractor) findShop(ctx context.Context, id int64) (*model.Shop, error) {
+	shop, err := interactor.ShopRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, errors.NewBusinessError("10001E", err)
+	}
+	if shop == nil {
+		return nil, errors.NewBusinessError("50001E", fmt.Errorf("shop not found: %d", id))
+	}
+	return shop, nil
+}
@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+)
+
+// newMemoryShopInteractor 初期データの店舗に加えて2つ目の店舗を作成したShopInteractorを返す
+func newMemoryShopInteractor(t *testing.T) (*ShopInteractor, *PetInteractor) {
+	t.Helper()
+	pets, sqlHandler := newMemoryPetInteractor(t)
+	in := map[string]interface{}{"name": "uma-arai shop 3rd", "location": "Tokyo"}
+	if err := sqlHandler.Create(testContext(), in, repository.ShopsTable); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return &ShopInteractor{
+		ShopRepository:     &repository.ShopRepository{SQLHandler: sqlHandler},
+		TransactionManager: &repository.TransactionManager{SQLHandler: sqlHandler},
+		Pets:               pets,
+	}, pets
+}
+
+func TestShopInteractor_GetShops(t *testing.T) {
+	interactor, _ := newMemoryShopInteractor(t)
+	ctx := testContext()
+
+	shops, err := interactor.GetShops(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(shops.Data) != 2 || shops.Data[0].ID != 1 || shops.Data[0].Name != "uma-arai shop 2nd" || shops.Data[1].Location != "Tokyo" {
+		t.Errorf("unexpected shops: %+v", shops.Data)
+	}
+
+	shop, err := interactor.GetShop(ctx, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if shop.Name != "uma-arai shop 3rd" {
+		t.Errorf("unexpected shop: %+v", shop)
+	}
+	_, err = interactor.GetShop(ctx, 999)
+	assertBusinessError(t, err, "50001E")
+}
+
+func TestShopInteractor_GetShopPets(t *testing.T) {
+	interactor, pets := newMemoryShopInteractor(t)
+	ctx := serviceContext()
+
+	// ペットを2つ目の店舗に移す
+	shopID := int64(2)
+	moved, err := pets.UpdatePet(ctx, "2", &model.PetChanges{ShopID: &shopID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if moved.Shop != (model.Shop{ID: 2, Name: "uma-arai shop 3rd", Location: "Tokyo"}) {
+		t.Errorf("unexpected shop after move: %+v", moved.Shop)
+	}
+
+	list, _, err := interactor.GetShopPets(ctx, 2, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != "2" {
+		t.Errorf("expected only moved pet, got %+v", list)
+	}
+	list, page, err := interactor.GetShopPets(ctx, 1, &model.PetFilter{Limit: 5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 5 || page.TotalCount != 11 {
+		t.Errorf("expected 5 of 11 pets, got %d of %d", len(list), page.TotalCount)
+	}
+	for _, p := range list {
+		if p.Shop.ID != 1 {
+			t.Errorf("unexpected pet from another shop: %+v", p)
+		}
+	}
+
+	_, _, err = interactor.GetShopPets(ctx, 999, nil)
+	assertBusinessError(t, err, "50001E")
+
+	// 存在しない店舗には移せない
+	unknown := int64(999)
+	_, err = pets.UpdatePet(ctx, "2", &model.PetChanges{ShopID: &unknown})
+	assertBusinessError(t, err, "50002E")
+}
+
+func TestShopInteractor_Schedule(t *testing.T) {
+	interactor, _ := newMemoryShopInteractor(t)
+	ctx := testContext()
+
+	// 初期データの店舗は毎日10:00〜18:00、追加した店舗は営業時間の登録がない
+	schedule, err := interactor.GetSchedule(ctx, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if schedule.Timezone != "Asia/Tokyo" || schedule.SlotMinutes != 60 || schedule.SlotCapacity != 2 || len(schedule.OpeningHours) != 7 ||
+		schedule.OpeningHours[0] != (model.OpeningHours{Weekday: 0, OpensAt: "10:00", ClosesAt: "18:00"}) {
+		t.Errorf("unexpected schedule: %+v", schedule)
+	}
+	if schedule, err := interactor.GetSchedule(ctx, 2); err != nil || len(schedule.OpeningHours) != 0 {
+		t.Errorf("expected no opening hours, got %+v (%v)", schedule, err)
+	}
+	_, err = interactor.GetSchedule(ctx, 999)
+	assertBusinessError(t, err, "50001E")
+
+	input := &model.ShopSchedule{
+		ShopID:       1,
+		Timezone:     "Europe/London",
+		SlotMinutes:  30,
+		SlotCapacity: 5,
+		OpeningHours: []model.OpeningHours{
+			{Weekday: 6, OpensAt: "09:00", ClosesAt: "12:00"},
+			{Weekday: 0, OpensAt: "13:00", ClosesAt: "17:30"},
+		},
+	}
+	userCtx := model.WithPrincipal(ctx, &model.Principal{ID: "user1", Kind: model.PrincipalKindUser})
+	_, err = interactor.UpdateSchedule(userCtx, input)
+	assertBusinessError(t, err, "00008E")
+
+	updated, err := interactor.UpdateSchedule(serviceContext(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := *input
+	expected.OpeningHours = []model.OpeningHours{input.OpeningHours[1], input.OpeningHours[0]}
+	if !reflect.DeepEqual(*updated, expected) {
+		t.Errorf("unexpected schedule after update: %+v", updated)
+	}
+
+	input.ShopID = 999
+	_, err = interactor.UpdateSchedule(serviceContext(), input)
+	assertBusinessError(t, err, "50001E")
+	input.ShopID = 1
+	input.OpeningHours = []model.OpeningHours{{Weekday: 1, OpensAt: "12:00", ClosesAt: "12:15"}}
+	_, err = interactor.UpdateSchedule(serviceContext(), input)
+	assertBusinessError(t, err, "50003E")
+}
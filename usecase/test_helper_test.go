@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	business_errors "github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/horsewin/echo-playground-v2/infrastructure/memory"
+)
+
+func init() {
+}
+
+// testContext はテスト用のコンテキストを作成する
+func testContext() context.Context {
+	return context.Background()
+}
+
+// serviceContext サービス間連携（APIキー）で認証されたコンテキストを返す
+func serviceContext() context.Context {
+	return model.WithPrincipal(testContext(), &model.Principal{ID: "partner", Kind: model.PrincipalKindService})
+}
+
+// newSeededSQLHandler 初期データを投入したインメモリDBを作成する
+func newSeededSQLHandler(t *testing.T) *memory.SQLHandler {
+	t.Helper()
+	sqlHandler, err := memory.NewSeededSQLHandler()
+	if err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	return sqlHandler
+}
+
+// assertBusinessError エラーが指定したコードのBusinessErrorであることを確認する
+func assertBusinessError(t *testing.T, err error, code string) {
+	t.Helper()
+	var be business_errors.BusinessError
+	if !errors.As(err, &be) {
+		t.Fatalf("expected BusinessError %s but got %v", code, err)
+	}
+	if be.Code() != code {
+		t.Errorf("expected error code %s but got %s", code, be.Code())
+	}
+}
+
+// testSlotStartsAt 初期データの店舗（Asia/Tokyo、毎日10:00〜18:00、1枠60分・2席）の2099年の予約枠の開始日時を返す
+func testSlotStartsAt(month time.Month, day int, hour int) time.Time {
+	return time.Date(2099, month, day, hour, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+}
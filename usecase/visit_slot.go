@@ -0,0 +1,171 @@
+package usecase
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+)
+
+// clockLayout 営業時間の時刻の形式
+const clockLayout = "15:04"
+
+// parseClock hh:mm形式の時刻を0時からの経過分に変換する
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse(clockLayout, clock)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// visitSlotsOn 店舗のタイムゾーンでdateの日に開始する予約枠を開始日時の昇順で返す
+//
+// 枠は開店時刻からSlotMinutesごとに区切り、閉店時刻までに終わる枠のみとする
+func visitSlotsOn(schedule *model.ShopSchedule, loc *time.Location, date time.Time) []model.VisitSlot {
+	y, m, d := date.In(loc).Date()
+	weekday := int(time.Date(y, m, d, 0, 0, 0, 0, loc).Weekday())
+
+	var slots []model.VisitSlot
+	for _, hours := range schedule.OpeningHours {
+		if hours.Weekday != weekday {
+			continue
+		}
+		opens, err := parseClock(hours.OpensAt)
+		if err != nil {
+			continue
+		}
+		closes, err := parseClock(hours.ClosesAt)
+		if err != nil {
+			continue
+		}
+		for minute := opens; minute+schedule.SlotMinutes <= closes; minute += schedule.SlotMinutes {
+			slots = append(slots, model.VisitSlot{
+				StartsAt: time.Date(y, m, d, 0, minute, 0, 0, loc),
+				EndsAt:   time.Date(y, m, d, 0, minute+schedule.SlotMinutes, 0, 0, loc),
+				Capacity: schedule.SlotCapacity,
+			})
+		}
+	}
+	return slots
+}
+
+// isVisitSlotStart startsAtが店舗の予約枠の開始日時かを判定する
+func isVisitSlotStart(schedule *model.ShopSchedule, loc *time.Location, startsAt time.Time) bool {
+	for _, slot := range visitSlotsOn(schedule, loc, startsAt) {
+		if slot.StartsAt.Equal(startsAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSchedule 店舗の受付設定を確認する（不正な場合は50003E）
+//
+// 曜日の重複、開店時刻が閉店時刻以降、1枠も取れない営業時間は受け付けない
+func validateSchedule(schedule *model.ShopSchedule) error {
+	seen := make(map[int]bool, len(schedule.OpeningHours))
+	for _, hours := range schedule.OpeningHours {
+		if seen[hours.Weekday] {
+			return errors.NewBusinessError("50003E", fmt.Errorf("duplicated weekday: %d", hours.Weekday))
+		}
+		seen[hours.Weekday] = true
+
+		opens, err := parseClock(hours.OpensAt)
+		if err != nil {
+			return errors.NewBusinessError("50003E", err)
+		}
+		closes, err := parseClock(hours.ClosesAt)
+		if err != nil {
+			return errors.NewBusinessError("50003E", err)
+		}
+		if opens+schedule.SlotMinutes > closes {
+			return errors.NewBusinessError("50003E", fmt.Errorf("no visit slot fits in %s-%s on weekday %d", hours.OpensAt, hours.ClosesAt, hours.Weekday))
+		}
+	}
+	return nil
+}
+
+// findSchedule 店舗の受付設定とタイムゾーンを取得する（店舗が存在しない場合は50001E）
+func findSchedule(ctx context.Context, shops repository.ShopRepositoryInterface, shopID int64) (*model.ShopSchedule, *time.Location, error) {
+	schedule, err := shops.FindSchedule(ctx, shopID)
+	if err != nil {
+		return nil, nil, errors.NewBusinessError("10001E", err)
+	}
+	if schedule == nil {
+		return nil, nil, errors.NewBusinessError("50001E", fmt.Errorf("shop not found: %d", shopID))
+	}
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return nil, nil, errors.NewBusinessError("10002E", err)
+	}
+	return schedule, loc, nil
+}
+
+// bookVisitSlot 店舗のstartsAtに開始する予約枠で空いている席を選ぶ
+//
+// 席は1〜SlotCapacityの番号で、exceptIDの予約（日程の変更の場合）が確保している席は空席として扱う
+// 同時に予約した場合に同じ席を選ばないよう、店舗の行をトランザクションの終了までロックして席の確保を直列化する
+// （トランザクション内でのみ利用できる）
+func bookVisitSlot(ctx context.Context, shops repository.ShopRepositoryInterface, reservations repository.ReservationRepositoryInterface, shopID int64, startsAt time.Time, now time.Time, exceptID int) (slot model.ReservationSlot, err error) {
+	shop, err := shops.FindByIDForUpdate(ctx, shopID)
+	if err != nil {
+		return slot, errors.NewBusinessError("10001E", err)
+	}
+	if shop == nil {
+		return slot, errors.NewBusinessError("50001E", fmt.Errorf("shop not found: %d", shopID))
+	}
+	schedule, loc, err := findSchedule(ctx, shops, shopID)
+	if err != nil {
+		return slot, err
+	}
+	if !startsAt.After(now) {
+		return slot, errors.NewBusinessError("20006E", nil)
+	}
+	if !isVisitSlotStart(schedule, loc, startsAt) {
+		return slot, errors.NewBusinessError("20009E", fmt.Errorf("not a visit slot: %s", startsAt.Format(time.RFC3339)))
+	}
+
+	seats, err := reservations.FindSeats(ctx, shopID, startsAt)
+	if err != nil {
+		return slot, errors.NewBusinessError("10001E", err)
+	}
+	// 受付数を減らした場合は番号の大きい席が確保されたまま残るため、確保済みの席の数も確認する
+	booked := 0
+	for _, id := range seats {
+		if id != exceptID {
+			booked++
+		}
+	}
+	seat := 0
+	for n := 1; n <= schedule.SlotCapacity; n++ {
+		if id, taken := seats[n]; !taken || id == exceptID {
+			seat = n
+			break
+		}
+	}
+	if seat == 0 || booked >= schedule.SlotCapacity {
+		return slot, errors.NewBusinessError("20010E", nil)
+	}
+
+	return model.ReservationSlot{
+		ShopID:   shopID,
+		StartsAt: startsAt.In(loc),
+		Timezone: schedule.Timezone,
+		Seat:     seat,
+	}, nil
+}
+
+// visitSlotWriteError 予約枠を確保した予約の保存のエラーを業務エラーに変換する
+//
+// 席の確保はbookVisitSlotで直列化しているが、ロックを経由せずに同じ席が確保された場合は一意制約の違反になる
+func visitSlotWriteError(err error) error {
+	if stderrors.Is(err, repository.ErrSlotSeatTaken) {
+		return errors.NewBusinessError("20011E", err)
+	}
+	return errors.NewBusinessError("10003E", err)
+}
@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+)
+
+func TestVisitSlotsOn(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	schedule := &model.ShopSchedule{
+		SlotMinutes:  90,
+		SlotCapacity: 3,
+		// 2099/06/20は土曜日
+		OpeningHours: []model.OpeningHours{{Weekday: 6, OpensAt: "10:00", ClosesAt: "14:00"}},
+	}
+
+	// 閉店時刻までに終わる枠のみとする
+	slots := visitSlotsOn(schedule, loc, time.Date(2099, 6, 20, 0, 0, 0, 0, loc))
+	if len(slots) != 2 {
+		t.Fatalf("expected 2 slots but got %+v", slots)
+	}
+	if slots[1].StartsAt.Format(time.RFC3339) != "2099-06-20T11:30:00+09:00" || slots[1].EndsAt.Format(time.RFC3339) != "2099-06-20T13:00:00+09:00" ||
+		slots[1].Capacity != 3 {
+		t.Errorf("unexpected slot: %+v", slots[1])
+	}
+
+	// 日付は店舗のタイムゾーンで判定する（UTCでは金曜日）
+	if slots := visitSlotsOn(schedule, loc, time.Date(2099, 6, 19, 20, 0, 0, 0, time.UTC)); len(slots) != 2 {
+		t.Errorf("expected slots on saturday in JST but got %+v", slots)
+	}
+	// 営業時間の登録のない曜日は定休日
+	if slots := visitSlotsOn(schedule, loc, time.Date(2099, 6, 21, 0, 0, 0, 0, loc)); len(slots) != 0 {
+		t.Errorf("expected no slots on a closed day but got %+v", slots)
+	}
+
+	if !isVisitSlotStart(schedule, loc, time.Date(2099, 6, 20, 2, 30, 0, 0, time.UTC)) {
+		t.Errorf("expected 11:30 JST to be a slot start")
+	}
+	if isVisitSlotStart(schedule, loc, time.Date(2099, 6, 20, 13, 0, 0, 0, loc)) {
+		t.Errorf("expected 13:00 not to be a slot start")
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	tests := []struct {
+		name  string
+		hours []model.OpeningHours
+		valid bool
+	}{
+		{name: "定休日のみ", valid: true},
+		{name: "1枠のみ", hours: []model.OpeningHours{{Weekday: 1, OpensAt: "10:00", ClosesAt: "11:00"}}, valid: true},
+		{name: "曜日の重複", hours: []model.OpeningHours{{Weekday: 1, OpensAt: "10:00", ClosesAt: "12:00"}, {Weekday: 1, OpensAt: "13:00", ClosesAt: "15:00"}}},
+		{name: "閉店時刻が開店時刻以前", hours: []model.OpeningHours{{Weekday: 1, OpensAt: "18:00", ClosesAt: "10:00"}}},
+		{name: "1枠も取れない", hours: []model.OpeningHours{{Weekday: 1, OpensAt: "10:00", ClosesAt: "10:30"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSchedule(&model.ShopSchedule{Timezone: "Asia/Tokyo", SlotMinutes: 60, SlotCapacity: 2, OpeningHours: tt.hours})
+			if tt.valid {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			assertBusinessError(t, err, "50003E")
+		})
+	}
+}
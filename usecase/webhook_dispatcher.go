@@ -0,0 +1,353 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"github.com/horsewin/echo-playground-v2/utils"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WebhookDispatcherのデフォルト値
+const (
+	defaultWebhookBatchSize      = 20
+	defaultWebhookPollInterval   = time.Second
+	defaultWebhookMaxAttempts    = 8
+	defaultWebhookInitialBackoff = 10 * time.Second
+	defaultWebhookMaxBackoff     = time.Hour
+	defaultWebhookLease          = 15 * time.Minute
+)
+
+// WebhookSender 署名したイベントを送信先にPOSTする
+type WebhookSender interface {
+	// Send 応答のステータスコードを返す。応答がない場合は0とエラー、2xx以外の応答の場合はステータスコードとエラーを返す
+	Send(ctx context.Context, subscription model.WebhookSubscription, delivery model.WebhookDelivery) (statusCode int, err error)
+}
+
+// WebhookFanout outboxのイベントを購読している送信先ごとに送信待ちとして記録する
+//
+// OutboxRelayの配信先として使う。配信はトランザクションの外で行われるため、
+// 途中で失敗した場合は再配信時に記録済みの送信先を除いて記録する
+type WebhookFanout struct {
+	PetRepository          repository.PetRepositoryInterface
+	SubscriptionRepository repository.WebhookSubscriptionRepositoryInterface
+	DeliveryRepository     repository.WebhookDeliveryRepositoryInterface
+}
+
+// webhookEventSubject イベントの対象のペット（店舗の特定に使う）
+type webhookEventSubject struct {
+	PetId       string `json:"pet_id"`
+	Reservation struct {
+		PetId string `json:"pet_id"`
+	} `json:"reservation"`
+	Pet struct {
+		ID   string     `json:"id"`
+		Shop model.Shop `json:"shop"`
+	} `json:"pet"`
+}
+
+// Publish ...
+func (f *WebhookFanout) Publish(ctx context.Context, message model.OutboxMessage) error {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-fanout")
+	ctx, span := tracer.Start(ctx, "WebhookFanout.Publish",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("event_id", message.ID),
+		attribute.String("event_type", message.EventType),
+	)
+
+	if !model.IsWebhookEventType(message.EventType) {
+		return nil
+	}
+
+	shopID, err := f.shopOf(ctx, message)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if shopID == 0 {
+		return nil
+	}
+	span.SetAttributes(attribute.Int64("shop_id", shopID))
+
+	subscriptions, err := f.SubscriptionRepository.FindActive(ctx, shopID, message.EventType)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	// 前回の配信が記録前に失敗した場合に同じイベントを二重に記録しない
+	existing, err := f.DeliveryRepository.FindByEventID(ctx, message.ID)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	recorded := make(map[int64]bool, len(existing))
+	for _, d := range existing {
+		recorded[d.SubscriptionID] = true
+	}
+
+	for _, subscription := range subscriptions {
+		if recorded[subscription.ID] {
+			continue
+		}
+		err := f.DeliveryRepository.Create(ctx, &model.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        message.ID,
+			EventType:      message.EventType,
+			Payload:        message.Payload,
+		})
+		if err != nil {
+			span.RecordError(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// shopOf イベントの対象のペットを扱う店舗のIDを返す
+//
+// 削除されたペットはイベントに含まれる削除時の店舗を返す（いずれも見つからない場合は0）
+func (f *WebhookFanout) shopOf(ctx context.Context, message model.OutboxMessage) (int64, error) {
+	var subject webhookEventSubject
+	if err := json.Unmarshal([]byte(message.Payload), &subject); err != nil {
+		return 0, fmt.Errorf("invalid %s payload: %w", message.EventType, err)
+	}
+	petID := subject.PetId
+	if petID == "" {
+		petID = subject.Reservation.PetId
+	}
+	if petID == "" {
+		petID = subject.Pet.ID
+	}
+	if petID == "" {
+		return 0, nil
+	}
+
+	pets, err := f.PetRepository.Find(ctx, &model.PetFilter{ID: petID})
+	if err != nil {
+		return 0, err
+	}
+	if len(pets.Data) == 0 {
+		return subject.Pet.Shop.ID, nil
+	}
+	return pets.Data[0].ShopID, nil
+}
+
+// WebhookDispatcher 送信待ちのWebhookを定期的に取り出して送信する
+type WebhookDispatcher struct {
+	DeliveryRepository     repository.WebhookDeliveryRepositoryInterface
+	SubscriptionRepository repository.WebhookSubscriptionRepositoryInterface
+	TransactionManager     repository.TransactionManagerInterface
+	Sender                 WebhookSender
+	// BatchSize 1回に取得して送信する上限
+	BatchSize int
+	// PollInterval 送信待ちを確認する間隔
+	PollInterval time.Duration
+	// MaxAttempts 送信を試行する回数の上限（超えた場合はdeadにする）
+	MaxAttempts int
+	// InitialBackoff 初回の失敗から再試行までの待ち時間（失敗するたびに倍にする）
+	InitialBackoff time.Duration
+	// MaxBackoff 再試行までの待ち時間の上限
+	MaxBackoff time.Duration
+	// Lease 取得した送信を他のタスクに取得させない期間（1バッチの送信にかかる時間より長くする）
+	Lease time.Duration
+	// Now 現在時刻を返す関数（未指定の場合はtime.Now）
+	Now func() time.Time
+}
+
+// Run ctxがキャンセルされるまでPollIntervalごとに送信する
+//
+// キャンセル時に処理中のバッチは最後まで送信して結果を記録してから戻る
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	pollBatches(ctx, d.pollInterval(), d.batchSize(), "webhook deliveries", d.DispatchOnce)
+}
+
+// DispatchOnce 送信待ちを1バッチ分送信し、処理した件数を返す
+//
+// 送信中にトランザクションと行ロックを保持しないよう、取得は短いトランザクションで行ってコミットし、
+// 送信はトランザクションの外で、結果の記録は送信ごとのトランザクションで行う
+func (d *WebhookDispatcher) DispatchOnce(ctx context.Context) (processed int, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-dispatcher")
+	ctx, span := tracer.Start(ctx, "WebhookDispatcher.DispatchOnce",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 取得した送信はリースの期限まで他のタスクに取得されない
+	var deliveries []model.WebhookDelivery
+	err = d.TransactionManager.Do(ctx, func(ctx context.Context) error {
+		now := d.now()
+		claimed, err := d.DeliveryRepository.ClaimPending(ctx, now, now.Add(d.lease()), d.batchSize())
+		if err != nil {
+			return errors.NewBusinessError("10001E", err)
+		}
+		deliveries = claimed
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+
+	// 記録に失敗した送信はリースの期限後に再送されるため、残りの送信の結果は続けて記録する
+	var recordErr error
+	for _, delivery := range deliveries {
+		attempt, err := d.send(ctx, &delivery)
+		if err == nil {
+			err = d.TransactionManager.Do(ctx, func(ctx context.Context) error {
+				return d.record(ctx, &delivery, attempt)
+			})
+		}
+		if err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Int64("delivery_id", delivery.ID).Msg("failed to record webhook delivery")
+			span.RecordError(err)
+			if recordErr == nil {
+				recordErr = errors.NewBusinessError("10003E", err)
+			}
+			continue
+		}
+		processed++
+	}
+
+	// 結果の属性を追加
+	span.SetAttributes(
+		attribute.Int("result_count", processed),
+	)
+	return processed, recordErr
+}
+
+// send 1件をトランザクションの外で送信し、試行の結果をdeliveryと戻り値の試行に反映する
+func (d *WebhookDispatcher) send(ctx context.Context, delivery *model.WebhookDelivery) (*model.WebhookDeliveryAttempt, error) {
+	delivery.Attempts++
+	eventType := attribute.String("event_type", delivery.EventType)
+
+	subscription, err := d.SubscriptionRepository.FindByID(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 削除・無効化された送信先には再試行しても送信しないため、すぐにdeadにする（再開後はリプレイで再送する）
+	var statusCode int
+	var sendErr error
+	giveUp := false
+	start := time.Now()
+	switch {
+	case subscription == nil:
+		sendErr = fmt.Errorf("webhook subscription %d was deleted", delivery.SubscriptionID)
+		giveUp = true
+	case !subscription.Active:
+		sendErr = fmt.Errorf("webhook subscription %d is inactive", delivery.SubscriptionID)
+		giveUp = true
+	default:
+		statusCode, sendErr = d.Sender.Send(ctx, *subscription, *delivery)
+	}
+
+	attempt := &model.WebhookDeliveryAttempt{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	delivery.ResponseCode = nil
+	if statusCode != 0 {
+		attempt.ResponseCode = &statusCode
+		delivery.ResponseCode = &statusCode
+	}
+
+	if sendErr == nil {
+		now := d.now()
+		delivery.Status = model.WebhookDeliveryStatusSucceeded
+		delivery.LastError = nil
+		delivery.DeliveredAt = &now
+		webhookDeliveriesCounter.Add(ctx, 1, metric.WithAttributes(eventType, attribute.String("result", "succeeded")))
+	} else {
+		message := sendErr.Error()
+		attempt.Error = &message
+		delivery.LastError = &message
+		delivery.Status = model.WebhookDeliveryStatusPending
+		delivery.NextAttemptAt = d.now().Add(d.backoff(delivery.Attempts))
+		webhookDeliveriesCounter.Add(ctx, 1, metric.WithAttributes(eventType, attribute.String("result", "failed")))
+		if giveUp || delivery.Attempts >= d.maxAttempts() {
+			delivery.Status = model.WebhookDeliveryStatusDead
+			webhookDeadLetteredCounter.Add(ctx, 1, metric.WithAttributes(eventType))
+		}
+		zerolog.Ctx(ctx).Warn().Err(sendErr).Int64("delivery_id", delivery.ID).Int("status_code", statusCode).
+			Int("attempts", delivery.Attempts).Str("status", delivery.Status).Msg("failed to deliver webhook")
+	}
+	return attempt, nil
+}
+
+// record 1件の試行の結果と送信の状態を記録する
+func (d *WebhookDispatcher) record(ctx context.Context, delivery *model.WebhookDelivery, attempt *model.WebhookDeliveryAttempt) error {
+	if err := d.DeliveryRepository.RecordAttempt(ctx, attempt); err != nil {
+		return err
+	}
+	return d.DeliveryRepository.UpdateResult(ctx, delivery)
+}
+
+// backoff attempts回目の失敗から再試行までの待ち時間を返す
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	initial, max := d.InitialBackoff, d.MaxBackoff
+	if initial <= 0 {
+		initial = defaultWebhookInitialBackoff
+	}
+	if max <= 0 {
+		max = defaultWebhookMaxBackoff
+	}
+	return utils.ExponentialBackoff(initial, max, attempts)
+}
+
+// now 現在時刻を返す
+func (d *WebhookDispatcher) now() time.Time {
+	if d.Now != nil {
+		return d.Now()
+	}
+	return time.Now()
+}
+
+// batchSize 1回に取得して送信する上限を返す
+func (d *WebhookDispatcher) batchSize() int {
+	if d.BatchSize > 0 {
+		return d.BatchSize
+	}
+	return defaultWebhookBatchSize
+}
+
+// pollInterval 送信待ちを確認する間隔を返す
+func (d *WebhookDispatcher) pollInterval() time.Duration {
+	if d.PollInterval > 0 {
+		return d.PollInterval
+	}
+	return defaultWebhookPollInterval
+}
+
+// lease 取得した送信を他のタスクに取得させない期間を返す
+func (d *WebhookDispatcher) lease() time.Duration {
+	if d.Lease > 0 {
+		return d.Lease
+	}
+	return defaultWebhookLease
+}
+
+// maxAttempts 送信を試行する回数の上限を返す
+func (d *WebhookDispatcher) maxAttempts() int {
+	if d.MaxAttempts > 0 {
+		return d.MaxAttempts
+	}
+	return defaultWebhookMaxAttempts
+}
@@ -0,0 +1,381 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+)
+
+// MockWebhookSender はWebhookSenderのモック実装
+// StatusCodesに設定したステータスコードを順に応答し（未設定の場合は200）、2xx以外の場合は送信に失敗する
+type MockWebhookSender struct {
+	StatusCodes []int
+	Sent        []model.WebhookDelivery
+}
+
+func (m *MockWebhookSender) Send(ctx context.Context, subscription model.WebhookSubscription, delivery model.WebhookDelivery) (int, error) {
+	m.Sent = append(m.Sent, delivery)
+	code := 200
+	if len(m.StatusCodes) > 0 {
+		code, m.StatusCodes = m.StatusCodes[0], m.StatusCodes[1:]
+	}
+	if code < 200 || code >= 300 {
+		return code, fmt.Errorf("webhook responded with status %d", code)
+	}
+	return code, nil
+}
+
+// webhookSenderFunc 関数をWebhookSenderとして使う
+type webhookSenderFunc func(ctx context.Context, subscription model.WebhookSubscription, delivery model.WebhookDelivery) (int, error)
+
+func (f webhookSenderFunc) Send(ctx context.Context, subscription model.WebhookSubscription, delivery model.WebhookDelivery) (int, error) {
+	return f(ctx, subscription, delivery)
+}
+
+// failingDeliveryRepository FailIDの送信の結果の更新に失敗するリポジトリ
+type failingDeliveryRepository struct {
+	repository.WebhookDeliveryRepositoryInterface
+	FailID int64
+}
+
+func (r *failingDeliveryRepository) UpdateResult(ctx context.Context, input *model.WebhookDelivery) error {
+	if input.ID == r.FailID {
+		return fmt.Errorf("failed to update webhook delivery %d", input.ID)
+	}
+	return r.WebhookDeliveryRepositoryInterface.UpdateResult(ctx, input)
+}
+
+// webhookFixture ペット1へのいいねを購読する送信先と、その送信待ちを作成した状態
+type webhookFixture struct {
+	interactor   *WebhookInteractor
+	fanout       *WebhookFanout
+	dispatcher   *WebhookDispatcher
+	subscription *model.WebhookSubscription
+	sqlHandler   database.SQLHandler
+	// now dispatcherの現在時刻
+	now time.Time
+}
+
+// newWebhookFixture 送信先を登録し、いいねをoutboxに記録してリレーで送信待ちにする
+func newWebhookFixture(t *testing.T, sender WebhookSender) *webhookFixture {
+	t.Helper()
+	interactor, sqlHandler := newMemoryWebhookInteractor(t)
+	ctx := serviceContext()
+	f := &webhookFixture{
+		interactor: interactor,
+		fanout: &WebhookFanout{
+			PetRepository:          &repository.PetRepository{SQLHandler: sqlHandler},
+			SubscriptionRepository: interactor.SubscriptionRepository,
+			DeliveryRepository:     interactor.DeliveryRepository,
+		},
+		subscription: &model.WebhookSubscription{
+			ShopID:    1,
+			EventType: model.EventPetLiked,
+			URL:       "https://partner.example.com/hooks",
+			Active:    true,
+		},
+		sqlHandler: sqlHandler,
+		now:        time.Date(2099, 6, 15, 12, 0, 0, 0, time.UTC),
+	}
+	f.dispatcher = &WebhookDispatcher{
+		DeliveryRepository:     interactor.DeliveryRepository,
+		SubscriptionRepository: interactor.SubscriptionRepository,
+		TransactionManager:     &repository.TransactionManager{SQLHandler: sqlHandler},
+		Sender:                 sender,
+		MaxAttempts:            3,
+		InitialBackoff:         time.Minute,
+		MaxBackoff:             time.Hour,
+		Now:                    func() time.Time { return f.now },
+	}
+	if err := interactor.CreateSubscription(ctx, f.subscription); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	outbox := &repository.OutboxRepository{SQLHandler: sqlHandler}
+	pets := &PetInteractor{
+		PetRepository:         &repository.PetRepository{SQLHandler: sqlHandler},
+		FavoriteRepository:    repository.FavoriteRepository{SQLHandler: sqlHandler},
+		TransactionManager:    &repository.TransactionManager{SQLHandler: sqlHandler},
+		Outbox:                outbox,
+		DisableLoadSimulation: true,
+	}
+	if err := pets.UpdateLikeCount(ctx, &model.InputUpdateLikeRequest{PetId: "1", UserId: "user456", Value: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	relay := &OutboxRelay{
+		Repository:         outbox,
+		TransactionManager: &repository.TransactionManager{SQLHandler: sqlHandler},
+		Publisher:          f.fanout,
+	}
+	if _, err := relay.RelayOnce(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return f
+}
+
+// deliveries 送信先への送信を新しい順に返す
+func (f *webhookFixture) deliveries(t *testing.T) []model.WebhookDelivery {
+	t.Helper()
+	deliveries, err := f.interactor.GetDeliveries(serviceContext(), f.subscription.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return deliveries.Data
+}
+
+func TestWebhookFanout_Publish(t *testing.T) {
+	f := newWebhookFixture(t, &MockWebhookSender{})
+	ctx := serviceContext()
+
+	deliveries := f.deliveries(t)
+	if len(deliveries) != 1 || deliveries[0].EventType != model.EventPetLiked || deliveries[0].Status != model.WebhookDeliveryStatusPending {
+		t.Fatalf("unexpected deliveries: %+v", deliveries)
+	}
+
+	// 同じイベントを再配信しても二重に記録しない
+	message := model.OutboxMessage{ID: deliveries[0].EventID, EventType: model.EventPetLiked, Payload: deliveries[0].Payload}
+	if err := f.fanout.Publish(ctx, message); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deliveries := f.deliveries(t); len(deliveries) != 1 {
+		t.Errorf("expected no duplicate delivery, got %+v", deliveries)
+	}
+
+	// 購読していないイベント・Webhookの対象外のイベントは記録しない
+	for _, message := range []model.OutboxMessage{
+		{ID: 100, EventType: model.EventReservationCreated, Payload: `{"reservation":{"pet_id":"1"}}`},
+		{ID: 101, EventType: "notification.created", Payload: `{}`},
+	} {
+		if err := f.fanout.Publish(ctx, message); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if deliveries := f.deliveries(t); len(deliveries) != 1 {
+		t.Errorf("expected only subscribed events, got %+v", deliveries)
+	}
+}
+
+func TestWebhookFanout_Publish_ShopID(t *testing.T) {
+	interactor, sqlHandler := newMemoryWebhookInteractor(t)
+	ctx := serviceContext()
+	fanout := &WebhookFanout{
+		PetRepository:          &repository.PetRepository{SQLHandler: sqlHandler},
+		SubscriptionRepository: interactor.SubscriptionRepository,
+		DeliveryRepository:     interactor.DeliveryRepository,
+	}
+
+	// 店舗名が同じ別の店舗の送信先には送信しない
+	var other struct {
+		ID int64 `db:"id"`
+	}
+	if err := sqlHandler.Insert(ctx, &other, map[string]interface{}{"name": "uma-arai shop 2nd", "location": "Tokyo"}, "shops"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	subscriptions := map[int64]*model.WebhookSubscription{}
+	for _, shopID := range []int64{1, other.ID} {
+		for _, eventType := range []string{model.EventPetLiked, model.EventPetDeleted} {
+			s := &model.WebhookSubscription{ShopID: shopID, EventType: eventType, URL: "https://partner.example.com/hooks", Active: true}
+			if err := interactor.CreateSubscription(ctx, s); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			subscriptions[s.ID] = s
+		}
+	}
+
+	// 削除されたペットはイベントに含まれる店舗の送信先に送信する
+	for _, message := range []model.OutboxMessage{
+		{ID: 1, EventType: model.EventPetLiked, Payload: `{"pet_id":"1"}`},
+		{ID: 2, EventType: model.EventPetDeleted, Payload: fmt.Sprintf(`{"pet":{"id":"999","shop":{"id":%d}}}`, other.ID)},
+	} {
+		if err := fanout.Publish(ctx, message); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for id, s := range subscriptions {
+		deliveries, err := interactor.GetDeliveries(ctx, id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := 0
+		if (s.ShopID == 1 && s.EventType == model.EventPetLiked) || (s.ShopID == other.ID && s.EventType == model.EventPetDeleted) {
+			want = 1
+		}
+		if len(deliveries.Data) != want {
+			t.Errorf("shop %d %s: unexpected deliveries %+v", s.ShopID, s.EventType, deliveries.Data)
+		}
+	}
+}
+
+func TestWebhookDispatcher_DispatchOnce(t *testing.T) {
+	sender := &MockWebhookSender{StatusCodes: []int{503, 200}}
+	f := newWebhookFixture(t, sender)
+	ctx := testContext()
+
+	// 1回目は失敗し、バックオフ後に再試行する
+	processed, err := f.dispatcher.DispatchOnce(ctx)
+	if err != nil || processed != 1 {
+		t.Fatalf("expected 1 processed, got %d (%v)", processed, err)
+	}
+	delivery := f.deliveries(t)[0]
+	if delivery.Status != model.WebhookDeliveryStatusPending || delivery.Attempts != 1 || delivery.ResponseCode == nil || *delivery.ResponseCode != 503 {
+		t.Fatalf("unexpected delivery after failure: %+v", delivery)
+	}
+	if !delivery.NextAttemptAt.Equal(f.now.Add(time.Minute)) {
+		t.Errorf("expected next attempt after 1m, got %v", delivery.NextAttemptAt)
+	}
+	if processed, _ := f.dispatcher.DispatchOnce(ctx); processed != 0 {
+		t.Errorf("expected no delivery before backoff, got %d", processed)
+	}
+
+	f.now = f.now.Add(time.Minute)
+	if processed, err := f.dispatcher.DispatchOnce(ctx); err != nil || processed != 1 {
+		t.Fatalf("expected 1 processed, got %d (%v)", processed, err)
+	}
+	detail, err := f.interactor.GetDelivery(serviceContext(), f.subscription.ID, delivery.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if detail.Status != model.WebhookDeliveryStatusSucceeded || detail.Attempts != 2 || detail.DeliveredAt == nil || detail.LastError != nil {
+		t.Errorf("unexpected delivery after success: %+v", detail.WebhookDelivery)
+	}
+	if len(detail.History) != 2 || *detail.History[0].ResponseCode != 503 || detail.History[0].Error == nil || *detail.History[1].ResponseCode != 200 {
+		t.Errorf("unexpected history: %+v", detail.History)
+	}
+	if len(sender.Sent) != 2 {
+		t.Errorf("expected 2 sends, got %d", len(sender.Sent))
+	}
+}
+
+func TestWebhookDispatcher_DeadLetter(t *testing.T) {
+	sender := &MockWebhookSender{StatusCodes: []int{500, 500, 500, 200}}
+	f := newWebhookFixture(t, sender)
+	ctx := testContext()
+
+	// MaxAttempts回失敗した送信はdeadにして再試行しない
+	for i := 0; i < 3; i++ {
+		if _, err := f.dispatcher.DispatchOnce(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		f.now = f.now.Add(time.Hour)
+	}
+	if processed, _ := f.dispatcher.DispatchOnce(ctx); processed != 0 {
+		t.Errorf("expected dead delivery not to be retried, got %d", processed)
+	}
+	delivery := f.deliveries(t)[0]
+	if delivery.Status != model.WebhookDeliveryStatusDead || delivery.Attempts != 3 || delivery.LastError == nil {
+		t.Fatalf("unexpected dead delivery: %+v", delivery)
+	}
+
+	// リプレイした送信は新しい送信として送信する
+	replay, err := f.interactor.ReplayDelivery(serviceContext(), f.subscription.ID, delivery.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if processed, err := f.dispatcher.DispatchOnce(ctx); err != nil || processed != 1 {
+		t.Fatalf("expected replay to be sent, got %d (%v)", processed, err)
+	}
+	if sent := sender.Sent[len(sender.Sent)-1]; sent.ID != replay.ID || sent.EventID != delivery.EventID {
+		t.Errorf("unexpected replayed delivery: %+v", sent)
+	}
+}
+
+func TestWebhookDispatcher_InactiveSubscription(t *testing.T) {
+	sender := &MockWebhookSender{}
+	f := newWebhookFixture(t, sender)
+
+	_, err := f.interactor.UpdateSubscription(serviceContext(), f.subscription.ID, model.WebhookSubscription{
+		EventType: f.subscription.EventType,
+		URL:       f.subscription.URL,
+		Active:    false,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 無効化された送信先には送信せず、すぐにdeadにする
+	if _, err := f.dispatcher.DispatchOnce(testContext()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sender.Sent) != 0 {
+		t.Errorf("expected no sends to inactive subscription, got %d", len(sender.Sent))
+	}
+	if delivery := f.deliveries(t)[0]; delivery.Status != model.WebhookDeliveryStatusDead || delivery.ResponseCode != nil {
+		t.Errorf("unexpected delivery: %+v", delivery)
+	}
+}
+
+func TestWebhookDispatcher_Lease(t *testing.T) {
+	sender := &MockWebhookSender{}
+	f := newWebhookFixture(t, sender)
+	ctx := testContext()
+	first := f.deliveries(t)[0]
+	second, err := f.interactor.ReplayDelivery(serviceContext(), f.subscription.ID, first.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.dispatcher.Lease = time.Minute
+
+	t.Run("送信はトランザクションの外で行う", func(t *testing.T) {
+		f.dispatcher.Sender = webhookSenderFunc(func(ctx context.Context, subscription model.WebhookSubscription, delivery model.WebhookDelivery) (int, error) {
+			var locked []model.WebhookDelivery
+			if err := f.sqlHandler.SelectForUpdate(ctx, &locked, repository.WebhookDeliveryTable, "", nil, "id", 0); !errors.Is(err, database.ErrNoTransaction) {
+				t.Errorf("expected to send outside of a transaction, got %v", err)
+			}
+			return 0, fmt.Errorf("unavailable")
+		})
+		if processed, err := f.dispatcher.DispatchOnce(ctx); err != nil || processed != 2 {
+			t.Fatalf("expected 2 processed, got %d (%v)", processed, err)
+		}
+		f.now = f.now.Add(time.Hour)
+	})
+
+	t.Run("送信後に結果の記録に失敗した送信はリースの期限後に再送し、他の送信の結果は記録する", func(t *testing.T) {
+		f.dispatcher.Sender = sender
+		f.dispatcher.DeliveryRepository = &failingDeliveryRepository{
+			WebhookDeliveryRepositoryInterface: f.interactor.DeliveryRepository,
+			FailID:                             first.ID,
+		}
+		processed, err := f.dispatcher.DispatchOnce(ctx)
+		assertBusinessError(t, err, "10003E")
+		if processed != 1 || len(sender.Sent) != 2 {
+			t.Fatalf("expected 2 sends and 1 recorded, got %d sends and %d recorded", len(sender.Sent), processed)
+		}
+
+		detail, err := f.interactor.GetDelivery(serviceContext(), f.subscription.ID, second.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if detail.Status != model.WebhookDeliveryStatusSucceeded || len(detail.History) != 2 {
+			t.Errorf("expected the other delivery to be recorded, got %+v (%+v)", detail.WebhookDelivery, detail.History)
+		}
+		detail, err = f.interactor.GetDelivery(serviceContext(), f.subscription.ID, first.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if detail.Status != model.WebhookDeliveryStatusPending || detail.Attempts != 1 || !detail.NextAttemptAt.Equal(f.now.Add(time.Minute)) {
+			t.Errorf("expected the delivery to stay leased, got %+v", detail.WebhookDelivery)
+		}
+
+		f.dispatcher.DeliveryRepository = f.interactor.DeliveryRepository
+		if processed, err := f.dispatcher.DispatchOnce(ctx); err != nil || processed != 0 {
+			t.Fatalf("expected leased delivery not to be sent, got %d (%v)", processed, err)
+		}
+		f.now = f.now.Add(time.Minute)
+		if processed, err := f.dispatcher.DispatchOnce(ctx); err != nil || processed != 1 {
+			t.Fatalf("expected 1 processed after the lease expired, got %d (%v)", processed, err)
+		}
+		if delivery, _ := f.interactor.DeliveryRepository.FindByID(ctx, first.ID); delivery.Status != model.WebhookDeliveryStatusSucceeded || delivery.Attempts != 2 {
+			t.Errorf("unexpected delivery after resending: %+v", delivery)
+		}
+		if len(sender.Sent) != 3 {
+			t.Errorf("expected 3 sends, got %d", len(sender.Sent))
+		}
+	})
+}
@@ -0,0 +1,327 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/model/errors"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// webhookSecretPrefix 署名に使う共有鍵の接頭辞（他の鍵と見分けられるようにする）
+	webhookSecretPrefix = "whsec_"
+	// webhookDeliveryHistoryLimit 送信履歴として返す件数の上限
+	webhookDeliveryHistoryLimit = 100
+)
+
+// WebhookInteractor 提携店舗のWebhookの送信先と送信履歴を管理する
+//
+// 送信先の管理はサービス間連携（APIキー）でのみ行える
+type WebhookInteractor struct {
+	SubscriptionRepository repository.WebhookSubscriptionRepositoryInterface
+	DeliveryRepository     repository.WebhookDeliveryRepositoryInterface
+	// ShopRepository 送信先に指定された店舗が存在するかを確認する
+	ShopRepository repository.ShopRepositoryInterface
+}
+
+// GetSubscriptions 店舗のWebhookの送信先を取得する（shopIDが0の場合はすべての店舗）
+func (interactor *WebhookInteractor) GetSubscriptions(ctx context.Context, shopID int64) (subscriptions model.WebhookSubscriptions, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-interactor")
+	ctx, span := tracer.Start(ctx, "WebhookInteractor.GetSubscriptions",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("shop_id", shopID),
+	)
+
+	if err = requireService(ctx); err != nil {
+		return subscriptions, err
+	}
+
+	subscriptions, err = interactor.SubscriptionRepository.FindByShop(ctx, shopID)
+	if err != nil {
+		return subscriptions, errors.NewBusinessError("10001E", err)
+	}
+	for i := range subscriptions.Data {
+		subscriptions.Data[i].Secret = ""
+	}
+	return
+}
+
+// GetSubscription Webhookの送信先を取得する
+func (interactor *WebhookInteractor) GetSubscription(ctx context.Context, id int64) (*model.WebhookSubscription, error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-interactor")
+	ctx, span := tracer.Start(ctx, "WebhookInteractor.GetSubscription",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("subscription_id", id),
+	)
+
+	if err := requireService(ctx); err != nil {
+		return nil, err
+	}
+
+	subscription, err := interactor.findSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	subscription.Secret = ""
+	return subscription, nil
+}
+
+// CreateSubscription Webhookの送信先を登録する（店舗が存在しない場合は50002E）
+//
+// 署名に使う共有鍵を生成し、登録時のレスポンスでのみ返す
+func (interactor *WebhookInteractor) CreateSubscription(ctx context.Context, input *model.WebhookSubscription) (err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-interactor")
+	ctx, span := tracer.Start(ctx, "WebhookInteractor.CreateSubscription",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("shop_id", input.ShopID),
+		attribute.String("event_type", input.EventType),
+	)
+
+	if err = requireService(ctx); err != nil {
+		return err
+	}
+	if !model.IsWebhookEventType(input.EventType) {
+		return errors.NewBusinessError("00004E", fmt.Errorf("unknown webhook event type: %s", input.EventType))
+	}
+	shop, err := interactor.ShopRepository.FindByID(ctx, input.ShopID)
+	if err != nil {
+		return errors.NewBusinessError("10001E", err)
+	}
+	if shop == nil {
+		return errors.NewBusinessError("50002E", fmt.Errorf("shop not found: %d", input.ShopID))
+	}
+
+	input.Secret, err = newWebhookSecret()
+	if err != nil {
+		return errors.NewBusinessError("99999E", err)
+	}
+	if err = interactor.SubscriptionRepository.Create(ctx, input); err != nil {
+		span.RecordError(err)
+		return errors.NewBusinessError("10003E", err)
+	}
+	return nil
+}
+
+// UpdateSubscription Webhookの送信先のイベント・URL・有効/無効を変更する
+func (interactor *WebhookInteractor) UpdateSubscription(ctx context.Context, id int64, input model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-interactor")
+	ctx, span := tracer.Start(ctx, "WebhookInteractor.UpdateSubscription",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("subscription_id", id),
+		attribute.String("event_type", input.EventType),
+		attribute.Bool("active", input.Active),
+	)
+
+	if err := requireService(ctx); err != nil {
+		return nil, err
+	}
+	if !model.IsWebhookEventType(input.EventType) {
+		return nil, errors.NewBusinessError("00004E", fmt.Errorf("unknown webhook event type: %s", input.EventType))
+	}
+
+	subscription, err := interactor.findSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	subscription.EventType = input.EventType
+	subscription.URL = input.URL
+	subscription.Active = input.Active
+	if err := interactor.SubscriptionRepository.Update(ctx, subscription); err != nil {
+		span.RecordError(err)
+		return nil, errors.NewBusinessError("10003E", err)
+	}
+
+	return interactor.GetSubscription(ctx, id)
+}
+
+// DeleteSubscription Webhookの送信先を削除する
+func (interactor *WebhookInteractor) DeleteSubscription(ctx context.Context, id int64) error {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-interactor")
+	ctx, span := tracer.Start(ctx, "WebhookInteractor.DeleteSubscription",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("subscription_id", id),
+	)
+
+	if err := requireService(ctx); err != nil {
+		return err
+	}
+	if _, err := interactor.findSubscription(ctx, id); err != nil {
+		return err
+	}
+	if err := interactor.SubscriptionRepository.Delete(ctx, id); err != nil {
+		span.RecordError(err)
+		return errors.NewBusinessError("10003E", err)
+	}
+	return nil
+}
+
+// GetDeliveries 送信先への送信を新しい順に取得する
+func (interactor *WebhookInteractor) GetDeliveries(ctx context.Context, subscriptionID int64) (deliveries model.WebhookDeliveries, err error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-interactor")
+	ctx, span := tracer.Start(ctx, "WebhookInteractor.GetDeliveries",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("subscription_id", subscriptionID),
+	)
+
+	if err = requireService(ctx); err != nil {
+		return deliveries, err
+	}
+	if _, err = interactor.findSubscription(ctx, subscriptionID); err != nil {
+		return deliveries, err
+	}
+
+	deliveries, err = interactor.DeliveryRepository.FindBySubscriptionID(ctx, subscriptionID, webhookDeliveryHistoryLimit)
+	if err != nil {
+		return deliveries, errors.NewBusinessError("10001E", err)
+	}
+	return
+}
+
+// GetDelivery 送信と試行ごとの結果を取得する
+func (interactor *WebhookInteractor) GetDelivery(ctx context.Context, subscriptionID int64, deliveryID int64) (*model.WebhookDeliveryDetail, error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-interactor")
+	ctx, span := tracer.Start(ctx, "WebhookInteractor.GetDelivery",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("subscription_id", subscriptionID),
+		attribute.Int64("delivery_id", deliveryID),
+	)
+
+	if err := requireService(ctx); err != nil {
+		return nil, err
+	}
+	delivery, err := interactor.findDelivery(ctx, subscriptionID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := interactor.DeliveryRepository.FindAttempts(ctx, deliveryID)
+	if err != nil {
+		return nil, errors.NewBusinessError("10001E", err)
+	}
+	if history == nil {
+		history = []model.WebhookDeliveryAttempt{}
+	}
+	return &model.WebhookDeliveryDetail{WebhookDelivery: *delivery, History: history}, nil
+}
+
+// ReplayDelivery 送信したイベントを新しい送信として再送する（送信の状態によらず再送できる）
+func (interactor *WebhookInteractor) ReplayDelivery(ctx context.Context, subscriptionID int64, deliveryID int64) (*model.WebhookDelivery, error) {
+	// スパンを作成
+	tracer := otel.Tracer("webhook-interactor")
+	ctx, span := tracer.Start(ctx, "WebhookInteractor.ReplayDelivery",
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	defer span.End()
+
+	// 属性を追加
+	span.SetAttributes(
+		attribute.Int64("subscription_id", subscriptionID),
+		attribute.Int64("delivery_id", deliveryID),
+	)
+
+	if err := requireService(ctx); err != nil {
+		return nil, err
+	}
+	original, err := interactor.findDelivery(ctx, subscriptionID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	replay := &model.WebhookDelivery{
+		SubscriptionID: original.SubscriptionID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+	}
+	if err := interactor.DeliveryRepository.Create(ctx, replay); err != nil {
+		span.RecordError(err)
+		return nil, errors.NewBusinessError("10003E", err)
+	}
+	span.SetAttributes(attribute.Int64("replay_delivery_id", replay.ID))
+	return replay, nil
+}
+
+// findSubscription Webhookの送信先を取得する（存在しない場合は30001E）
+func (interactor *WebhookInteractor) findSubscription(ctx context.Context, id int64) (*model.WebhookSubscription, error) {
+	subscription, err := interactor.SubscriptionRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, errors.NewBusinessError("10001E", err)
+	}
+	if subscription == nil {
+		return nil, errors.NewBusinessError("30001E", nil)
+	}
+	return subscription, nil
+}
+
+// findDelivery 送信先への送信を取得する（存在しない場合・他の送信先の送信の場合は30002E）
+func (interactor *WebhookInteractor) findDelivery(ctx context.Context, subscriptionID int64, deliveryID int64) (*model.WebhookDelivery, error) {
+	if _, err := interactor.findSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	delivery, err := interactor.DeliveryRepository.FindByID(ctx, deliveryID)
+	if err != nil {
+		return nil, errors.NewBusinessError("10001E", err)
+	}
+	if delivery == nil || delivery.SubscriptionID != subscriptionID {
+		return nil, errors.NewBusinessError("30002E", nil)
+	}
+	return delivery, nil
+}
+
+// newWebhookSecret 署名に使う共有鍵を生成する
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/horsewin/echo-playground-v2/domain/model"
+	"github.com/horsewin/echo-playground-v2/domain/repository"
+	"github.com/horsewin/echo-playground-v2/interface/database"
+)
+
+// newMemoryWebhookInteractor 初期データを投入したインメモリDBを利用するWebhookInteractorを返す
+func newMemoryWebhookInteractor(t *testing.T) (*WebhookInteractor, database.SQLHandler) {
+	t.Helper()
+	sqlHandler := newSeededSQLHandler(t)
+	return &WebhookInteractor{
+		SubscriptionRepository: &repository.WebhookSubscriptionRepository{SQLHandler: sqlHandler},
+		DeliveryRepository:     &repository.WebhookDeliveryRepository{SQLHandler: sqlHandler},
+		ShopRepository:         &repository.ShopRepository{SQLHandler: sqlHandler},
+	}, sqlHandler
+}
+
+func TestWebhookInteractor_SubscriptionLifecycle(t *testing.T) {
+	interactor, _ := newMemoryWebhookInteractor(t)
+	ctx := serviceContext()
+
+	subscription := &model.WebhookSubscription{
+		ShopID:    1,
+		EventType: model.EventReservationCreated,
+		URL:       "https://partner.example.com/hooks",
+		Active:    true,
+	}
+	if err := interactor.CreateSubscription(ctx, subscription); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if subscription.ID == 0 || !strings.HasPrefix(subscription.Secret, webhookSecretPrefix) {
+		t.Fatalf("expected created subscription with secret, got %+v", subscription)
+	}
+
+	// 共有鍵は登録時のレスポンスでのみ返す
+	found, err := interactor.GetSubscription(ctx, subscription.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if found.Secret != "" || found.URL != subscription.URL {
+		t.Errorf("unexpected subscription: %+v", found)
+	}
+	list, err := interactor.GetSubscriptions(ctx, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list.Data) != 1 || list.Data[0].Secret != "" {
+		t.Errorf("unexpected subscriptions: %+v", list.Data)
+	}
+	if list, _ := interactor.GetSubscriptions(ctx, 2); len(list.Data) != 0 {
+		t.Errorf("expected no subscriptions for other shop, got %+v", list.Data)
+	}
+
+	updated, err := interactor.UpdateSubscription(ctx, subscription.ID, model.WebhookSubscription{
+		EventType: model.EventReservationStatusChanged,
+		URL:       "https://partner.example.com/v2/hooks",
+		Active:    false,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.EventType != model.EventReservationStatusChanged || updated.Active || updated.ShopID != 1 || updated.Secret != "" {
+		t.Errorf("unexpected updated subscription: %+v", updated)
+	}
+
+	// 存在しない店舗の送信先は登録できない
+	err = interactor.CreateSubscription(ctx, &model.WebhookSubscription{ShopID: 999, EventType: model.EventPetLiked, URL: subscription.URL})
+	assertBusinessError(t, err, "50002E")
+
+	_, err = interactor.UpdateSubscription(ctx, subscription.ID, model.WebhookSubscription{EventType: model.EventPetAdded, URL: updated.URL})
+	assertBusinessError(t, err, "00004E")
+
+	if err := interactor.DeleteSubscription(ctx, subscription.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = interactor.GetSubscription(ctx, subscription.ID)
+	assertBusinessError(t, err, "30001E")
+	assertBusinessError(t, interactor.DeleteSubscription(ctx, subscription.ID), "30001E")
+}
+
+func TestWebhookInteractor_RequiresService(t *testing.T) {
+	interactor, _ := newMemoryWebhookInteractor(t)
+	ctx := model.WithPrincipal(testContext(), &model.Principal{ID: "user1", Kind: model.PrincipalKindUser})
+
+	_, err := interactor.GetSubscriptions(ctx, 0)
+	assertBusinessError(t, err, "00008E")
+	err = interactor.CreateSubscription(ctx, &model.WebhookSubscription{
+		ShopID:    1,
+		EventType: model.EventPetLiked,
+		URL:       "https://partner.example.com/hooks",
+	})
+	assertBusinessError(t, err, "00008E")
+	_, err = interactor.ReplayDelivery(ctx, 1, 1)
+	assertBusinessError(t, err, "00008E")
+}
+
+func TestWebhookInteractor_Deliveries(t *testing.T) {
+	interactor, _ := newMemoryWebhookInteractor(t)
+	ctx := serviceContext()
+
+	subscription := &model.WebhookSubscription{ShopID: 1, EventType: model.EventPetLiked, URL: "https://partner.example.com/hooks", Active: true}
+	other := &model.WebhookSubscription{ShopID: 1, EventType: model.EventPetLiked, URL: "https://other.example.com/hooks", Active: true}
+	for _, s := range []*model.WebhookSubscription{subscription, other} {
+		if err := interactor.CreateSubscription(ctx, s); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	delivery := &model.WebhookDelivery{SubscriptionID: subscription.ID, EventID: 42, EventType: model.EventPetLiked, Payload: `{"pet_id":"1"}`}
+	if err := interactor.DeliveryRepository.Create(ctx, delivery); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code := 500
+	message := "webhook responded with status 500"
+	if err := interactor.DeliveryRepository.RecordAttempt(ctx, &model.WebhookDeliveryAttempt{DeliveryID: delivery.ID, Attempt: 1, ResponseCode: &code, Error: &message}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	detail, err := interactor.GetDelivery(ctx, subscription.ID, delivery.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if detail.EventID != 42 || len(detail.History) != 1 || *detail.History[0].ResponseCode != 500 {
+		t.Errorf("unexpected delivery detail: %+v", detail)
+	}
+
+	// 他の送信先の送信は参照・リプレイできない
+	_, err = interactor.GetDelivery(ctx, other.ID, delivery.ID)
+	assertBusinessError(t, err, "30002E")
+	_, err = interactor.ReplayDelivery(ctx, other.ID, delivery.ID)
+	assertBusinessError(t, err, "30002E")
+	_, err = interactor.GetDeliveries(ctx, 9999)
+	assertBusinessError(t, err, "30001E")
+
+	replay, err := interactor.ReplayDelivery(ctx, subscription.ID, delivery.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replay.ID == delivery.ID || replay.EventID != 42 || replay.Payload != delivery.Payload || replay.Status != model.WebhookDeliveryStatusPending {
+		t.Errorf("unexpected replay: %+v", replay)
+	}
+	deliveries, err := interactor.GetDeliveries(ctx, subscription.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(deliveries.Data) != 2 || deliveries.Data[0].ID != replay.ID {
+		t.Errorf("expected replay first, got %+v", deliveries.Data)
+	}
+}
@@ -0,0 +1,15 @@
+package utils
+
+import "time"
+
+// ExponentialBackoff attempts回目の失敗から再試行までの待ち時間を返す（initialから倍にしてmaxで打ち止める）
+func ExponentialBackoff(initial time.Duration, max time.Duration, attempts int) time.Duration {
+	d := initial
+	for i := 1; i < attempts && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	initial := 500 * time.Millisecond
+	max := 3 * time.Second
+
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: 500 * time.Millisecond},
+		{attempts: 2, expected: time.Second},
+		{attempts: 3, expected: 2 * time.Second},
+		{attempts: 4, expected: 3 * time.Second},
+		{attempts: 10, expected: 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := ExponentialBackoff(initial, max, tt.attempts); got != tt.expected {
+			t.Errorf("attempts %d: expected %s but got %s", tt.attempts, tt.expected, got)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // APIConfig ...
@@ -12,16 +15,110 @@ type APIConfig struct {
 		ClientID string
 	}
 	EnableTracing bool
+	// EnableMetrics OTLPでメトリクスを送出するか
+	EnableMetrics bool
+	// MetricsInterval OTLPでメトリクスを送出する間隔
+	MetricsInterval time.Duration
+	// MetricsAddr Prometheus用の/metricsを公開する管理サーバのアドレス（空の場合は公開しない）
+	MetricsAddr string
+	// ReservationCountMode ペット一覧の予約数の取得方法（"batch"または"n_plus_one"）
+	ReservationCountMode string
+	// ShutdownDrainDelay SIGTERM受信後、readinessを失敗させてからサーバを停止するまでの待機時間
+	ShutdownDrainDelay time.Duration
+	// NotificationFanout 通知イベントの配信方式（"local"はプロセス内のみ、"postgres"はLISTEN/NOTIFYで全タスクに配信する）
+	NotificationFanout string
+	// SSEHeartbeatInterval 通知ストリームの接続を維持するためにハートビートを送る間隔
+	SSEHeartbeatInterval time.Duration
+	// Outbox 外部システムへのドメインイベントの配信の設定
+	Outbox struct {
+		// Publisher 配信先（"log"、"http"、"file"）
+		Publisher string
+		// WebhookURL Publisherが"http"の場合にイベントをPOSTするURL
+		WebhookURL string
+		// File Publisherが"file"の場合にイベントを追記するファイルのパス
+		File string
+		// PollInterval 配信待ちのイベントを確認する間隔
+		PollInterval time.Duration
+		// BatchSize 1回に取得して配信するイベントの上限
+		BatchSize int
+		// MaxAttempts 配信を試行する回数の上限（超えた場合はdead letterとして残す）
+		MaxAttempts int
+		// Lease 取得したイベントを他のタスクに取得させない期間（1バッチの配信にかかる時間より長くする）
+		Lease time.Duration
+	}
+	// Webhook 提携店舗へのWebhookの送信の設定
+	Webhook struct {
+		// PollInterval 送信待ちを確認する間隔
+		PollInterval time.Duration
+		// MaxAttempts 送信を試行する回数の上限（超えた場合はdeadにする）
+		MaxAttempts int
+		// Timeout 1回の送信のタイムアウト
+		Timeout time.Duration
+		// Lease 取得した送信を他のタスクに取得させない期間（1バッチの送信にかかる時間より長くする）
+		Lease time.Duration
+	}
+	// Idempotency Idempotency-Keyヘッダーによる再送の重複排除の設定
+	Idempotency struct {
+		// TTL 処理結果を保持する期間（経過後は同じキーを新しいリクエストとして扱う）
+		TTL time.Duration
+		// PurgeInterval 期限切れの処理結果を削除する間隔
+		PurgeInterval time.Duration
+	}
+	// Auth 認証の設定
+	Auth struct {
+		// Modes 有効な認証方式（"jwt"、"api_key"）。空の場合は認証を行わない
+		Modes []string
+		// JWTHS256Secret HS256の署名検証に使う共有鍵
+		JWTHS256Secret string
+		// JWTRS256PublicKeyFile RS256の署名検証に使うPEM形式の公開鍵のパス
+		JWTRS256PublicKeyFile string
+		// JWTJWKSFile RS256の署名検証に使うJWKSファイルのパス（kidで鍵を選択する）
+		JWTJWKSFile string
+		// JWTIssuer 指定した場合、issクレームが一致することを検証する
+		JWTIssuer string
+		// JWTAudience 指定した場合、audクレームに含まれることを検証する
+		JWTAudience string
+		// APIKeys サービス間連携用のAPIキーとサービス名の対応
+		APIKeys map[string]string
+	}
 }
 
 // ConfigDB ...
 type ConfigDB struct {
 	Postgres struct {
 		DBMS     string
+		Host     string
+		Port     string
 		Username string
 		Password string
 		DBName   string
+		// SSLMode disable, require, verify-ca, verify-full
+		SSLMode string
+		// SSLRootCert verify-ca/verify-fullで利用するCA証明書のパス
+		SSLRootCert string
+		// ApplicationName pg_stat_activityに表示されるアプリケーション名
+		ApplicationName string
+		// ConnectTimeout 1回の接続試行のタイムアウト（0以下の場合は既定値の5秒）
+		ConnectTimeout time.Duration
+		// StatementTimeout 1ステートメントの実行時間の上限（0は無制限）
+		StatementTimeout time.Duration
+	}
+	Pool struct {
+		MaxOpenConns    int
+		MaxIdleConns    int
+		ConnMaxLifetime time.Duration
+		ConnMaxIdleTime time.Duration
 	}
+	// Retry 起動時の接続リトライ設定
+	Retry struct {
+		MaxAttempts     int
+		InitialInterval time.Duration
+		MaxInterval     time.Duration
+	}
+	// MigrateOnStart 起動時にDB接続を払い出す前にマイグレーションを適用するか
+	MigrateOnStart bool
+	// SeedOnStart 起動時に初期データを投入するか
+	SeedOnStart bool
 }
 
 // NewAPIConfig ...
@@ -30,18 +127,60 @@ func NewAPIConfig() *APIConfig {
 	config.HeaderValue.ClientID = os.Getenv("SBCNTR_CLIENT_ID_HEADER")
 
 	// 環境変数[SBCNTR_ENABLE_TRACING]を見てトレースを有効にする。対応しているTracingはAWS_XRAYのみ。
-	enableKey := os.Getenv("SBCNTR_ENABLE_TRACING")
-	if strings.ToLower(enableKey) == "true" || enableKey == "1" {
-		config.EnableTracing = true
-	} else {
-		config.EnableTracing = false
-	}
+	config.EnableTracing = isTruthy(os.Getenv("SBCNTR_ENABLE_TRACING"))
+
+	config.EnableMetrics = isTruthy(os.Getenv("SBCNTR_ENABLE_METRICS"))
+	config.MetricsInterval = getEnvDuration("SBCNTR_METRICS_INTERVAL", 60*time.Second)
+	config.MetricsAddr = os.Getenv("SBCNTR_METRICS_ADDR")
+
+	// N+1問題のデモを行う場合は"n_plus_one"を指定する
+	config.ReservationCountMode = getEnv("SBCNTR_RESERVATION_COUNT_MODE", "batch")
+
+	config.ShutdownDrainDelay = getEnvDuration("SBCNTR_SHUTDOWN_DRAIN_DELAY", 5*time.Second)
+
+	// 複数タスクで動かす場合は"postgres"を指定し、他のタスクでの通知の変更もストリームに配信する
+	config.NotificationFanout = getEnv("SBCNTR_NOTIFICATION_FANOUT", "local")
+	config.SSEHeartbeatInterval = getEnvDuration("SBCNTR_SSE_HEARTBEAT_INTERVAL", 15*time.Second)
+
+	// 外部システムと連携する場合は"http"、ローカルで配信内容を確認する場合は"file"を指定する
+	config.Outbox.Publisher = getEnv("SBCNTR_OUTBOX_PUBLISHER", "log")
+	config.Outbox.WebhookURL = os.Getenv("SBCNTR_OUTBOX_WEBHOOK_URL")
+	config.Outbox.File = os.Getenv("SBCNTR_OUTBOX_FILE")
+	config.Outbox.PollInterval = getEnvDuration("SBCNTR_OUTBOX_POLL_INTERVAL", time.Second)
+	config.Outbox.BatchSize = getEnvInt("SBCNTR_OUTBOX_BATCH_SIZE", 50)
+	config.Outbox.MaxAttempts = getEnvInt("SBCNTR_OUTBOX_MAX_ATTEMPTS", 10)
+	config.Outbox.Lease = getEnvDuration("SBCNTR_OUTBOX_LEASE", 15*time.Minute)
+
+	config.Webhook.PollInterval = getEnvDuration("SBCNTR_WEBHOOK_POLL_INTERVAL", time.Second)
+	config.Webhook.MaxAttempts = getEnvInt("SBCNTR_WEBHOOK_MAX_ATTEMPTS", 8)
+	config.Webhook.Timeout = getEnvDuration("SBCNTR_WEBHOOK_TIMEOUT", 10*time.Second)
+	config.Webhook.Lease = getEnvDuration("SBCNTR_WEBHOOK_LEASE", 15*time.Minute)
+
+	config.Idempotency.TTL = getEnvDuration("SBCNTR_IDEMPOTENCY_TTL", 24*time.Hour)
+	config.Idempotency.PurgeInterval = getEnvDuration("SBCNTR_IDEMPOTENCY_PURGE_INTERVAL", 10*time.Minute)
 
 	config.Env = os.Getenv("APP_ENV")
 	if config.Env == "" {
 		config.Env = "development"
 	}
 
+	// 開発環境以外では既定で認証を必須とする
+	defaultAuthMode := "jwt,api_key"
+	if config.Env == "development" {
+		defaultAuthMode = "none"
+	}
+	for _, mode := range strings.Split(getEnv("SBCNTR_AUTH_MODE", defaultAuthMode), ",") {
+		if mode = strings.TrimSpace(mode); mode != "" && mode != "none" {
+			config.Auth.Modes = append(config.Auth.Modes, mode)
+		}
+	}
+	config.Auth.JWTHS256Secret = os.Getenv("SBCNTR_JWT_HS256_SECRET")
+	config.Auth.JWTRS256PublicKeyFile = os.Getenv("SBCNTR_JWT_RS256_PUBLIC_KEY_FILE")
+	config.Auth.JWTJWKSFile = os.Getenv("SBCNTR_JWT_JWKS_FILE")
+	config.Auth.JWTIssuer = os.Getenv("SBCNTR_JWT_ISSUER")
+	config.Auth.JWTAudience = os.Getenv("SBCNTR_JWT_AUDIENCE")
+	config.Auth.APIKeys = parseAPIKeys(os.Getenv("SBCNTR_API_KEYS"))
+
 	return config
 }
 
@@ -50,9 +189,91 @@ func NewConfigDB() *ConfigDB {
 	config := new(ConfigDB)
 
 	config.Postgres.DBMS = "postgres"
+	config.Postgres.Host = os.Getenv("DB_HOST")
+	config.Postgres.Port = getEnv("DB_PORT", "5432")
 	config.Postgres.Username = os.Getenv("DB_USERNAME")
 	config.Postgres.Password = os.Getenv("DB_PASSWORD")
 	config.Postgres.DBName = os.Getenv("DB_NAME")
 
+	// 未指定の場合、localhostのDBはSSLを無効化し、それ以外（本番環境）はSSLを有効にする
+	defaultSSLMode := "require"
+	if config.Postgres.Host == "localhost" {
+		defaultSSLMode = "disable"
+	}
+	config.Postgres.SSLMode = getEnv("DB_SSLMODE", defaultSSLMode)
+	config.Postgres.SSLRootCert = os.Getenv("DB_SSLROOTCERT")
+	config.Postgres.ApplicationName = getEnv("DB_APPLICATION_NAME", "echo-playground-v2")
+	config.Postgres.ConnectTimeout = getEnvDuration("DB_CONNECT_TIMEOUT", 5*time.Second)
+	config.Postgres.StatementTimeout = getEnvDuration("DB_STATEMENT_TIMEOUT", 30*time.Second)
+
+	config.Pool.MaxOpenConns = getEnvInt("DB_MAX_OPEN_CONNS", 25)
+	config.Pool.MaxIdleConns = getEnvInt("DB_MAX_IDLE_CONNS", 5)
+	config.Pool.ConnMaxLifetime = getEnvDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute)
+	config.Pool.ConnMaxIdleTime = getEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute)
+
+	config.Retry.MaxAttempts = getEnvInt("DB_CONNECT_MAX_ATTEMPTS", 10)
+	config.Retry.InitialInterval = getEnvDuration("DB_CONNECT_RETRY_INTERVAL", 500*time.Millisecond)
+	config.Retry.MaxInterval = getEnvDuration("DB_CONNECT_RETRY_MAX_INTERVAL", 10*time.Second)
+
+	config.MigrateOnStart = isTruthy(os.Getenv("DB_MIGRATE_ON_START"))
+	config.SeedOnStart = isTruthy(os.Getenv("DB_SEED_ON_START"))
+
 	return config
 }
+
+// parseAPIKeys "サービス名:キー"をカンマ区切りで並べた値をキーとサービス名の対応に変換する
+func parseAPIKeys(value string) map[string]string {
+	keys := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		service, key, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || service == "" || key == "" {
+			if entry != "" {
+				log.Printf("[WARN] invalid entry in SBCNTR_API_KEYS, expected service:key")
+			}
+			continue
+		}
+		keys[key] = service
+	}
+	return keys
+}
+
+// isTruthy 環境変数の値が"true"または"1"であるかを判定する
+func isTruthy(value string) bool {
+	return strings.ToLower(value) == "true" || value == "1"
+}
+
+// getEnv 環境変数の値を返す（未設定の場合はdefaultValue）
+func getEnv(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvInt 環境変数の値を整数として返す（未設定・不正な値の場合はdefaultValue）
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("[WARN] invalid value for %s: %q, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// getEnvDuration 環境変数の値を"30s"や"5m"形式の時間として返す（未設定・不正な値の場合はdefaultValue）
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("[WARN] invalid value for %s: %q, using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}